@@ -0,0 +1,10 @@
+package main
+
+import (
+	"coral-lang/src/driver"
+	"os"
+)
+
+func main() {
+	os.Exit(driver.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
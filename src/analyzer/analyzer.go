@@ -2,8 +2,11 @@ package analyzer
 
 import (
 	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
 	. "coral-lang/src/lexer"
 	. "coral-lang/src/parser"
+	"fmt"
+	"go/constant"
 )
 
 const (
@@ -13,8 +16,18 @@ const (
 )
 
 type Symbol struct {
-	Token *Token // 符号相应 token
+	Token   *Token // 符号相应 token，内建符号为 nil
+	Package string // 定义在声明了包名的文件顶层的符号所属的包，其余为空
 }
+
+// 以包名限定的符号名称，如 shapes.Circle；不属于任何包时即符号名称
+func (symbol *Symbol) QualifiedName() string {
+	if symbol.Package == "" {
+		return symbol.Token.Str
+	}
+	return symbol.Package + "." + symbol.Token.Str
+}
+
 type ISymbol interface {
 	GetToken() *Token
 	GetKind() int
@@ -23,7 +36,13 @@ type ISymbol interface {
 // 标识符号表
 type IdSymbol struct {
 	*Symbol
-	Type *TypeSymbol // 符号所属的类型
+	Type      Type           // 符号的类型，尚未推断出时为 nil
+	Constant  constant.Value // 以常量初始化的 val 的值，其余符号为 nil
+	Immutable bool           // 以 val 定义的符号，不能再被赋值
+	Function  bool           // 以 fn 定义的函数与方法，同样不能被赋值
+	Owner     *TypeSymbol    // 类与接口的成员所属的类型，其余符号为 nil
+	Private   bool           // 只能在所属的类中访问的成员
+	Static    bool           // 属于类本身的成员，通过类名访问
 }
 
 func (idSymbol *IdSymbol) GetToken() *Token {
@@ -36,10 +55,11 @@ func (idSymbol *IdSymbol) GetKind() int {
 // 类型符号表
 type TypeSymbol struct {
 	*Symbol
-	IsFn        bool            // @private 是否为函数
-	Signature   *Signature      // @private 函数签名
+	Type        Type            // 此符号所代表的类型
 	Description TypeDescription // 类型描述
 	DescType    int             // 类型描述的枚举
+	Declaration Statement       // 定义此类型的类、接口语句，其余情况为 nil
+	Members     *BlockScope     // 类、接口的成员作用域
 }
 
 func (typeSymbol *TypeSymbol) GetToken() *Token {
@@ -49,6 +69,24 @@ func (typeSymbol *TypeSymbol) GetKind() int {
 	return TypeSymbolKind
 }
 
+// 创建函数的标识符号，其函数类型在签名解析之后才能确定
+func NewFunctionSymbol(name *Token) *IdSymbol {
+	return &IdSymbol{Symbol: &Symbol{Token: name}, Function: true}
+}
+
+// 创建由类或接口定义的类型符号
+func NewDeclaredTypeSymbol(definition *ClassIdentifier, declaration Statement) *TypeSymbol {
+	typeSymbol := &TypeSymbol{
+		Symbol:      &Symbol{Token: definition.Name.Token},
+		Description: &TypeName{Span: definition.Name.Span, Identifier: definition.Name},
+		DescType:    TypeDescriptionTypeName,
+		Declaration: declaration,
+	}
+	_, isInterface := declaration.(*InterfaceDeclarationStatement)
+	typeSymbol.Type = &ClassType{Symbol: typeSymbol, IsInterface: isInterface, TypeParams: newTypeParams(definition.Generics)}
+	return typeSymbol
+}
+
 // 枚举符号
 type EnumSymbol struct {
 	*Symbol
@@ -71,21 +109,78 @@ type BlockScope struct {
 	SymbolMap map[string]ISymbol
 }
 
+func NewBlockScope(outer *BlockScope) *BlockScope {
+	return &BlockScope{
+		OuterScope: outer,
+		SymbolMap:  make(map[string]ISymbol),
+	}
+}
+
+// 由内向外逐层查找符号，找不到则返回 nil
+func (scope *BlockScope) Lookup(name string) ISymbol {
+	for current := scope; current != nil; current = current.OuterScope {
+		if symbol, ok := current.SymbolMap[name]; ok {
+			return symbol
+		}
+	}
+	return nil
+}
+
 type Analyzer struct {
 	parser *Parser // @private 语法解析器
 
-	RootScope    *BlockScope // 顶层区块
-	CurrentScope *BlockScope // 遍历区块层级时的指针
-	Ast          *Program    // AST
+	BuiltinScope *BlockScope                   // 内建符号所在的区块，是顶层区块的外层
+	RootScope    *BlockScope                   // 顶层区块
+	CurrentScope *BlockScope                   // 遍历区块层级时的指针
+	Ast          *Program                      // AST
+	Errors       []*CoralCompileError          // 语法解析与语义分析过程中的所有错误
+	Diagnostics  []*Diagnostic                 // 语法解析与语义分析过程中带位置的错误与警告
+	Types        map[Expression]Type           // 类型检查得出的每个表达式的类型
+	Constants    map[Expression]constant.Value // 编译期求出的常量表达式的值
+	Symbols      map[Node]ISymbol              // 每个函数、方法、类、接口、变量定义与 catch 所创建的符号，以及引入语句所引入的符号
+	Imports      map[Node]*LoadedModule        // 每个引入语句所加载的模块
+	Loader       *ModuleLoader                 // 加载引入的模块，为 nil 时在首次引入时创建
+	Package      string                        // 源文件以 package 语句声明的包名，没有声明时为空
+
+	// 以下只记录此文件本身，不与引入的模块共用，供编辑器按位置查找符号
+	References  map[*Identifier]ISymbol // 每个以名称引用符号的标识符所指的符号，包括类型名与成员名
+	Definitions map[*Token]ISymbol      // 每个定义在作用域中的符号，以定义它的 Token 为键
+	Scopes      map[Node]*BlockScope    // 区块、函数体、类与接口等所在的作用域，其中可见的符号即由此查找
+
+	currentClass    *TypeSymbol   // @private 正在检查的类
+	inStatic        bool          // @private 正在检查静态方法或静态成员变量的初始值，其中不能使用 this 与实例成员
+	currentFunction *FunctionType // @private 正在检查的函数，用于检查返回语句
+	catching        [][]Type      // @private 当前函数中由内向外包围着正在检查的语句的各个 try 语句所捕获的异常类型
+
+	signatureScopes map[*Signature]*BlockScope // @private 函数签名中的泛型参数与形参所在的作用域，函数体也在其中检查
+	resolving       bool                       // @private 正在解析函数签名与类、接口的成员
+	deferredChecks  []func()                   // @private 解析声明时推迟的检查，在全部声明解析完毕后进行
 }
 
 func (analyzer *Analyzer) InitAnalyzerCommon() {
-	analyzer.Ast = analyzer.parser.ParseProgram() // 获取抽象语法树
-	rootScope := new(BlockScope)
-	rootScope.SymbolMap = make(map[string]ISymbol)
+	analyzer.Ast, analyzer.Errors = analyzer.parser.ParseProgram() // 获取抽象语法树
+	analyzer.Diagnostics = append(analyzer.Diagnostics, analyzer.parser.Diagnostics...)
+	analyzer.initScopes()
+}
 
-	analyzer.RootScope = rootScope
+// 以空程序初始化，之后由 CheckStatements 逐次检查新输入的语句，供交互式环境使用
+func (analyzer *Analyzer) InitAnalyzerIncremental() {
+	analyzer.parser = new(Parser)
+	analyzer.Ast = new(Program)
+	analyzer.initScopes()
+}
+func (analyzer *Analyzer) initScopes() {
+	analyzer.BuiltinScope = NewBuiltinScope()
+	analyzer.RootScope = NewBlockScope(analyzer.BuiltinScope)
 	analyzer.CurrentScope = analyzer.RootScope
+	analyzer.Types = make(map[Expression]Type)
+	analyzer.Constants = make(map[Expression]constant.Value)
+	analyzer.Symbols = make(map[Node]ISymbol)
+	analyzer.Imports = make(map[Node]*LoadedModule)
+	analyzer.signatureScopes = make(map[*Signature]*BlockScope)
+	analyzer.References = make(map[*Identifier]ISymbol)
+	analyzer.Definitions = make(map[*Token]ISymbol)
+	analyzer.Scopes = make(map[Node]*BlockScope)
 }
 func (analyzer *Analyzer) InitAnalyzerFromString(content string) {
 	parser := new(Parser)
@@ -99,11 +194,133 @@ func (analyzer *Analyzer) InitAnalyzerFromBytes(content []byte) {
 	analyzer.parser = parser
 	analyzer.InitAnalyzerCommon()
 }
+
+// 使用一个已经初始化好的语法解析器，调用方可借此设置文件名等信息
+func (analyzer *Analyzer) InitAnalyzerFromParser(parser *Parser) {
+	analyzer.parser = parser
+	analyzer.InitAnalyzerCommon()
+}
+func (analyzer *Analyzer) InitAnalyzerFromFile(filePath string) *CoralCompileError {
+	parser := new(Parser)
+	if err := parser.InitFromFile(filePath); err != nil {
+		return err
+	}
+	analyzer.parser = parser
+	analyzer.InitAnalyzerCommon()
+	return nil
+}
+func (analyzer *Analyzer) GetParser() *Parser {
+	return analyzer.parser
+}
+
+// 对整个程序的顶层语句逐条进行语义检查，返回包括语法错误在内的全部错误
+func (analyzer *Analyzer) CheckProgram() []*CoralCompileError {
+	loader := analyzer.ModuleLoader()
+	module := loader.enter(analyzer)
+	analyzer.CheckStatementList(analyzer.Ast.Root)
+	loader.leave(module)
+	return analyzer.Errors
+}
+
+// 在已有的顶层区块中检查一组新解析出的语句，返回本次检查的全部错误
+// 没有错误时语句追加到 Ast 中；有错误时撤销本次定义的顶层符号，使得修正后可以重新输入
+func (analyzer *Analyzer) CheckStatements(parser *Parser, stmts []Statement) []*CoralCompileError {
+	analyzer.parser = parser
+	analyzer.Errors = nil
+	analyzer.Diagnostics = nil
+	analyzer.CurrentScope = analyzer.RootScope
+
+	defined := make(map[string]bool, len(analyzer.RootScope.SymbolMap))
+	for name := range analyzer.RootScope.SymbolMap {
+		defined[name] = true
+	}
+	analyzer.CheckStatementList(stmts)
+	if len(analyzer.Errors) > 0 {
+		for name := range analyzer.RootScope.SymbolMap {
+			if !defined[name] {
+				delete(analyzer.RootScope.SymbolMap, name)
+			}
+		}
+		return analyzer.Errors
+	}
+	analyzer.Ast.Root = append(analyzer.Ast.Root, stmts...)
+	return nil
+}
 func (analyzer *Analyzer) EnterNewBlockScope() {
-	newScope := new(BlockScope)
-	newScope.OuterScope = analyzer.CurrentScope
-	analyzer.CurrentScope = newScope
+	analyzer.CurrentScope = NewBlockScope(analyzer.CurrentScope)
+}
+func (analyzer *Analyzer) EnterBlockScope(scope *BlockScope) {
+	analyzer.CurrentScope = scope
 }
 func (analyzer *Analyzer) LeaveCurrentBlockScope() {
 	analyzer.CurrentScope = analyzer.CurrentScope.OuterScope
 }
+
+// 记下 node 所在的作用域即当前区块
+func (analyzer *Analyzer) recordScope(node Node) {
+	analyzer.Scopes[node] = analyzer.CurrentScope
+}
+
+// 报告一个语义错误并标出相应的范围，返回诊断信息以便追加说明
+func (analyzer *Analyzer) ReportError(span Span, err *CoralCompileError) *Diagnostic {
+	analyzer.Errors = append(analyzer.Errors, err)
+	diagnostic := NewDiagnostic(analyzer.parser.FileName, toPosition(span.Start), toPosition(span.End), err)
+	analyzer.Diagnostics = append(analyzer.Diagnostics, diagnostic)
+	return diagnostic
+}
+
+// 在当前区块中定义一个符号，同一区块中重名时报错并指出此前的定义
+func (analyzer *Analyzer) DefineSymbol(token *Token, symbol ISymbol) bool {
+	if defined, ok := analyzer.CurrentScope.SymbolMap[token.Str]; ok {
+		diagnostic := analyzer.ReportError(TokenSpan(token), NewCoralError("Compile",
+			fmt.Sprintf("\"%s\" is already defined in this scope!", token.Str), DuplicateDefinition))
+		if definedToken := defined.GetToken(); definedToken != nil {
+			diagnostic.AddNote(analyzer.parser.FileName, toPosition(definedToken.Start), toPosition(definedToken.End),
+				fmt.Sprintf("previous definition of \"%s\" is here", token.Str))
+		}
+		return false
+	}
+	analyzer.CurrentScope.SymbolMap[token.Str] = symbol
+	analyzer.Definitions[token] = symbol
+	return true
+}
+
+// 查找标识符所指的符号，未定义时报错
+func (analyzer *Analyzer) ResolveIdentifier(identifier *Identifier) ISymbol {
+	symbol := analyzer.lookupName(identifier.GetName())
+	if symbol == nil {
+		analyzer.ReportError(identifier.Span, NewCoralError("Compile",
+			fmt.Sprintf("undeclared identifier \"%s\"!", identifier.GetName()), UndeclaredIdentifier))
+		return nil
+	}
+	analyzer.References[identifier] = symbol
+	return symbol
+}
+
+// 由内向外查找名称；在类的方法中，继承自父类的成员位于类自身的成员与外层区块之间
+// 构造方法与类同名，但在类中以类名指的总是类本身
+func (analyzer *Analyzer) lookupName(name string) ISymbol {
+	for scope := analyzer.CurrentScope; scope != nil; scope = scope.OuterScope {
+		if symbol, ok := scope.SymbolMap[name]; ok && !isConstructor(symbol) {
+			return symbol
+		}
+		if analyzer.currentClass != nil && scope == analyzer.currentClass.Members {
+			if base := analyzer.currentClass.Type.(*ClassType).Base; base != nil {
+				// 父类的私有成员在子类中不可见
+				if inherited, owner := base.MemberOf(name); inherited != nil && !inherited.Private {
+					return inheritedSymbol(inherited, owner)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func isConstructor(symbol ISymbol) bool {
+	member, isId := symbol.(*IdSymbol)
+	return isId && member.Owner != nil && member.Token.Str == member.Owner.Token.Str
+}
+
+func toPosition(pos Pos) Position {
+	return Position{Line: pos.Line, Col: pos.Col}
+}
@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	. "coral-lang/src/lexer"
+)
+
+// 基本类型的种类
+const (
+	BasicKindInteger = iota
+	BasicKindFloat
+	BasicKindRune
+	BasicKindBool
+	BasicKindString
+)
+
+// 内建的基本类型
+type BasicType struct {
+	Name   string
+	Kind   int         // 基本类型的种类
+	Width  int         // 值所占的字节数，String 为引用类型，记为 0
+	Signed bool        // 是否为有符号数
+	Zero   interface{} // 零值：有符号整数为 int64，无符号整数与 rune 为 uint64，浮点数为 float64
+}
+
+func (it *BasicType) TypeKind() int {
+	return TypeKindBasic
+}
+func (it *BasicType) String() string {
+	return it.Name
+}
+
+// 值所能表示的位数
+func (it *BasicType) Bits() int {
+	return it.Width * 8
+}
+
+// 基本类型，见 docs_src/types.md
+var (
+	IntType    = &BasicType{Name: "int", Kind: BasicKindInteger, Width: 4, Signed: true, Zero: int64(0)}
+	Int8Type   = &BasicType{Name: "int8", Kind: BasicKindInteger, Width: 1, Signed: true, Zero: int64(0)}
+	Int16Type  = &BasicType{Name: "int16", Kind: BasicKindInteger, Width: 2, Signed: true, Zero: int64(0)}
+	Int64Type  = &BasicType{Name: "int64", Kind: BasicKindInteger, Width: 8, Signed: true, Zero: int64(0)}
+	UintType   = &BasicType{Name: "uint", Kind: BasicKindInteger, Width: 4, Zero: uint64(0)}
+	Uint8Type  = &BasicType{Name: "uint8", Kind: BasicKindInteger, Width: 1, Zero: uint64(0)}
+	Uint16Type = &BasicType{Name: "uint16", Kind: BasicKindInteger, Width: 2, Zero: uint64(0)}
+	Uint64Type = &BasicType{Name: "uint64", Kind: BasicKindInteger, Width: 8, Zero: uint64(0)}
+	FloatType  = &BasicType{Name: "float", Kind: BasicKindFloat, Width: 4, Signed: true, Zero: float64(0)}
+	DoubleType = &BasicType{Name: "double", Kind: BasicKindFloat, Width: 8, Signed: true, Zero: float64(0)}
+	RuneType   = &BasicType{Name: "rune", Kind: BasicKindRune, Width: 2, Zero: uint64(0)} // 实际上等同于 uint16
+	BoolType   = &BasicType{Name: "bool", Kind: BasicKindBool, Width: 1, Zero: false}
+	StringType = &BasicType{Name: "String", Kind: BasicKindString, Zero: ""}
+)
+
+// 全部基本类型，按照预定义的顺序排列，供代码生成等后续阶段使用
+var BasicTypes = []*BasicType{
+	IntType, Int8Type, Int16Type, Int64Type,
+	UintType, Uint8Type, Uint16Type, Uint64Type,
+	FloatType, DoubleType, RuneType, BoolType, StringType,
+}
+
+// 内建函数：无需定义即可在任何地方直接调用
+var builtinFunctions = []string{
+	"print",
+	"println",
+	"printf",
+}
+
+// 内建的异常类：throw 语句抛出的值、catch 捕获的类型以及 throws 声明的类型都须是它或它的子类
+// 它有一个公开的只读字段 message，构造函数以 message 为唯一的参数
+var ExceptionType = newExceptionType()
+
+func newExceptionType() *ClassType {
+	typeSymbol := &TypeSymbol{
+		Symbol:  &Symbol{Token: &Token{Kind: TokenTypeIdentifier, Str: "Exception"}},
+		Members: NewBlockScope(nil),
+	}
+	exceptionType := &ClassType{Symbol: typeSymbol}
+	typeSymbol.Type = exceptionType
+	typeSymbol.Members.SymbolMap["message"] = &IdSymbol{
+		Symbol:    &Symbol{Token: &Token{Kind: TokenTypeIdentifier, Str: "message"}},
+		Type:      StringType,
+		Immutable: true,
+		Owner:     typeSymbol,
+	}
+	typeSymbol.Members.SymbolMap["Exception"] = &IdSymbol{
+		Symbol:   &Symbol{Token: &Token{Kind: TokenTypeIdentifier, Str: "Exception"}},
+		Type:     &FunctionType{Params: []Type{StringType}},
+		Function: true,
+		Owner:    typeSymbol,
+	}
+	return exceptionType
+}
+
+// 创建内建符号所在的区块，作为所有源文件顶层区块的外层
+// 基本类型与内建函数都定义在其中，因而可以被源文件中的定义遮蔽
+func NewBuiltinScope() *BlockScope {
+	scope := NewBlockScope(nil)
+	for _, basicType := range BasicTypes {
+		scope.SymbolMap[basicType.Name] = &TypeSymbol{
+			Symbol: &Symbol{},
+			Type:   basicType,
+		}
+	}
+	scope.SymbolMap["Exception"] = ExceptionType.Symbol
+	for _, name := range builtinFunctions {
+		scope.SymbolMap[name] = &IdSymbol{
+			Symbol:   &Symbol{},
+			Type:     &FunctionType{Variadic: true},
+			Function: true,
+		}
+	}
+	return scope
+}
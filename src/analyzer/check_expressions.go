@@ -0,0 +1,611 @@
+package analyzer
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/lexer"
+	"fmt"
+)
+
+// 检查表达式并返回其类型，类型同时记录在 analyzer.Types 中
+func (analyzer *Analyzer) CheckExpression(expression Expression) Type {
+	if expression == nil {
+		return Unknown
+	}
+	var exprType Type
+	switch expression.ExpressionNodeType() {
+	case ExpressionTypePrimary:
+		exprType = analyzer.CheckPrimaryExpression(expression.(PrimaryExpression))
+	case ExpressionTypeNewInstance:
+		exprType = analyzer.CheckNewInstanceExpression(expression.(*NewInstanceExpression))
+	case ExpressionTypeUnary:
+		exprType = analyzer.CheckUnaryExpression(expression.(*UnaryExpression))
+	case ExpressionTypeBinary:
+		exprType = analyzer.CheckBinaryExpression(expression.(*BinaryExpression))
+	case ExpressionTypeRange:
+		exprType = analyzer.CheckRangeExpression(expression.(*RangeExpression))
+	case ExpressionTypeCast:
+		exprType = analyzer.CheckCastExpression(expression.(*CastExpression))
+	}
+	if exprType == nil {
+		exprType = Unknown
+	}
+	analyzer.Types[expression] = exprType
+	if value := analyzer.EvaluateConstant(expression, exprType); value != nil {
+		analyzer.Constants[expression] = value
+	}
+	return exprType
+}
+
+// 检查用作单个值的表达式：没有返回值或者有多个返回值的函数调用都不能作为值使用
+func (analyzer *Analyzer) CheckValueExpression(expression Expression) Type {
+	return analyzer.valueType(expression, analyzer.CheckExpression(expression))
+}
+func (analyzer *Analyzer) valueType(expression Expression, exprType Type) Type {
+	switch exprType.TypeKind() {
+	case TypeKindVoid:
+		analyzer.ReportTypeError(expression, TypeMismatch, "expression has no value but is used as a value!")
+		return Unknown
+	case TypeKindTuple:
+		analyzer.ReportTypeError(expression, ValueCountMismatch, "multiple-value %s in single-value context!", exprType)
+		return Unknown
+	case TypeKindStatic:
+		analyzer.ReportTypeError(expression, TypeMismatch, "type %s is not an expression!", exprType.(*StaticType).Class)
+		return Unknown
+	}
+	return exprType
+}
+
+// 检查一组用作值的表达式，若仅有一个多返回值的函数调用，则将其展开为多个值
+// 返回每个值对应的表达式（展开时都对应同一个调用）与类型，常量表达式会被折叠为字面量
+func (analyzer *Analyzer) CheckExpressionList(expressions []Expression) ([]Expression, []Type) {
+	if len(expressions) == 1 {
+		exprType := analyzer.CheckExpression(expressions[0])
+		if tuple, isTuple := exprType.(*TupleType); isTuple {
+			values := make([]Expression, len(tuple.Types))
+			for i := range values {
+				values[i] = expressions[0]
+			}
+			return values, tuple.Types
+		}
+		exprType = analyzer.valueType(expressions[0], exprType)
+		expressions[0] = analyzer.FoldConstant(expressions[0])
+		return expressions, []Type{exprType}
+	}
+	var types []Type
+	for i, expression := range expressions {
+		types = append(types, analyzer.CheckValueExpression(expression))
+		expressions[i] = analyzer.FoldConstant(expression)
+	}
+	return expressions, types
+}
+
+// 检查赋值的目标：只有变量、成员变量与下标表达式可以被赋值，以 val 定义的变量与成员、函数与方法除外
+func (analyzer *Analyzer) CheckAssignTarget(target Expression) Type {
+	targetType := analyzer.CheckExpression(target)
+	if primary, isPrimary := target.(PrimaryExpression); isPrimary {
+		switch primary.PrimaryExpressionNode() {
+		case PrimaryExprTypeIndex:
+			return targetType
+		case PrimaryExprTypeMember:
+			name := lastMember(primary.(*MemberExpression)).It
+			if idSymbol, isId := analyzer.References[name].(*IdSymbol); isId {
+				if analyzer.CheckMutable(target, name.GetName(), idSymbol) {
+					return targetType
+				}
+				return Unknown
+			}
+			if IsUnknown(targetType) {
+				return Unknown // 成员链中的错误已经报过错
+			}
+			// 枚举元素、数组与字符串的 length 等不是变量
+			analyzer.ReportTypeError(target, InvalidOperation, "cannot assign to \"%s\", it is not a variable!", name.GetName())
+			return Unknown
+		case PrimaryExprTypeBasic:
+			if operandName, isName := primary.(*BasicPrimaryExpression).It.(*OperandName); isName {
+				if idSymbol, isId := analyzer.CurrentScope.Lookup(operandName.GetFullName()).(*IdSymbol); isId {
+					if analyzer.CheckMutable(target, operandName.GetFullName(), idSymbol) {
+						return targetType
+					}
+					return Unknown
+				}
+				if IsUnknown(targetType) {
+					return Unknown // 未定义的标识符已经报过错
+				}
+			}
+		}
+	}
+	analyzer.ReportTypeError(target, InvalidOperation, "cannot assign to this expression!")
+	return Unknown
+}
+
+// 对以 val 定义的符号或者函数、方法赋值时报错，并指出其定义的位置
+func (analyzer *Analyzer) CheckMutable(target Expression, name string, idSymbol *IdSymbol) bool {
+	if !idSymbol.Immutable && !idSymbol.Function {
+		return true
+	}
+	message := fmt.Sprintf("cannot assign to \"%s\", it is declared by val!", name)
+	if idSymbol.Function {
+		message = fmt.Sprintf("cannot assign to function \"%s\"!", name)
+	}
+	diagnostic := analyzer.ReportError(*target.GetSpan(), NewCoralError("Compile", message, ImmutableAssignment))
+	// 内建的函数与内建类的成员没有定义的位置
+	if idSymbol.Token != nil && (idSymbol.Owner == nil || idSymbol.Owner.Declaration != nil) {
+		diagnostic.AddNote(analyzer.parser.FileName, toPosition(idSymbol.Token.Start), toPosition(idSymbol.Token.End),
+			fmt.Sprintf("\"%s\" is declared here", name))
+	}
+	return false
+}
+
+// 成员链上的最后一个成员，即赋值时被写入的成员
+func lastMember(memberExpr *MemberExpression) *MemberLinkNode {
+	member := memberExpr.Member
+	for member.MemberNext != nil {
+		member = member.MemberNext
+	}
+	return member
+}
+
+// 查找类的成员符号（包括继承的成员）及定义它的类，类型不是类或没有该成员时返回 nil
+// 泛型参数的成员即其约束的成员
+func classMember(ownerType Type, name string) (*IdSymbol, *ClassType) {
+	switch ownerType := ownerType.(type) {
+	case *ClassType:
+		return ownerType.MemberOf(name)
+	case *StaticType:
+		return ownerType.Class.MemberOf(name)
+	case *TypeParamType:
+		if ownerType.Constraint != nil {
+			return ownerType.Constraint.MemberOf(name)
+		}
+	}
+	return nil, nil
+}
+
+func (analyzer *Analyzer) CheckPrimaryExpression(primaryExpr PrimaryExpression) Type {
+	switch primaryExpr.PrimaryExpressionNode() {
+	case PrimaryExprTypeBasic:
+		return analyzer.CheckOperand(primaryExpr.(*BasicPrimaryExpression).It)
+	case PrimaryExprTypeIndex:
+		return analyzer.CheckIndexExpression(primaryExpr.(*IndexExpression))
+	case PrimaryExprTypeSlice:
+		return analyzer.CheckSliceExpression(primaryExpr.(*SliceExpression))
+	case PrimaryExprTypeCall:
+		return analyzer.CheckCallExpression(primaryExpr.(*CallExpression))
+	case PrimaryExprTypeMember:
+		return analyzer.CheckMemberExpression(primaryExpr.(*MemberExpression))
+	}
+	return Unknown
+}
+
+// 下标必须为整数；表的下标则为字符串键
+func (analyzer *Analyzer) CheckIndexExpression(indexExpr *IndexExpression) Type {
+	operandType := analyzer.CheckValueExpression(indexExpr.Operand)
+	indexType := analyzer.CheckValueExpression(indexExpr.Index)
+	switch operandType.TypeKind() {
+	case TypeKindArray:
+		analyzer.CheckIntegerIndex(indexExpr.Index, indexType)
+		return operandType.(*ArrayType).Element
+	case TypeKindTable:
+		analyzer.CheckAssignable(indexExpr.Index, indexType, StringType, "table index")
+		return operandType.(*TableType).Value
+	case TypeKindUnknown:
+		return Unknown
+	}
+	if operandType == StringType {
+		analyzer.CheckIntegerIndex(indexExpr.Index, indexType)
+		return RuneType
+	}
+	analyzer.ReportTypeError(indexExpr.Operand, InvalidOperation, "cannot index value of type %s!", operandType)
+	return Unknown
+}
+func (analyzer *Analyzer) CheckIntegerIndex(index Expression, indexType Type) {
+	if !IsUnknown(indexType) && !IsIntegerType(indexType) {
+		analyzer.ReportTypeError(index, TypeMismatch, "index must be an integer, got %s!", indexType)
+	}
+}
+
+// 切片只能作用于数组与字符串，结果的类型与原值相同
+func (analyzer *Analyzer) CheckSliceExpression(sliceExpr *SliceExpression) Type {
+	operandType := analyzer.CheckValueExpression(sliceExpr.Operand)
+	for _, position := range []Expression{sliceExpr.Start, sliceExpr.End} {
+		if position != nil {
+			analyzer.CheckIntegerIndex(position, analyzer.CheckValueExpression(position))
+		}
+	}
+	if IsUnknown(operandType) || operandType.TypeKind() == TypeKindArray || operandType == StringType {
+		return operandType
+	}
+	analyzer.ReportTypeError(sliceExpr.Operand, InvalidOperation, "cannot slice value of type %s!", operandType)
+	return Unknown
+}
+
+func (analyzer *Analyzer) CheckCallExpression(callExpr *CallExpression) Type {
+	calleeType := analyzer.CheckValueExpression(callExpr.Operand)
+	if baseType, isClass := calleeType.(*ClassType); isClass && isSuperOperand(callExpr.Operand) {
+		return analyzer.CheckSuperCall(callExpr, baseType)
+	}
+	if calleeType.TypeKind() != TypeKindFunction {
+		analyzer.CheckExpressionList(callExpr.Params)
+		if moduleType, isModule := calleeType.(*ModuleType); isModule && moduleType.Module == nil {
+			return Unknown // 加载失败的模块已经报过错
+		}
+		if !IsUnknown(calleeType) {
+			analyzer.ReportTypeError(callExpr.Operand, InvalidOperation, "cannot call non-function value of type %s!", calleeType)
+		}
+		return Unknown
+	}
+	fnType := calleeType.(*FunctionType)
+	if len(fnType.TypeParams) > 0 {
+		// 泛型函数先由实参推断泛型实参，再以代入后的签名检查实参
+		args, argTypes := analyzer.CheckExpressionList(callExpr.Params)
+		fnType = analyzer.InferTypeArguments(callExpr, fnType, argTypes)
+		analyzer.checkArgumentTypes(callExpr, args, argTypes, fnType)
+	} else {
+		analyzer.CheckArguments(callExpr, callExpr.Params, fnType)
+	}
+	analyzer.CheckThrows(callExpr, fnType.Throws)
+	return fnType.Result()
+}
+
+// 检查实参的个数与类型是否与函数签名一致
+func (analyzer *Analyzer) CheckArguments(call Node, params []Expression, fnType *FunctionType) {
+	args, argTypes := analyzer.CheckExpressionList(params)
+	analyzer.checkArgumentTypes(call, args, argTypes, fnType)
+}
+func (analyzer *Analyzer) checkArgumentTypes(call Node, args []Expression, argTypes []Type, fnType *FunctionType) {
+	if fnType.Variadic {
+		return
+	}
+	if len(argTypes) != len(fnType.Params) {
+		analyzer.ReportTypeError(call, ValueCountMismatch, "wrong number of arguments in call: expected %d, got %d!",
+			len(fnType.Params), len(argTypes))
+		return
+	}
+	for i, paramType := range fnType.Params {
+		analyzer.CheckAssignable(args[i], argTypes[i], paramType, "argument")
+	}
+}
+
+// 逐个访问成员链上的成员
+func (analyzer *Analyzer) CheckMemberExpression(memberExpr *MemberExpression) Type {
+	// 类名只能用于访问静态成员，此处不视为取值
+	currentType := analyzer.CheckExpression(memberExpr.Operand)
+	if currentType.TypeKind() != TypeKindStatic {
+		currentType = analyzer.valueType(memberExpr.Operand, currentType)
+	}
+	for member := memberExpr.Member; member != nil; member = member.MemberNext {
+		currentType = analyzer.LookupMember(currentType, member.It)
+	}
+	return currentType
+}
+
+// 查找类型的成员并返回其类型；数组与字符串只有 length 一个成员
+func (analyzer *Analyzer) LookupMember(ownerType Type, name *Identifier) Type {
+	switch ownerType.TypeKind() {
+	case TypeKindUnknown:
+		return Unknown
+	case TypeKindModule:
+		return analyzer.LookupModuleMember(ownerType.(*ModuleType), name)
+	case TypeKindClass, TypeKindStatic, TypeKindTypeParam:
+		if memberSymbol, owner := classMember(ownerType, name.GetName()); memberSymbol != nil {
+			analyzer.References[name] = memberSymbol
+			analyzer.CheckMemberAccess(name, memberSymbol, ownerType.TypeKind() == TypeKindStatic)
+			if memberSymbol.Type == nil {
+				return Unknown
+			}
+			return memberType(memberSymbol, owner)
+		}
+		if classType, isClass := ownerType.(*ClassType); isClass && classType.Symbol.Members == nil {
+			return Unknown
+		}
+	case TypeKindEnum:
+		if _, ok := ownerType.(*EnumType).Symbol.ElementsMap[name.GetName()]; ok {
+			return ownerType
+		}
+	case TypeKindArray:
+		if name.GetName() == "length" {
+			return IntType
+		}
+	default:
+		if ownerType == StringType && name.GetName() == "length" {
+			return IntType
+		}
+	}
+	analyzer.ReportTypeError(name, UndeclaredIdentifier, "type %s has no member \"%s\"!", ownerType, name.GetName())
+	return Unknown
+}
+
+func (analyzer *Analyzer) CheckOperand(operand Operand) Type {
+	switch operand.OperandNodeType() {
+	case OperandTypeName:
+		symbol := analyzer.ResolveIdentifier(operand.(*OperandName).Name)
+		if symbol == nil {
+			return Unknown
+		}
+		if member, isId := symbol.(*IdSymbol); isId && member.Owner != nil && !member.Static && analyzer.inStatic {
+			analyzer.ReportTypeError(operand, StaticMemberAccess, "cannot use instance member \"%s\" in a static context!",
+				member.Token.Str)
+		}
+		return analyzer.SymbolType(operand, symbol)
+	case OperandTypeLiteral:
+		return analyzer.CheckLiteral(operand.(Literal))
+	}
+	return Unknown
+}
+
+// 以名称引用符号时的类型
+func (analyzer *Analyzer) SymbolType(node Node, symbol ISymbol) Type {
+	switch symbol.GetKind() {
+	case IdentifierSymbolKind:
+		if idType := symbol.(*IdSymbol).Type; idType != nil {
+			return idType
+		}
+	case TypeSymbolKind:
+		// 以类型名作为值：访问类的静态成员，基本类型则没有成员可访问
+		typeSymbol := symbol.(*TypeSymbol)
+		if typeSymbol.Type.TypeKind() == TypeKindBasic {
+			analyzer.ReportTypeError(node, TypeMismatch, "type %s is not an expression!", typeSymbol.Type)
+			return Unknown
+		}
+		if classType, isClass := typeSymbol.Type.(*ClassType); isClass {
+			return &StaticType{Class: classType}
+		}
+		return typeSymbol.Type
+	case EnumSymbolKind:
+		return &EnumType{Symbol: symbol.(*EnumSymbol)}
+	}
+	return Unknown
+}
+
+func (analyzer *Analyzer) CheckLiteral(literal Literal) Type {
+	switch literal.LiteralNodeType() {
+	case LiteralNodeTypeNil:
+		return Nil
+	case LiteralNodeTypeTrue, LiteralNodeTypeFalse:
+		return BoolType
+	case LiteralNodeTypeDecimal, LiteralNodeTypeHexadecimal, LiteralNodeTypeOctal, LiteralNodeTypeBinary:
+		return UntypedInt
+	case LiteralNodeTypeFloat:
+		// 小数位数超过 float 的精度时默认为 double
+		if literal.(*FloatLit).Accuracy > 6 {
+			return UntypedDoubleFloat
+		}
+		return UntypedFloat
+	case LiteralNodeTypeExponent:
+		return UntypedDoubleFloat
+	case LiteralNodeTypeChar:
+		return RuneType
+	case LiteralNodeTypeString:
+		return StringType
+	case LiteralNodeTypeArray:
+		return &ArrayType{Element: analyzer.CheckElementTypes(literal.(*ArrayLit).ValueList)}
+	case LiteralNodeTypeMap:
+		var values []Expression
+		for _, element := range literal.(*TableLit).KeyValueList {
+			values = append(values, element.Value)
+		}
+		return &TableType{Value: analyzer.CheckElementTypes(values)}
+	case LiteralNodeTypeLambda:
+		return analyzer.CheckLambdaLiteral(literal.(*LambdaLit))
+	case LiteralNodeTypeThis, LiteralNodeTypeSuper:
+		return analyzer.CheckThisOrSuper(literal)
+	}
+	return Unknown
+}
+
+// 数组、表字面量中的元素需要有相容的类型
+func (analyzer *Analyzer) CheckElementTypes(values []Expression) Type {
+	var elementType Type = Unknown
+	for i, value := range values {
+		valueType := analyzer.CheckValueExpression(value)
+		if i == 0 {
+			elementType = valueType
+			continue
+		}
+		if unified := unifyTypes(elementType, valueType); unified != nil {
+			elementType = unified
+		} else {
+			analyzer.ReportTypeError(value, TypeMismatch, "mismatched element types %s and %s in literal!", elementType, valueType)
+			elementType = Unknown
+		}
+	}
+	return elementType
+}
+
+// lambda 以表达式为结果且没有声明返回值时，其返回值类型即表达式的类型
+func (analyzer *Analyzer) CheckLambdaLiteral(lambda *LambdaLit) Type {
+	fnType := analyzer.ResolveSignature(lambda.Signature)
+	if block, isBlock := lambda.Result.(*BlockStatement); isBlock {
+		analyzer.CheckFunctionBody(lambda.Signature, fnType, block)
+		return fnType
+	}
+
+	outerFunction, outerCatching := analyzer.currentFunction, analyzer.catching
+	analyzer.currentFunction, analyzer.catching = fnType, nil
+	analyzer.EnterBlockScope(analyzer.signatureScopes[lambda.Signature])
+	analyzer.recordScope(lambda)
+	if result, isExpression := lambda.Result.(Expression); isExpression {
+		resultType := analyzer.CheckExpression(result)
+		switch {
+		case len(fnType.Returns) == 0 && resultType.TypeKind() != TypeKindVoid:
+			fnType.Returns = []Type{DefaultType(resultType)}
+		case len(fnType.Returns) == 1:
+			analyzer.CheckAssignable(result, resultType, fnType.Returns[0], "lambda result")
+		}
+	}
+	analyzer.LeaveCurrentBlockScope()
+	analyzer.currentFunction, analyzer.catching = outerFunction, outerCatching
+	return fnType
+}
+
+// 实例化类并以构造方法检查实参
+func (analyzer *Analyzer) CheckNewInstanceExpression(newInstanceExpr *NewInstanceExpression) Type {
+	instanceType := analyzer.ResolveType(newInstanceExpr.Class)
+	classType, isClass := instanceType.(*ClassType)
+	if !isClass || classType.IsInterface {
+		analyzer.CheckExpressionList(newInstanceExpr.InitParams)
+		if !IsUnknown(instanceType) {
+			analyzer.ReportTypeError(newInstanceExpr.Class, InvalidOperation, "cannot create an instance of %s!", instanceType)
+		}
+		return Unknown
+	}
+
+	constructor := constructorType(classType)
+	analyzer.CheckArguments(newInstanceExpr, newInstanceExpr.InitParams, constructor)
+	analyzer.CheckThrows(newInstanceExpr, constructor.Throws)
+	return instanceType
+}
+
+func (analyzer *Analyzer) CheckUnaryExpression(unaryExpr *UnaryExpression) Type {
+	operandType := analyzer.CheckValueExpression(unaryExpr.Operand)
+	unaryExpr.Operand = analyzer.FoldConstant(unaryExpr.Operand)
+	if IsUnknown(operandType) {
+		return Unknown
+	}
+	valid := false
+	switch unaryExpr.Operator.Kind {
+	case TokenTypeMinus:
+		valid = IsNumericType(operandType)
+	case TokenTypeBang:
+		valid = operandType == BoolType
+	case TokenTypeWavy:
+		valid = IsIntegerType(operandType)
+	}
+	if !valid {
+		analyzer.ReportTypeError(unaryExpr, InvalidOperation, "invalid operation: operator %s not defined on type %s!",
+			unaryExpr.Operator.Str, operandType)
+		return Unknown
+	}
+	if unaryExpr.Operator.Kind == TokenTypeBang {
+		return BoolType
+	}
+	return operandType
+}
+
+// 各个复合赋值运算符对应的二元运算符
+var compoundAssignOperators = map[TokenType]TokenType{
+	TokenTypePlusEqual:             TokenTypePlus,
+	TokenTypeMinusEqual:            TokenTypeMinus,
+	TokenTypeStarEqual:             TokenTypeStar,
+	TokenTypeSlashEqual:            TokenTypeSlash,
+	TokenTypePercentEqual:          TokenTypePercent,
+	TokenTypeDoubleLeftAngleEqual:  TokenTypeDoubleLeftAngle,
+	TokenTypeDoubleRightAngleEqual: TokenTypeDoubleRightAngle,
+	TokenTypeAmpersandEqual:        TokenTypeAmpersand,
+	TokenTypeVerticalEqual:         TokenTypeVertical,
+	TokenTypeCaretEqual:            TokenTypeCaret,
+}
+
+func (analyzer *Analyzer) CheckBinaryExpression(binaryExpr *BinaryExpression) Type {
+	operator := binaryExpr.Operator.Kind
+	if operator == TokenTypeEqual {
+		leftType := analyzer.CheckAssignTarget(binaryExpr.Left)
+		rightType := analyzer.CheckValueExpression(binaryExpr.Right)
+		binaryExpr.Right = analyzer.FoldConstant(binaryExpr.Right)
+		analyzer.CheckAssignable(binaryExpr.Right, rightType, leftType, "assignment")
+		return leftType
+	}
+	if binaryOperator, isCompound := compoundAssignOperators[operator]; isCompound {
+		leftType := analyzer.CheckAssignTarget(binaryExpr.Left)
+		rightType := analyzer.CheckValueExpression(binaryExpr.Right)
+		binaryExpr.Right = analyzer.FoldConstant(binaryExpr.Right)
+		resultType := analyzer.BinaryOperationType(binaryExpr, binaryOperator, leftType, rightType)
+		analyzer.CheckAssignable(binaryExpr, resultType, leftType, "assignment")
+		return leftType
+	}
+
+	leftType := analyzer.CheckValueExpression(binaryExpr.Left)
+	rightType := analyzer.CheckValueExpression(binaryExpr.Right)
+	binaryExpr.Left = analyzer.FoldConstant(binaryExpr.Left)
+	binaryExpr.Right = analyzer.FoldConstant(binaryExpr.Right)
+	return analyzer.BinaryOperationType(binaryExpr, operator, leftType, rightType)
+}
+
+// 计算二元运算的结果类型，操作数类型不符合运算符的要求时报错
+func (analyzer *Analyzer) BinaryOperationType(binaryExpr *BinaryExpression, operator TokenType, leftType, rightType Type) Type {
+	if IsUnknown(leftType) || IsUnknown(rightType) {
+		switch operator {
+		case TokenTypeDoubleEqual, TokenTypeBangEqual, TokenTypeLeftAngle, TokenTypeRightAngle,
+			TokenTypeLeftAngleEqual, TokenTypeRightAngleEqual, TokenTypeDoubleAmpersand, TokenTypeDoubleVertical:
+			return BoolType
+		}
+		return Unknown
+	}
+
+	switch operator {
+	case TokenTypeDoubleAmpersand, TokenTypeDoubleVertical:
+		if leftType == BoolType && rightType == BoolType {
+			return BoolType
+		}
+	case TokenTypeDoubleEqual, TokenTypeBangEqual:
+		if unifyTypes(leftType, rightType) != nil || AssignableTo(leftType, rightType) || AssignableTo(rightType, leftType) {
+			return BoolType
+		}
+	case TokenTypeLeftAngle, TokenTypeRightAngle, TokenTypeLeftAngleEqual, TokenTypeRightAngleEqual:
+		operandType := unifyTypes(leftType, rightType)
+		if operandType != nil && (IsNumericType(operandType) || operandType == StringType) {
+			return BoolType
+		}
+	case TokenTypePlus:
+		if leftType == StringType && rightType == StringType {
+			return StringType
+		}
+		return analyzer.arithmeticType(binaryExpr, leftType, rightType, IsNumericType)
+	case TokenTypeMinus, TokenTypeStar, TokenTypeSlash, TokenTypeDoubleStar:
+		return analyzer.arithmeticType(binaryExpr, leftType, rightType, IsNumericType)
+	case TokenTypePercent, TokenTypeAmpersand, TokenTypeVertical, TokenTypeCaret:
+		return analyzer.arithmeticType(binaryExpr, leftType, rightType, IsIntegerType)
+	case TokenTypeDoubleLeftAngle, TokenTypeDoubleRightAngle:
+		// 移位运算的结果与左操作数类型相同
+		if IsIntegerType(leftType) && IsIntegerType(rightType) {
+			return leftType
+		}
+	}
+	analyzer.reportMismatchedOperands(binaryExpr, leftType, rightType)
+	return Unknown
+}
+
+// 算术运算两侧的操作数需要有相同的类型，未定类型的常量则转为另一侧的类型
+func (analyzer *Analyzer) arithmeticType(binaryExpr *BinaryExpression, leftType, rightType Type, accept func(Type) bool) Type {
+	if operandType := unifyTypes(leftType, rightType); operandType != nil && accept(operandType) {
+		return operandType
+	}
+	analyzer.reportMismatchedOperands(binaryExpr, leftType, rightType)
+	return Unknown
+}
+func (analyzer *Analyzer) reportMismatchedOperands(binaryExpr *BinaryExpression, leftType, rightType Type) {
+	analyzer.ReportTypeError(binaryExpr, InvalidOperation, "invalid operation: operator %s not defined on %s and %s!",
+		binaryExpr.Operator.Str, leftType, rightType)
+}
+
+// 区间的两端需要是相同类型的整数，区间被视作由其元素组成的数组
+func (analyzer *Analyzer) CheckRangeExpression(rangeExpr *RangeExpression) Type {
+	startType := analyzer.CheckValueExpression(rangeExpr.Start)
+	endType := analyzer.CheckValueExpression(rangeExpr.End)
+	elementType := unifyTypes(startType, endType)
+	if elementType == nil || !(IsUnknown(elementType) || IsIntegerType(elementType)) {
+		analyzer.ReportTypeError(rangeExpr, TypeMismatch, "range endpoints must be integers of the same type, got %s and %s!",
+			startType, endType)
+		return Unknown
+	}
+	return &ArrayType{Element: DefaultType(elementType)}
+}
+
+// 基本类型之间（字符串除外）可以相互转换，其余情况下只能转换为兼容的类型
+func (analyzer *Analyzer) CheckCastExpression(castExpr *CastExpression) Type {
+	sourceType := analyzer.CheckValueExpression(castExpr.Source)
+	castExpr.Source = analyzer.FoldConstant(castExpr.Source)
+	targetType := analyzer.ResolveType(castExpr.Type)
+	if AssignableTo(sourceType, targetType) || AssignableTo(targetType, sourceType) {
+		return targetType
+	}
+	if isConvertibleBasic(sourceType) && isConvertibleBasic(targetType) {
+		return targetType
+	}
+	analyzer.ReportTypeError(castExpr, TypeMismatch, "cannot convert value of type %s to type %s!", sourceType, targetType)
+	return targetType
+}
+func isConvertibleBasic(t Type) bool {
+	return IsNumericType(t) || t == BoolType
+}
@@ -2,54 +2,550 @@ package analyzer
 
 import (
 	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	"fmt"
+	"go/constant"
 )
 
+// 检查一组同一区块中的语句，分为三遍：
+// 1. 加载引入的模块，定义函数、类、接口与枚举的名称，使得它们可以在定义之前被引用
+// 2. 解析函数签名与类、接口的成员，使得调用与成员访问可以在检查函数体之前确定类型
+// 3. 按顺序检查每一条语句
+func (analyzer *Analyzer) CheckStatementList(stmts []Statement) {
+	for _, stmt := range stmts {
+		analyzer.DeclareStatement(stmt)
+	}
+	outerResolving := analyzer.resolving
+	analyzer.resolving = true
+	for _, stmt := range stmts {
+		analyzer.ResolveDeclaration(stmt)
+	}
+	analyzer.resolving = outerResolving
+	if !analyzer.resolving {
+		deferred := analyzer.deferredChecks
+		analyzer.deferredChecks = nil
+		for _, check := range deferred {
+			check()
+		}
+	}
+	for _, stmt := range stmts {
+		analyzer.CheckStatement(stmt)
+	}
+}
+
+// 为引入的模块以及函数、类、接口与枚举定义符号，其余语句的符号在检查时按顺序定义
+func (analyzer *Analyzer) DeclareStatement(stmt Statement) {
+	switch stmt.StatementNodeType() {
+	case StatementTypePackage:
+		analyzer.DeclarePackageStatement(stmt.(*PackageStatement))
+	case StatementTypeImport:
+		analyzer.DeclareImportStatement(stmt.(ImportStatement))
+	case StatementTypeEnum:
+		analyzer.DeclareEnumStatement(stmt.(*EnumStatement))
+	case StatementTypeFunctionDecl:
+		fnStmt := stmt.(*FunctionDeclarationStatement)
+		fnSymbol := NewFunctionSymbol(fnStmt.Name.Token)
+		fnSymbol.Package = analyzer.declaringPackage()
+		analyzer.Symbols[fnStmt] = fnSymbol
+		analyzer.DefineSymbol(fnStmt.Name.Token, fnSymbol)
+	case StatementTypeClassDecl:
+		classStmt := stmt.(*ClassDeclarationStatement)
+		classSymbol := NewDeclaredTypeSymbol(classStmt.Definition, classStmt)
+		classSymbol.Package = analyzer.declaringPackage()
+		analyzer.Symbols[classStmt] = classSymbol
+		analyzer.DefineSymbol(classStmt.Definition.Name.Token, classSymbol)
+	case StatementTypeInterfaceDecl:
+		interfaceStmt := stmt.(*InterfaceDeclarationStatement)
+		interfaceSymbol := NewDeclaredTypeSymbol(interfaceStmt.Definition, interfaceStmt)
+		interfaceSymbol.Package = analyzer.declaringPackage()
+		analyzer.Symbols[interfaceStmt] = interfaceSymbol
+		analyzer.DefineSymbol(interfaceStmt.Definition.Name.Token, interfaceSymbol)
+	}
+}
+
+// 解析函数签名以及类、接口的成员
+func (analyzer *Analyzer) ResolveDeclaration(stmt Statement) {
+	switch stmt.StatementNodeType() {
+	case StatementTypeFunctionDecl:
+		fnStmt := stmt.(*FunctionDeclarationStatement)
+		analyzer.Symbols[fnStmt].(*IdSymbol).Type = analyzer.ResolveSignature(fnStmt.Signature)
+	case StatementTypeClassDecl:
+		classStmt := stmt.(*ClassDeclarationStatement)
+		analyzer.ResolveClassMembers(classStmt)
+	case StatementTypeInterfaceDecl:
+		interfaceStmt := stmt.(*InterfaceDeclarationStatement)
+		analyzer.ResolveInterfaceMethods(interfaceStmt)
+	}
+}
+
 func (analyzer *Analyzer) CheckStatement(stmt Statement) {
 	switch stmt.StatementNodeType() {
 	case StatementTypeSimple:
-		simpleStmt := stmt.(SimpleStatement)
-		analyzer.CheckSimpleStatement(simpleStmt)
-	case StatementTypeEnum:
-		enumStmt := stmt.(*EnumStatement)
-		analyzer.CheckEnumStatement(enumStmt)
+		// return、throw、break、continue 也属于简单语句，但并不实现 SimpleStatement 接口
+		if returnStmt, isReturn := stmt.(*ReturnStatement); isReturn {
+			analyzer.CheckReturnStatement(returnStmt)
+		} else if throwStmt, isThrow := stmt.(*ThrowStatement); isThrow {
+			analyzer.CheckThrowStatement(throwStmt)
+		} else if simpleStmt, isSimple := stmt.(SimpleStatement); isSimple {
+			analyzer.CheckSimpleStatement(simpleStmt)
+		}
+	case StatementTypePackage, StatementTypeImport, StatementTypeEnum:
+		// 包名、引入的符号与枚举在 DeclareStatement 中已经定义完毕
 	case StatementTypeBlock:
-		analyzer.EnterNewBlockScope()
 		blockStmt := stmt.(*BlockStatement)
-		analyzer.CheckBlockStatement(blockStmt)
-		analyzer.LeaveCurrentBlockScope()
+		analyzer.CheckScopedBlock(blockStmt)
 	case StatementTypeIf:
+		ifStmt := stmt.(*IfStatement)
+		analyzer.CheckIfStatement(ifStmt)
 	case StatementTypeSwitch:
+		switchStmt := stmt.(*SwitchStatement)
+		analyzer.CheckSwitchStatement(switchStmt)
 	case StatementTypeWhile:
+		whileStmt := stmt.(*WhileStatement)
+		analyzer.CheckCondition(whileStmt.Condition)
+		analyzer.CheckScopedBlock(whileStmt.Block)
 	case StatementTypeFor:
+		forStmt := stmt.(*ForStatement)
+		analyzer.CheckForStatement(forStmt)
 	case StatementTypeEach:
+		eachStmt := stmt.(*EachStatement)
+		analyzer.CheckEachStatement(eachStmt)
 	case StatementTypeFunctionDecl:
+		fnStmt := stmt.(*FunctionDeclarationStatement)
+		analyzer.CheckFunctionBody(fnStmt.Signature, analyzer.Symbols[fnStmt].(*IdSymbol).Type.(*FunctionType), fnStmt.Block)
 	case StatementTypeClassDecl:
+		classStmt := stmt.(*ClassDeclarationStatement)
+		analyzer.CheckClassStatement(classStmt)
 	case StatementTypeInterfaceDecl:
+		// 接口只有方法声明，在 ResolveDeclaration 中已经检查完毕
 	case StatementTypeTryCatch:
+		tryCatchStmt := stmt.(*TryCatchStatement)
+		analyzer.CheckTryCatchStatement(tryCatchStmt)
 	}
 }
 
 func (analyzer *Analyzer) CheckSimpleStatement(simpleStmt SimpleStatement) {
 	switch simpleStmt.SimpleStatementNodeType() {
 	case SimpleStmtTypeExpression:
+		analyzer.CheckExpression(simpleStmt.(Expression))
 	case SimpleStmtTypeVariableDecl:
+		varDeclStmt := simpleStmt.(*VarDeclStatement)
+		analyzer.CheckVarDeclStatement(varDeclStmt)
 	case SimpleStmtTypeAssignList:
+		assignListStmt := simpleStmt.(*AssignListStatement)
+		analyzer.CheckAssignListStatement(assignListStmt)
 	case SimpleStmtTypeIncDecStmt:
+		incDecStmt := simpleStmt.(*IncDecStatement)
+		operandType := analyzer.CheckAssignTarget(incDecStmt.Expression)
+		if !IsUnknown(operandType) && !IsNumericType(operandType) {
+			analyzer.ReportTypeError(incDecStmt, InvalidOperation, "invalid operation: %s on non-numeric type %s!",
+				incDecStmt.Operator.Str, operandType)
+		}
 	}
 }
 
-func (analyzer *Analyzer) CheckEnumStatement(enumStmt *EnumStatement) {
+// 先检查初始值再定义变量，因此 var a = a; 中的 a 是未定义的
+// 没有类型标注的变量取初始值的类型，未定类型的常量取其默认类型
+// 以常量初始化的 val 记下其值，以便在之后的常量表达式中使用
+func (analyzer *Analyzer) CheckVarDeclStatement(varDeclStmt *VarDeclStatement) {
+	for _, element := range varDeclStmt.Declarations {
+		idSymbol := &IdSymbol{
+			Symbol:    &Symbol{Token: element.VarName},
+			Type:      analyzer.CheckVarDeclElement(element),
+			Immutable: !varDeclStmt.Mutable,
+		}
+		if idSymbol.Immutable {
+			analyzer.CheckValueDeclaration(element, idSymbol.Type)
+			if element.InitValue != nil {
+				idSymbol.Constant = constantOfType(analyzer.Constants[element.InitValue], idSymbol.Type)
+			}
+		}
+		analyzer.Symbols[element] = idSymbol
+		analyzer.DefineSymbol(element.VarName, idSymbol)
+	}
+}
+
+// val 必须给出初始值，且只能是布尔型、数值型或字符串
+func (analyzer *Analyzer) CheckValueDeclaration(element *VarDeclElement, valueType Type) {
+	name := element.VarName.Str
+	if element.InitValue == nil {
+		analyzer.ReportError(TokenSpan(element.VarName), NewCoralError("Compile",
+			fmt.Sprintf("missing initial value for val \"%s\"!", name), UninitializedValue))
+	}
+	if !IsUnknown(valueType) && !IsNumericType(valueType) && valueType != BoolType && valueType != StringType {
+		analyzer.ReportTypeError(element, InvalidValueType, "val \"%s\" cannot be of type %s, only bool, numeric and String are allowed!",
+			name, valueType)
+	}
+}
+func (analyzer *Analyzer) CheckVarDeclElement(element *VarDeclElement) Type {
+	return analyzer.CheckVarDeclValue(element, analyzer.ResolveType(element.Type))
+}
+
+// 以已解析的类型标注检查变量的初始值，类的成员变量的类型标注在解析成员时已经解析过
+func (analyzer *Analyzer) CheckVarDeclValue(element *VarDeclElement, varType Type) Type {
+	if element.InitValue == nil {
+		return varType
+	}
+	valueType := analyzer.CheckValueExpression(element.InitValue)
+	element.InitValue = analyzer.FoldConstant(element.InitValue)
+	if varType == nil {
+		varType = DefaultType(valueType)
+	}
+	analyzer.CheckAssignable(element.InitValue, valueType, varType, "variable declaration")
+	return varType
+}
+
+// 多个值同时赋值：a, b = 1, 2 或者 a, b = f()
+func (analyzer *Analyzer) CheckAssignListStatement(assignListStmt *AssignListStatement) {
+	var targetTypes []Type
+	for _, target := range assignListStmt.Targets {
+		targetTypes = append(targetTypes, analyzer.CheckAssignTarget(target))
+	}
+	values, valueTypes := analyzer.CheckExpressionList(assignListStmt.Values)
+	if len(valueTypes) != len(targetTypes) {
+		analyzer.ReportTypeError(assignListStmt, ValueCountMismatch, "assignment mismatch: %d variables but %d values!",
+			len(targetTypes), len(valueTypes))
+		return
+	}
+	for i, targetType := range targetTypes {
+		analyzer.CheckAssignable(values[i], valueTypes[i], targetType, "assignment")
+	}
+}
+
+// 检查返回值的个数与类型是否与所在函数的签名一致
+func (analyzer *Analyzer) CheckReturnStatement(returnStmt *ReturnStatement) {
+	values, valueTypes := analyzer.CheckExpressionList(returnStmt.Expression)
+	fnType := analyzer.currentFunction
+	if fnType == nil {
+		return
+	}
+	if len(valueTypes) != len(fnType.Returns) {
+		analyzer.ReportTypeError(returnStmt, ValueCountMismatch, "wrong number of return values: expected %d, got %d!",
+			len(fnType.Returns), len(valueTypes))
+		return
+	}
+	for i, returnType := range fnType.Returns {
+		analyzer.CheckAssignable(values[i], valueTypes[i], returnType, "return statement")
+	}
+}
+
+func (analyzer *Analyzer) DeclareEnumStatement(enumStmt *EnumStatement) {
 	enumSymbol := new(EnumSymbol)
-	enumSymbol.Token = enumStmt.Name.Token
+	enumSymbol.Symbol = &Symbol{Token: enumStmt.Name.Token, Package: analyzer.declaringPackage()}
 	enumSymbol.CollectionName = enumStmt.Name.GetName()
+	enumSymbol.ElementsMap = make(map[string]*EnumElement)
 	for _, enumElement := range enumStmt.Elements {
-		enumSymbol.ElementsMap[enumElement.Name.GetName()] = enumElement
+		name := enumElement.Name.GetName()
+		if defined, ok := enumSymbol.ElementsMap[name]; ok {
+			analyzer.ReportError(enumElement.Name.Span, NewCoralError("Compile",
+				fmt.Sprintf("enum element \"%s\" is already defined in enum \"%s\"!", name, enumSymbol.CollectionName),
+				DuplicateDefinition)).
+				AddNote(analyzer.parser.FileName, toPosition(defined.Name.Start), toPosition(defined.Name.End),
+					fmt.Sprintf("previous definition of \"%s\" is here", name))
+			continue
+		}
+		enumSymbol.ElementsMap[name] = enumElement
+	}
+	analyzer.DefineSymbol(enumStmt.Name.Token, enumSymbol)
+}
+
+// 在新的区块作用域中检查块语句
+func (analyzer *Analyzer) CheckScopedBlock(blockStmt *BlockStatement) {
+	if blockStmt == nil {
+		return
 	}
-	analyzer.CurrentScope.SymbolMap[enumSymbol.CollectionName] = enumSymbol
+	analyzer.EnterNewBlockScope()
+	analyzer.recordScope(blockStmt)
+	analyzer.CheckBlockStatement(blockStmt)
+	analyzer.LeaveCurrentBlockScope()
 }
 
+// 在当前作用域中检查块语句，供函数体等需要与参数共享作用域的场合使用
 func (analyzer *Analyzer) CheckBlockStatement(blockStmt *BlockStatement) {
-	for _, stmt := range blockStmt.Statements {
-		analyzer.CheckStatement(stmt)
+	analyzer.CheckStatementList(blockStmt.Statements)
+}
+
+// 条件表达式必须为 bool 类型
+func (analyzer *Analyzer) CheckCondition(condition Expression) {
+	conditionType := analyzer.CheckValueExpression(condition)
+	if !AssignableTo(conditionType, BoolType) {
+		analyzer.ReportTypeError(condition, TypeMismatch, "condition must be of type bool, got %s!", conditionType)
+	}
+}
+
+func (analyzer *Analyzer) CheckIfStatement(ifStmt *IfStatement) {
+	for _, ifElement := range append([]*IfElement{ifStmt.If}, ifStmt.Elif...) {
+		analyzer.CheckCondition(ifElement.Condition)
+		analyzer.CheckScopedBlock(ifElement.Block)
+	}
+	analyzer.CheckScopedBlock(ifStmt.Else)
+}
+
+// 每个匹配条件都需要能与 switch 的入口表达式相比较
+func (analyzer *Analyzer) CheckSwitchStatement(switchStmt *SwitchStatement) {
+	entryType := analyzer.CheckValueExpression(switchStmt.Entry)
+	for _, switchCase := range switchStmt.Cases {
+		switch switchCase.SwitchStatementCaseNodeType() {
+		case SwitchStatementTypeNormal:
+			normalCase := switchCase.(*SwitchStatementNormalCase)
+			for _, condition := range normalCase.Conditions {
+				analyzer.CheckComparable(condition, analyzer.CheckValueExpression(condition), entryType)
+			}
+			analyzer.CheckScopedBlock(normalCase.Block)
+		case SwitchStatementTypeRange:
+			rangeCase := switchCase.(*SwitchStatementRangeCase)
+			rangeType := analyzer.CheckExpression(rangeCase.Range)
+			if rangeArray, ok := rangeType.(*ArrayType); ok {
+				analyzer.CheckComparable(rangeCase.Range, rangeArray.Element, entryType)
+			}
+			analyzer.CheckScopedBlock(rangeCase.Block)
+		}
+	}
+	analyzer.CheckScopedBlock(switchStmt.Default)
+}
+func (analyzer *Analyzer) CheckComparable(value Node, valueType, entryType Type) {
+	if unifyTypes(valueType, entryType) == nil {
+		analyzer.ReportTypeError(value, TypeMismatch, "cannot compare value of type %s with switch entry of type %s!",
+			valueType, entryType)
+	}
+}
+
+// for 语句的初始化部分自成一个作用域，循环体则是其中的内层作用域
+func (analyzer *Analyzer) CheckForStatement(forStmt *ForStatement) {
+	analyzer.EnterNewBlockScope()
+	analyzer.recordScope(forStmt)
+	if forStmt.Initial != nil {
+		analyzer.CheckSimpleStatement(forStmt.Initial)
+	}
+	if forStmt.Condition != nil {
+		analyzer.CheckCondition(forStmt.Condition)
+	}
+	for _, appendix := range forStmt.Appendix {
+		analyzer.CheckSimpleStatement(appendix)
+	}
+	analyzer.CheckScopedBlock(forStmt.Block)
+	analyzer.LeaveCurrentBlockScope()
+}
+
+// 数组与区间逐个取出元素，键为下标；表的键为字符串；字符串逐个取出字符
+func (analyzer *Analyzer) CheckEachStatement(eachStmt *EachStatement) {
+	targetType := analyzer.CheckValueExpression(eachStmt.Target)
+	var elementType, keyType Type = Unknown, Unknown
+	switch targetType.TypeKind() {
+	case TypeKindArray:
+		elementType, keyType = targetType.(*ArrayType).Element, IntType
+	case TypeKindTable:
+		elementType, keyType = targetType.(*TableType).Value, StringType
+	case TypeKindUnknown:
+	default:
+		if targetType == StringType {
+			elementType, keyType = RuneType, IntType
+		} else {
+			analyzer.ReportTypeError(eachStmt.Target, InvalidOperation, "cannot iterate over value of type %s!", targetType)
+		}
+	}
+
+	analyzer.EnterNewBlockScope()
+	analyzer.DefineSymbol(eachStmt.Element.Token, &IdSymbol{Symbol: &Symbol{Token: eachStmt.Element.Token}, Type: elementType})
+	if eachStmt.Key != nil {
+		analyzer.DefineSymbol(eachStmt.Key.Token, &IdSymbol{Symbol: &Symbol{Token: eachStmt.Key.Token}, Type: keyType})
+	}
+	analyzer.recordScope(eachStmt.Block)
+	analyzer.CheckBlockStatement(eachStmt.Block)
+	analyzer.LeaveCurrentBlockScope()
+}
+
+// 函数体与泛型参数、形参共享同一个作用域，因此形参不可在函数体中重复定义
+func (analyzer *Analyzer) CheckFunctionBody(signature *Signature, fnType *FunctionType, body *BlockStatement) {
+	outerFunction, outerCatching := analyzer.currentFunction, analyzer.catching
+	analyzer.currentFunction, analyzer.catching = fnType, nil
+	analyzer.EnterBlockScope(analyzer.signatureScopes[signature])
+	if body != nil {
+		analyzer.recordScope(body)
+		analyzer.CheckBlockStatement(body)
+		if len(fnType.Returns) > 0 && !analyzer.terminates(body) {
+			closing := body.End // 在函数体的右花括号处报错
+			closing.Col--
+			closing.Offset--
+			analyzer.ReportError(Span{Start: closing, End: body.End}, NewCoralError("Type",
+				"missing return at the end of a function with return values!", MissingReturn))
+		}
+	}
+	analyzer.LeaveCurrentBlockScope()
+	analyzer.currentFunction, analyzer.catching = outerFunction, outerCatching
+}
+
+// 语句执行完之后不会接着执行其后的语句：return 与 throw，以及每个分支都是如此的 if、switch 与 try 语句
+// 条件恒为 true 且其中没有 break 的循环也不会正常结束
+func (analyzer *Analyzer) terminates(stmt Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ReturnStatement, *ThrowStatement:
+		return true
+	case *BlockStatement:
+		return len(stmt.Statements) > 0 && analyzer.terminates(stmt.Statements[len(stmt.Statements)-1])
+	case *IfStatement:
+		if stmt.Else == nil || !analyzer.terminates(stmt.If.Block) || !analyzer.terminates(stmt.Else) {
+			return false
+		}
+		for _, elif := range stmt.Elif {
+			if !analyzer.terminates(elif.Block) {
+				return false
+			}
+		}
+		return true
+	case *SwitchStatement:
+		if stmt.Default == nil || !analyzer.terminates(stmt.Default) {
+			return false
+		}
+		for _, switchCase := range stmt.Cases {
+			switch switchCase := switchCase.(type) {
+			case *SwitchStatementNormalCase:
+				if !analyzer.terminates(switchCase.Block) {
+					return false
+				}
+			case *SwitchStatementRangeCase:
+				if !analyzer.terminates(switchCase.Block) {
+					return false
+				}
+			}
+		}
+		return true
+	case *TryCatchStatement:
+		if stmt.Finally != nil && analyzer.terminates(stmt.Finally) {
+			return true
+		}
+		if !analyzer.terminates(stmt.TryBlock) {
+			return false
+		}
+		for _, handler := range stmt.Handlers {
+			if !analyzer.terminates(handler.Handler) {
+				return false
+			}
+		}
+		return true
+	case *WhileStatement:
+		return analyzer.isConstantTrue(stmt.Condition) && !hasBreak(stmt.Block)
+	case *ForStatement:
+		return analyzer.isConstantTrue(stmt.Condition) && !hasBreak(stmt.Block)
+	}
+	return false
+}
+
+func (analyzer *Analyzer) isConstantTrue(condition Expression) bool {
+	value := analyzer.Constants[condition]
+	return value != nil && value.Kind() == constant.Bool && constant.BoolVal(value)
+}
+
+// 循环体中是否有跳出这一层循环的 break，内层的循环与 lambda 中的 break 不算
+func hasBreak(block *BlockStatement) bool {
+	found := false
+	Inspect(block, func(node Node) bool {
+		switch node.(type) {
+		case *BreakStatement:
+			found = true
+		case *WhileStatement, *ForStatement, *EachStatement, *LambdaLit:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// 类的成员作用域：先定义全部成员，再逐个检查方法体，方法之间因此可以相互引用
+func (analyzer *Analyzer) ResolveClassMembers(classStmt *ClassDeclarationStatement) {
+	classSymbol := analyzer.Symbols[classStmt].(*TypeSymbol)
+	classType := classSymbol.Type.(*ClassType)
+	analyzer.EnterNewBlockScope()
+	classSymbol.Members = analyzer.CurrentScope
+	analyzer.DefineGenerics(classStmt.Definition.Generics, classType.TypeParams)
+	analyzer.ResolveClassHierarchy(classType, classStmt.Extends, classStmt.Implements)
+
+	for _, member := range classStmt.Members {
+		switch member.ClassMemberNodeType() {
+		case ClassMemberTypeVar:
+			// 成员变量的类型若需由初始值推断，则待检查初始值时再确定
+			field := member.(*ClassMemberVar)
+			for _, element := range field.VarDecl.Declarations {
+				fieldSymbol := &IdSymbol{
+					Symbol:    &Symbol{Token: element.VarName},
+					Type:      analyzer.ResolveType(element.Type),
+					Immutable: !field.VarDecl.Mutable,
+					Owner:     classSymbol,
+					Private:   field.Scope == ClassMemberScopePrivate,
+					Static:    field.Static,
+				}
+				analyzer.Symbols[element] = fieldSymbol
+				analyzer.DefineSymbol(element.VarName, fieldSymbol)
+			}
+		case ClassMemberTypeMethod:
+			method := member.(*ClassMemberMethod)
+			methodDecl := method.MethodDecl
+			methodSymbol := NewFunctionSymbol(methodDecl.Name.Token)
+			methodSymbol.Type = analyzer.ResolveSignature(methodDecl.Signature)
+			methodSymbol.Owner = classSymbol
+			methodSymbol.Private = method.Scope == ClassMemberScopePrivate
+			methodSymbol.Static = method.Static
+			analyzer.Symbols[methodDecl] = methodSymbol
+			analyzer.DefineSymbol(methodDecl.Name.Token, methodSymbol)
+		}
+	}
+	analyzer.LeaveCurrentBlockScope()
+}
+func (analyzer *Analyzer) CheckClassStatement(classStmt *ClassDeclarationStatement) {
+	classSymbol := analyzer.Symbols[classStmt].(*TypeSymbol)
+	classType := classSymbol.Type.(*ClassType)
+	analyzer.CheckConstructor(classStmt, classType)
+	analyzer.CheckOverrides(classStmt, classType)
+	analyzer.CheckImplements(classStmt, classType)
+
+	// 成员变量的初始值不属于外层的函数，其中抛出的异常须在初始值中捕获
+	outerClass, outerStatic := analyzer.currentClass, analyzer.inStatic
+	outerFunction, outerCatching := analyzer.currentFunction, analyzer.catching
+	analyzer.currentClass = classSymbol
+	analyzer.currentFunction, analyzer.catching = nil, nil
+	analyzer.EnterBlockScope(classSymbol.Members)
+	analyzer.recordScope(classStmt)
+
+	for _, member := range classStmt.Members {
+		if field, isField := member.(*ClassMemberVar); isField {
+			analyzer.inStatic = field.Static
+			for _, element := range field.VarDecl.Declarations {
+				fieldSymbol := analyzer.Symbols[element].(*IdSymbol)
+				fieldSymbol.Type = analyzer.CheckVarDeclValue(element, fieldSymbol.Type)
+				if fieldSymbol.Immutable {
+					analyzer.CheckValueDeclaration(element, fieldSymbol.Type)
+				}
+			}
+		}
+	}
+	for _, member := range classStmt.Members {
+		if method, isMethod := member.(*ClassMemberMethod); isMethod {
+			analyzer.inStatic = method.Static
+			methodDecl := method.MethodDecl
+			analyzer.CheckFunctionBody(methodDecl.Signature, analyzer.Symbols[methodDecl].(*IdSymbol).Type.(*FunctionType), methodDecl.Block)
+		}
+	}
+	analyzer.LeaveCurrentBlockScope()
+	analyzer.currentClass, analyzer.inStatic = outerClass, outerStatic
+	analyzer.currentFunction, analyzer.catching = outerFunction, outerCatching
+}
+
+func (analyzer *Analyzer) ResolveInterfaceMethods(interfaceStmt *InterfaceDeclarationStatement) {
+	interfaceSymbol := analyzer.Symbols[interfaceStmt].(*TypeSymbol)
+	interfaceType := interfaceSymbol.Type.(*ClassType)
+	analyzer.EnterNewBlockScope()
+	interfaceSymbol.Members = analyzer.CurrentScope
+	analyzer.recordScope(interfaceStmt)
+	analyzer.DefineGenerics(interfaceStmt.Definition.Generics, interfaceType.TypeParams)
+	analyzer.ResolveClassHierarchy(interfaceType, interfaceStmt.Extends, nil)
+
+	for _, method := range interfaceStmt.Methods {
+		// 接口的方法总是公开的
+		methodSymbol := NewFunctionSymbol(method.Name.Token)
+		methodSymbol.Type = analyzer.ResolveSignature(method.Signature)
+		methodSymbol.Owner = interfaceSymbol
+		analyzer.Symbols[method] = methodSymbol
+		analyzer.DefineSymbol(method.Name.Token, methodSymbol)
 	}
+	analyzer.LeaveCurrentBlockScope()
 }
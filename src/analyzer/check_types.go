@@ -0,0 +1,134 @@
+package analyzer
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	"fmt"
+)
+
+// 报告一个类型错误
+func (analyzer *Analyzer) ReportTypeError(node Node, errEnum int, format string, args ...interface{}) *Diagnostic {
+	return analyzer.ReportError(*node.GetSpan(), NewCoralError("Type", fmt.Sprintf(format, args...), errEnum))
+}
+
+// 将类型标注解析为类型，没有标注时返回 nil，标注有误时报错并返回 Unknown
+func (analyzer *Analyzer) ResolveType(description TypeDescription) Type {
+	if description == nil {
+		return nil
+	}
+	switch description.TypeDescriptionNode() {
+	case TypeDescriptionTypeName:
+		name := description.(*TypeName).Identifier
+		resolved := analyzer.ResolveTypeName(name)
+		if classType, isClass := resolved.(*ClassType); isClass && len(classType.TypeParams) > 0 {
+			analyzer.ReportTypeError(name, TypeArgumentMismatch, "generic type %s cannot be used without type arguments!", classType)
+			return Unknown
+		}
+		return resolved
+	case TypeDescriptionTypeArrayLit:
+		return &ArrayType{Element: analyzer.ResolveType(description.(*ArrayTypeLit).ElementType)}
+	case TypeDescriptionTypeGenerics:
+		genericsType := description.(*GenericsTypeLit)
+		generic := analyzer.ResolveTypeName(genericsType.BasicType.Identifier)
+		args := make([]Type, len(genericsType.GenericsArgs))
+		for i, arg := range genericsType.GenericsArgs {
+			args[i] = analyzer.ResolveType(arg)
+		}
+		return analyzer.InstantiateType(genericsType, generic, args)
+	case TypeDescriptionFunction:
+		funcType := description.(*FuncType)
+		fnType := new(FunctionType)
+		for _, argType := range funcType.ArgTypes {
+			fnType.Params = append(fnType.Params, analyzer.ResolveType(argType))
+		}
+		for _, returnType := range funcType.ReturnTypes {
+			fnType.Returns = append(fnType.Returns, analyzer.ResolveType(returnType))
+		}
+		return fnType
+	}
+	return Unknown
+}
+
+func (analyzer *Analyzer) ResolveTypeName(name *Identifier) Type {
+	symbol := analyzer.lookupName(name.GetName())
+	if symbol == nil {
+		analyzer.ReportTypeError(name, UndeclaredIdentifier, "undeclared type \"%s\"!", name.GetName())
+		return Unknown
+	}
+	analyzer.References[name] = symbol
+	switch symbol.GetKind() {
+	case TypeSymbolKind:
+		return symbol.(*TypeSymbol).Type
+	case EnumSymbolKind:
+		return &EnumType{Symbol: symbol.(*EnumSymbol)}
+	}
+	analyzer.ReportTypeError(name, TypeMismatch, "\"%s\" is not a type!", name.GetName())
+	return Unknown
+}
+
+// 在新的作用域中定义签名的泛型参数与形参，并解析出函数类型
+// 作用域会被记录下来，函数体随后在其中检查
+func (analyzer *Analyzer) ResolveSignature(signature *Signature) *FunctionType {
+	fnType := &FunctionType{TypeParams: newTypeParams(signature.Generics)}
+	analyzer.EnterNewBlockScope()
+	analyzer.signatureScopes[signature] = analyzer.CurrentScope
+	analyzer.DefineGenerics(signature.Generics, fnType.TypeParams)
+	for _, argument := range signature.Arguments {
+		argType := analyzer.ResolveType(argument.Type)
+		if argType == nil {
+			argType = Unknown
+		}
+		fnType.Params = append(fnType.Params, argType)
+		analyzer.DefineSymbol(argument.Name.Token, &IdSymbol{
+			Symbol: &Symbol{Token: argument.Name.Token},
+			Type:   argType,
+		})
+	}
+	for _, returnType := range signature.Returns {
+		fnType.Returns = append(fnType.Returns, analyzer.ResolveType(returnType))
+	}
+	for _, throwType := range signature.Throws {
+		exceptionType := analyzer.ResolveType(throwType)
+		if analyzer.CheckExceptionType(throwType, exceptionType, "throws clause") {
+			fnType.Throws = append(fnType.Throws, exceptionType)
+		}
+	}
+	analyzer.LeaveCurrentBlockScope()
+	return fnType
+}
+
+// 检查 value 的值能否赋给类型为 target 的变量，不能时报错
+func (analyzer *Analyzer) CheckAssignable(value Node, valueType, target Type, context string) bool {
+	if AssignableTo(valueType, target) {
+		// 未定类型的常量还需要在目标类型的范围之内
+		if expression, isExpression := value.(Expression); isExpression && IsUntyped(valueType) {
+			if _, isConstant := analyzer.Constants[expression]; isConstant {
+				return analyzer.ConvertConstantTo(expression, target) != nil
+			}
+		}
+		return true
+	}
+	analyzer.ReportTypeError(value, TypeMismatch, "cannot use value of type %s as type %s in %s!",
+		valueType, target, context)
+	return false
+}
+
+// 两个类型的公共类型：用于数组元素、运算符两侧的操作数等，不相容时返回 nil
+func unifyTypes(a, b Type) Type {
+	switch {
+	case IdenticalTypes(a, b):
+		return a
+	case IsUnknown(a) || IsUnknown(b):
+		return Unknown
+	case IsUntyped(a) && IsUntyped(b):
+		if a.TypeKind() == TypeKindUntypedFloat {
+			return a
+		}
+		return b
+	case isUntypedValue(b) && AssignableTo(b, a):
+		return a
+	case isUntypedValue(a) && AssignableTo(a, b):
+		return b
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package analyzer
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	"fmt"
+	"strings"
+)
+
+// 解析类继承的父类与实现的接口，或者接口继承的接口
+// 在类的成员作用域中、定义成员之前解析，父类的泛型实参可以引用类自身的泛型参数；形成循环的继承关系报错且不予记录
+func (analyzer *Analyzer) ResolveClassHierarchy(classType *ClassType, extends *ClassIdentifier, implements []*ClassIdentifier) {
+	if extends != nil {
+		if base := analyzer.resolveSuperType(classType, extends, classType.IsInterface); base != nil {
+			if cycle := inheritanceCycle(classType, base); cycle != nil {
+				analyzer.ReportTypeError(extends.Name, InheritanceCycle, "inheritance cycle: %s!", strings.Join(cycle, " -> "))
+			} else {
+				classType.Base = base
+			}
+		}
+	}
+	for _, implemented := range implements {
+		if interfaceType := analyzer.resolveSuperType(classType, implemented, true); interfaceType != nil {
+			classType.Interfaces = append(classType.Interfaces, interfaceType)
+		}
+	}
+}
+
+// 类只能继承类、实现接口，接口只能继承接口
+func (analyzer *Analyzer) resolveSuperType(classType *ClassType, superId *ClassIdentifier, wantInterface bool) *ClassType {
+	resolved := analyzer.ResolveTypeName(superId.Name)
+	superType, isClass := resolved.(*ClassType)
+	switch {
+	case IsUnknown(resolved):
+		return nil
+	case !isClass && wantInterface:
+		analyzer.ReportTypeError(superId.Name, TypeMismatch, "%s is not an interface!", resolved)
+		return nil
+	case !isClass:
+		analyzer.ReportTypeError(superId.Name, TypeMismatch, "%s is not a class!", resolved)
+		return nil
+	case superType.IsInterface && !wantInterface:
+		analyzer.ReportTypeError(superId.Name, TypeMismatch, "class %s cannot extend interface %s, use <- to implement it!",
+			classType, superType)
+		return nil
+	case !superType.IsInterface && wantInterface:
+		analyzer.ReportTypeError(superId.Name, TypeMismatch, "%s is a class, not an interface!", superType)
+		return nil
+	case superId.Generics == nil && len(superType.TypeParams) > 0:
+		analyzer.ReportTypeError(superId.Name, TypeArgumentMismatch, "generic type %s cannot be used without type arguments!", superType)
+		return nil
+	case superId.Generics != nil:
+		instance, _ := analyzer.InstantiateType(superId, superType, analyzer.resolveTypeArgs(superId.Generics)).(*ClassType)
+		return instance
+	}
+	return superType
+}
+
+// 父类与接口的泛型实参以泛型参数列表的语法书写，每个实参都是一个类型名，可以带有自己的实参
+func (analyzer *Analyzer) resolveTypeArgs(generics *GenericArgs) []Type {
+	args := make([]Type, len(generics.Args))
+	for i, arg := range generics.Args {
+		if arg.Constraint != nil {
+			analyzer.ReportTypeError(arg.Constraint, TypeArgumentMismatch, "type argument %s cannot have a constraint!", arg.ArgName.GetName())
+		}
+		if arg.Generics == nil {
+			args[i] = analyzer.ResolveType(&TypeName{Span: arg.ArgName.Span, Identifier: arg.ArgName})
+		} else {
+			args[i] = analyzer.InstantiateType(arg, analyzer.ResolveTypeName(arg.ArgName), analyzer.resolveTypeArgs(arg.Generics))
+		}
+	}
+	return args
+}
+
+// 以 base 作为 classType 的父类时形成的继承环，不形成环时返回 nil
+func inheritanceCycle(classType *ClassType, base *ClassType) []string {
+	names := []string{classType.String()}
+	for current := base; current != nil; current = current.BaseType() {
+		names = append(names, current.String())
+		if current.Symbol == classType.Symbol {
+			return names
+		}
+	}
+	return nil
+}
+
+// 类定义语句中的构造方法：与类同名的方法
+func constructorOf(classStmt *ClassDeclarationStatement) *FunctionDeclarationStatement {
+	for _, member := range classStmt.Members {
+		if method, isMethod := member.(*ClassMemberMethod); isMethod &&
+			method.MethodDecl.Name.GetName() == classStmt.Definition.Name.GetName() {
+			return method.MethodDecl
+		}
+	}
+	return nil
+}
+
+// 类的构造方法的类型，泛型类的实例以其实参代入；没有构造方法时视为没有参数
+func constructorType(classType *ClassType) *FunctionType {
+	if members := classType.Symbol.Members; members != nil {
+		if constructor, ok := members.SymbolMap[classType.Symbol.Token.Str].(*IdSymbol); ok {
+			if fnType, isFn := memberType(constructor, classType).(*FunctionType); isFn {
+				return fnType
+			}
+		}
+	}
+	return &FunctionType{}
+}
+
+// 类必须有构造方法且不能有返回值；父类的构造方法需要参数时，子类的构造方法须以 super(...) 调用它
+func (analyzer *Analyzer) CheckConstructor(classStmt *ClassDeclarationStatement, classType *ClassType) {
+	name := classStmt.Definition.Name
+	constructor := constructorOf(classStmt)
+	if constructor == nil {
+		analyzer.ReportError(name.Span, NewCoralError("Compile",
+			fmt.Sprintf("expected a constructor for class \"%s\"!", name.GetName()), NoConstructorMethod))
+		return
+	}
+	if len(constructor.Signature.Returns) > 0 {
+		analyzer.ReportError(constructor.Name.Span, NewCoralError("Compile",
+			fmt.Sprintf("Any returns by constructor method of class \"%s\" are not allowed!", name.GetName()), NoConstructorMethod))
+	}
+	if classType.Base == nil || len(constructorType(classType.Base).Params) == 0 || callsSuper(constructor.Block) {
+		return
+	}
+	analyzer.ReportError(constructor.Name.Span, NewCoralError("Compile",
+		fmt.Sprintf("constructor of class \"%s\" must call super(...) to initialize class \"%s\"!", name.GetName(), classType.Base),
+		NoConstructorMethod))
+}
+
+// 构造方法体的顶层语句中是否以 super(...) 调用了父类的构造方法
+func callsSuper(body *BlockStatement) bool {
+	if body == nil {
+		return false
+	}
+	for _, stmt := range body.Statements {
+		if callExpr, isCall := stmt.(*CallExpression); isCall && isSuperOperand(callExpr.Operand) {
+			return true
+		}
+	}
+	return false
+}
+func isSuperOperand(expression Expression) bool {
+	if primary, isPrimary := expression.(*BasicPrimaryExpression); isPrimary {
+		_, isSuper := primary.It.(*SuperLit)
+		return isSuper
+	}
+	return false
+}
+
+// 子类中与父类成员同名的方法覆盖父类的方法，签名必须一致；同名的成员变量则视为重复定义
+// 父类的私有成员在子类中不可见，子类可以定义同名的成员
+func (analyzer *Analyzer) CheckOverrides(classStmt *ClassDeclarationStatement, classType *ClassType) {
+	if classType.Base == nil {
+		return
+	}
+	for _, member := range classStmt.Members {
+		switch member := member.(type) {
+		case *ClassMemberMethod:
+			methodDecl := member.MethodDecl
+			inherited, owner := classType.Base.MemberOf(methodDecl.Name.GetName())
+			if inherited == nil || inherited.Private || methodDecl.Name.GetName() == classStmt.Definition.Name.GetName() {
+				continue
+			}
+			have, _ := analyzer.Symbols[methodDecl].(*IdSymbol).Type.(*FunctionType)
+			want, isMethod := memberType(inherited, owner).(*FunctionType)
+			if isMethod && have != nil && !signatureMatches(have, want) {
+				analyzer.ReportTypeError(methodDecl.Name, TypeMismatch, "method \"%s\" of class %s has type %s, but overrides %s of class %s!",
+					methodDecl.Name.GetName(), classType, have, want, classType.Base)
+			}
+		case *ClassMemberVar:
+			for _, element := range member.VarDecl.Declarations {
+				if inherited := classType.Base.Member(element.VarName.Str); inherited != nil && !inherited.Private {
+					analyzer.ReportTypeError(element, DuplicateDefinition, "\"%s\" is already defined in base class %s!",
+						element.VarName.Str, classType.Base)
+				}
+			}
+		}
+	}
+}
+
+// 类须以公开的实例方法实现其声明的接口以及这些接口所继承的接口中的全部方法，签名必须一致
+func (analyzer *Analyzer) CheckImplements(classStmt *ClassDeclarationStatement, classType *ClassType) {
+	for i, interfaceType := range classType.Interfaces {
+		for current := interfaceType; current != nil; current = current.BaseType() {
+			interfaceStmt, _ := current.Symbol.Declaration.(*InterfaceDeclarationStatement)
+			if interfaceStmt == nil {
+				continue
+			}
+			for _, method := range interfaceStmt.Methods {
+				name := method.Name.GetName()
+				wantSymbol, isId := current.Symbol.Members.SymbolMap[name].(*IdSymbol)
+				if !isId {
+					continue // 与泛型参数同名等错误已经在解析接口时报告过
+				}
+				want := memberType(wantSymbol, current)
+				have, owner := classType.MemberOf(name)
+				switch {
+				case have == nil:
+					analyzer.ReportTypeError(classStmt.Implements[i].Name, InterfaceNotImplemented,
+						"class %s does not implement %s: missing method \"%s\"!", classType, interfaceType, name)
+				case !typeMatches(memberType(have, owner), want):
+					analyzer.ReportTypeError(classStmt.Implements[i].Name, InterfaceNotImplemented,
+						"class %s does not implement %s: method \"%s\" has type %s, want %s!",
+						classType, interfaceType, name, memberType(have, owner), want)
+				case have.Private || have.Static:
+					analyzer.ReportTypeError(classStmt.Implements[i].Name, InterfaceNotImplemented,
+						"class %s does not implement %s: method \"%s\" must be a public instance method!", classType, interfaceType, name)
+				}
+			}
+		}
+	}
+}
+
+// 两个方法的签名是否一致；类的泛型参数已代入实参，方法自身的泛型参数则按位置对应
+func signatureMatches(have, want *FunctionType) bool {
+	if have.Variadic != want.Variadic || len(have.TypeParams) != len(want.TypeParams) ||
+		len(have.Params) != len(want.Params) || len(have.Returns) != len(want.Returns) {
+		return false
+	}
+	bindings := make(map[*TypeSymbol]Type, len(want.TypeParams))
+	for i, param := range want.TypeParams {
+		bindings[param.Symbol] = have.TypeParams[i]
+	}
+	want = Substitute(want, bindings).(*FunctionType)
+	for i := range have.Params {
+		if !typeMatches(have.Params[i], want.Params[i]) {
+			return false
+		}
+	}
+	for i := range have.Returns {
+		if !typeMatches(have.Returns[i], want.Returns[i]) {
+			return false
+		}
+	}
+	// 重写的方法不能抛出被重写的方法没有声明的异常
+	return ThrowsCovered(have.Throws, want.Throws)
+}
+func typeMatches(have, want Type) bool {
+	if have == nil || want == nil {
+		return have == want
+	}
+	haveFn, isHaveFn := have.(*FunctionType)
+	wantFn, isWantFn := want.(*FunctionType)
+	if isHaveFn && isWantFn {
+		return signatureMatches(haveFn, wantFn)
+	}
+	return IdenticalTypes(have, want)
+}
+
+// this 指向正在检查的类，super 指向其父类，并记录在字面量节点中
+func (analyzer *Analyzer) CheckThisOrSuper(literal Literal) Type {
+	if analyzer.currentClass == nil {
+		analyzer.ReportTypeError(literal, InvalidOperation, "cannot use %s outside of a class!", literalKeyword(literal))
+		return Unknown
+	}
+	if analyzer.inStatic {
+		analyzer.ReportTypeError(literal, StaticMemberAccess, "cannot use %s in a static context!", literalKeyword(literal))
+		return Unknown
+	}
+	classType := analyzer.currentClass.Type.(*ClassType)
+	classStmt := analyzer.currentClass.Declaration.(*ClassDeclarationStatement)
+	switch literal := literal.(type) {
+	case *ThisLit:
+		literal.BelongsTo = classStmt.Definition
+		return classType
+	case *SuperLit:
+		if classType.Base == nil {
+			if classStmt.Extends == nil {
+				analyzer.ReportTypeError(literal, InvalidOperation, "cannot use super in class %s, it has no base class!", classType)
+			}
+			return Unknown // 父类有误时已经报过错
+		}
+		if baseStmt, isClass := classType.Base.Symbol.Declaration.(*ClassDeclarationStatement); isClass {
+			literal.BelongsTo = baseStmt.Definition
+		}
+		return classType.Base
+	}
+	return Unknown
+}
+func literalKeyword(literal Literal) string {
+	if _, isThis := literal.(*ThisLit); isThis {
+		return "this"
+	}
+	return "super"
+}
+
+// super(...) 调用父类的构造方法，只能出现在构造方法中
+func (analyzer *Analyzer) CheckSuperCall(callExpr *CallExpression, baseType *ClassType) Type {
+	classSymbol := analyzer.currentClass
+	constructor := constructorOf(classSymbol.Declaration.(*ClassDeclarationStatement))
+	if constructor == nil || analyzer.currentFunction != analyzer.Symbols[constructor].(*IdSymbol).Type {
+		analyzer.CheckExpressionList(callExpr.Params)
+		analyzer.ReportTypeError(callExpr, InvalidOperation, "super(...) can only be called in the constructor of class %s!",
+			classSymbol.Type)
+		return Void
+	}
+	baseConstructor := constructorType(baseType)
+	analyzer.CheckArguments(callExpr, callExpr.Params, baseConstructor)
+	analyzer.CheckThrows(callExpr, baseConstructor.Throws)
+	return Void
+}
+
+// 私有成员只能在其所属的类中访问；静态成员通过类名访问，实例成员通过实例访问
+func (analyzer *Analyzer) CheckMemberAccess(name *Identifier, member *IdSymbol, throughClass bool) {
+	owner := member.Owner
+	if owner == nil {
+		return
+	}
+	if member.Private && analyzer.currentClass != owner {
+		analyzer.ReportTypeError(name, PrivateMemberAccess, "\"%s\" is private to class %s!", name.GetName(), owner.Type)
+	}
+	switch {
+	case throughClass && !member.Static:
+		analyzer.ReportTypeError(name, StaticMemberAccess, "cannot access instance member \"%s\" of class %s through the class name!",
+			name.GetName(), owner.Type)
+	case !throughClass && member.Static:
+		analyzer.ReportTypeError(name, StaticMemberAccess, "static member \"%s\" of class %s must be accessed through the class name!",
+			name.GetName(), owner.Type)
+	}
+}
@@ -0,0 +1,338 @@
+package analyzer
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/lexer"
+	"go/constant"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 编译期可以求值的表达式：字面量、以常量初始化的 val 以及由它们组成的运算
+// 未定类型的常量以任意精度计算，只有在转为具体类型时才检查是否溢出
+
+// 移位运算的位数上限，超过时不再求值，以免生成过大的常量
+const maxConstantShift = 1 << 12
+
+// 在表达式的类型检查完成后求出其常量值，不是常量或求值出错时返回 nil
+func (analyzer *Analyzer) EvaluateConstant(expression Expression, exprType Type) constant.Value {
+	if IsUnknown(exprType) {
+		return nil
+	}
+	var value constant.Value
+	switch expression.ExpressionNodeType() {
+	case ExpressionTypePrimary:
+		if basicExpr, isBasic := expression.(*BasicPrimaryExpression); isBasic {
+			value = analyzer.operandConstant(basicExpr.It)
+		}
+	case ExpressionTypeUnary:
+		value = analyzer.unaryConstant(expression.(*UnaryExpression), exprType)
+	case ExpressionTypeBinary:
+		value = analyzer.binaryConstant(expression.(*BinaryExpression), exprType)
+	case ExpressionTypeCast:
+		// 只有基本类型之间的强转可以求值
+		if _, isBasic := exprType.(*BasicType); isBasic {
+			value = analyzer.Constants[expression.(*CastExpression).Source]
+		}
+	}
+	if value == nil || value.Kind() == constant.Unknown {
+		return nil
+	}
+	value = convertConstant(value, exprType)
+	if value == nil || !analyzer.CheckRepresentable(expression, value, exprType) {
+		return nil
+	}
+	return value
+}
+
+func (analyzer *Analyzer) operandConstant(operand Operand) constant.Value {
+	switch operand.OperandNodeType() {
+	case OperandTypeName:
+		symbol := analyzer.CurrentScope.Lookup(operand.(*OperandName).Name.GetName())
+		if idSymbol, isId := symbol.(*IdSymbol); isId {
+			return idSymbol.Constant
+		}
+	case OperandTypeLiteral:
+		return literalConstant(operand.(Literal))
+	}
+	return nil
+}
+
+// 字面量的值，词法分析时数字已经规范为 Go 的写法，字符串与字符也已经处理了转义
+func literalConstant(literal Literal) constant.Value {
+	switch literal.LiteralNodeType() {
+	case LiteralNodeTypeTrue:
+		return constant.MakeBool(true)
+	case LiteralNodeTypeFalse:
+		return constant.MakeBool(false)
+	case LiteralNodeTypeDecimal:
+		return constant.MakeFromLiteral(literal.(*DecimalLit).Value.Str, token.INT, 0)
+	case LiteralNodeTypeHexadecimal:
+		return constant.MakeFromLiteral(literal.(*HexadecimalLit).Value.Str, token.INT, 0)
+	case LiteralNodeTypeOctal:
+		return constant.MakeFromLiteral(literal.(*OctalLit).Value.Str, token.INT, 0)
+	case LiteralNodeTypeBinary:
+		return constant.MakeFromLiteral(literal.(*BinaryLit).Value.Str, token.INT, 0)
+	case LiteralNodeTypeFloat:
+		return constant.MakeFromLiteral(literal.(*FloatLit).Value.Str, token.FLOAT, 0)
+	case LiteralNodeTypeExponent:
+		return constant.MakeFromLiteral(literal.(*ExponentLit).Value.Str, token.FLOAT, 0)
+	case LiteralNodeTypeChar:
+		char, _ := utf8.DecodeRuneInString(literal.(*RuneLit).Value.Str)
+		return constant.MakeInt64(int64(char))
+	case LiteralNodeTypeString:
+		return constant.MakeString(literal.(*StringLit).Value.Str)
+	}
+	return nil
+}
+
+func (analyzer *Analyzer) unaryConstant(unaryExpr *UnaryExpression, exprType Type) constant.Value {
+	operand := analyzer.Constants[unaryExpr.Operand]
+	if operand == nil {
+		return nil
+	}
+	switch unaryExpr.Operator.Kind {
+	case TokenTypeMinus:
+		return constant.UnaryOp(token.SUB, operand, 0)
+	case TokenTypeBang:
+		return constant.UnaryOp(token.NOT, operand, 0)
+	case TokenTypeWavy:
+		// 无符号数按位取反时只保留其位数之内的部分
+		precision := uint(0)
+		if basicType, isBasic := exprType.(*BasicType); isBasic && !basicType.Signed {
+			precision = uint(basicType.Bits())
+		}
+		return constant.UnaryOp(token.XOR, operand, precision)
+	}
+	return nil
+}
+
+// 各个算术与位运算符对应的常量运算
+var constantOperators = map[TokenType]token.Token{
+	TokenTypePlus:      token.ADD,
+	TokenTypeMinus:     token.SUB,
+	TokenTypeStar:      token.MUL,
+	TokenTypeSlash:     token.QUO,
+	TokenTypePercent:   token.REM,
+	TokenTypeAmpersand: token.AND,
+	TokenTypeVertical:  token.OR,
+	TokenTypeCaret:     token.XOR,
+
+	TokenTypeDoubleAmpersand: token.LAND,
+	TokenTypeDoubleVertical:  token.LOR,
+}
+
+// 各个比较运算符对应的常量比较
+var constantComparisons = map[TokenType]token.Token{
+	TokenTypeDoubleEqual:     token.EQL,
+	TokenTypeBangEqual:       token.NEQ,
+	TokenTypeLeftAngle:       token.LSS,
+	TokenTypeRightAngle:      token.GTR,
+	TokenTypeLeftAngleEqual:  token.LEQ,
+	TokenTypeRightAngleEqual: token.GEQ,
+}
+
+func (analyzer *Analyzer) binaryConstant(binaryExpr *BinaryExpression, exprType Type) constant.Value {
+	left, right := analyzer.Constants[binaryExpr.Left], analyzer.Constants[binaryExpr.Right]
+	if left == nil || right == nil {
+		return nil
+	}
+	operator := binaryExpr.Operator.Kind
+	if comparison, isComparison := constantComparisons[operator]; isComparison {
+		return constant.MakeBool(constant.Compare(left, comparison, right))
+	}
+	switch operator {
+	case TokenTypeDoubleLeftAngle, TokenTypeDoubleRightAngle:
+		shift, exact := constant.Uint64Val(constant.ToInt(right))
+		if !exact || shift > maxConstantShift {
+			analyzer.ReportTypeError(binaryExpr.Right, InvalidOperation, "invalid shift count %s!", right)
+			return nil
+		}
+		if operator == TokenTypeDoubleLeftAngle {
+			return constant.Shift(constant.ToInt(left), token.SHL, uint(shift))
+		}
+		return constant.Shift(constant.ToInt(left), token.SHR, uint(shift))
+	case TokenTypeDoubleStar:
+		return powerConstant(left, right)
+	case TokenTypeSlash, TokenTypePercent:
+		if constant.Sign(right) == 0 {
+			analyzer.ReportTypeError(binaryExpr, ConstantDivisionByZero, "division by zero in constant expression!")
+			return nil
+		}
+		// 整数相除时舍去小数部分
+		if operator == TokenTypeSlash && IsIntegerType(exprType) {
+			return constant.BinaryOp(constant.ToInt(left), token.QUO_ASSIGN, constant.ToInt(right))
+		}
+	}
+	if binaryOperator, ok := constantOperators[operator]; ok {
+		return constant.BinaryOp(left, binaryOperator, right)
+	}
+	return nil
+}
+
+// 乘方：仅对非负整数次幂求值，以平方求幂计算
+func powerConstant(base, exponent constant.Value) constant.Value {
+	exponent = constant.ToInt(exponent)
+	n, exact := constant.Uint64Val(exponent)
+	if exponent.Kind() != constant.Int || !exact || n > maxConstantShift {
+		return nil
+	}
+	result := constant.MakeInt64(1)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result = constant.BinaryOp(result, token.MUL, base)
+		}
+		base = constant.BinaryOp(base, token.MUL, base)
+	}
+	return result
+}
+
+// 将常量转为类型 t 的值：整数类型的值必须为整数，浮点数类型的值统一为浮点数
+// 浮点数强转为整数时舍去小数部分，无法转换时返回 nil
+func convertConstant(value constant.Value, t Type) constant.Value {
+	switch {
+	case IsIntegerType(t):
+		if value.Kind() == constant.Float {
+			float, _ := constant.Float64Val(value)
+			if math.IsInf(float, 0) {
+				return nil
+			}
+			value = constant.MakeFloat64(math.Trunc(float))
+		}
+		value = constant.ToInt(value)
+	case IsFloatType(t):
+		value = constant.ToFloat(value)
+	}
+	if value.Kind() == constant.Unknown {
+		return nil
+	}
+	return value
+}
+
+// 未定类型的常量赋给类型为 t 的变量时转为 t 的值，不能表示时报错并返回 nil
+// 表达式不是常量时也返回 nil
+func (analyzer *Analyzer) ConvertConstantTo(expression Expression, t Type) constant.Value {
+	value := analyzer.Constants[expression]
+	if value == nil || IsUnknown(t) {
+		return nil
+	}
+	if value = convertConstant(value, t); value == nil || !analyzer.CheckRepresentable(expression, value, t) {
+		return nil
+	}
+	return value
+}
+
+// 常量作为类型 t 的值，无法表示时返回 nil，溢出已经在赋值检查时报告过
+func constantOfType(value constant.Value, t Type) constant.Value {
+	if value == nil || IsUnknown(t) {
+		return nil
+	}
+	if value = convertConstant(value, t); value == nil {
+		return nil
+	}
+	if basicType, isBasic := t.(*BasicType); isBasic && !representable(value, basicType) {
+		return nil
+	}
+	return value
+}
+
+// 检查常量能否以类型 t 表示，溢出时报错
+func (analyzer *Analyzer) CheckRepresentable(node Node, value constant.Value, t Type) bool {
+	basicType, isBasic := t.(*BasicType)
+	if !isBasic || representable(value, basicType) {
+		return true
+	}
+	analyzer.ReportTypeError(node, ConstantOverflow, "constant %s overflows %s!", value, basicType)
+	return false
+}
+
+func representable(value constant.Value, basicType *BasicType) bool {
+	switch basicType.Kind {
+	case BasicKindInteger, BasicKindRune:
+		value = constant.ToInt(value)
+		if value.Kind() != constant.Int {
+			return false
+		}
+		bits := uint(basicType.Bits())
+		var min, max constant.Value
+		if basicType.Signed {
+			min = constant.UnaryOp(token.SUB, constant.Shift(constant.MakeInt64(1), token.SHL, bits-1), 0)
+			max = constant.BinaryOp(constant.Shift(constant.MakeInt64(1), token.SHL, bits-1), token.SUB, constant.MakeInt64(1))
+		} else {
+			min = constant.MakeInt64(0)
+			max = constant.BinaryOp(constant.Shift(constant.MakeInt64(1), token.SHL, bits), token.SUB, constant.MakeInt64(1))
+		}
+		return constant.Compare(value, token.GEQ, min) && constant.Compare(value, token.LEQ, max)
+	case BasicKindFloat:
+		if basicType.Width == 4 {
+			float, _ := constant.Float32Val(value)
+			return !math.IsInf(float64(float), 0)
+		}
+		float, _ := constant.Float64Val(value)
+		return !math.IsInf(float, 0)
+	}
+	return true
+}
+
+// 将求出常量值的表达式替换为相应的字面量节点，其类型与常量值一并记录
+// 本身就是字面量或者不是常量的表达式原样返回
+func (analyzer *Analyzer) FoldConstant(expression Expression) Expression {
+	value := analyzer.Constants[expression]
+	if value == nil {
+		return expression
+	}
+	if basicExpr, isBasic := expression.(*BasicPrimaryExpression); isBasic {
+		if _, isLiteral := basicExpr.It.(Literal); isLiteral {
+			return expression
+		}
+	}
+	literal := constantLiteral(value, analyzer.Types[expression], *expression.GetSpan())
+	if literal == nil {
+		return expression
+	}
+	folded := &BasicPrimaryExpression{Span: *expression.GetSpan(), It: literal}
+	analyzer.Types[folded] = analyzer.Types[expression]
+	analyzer.Constants[folded] = value
+	return folded
+}
+
+// 以常量值构造字面量，其 token 的内容即常量的 Go 写法
+func constantLiteral(value constant.Value, t Type, span Span) Literal {
+	makeToken := func(kind TokenType, str string) *Token {
+		return &Token{Kind: kind, Str: str, Start: span.Start, End: span.End, Line: span.End.Line, Col: span.End.Col}
+	}
+	switch value.Kind() {
+	case constant.Bool:
+		if constant.BoolVal(value) {
+			return &TrueLit{Span: span, Value: makeToken(TokenTypeTrue, "true")}
+		}
+		return &FalseLit{Span: span, Value: makeToken(TokenTypeFalse, "false")}
+	case constant.String:
+		return &StringLit{Span: span, Value: makeToken(TokenTypeString, constant.StringVal(value))}
+	case constant.Int:
+		if t == RuneType {
+			char, _ := constant.Int64Val(value)
+			return &RuneLit{Span: span, Value: makeToken(TokenTypeRune, string(rune(char)))}
+		}
+		return &DecimalLit{Span: span, Value: makeToken(TokenTypeDecimalInteger, value.ExactString())}
+	case constant.Float:
+		float, _ := constant.Float64Val(value)
+		str := strconv.FormatFloat(float, 'g', -1, 64)
+		if strings.Contains(str, "e") {
+			return &ExponentLit{Span: span, Value: makeToken(TokenTypeExponent, str)}
+		}
+		if !strings.Contains(str, ".") {
+			str += ".0"
+		}
+		accuracy := 6
+		if t == DoubleType || t == UntypedDoubleFloat {
+			accuracy = 15
+		}
+		return &FloatLit{Span: span, Value: makeToken(TokenTypeFloat, str), Accuracy: accuracy}
+	}
+	return nil
+}
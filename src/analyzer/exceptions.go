@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	"fmt"
+)
+
+/* 受检异常：函数中抛出的每个异常，要么被包围它的 try 语句捕获，要么在函数的 throws 子句中声明
+ * 调用函数、创建实例时，被调用者声明的异常视为在调用处抛出；顶层语句没有 throws 子句，其中的异常必须被捕获
+ */
+
+// 类型须是内建的 Exception 类或其子类，不是时报错
+func (analyzer *Analyzer) CheckExceptionType(node Node, t Type, context string) bool {
+	if IsUnknown(t) {
+		return false
+	}
+	if classType, isClass := t.(*ClassType); isClass && !classType.IsInterface && classType.IsSubtypeOf(ExceptionType) {
+		return true
+	}
+	analyzer.ReportTypeError(node, TypeMismatch, "%s is not an exception type in %s!", t, context)
+	return false
+}
+
+// 检查在 node 处抛出的异常是否都已被捕获或声明
+func (analyzer *Analyzer) CheckThrows(node Node, throws []Type) {
+	for _, thrown := range throws {
+		if analyzer.isHandled(thrown) {
+			continue
+		}
+		if analyzer.currentFunction == nil {
+			analyzer.ReportTypeError(node, UnhandledException, "exception %s must be caught!", thrown)
+		} else {
+			analyzer.ReportTypeError(node, UnhandledException,
+				"exception %s must be caught or declared in the throws clause of the function!", thrown)
+		}
+	}
+}
+func (analyzer *Analyzer) isHandled(thrown Type) bool {
+	for _, caught := range analyzer.catching {
+		if ExceptionCovered(thrown, caught) {
+			return true
+		}
+	}
+	return analyzer.currentFunction != nil && ExceptionCovered(thrown, analyzer.currentFunction.Throws)
+}
+
+func (analyzer *Analyzer) CheckThrowStatement(throwStmt *ThrowStatement) {
+	valueType := analyzer.CheckValueExpression(throwStmt.Value)
+	if analyzer.CheckExceptionType(throwStmt.Value, valueType, "throw statement") {
+		analyzer.CheckThrows(throwStmt, []Type{valueType})
+	}
+}
+
+// try 块中抛出的异常可以被其后的 catch 捕获；catch 与 finally 块中抛出的异常则不会被同一个 try 语句捕获
+// 前面的 catch 已经捕获了同一类型或其父类时，后面的 catch 永远不会执行
+func (analyzer *Analyzer) CheckTryCatchStatement(tryCatchStmt *TryCatchStatement) {
+	caught := make([]Type, len(tryCatchStmt.Handlers))
+	for i, handler := range tryCatchStmt.Handlers {
+		caught[i] = analyzer.ResolveType(handler.ErrorType)
+		if !analyzer.CheckExceptionType(handler.ErrorType, caught[i], "catch clause") {
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if !IsUnknown(caught[j]) && AssignableTo(caught[i], caught[j]) {
+				shadowing := tryCatchStmt.Handlers[j].ErrorType.GetSpan()
+				analyzer.ReportTypeError(handler.ErrorType, UnreachableCatch,
+					"unreachable catch clause: %s has already been caught!", caught[i]).
+					AddNote(analyzer.parser.FileName, toPosition(shadowing.Start), toPosition(shadowing.End),
+						fmt.Sprintf("%s is caught here", caught[j]))
+				break
+			}
+		}
+	}
+
+	analyzer.catching = append(analyzer.catching, caught)
+	analyzer.CheckScopedBlock(tryCatchStmt.TryBlock)
+	analyzer.catching = analyzer.catching[:len(analyzer.catching)-1]
+
+	for i, handler := range tryCatchStmt.Handlers {
+		analyzer.EnterNewBlockScope()
+		handlerSymbol := &IdSymbol{
+			Symbol: &Symbol{Token: handler.Name.Token},
+			Type:   caught[i],
+		}
+		analyzer.Symbols[handler] = handlerSymbol
+		analyzer.DefineSymbol(handler.Name.Token, handlerSymbol)
+		analyzer.recordScope(handler.Handler)
+		analyzer.CheckBlockStatement(handler.Handler)
+		analyzer.LeaveCurrentBlockScope()
+	}
+	analyzer.CheckScopedBlock(tryCatchStmt.Finally)
+}
@@ -0,0 +1,231 @@
+package analyzer
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+)
+
+/* 泛型采用统一表示：泛型函数与泛型类只检查、编译一次，
+ * 泛型参数在类型检查时代入实参，运行时的值本身就带有类型，不需要为每组实参生成一份代码
+ */
+
+// 为泛型参数列表创建泛型参数，约束待解析时再确定
+func newTypeParams(generics *GenericArgs) []*TypeParamType {
+	if generics == nil {
+		return nil
+	}
+	params := make([]*TypeParamType, len(generics.Args))
+	for i, arg := range generics.Args {
+		typeSymbol := &TypeSymbol{
+			Symbol:      &Symbol{Token: arg.ArgName.Token},
+			Description: &TypeName{Span: arg.ArgName.Span, Identifier: arg.ArgName},
+			DescType:    TypeDescriptionTypeName,
+		}
+		params[i] = &TypeParamType{Symbol: typeSymbol}
+		typeSymbol.Type = params[i]
+	}
+	return params
+}
+
+// 在当前作用域中定义泛型参数，再解析它们的约束，因而约束中可以引用同一列表中的泛型参数
+func (analyzer *Analyzer) DefineGenerics(generics *GenericArgs, params []*TypeParamType) {
+	if generics == nil {
+		return
+	}
+	for i, arg := range generics.Args {
+		analyzer.DefineSymbol(arg.ArgName.Token, params[i].Symbol)
+	}
+	for i, arg := range generics.Args {
+		if arg.Generics != nil {
+			analyzer.ReportTypeError(arg.Generics, TypeArgumentMismatch, "type parameter %s cannot have type parameters!",
+				arg.ArgName.GetName())
+		}
+		if arg.Constraint == nil {
+			continue
+		}
+		constraint := analyzer.ResolveType(arg.Constraint)
+		if classType, isClass := constraint.(*ClassType); isClass {
+			params[i].Constraint = classType
+		} else if !IsUnknown(constraint) {
+			analyzer.ReportTypeError(arg.Constraint, TypeMismatch, "constraint of type parameter %s must be a class or interface, got %s!",
+				arg.ArgName.GetName(), constraint)
+		}
+	}
+}
+
+// 以类型标注中的实参实例化泛型类，实参的个数须与泛型参数一致，且满足各自的约束
+func (analyzer *Analyzer) InstantiateType(node Node, generic Type, args []Type) Type {
+	if IsUnknown(generic) {
+		return Unknown
+	}
+	classType, isClass := generic.(*ClassType)
+	if !isClass || len(classType.TypeParams) == 0 {
+		analyzer.ReportTypeError(node, TypeArgumentMismatch, "type %s is not generic!", generic)
+		return Unknown
+	}
+	if len(args) != len(classType.TypeParams) {
+		analyzer.ReportTypeError(node, TypeArgumentMismatch, "wrong number of type arguments for %s: expected %d, got %d!",
+			classType, len(classType.TypeParams), len(args))
+		return Unknown
+	}
+	instance := classType.Instantiate(args)
+	analyzer.CheckConstraints(node, classType.TypeParams, instance.Bindings())
+	return instance
+}
+
+// 检查实参是否满足泛型参数的约束；解析声明时约束可能尚未解析，检查推迟到全部声明解析完毕之后
+func (analyzer *Analyzer) CheckConstraints(node Node, params []*TypeParamType, bindings map[*TypeSymbol]Type) {
+	if analyzer.resolving {
+		analyzer.deferredChecks = append(analyzer.deferredChecks, func() {
+			analyzer.CheckConstraints(node, params, bindings)
+		})
+		return
+	}
+	for _, param := range params {
+		arg, bound := bindings[param.Symbol]
+		if !bound || param.Constraint == nil {
+			continue
+		}
+		constraint := Substitute(param.Constraint, bindings).(*ClassType)
+		if !satisfies(arg, constraint) {
+			analyzer.ReportTypeError(node, ConstraintNotSatisfied, "%s does not satisfy constraint %s of type parameter %s!",
+				arg, constraint, param)
+		}
+	}
+}
+
+// 类型是否满足约束：是约束的子类或实现了约束接口，泛型参数则看其自身的约束
+func satisfies(t Type, constraint *ClassType) bool {
+	switch t := t.(type) {
+	case *ClassType:
+		return t.IsSubtypeOf(constraint)
+	case *TypeParamType:
+		return t.Constraint != nil && t.Constraint.IsSubtypeOf(constraint)
+	}
+	return IsUnknown(t)
+}
+
+// 由实参推断泛型函数的泛型实参，得到代入实参后的函数类型；无法推断时报错并以 Unknown 代替
+func (analyzer *Analyzer) InferTypeArguments(call Node, fnType *FunctionType, argTypes []Type) *FunctionType {
+	bindings := make(map[*TypeSymbol]Type)
+	for i, paramType := range fnType.Params {
+		if i < len(argTypes) {
+			inferBindings(fnType.TypeParams, paramType, argTypes[i], bindings)
+		}
+	}
+	for _, param := range fnType.TypeParams {
+		if _, bound := bindings[param.Symbol]; !bound {
+			analyzer.ReportTypeError(call, TypeArgumentMismatch, "cannot infer type argument %s of %s!", param, fnType)
+			bindings[param.Symbol] = Unknown
+		}
+	}
+	analyzer.CheckConstraints(call, fnType.TypeParams, bindings)
+	instance := Substitute(fnType, bindings).(*FunctionType)
+	instance.TypeParams = nil
+	return instance
+}
+
+// 对照形参与实参的类型结构，为出现在形参中的泛型参数绑定实参中对应位置的类型，先绑定者优先
+func inferBindings(params []*TypeParamType, paramType, argType Type, bindings map[*TypeSymbol]Type) {
+	if IsUnknown(argType) {
+		return
+	}
+	switch paramType := paramType.(type) {
+	case *TypeParamType:
+		if _, bound := bindings[paramType.Symbol]; !bound && argType.TypeKind() != TypeKindNil && isTypeParamOf(params, paramType) {
+			bindings[paramType.Symbol] = DefaultType(argType)
+		}
+	case *ArrayType:
+		if argArray, isArray := argType.(*ArrayType); isArray {
+			inferBindings(params, paramType.Element, argArray.Element, bindings)
+		}
+	case *TableType:
+		if argTable, isTable := argType.(*TableType); isTable {
+			inferBindings(params, paramType.Value, argTable.Value, bindings)
+		}
+	case *FunctionType:
+		if argFn, isFn := argType.(*FunctionType); isFn &&
+			len(argFn.Params) == len(paramType.Params) && len(argFn.Returns) == len(paramType.Returns) {
+			for i := range paramType.Params {
+				inferBindings(params, paramType.Params[i], argFn.Params[i], bindings)
+			}
+			for i := range paramType.Returns {
+				inferBindings(params, paramType.Returns[i], argFn.Returns[i], bindings)
+			}
+		}
+	case *ClassType:
+		if argClass, isClass := argType.(*ClassType); isClass {
+			if ancestor := argClass.Ancestor(paramType.Symbol); ancestor != nil {
+				paramArgs, argArgs := paramType.Args(), ancestor.Args()
+				for i := 0; i < len(paramArgs) && i < len(argArgs); i++ {
+					inferBindings(params, paramArgs[i], argArgs[i], bindings)
+				}
+			}
+		}
+	}
+}
+func isTypeParamOf(params []*TypeParamType, param *TypeParamType) bool {
+	for _, it := range params {
+		if it == param {
+			return true
+		}
+	}
+	return false
+}
+
+// 将类型中的泛型参数替换为绑定的实参，没有需要替换的部分时返回原类型
+func Substitute(t Type, bindings map[*TypeSymbol]Type) Type {
+	if len(bindings) == 0 || t == nil {
+		return t
+	}
+	switch t := t.(type) {
+	case *TypeParamType:
+		if bound, ok := bindings[t.Symbol]; ok {
+			return bound
+		}
+	case *ArrayType:
+		return &ArrayType{Element: Substitute(t.Element, bindings)}
+	case *TableType:
+		return &TableType{Value: Substitute(t.Value, bindings)}
+	case *TupleType:
+		return &TupleType{Types: substituteList(t.Types, bindings)}
+	case *FunctionType:
+		return &FunctionType{
+			TypeParams: t.TypeParams,
+			Params:     substituteList(t.Params, bindings),
+			Returns:    substituteList(t.Returns, bindings),
+			Variadic:   t.Variadic,
+			Throws:     substituteList(t.Throws, bindings),
+		}
+	case *ClassType:
+		if args := t.Args(); len(args) > 0 {
+			return t.Instantiate(substituteList(args, bindings))
+		}
+	}
+	return t
+}
+func substituteList(types []Type, bindings map[*TypeSymbol]Type) []Type {
+	substituted := make([]Type, len(types))
+	for i, t := range types {
+		substituted[i] = Substitute(t, bindings)
+	}
+	return substituted
+}
+
+// 类成员在所属类（可能是泛型类的实例）中的类型
+func memberType(member *IdSymbol, owner *ClassType) Type {
+	if member.Type == nil {
+		return nil
+	}
+	return Substitute(member.Type, owner.Bindings())
+}
+
+// 子类中以名称引用父类成员时，父类若是泛型类的实例，则以代入实参后的类型作为符号的类型
+func inheritedSymbol(member *IdSymbol, owner *ClassType) *IdSymbol {
+	if len(owner.Bindings()) == 0 {
+		return member
+	}
+	inherited := *member
+	inherited.Type = memberType(member, owner)
+	return &inherited
+}
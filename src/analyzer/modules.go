@@ -0,0 +1,265 @@
+package analyzer
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/lexer"
+	. "coral-lang/src/parser"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 源文件的扩展名，引入路径没有扩展名时补上
+const SourceExtension = ".cr"
+
+// 一个已经加载的模块，即一个源文件
+type LoadedModule struct {
+	Name     string    // 模块名：去掉扩展名的文件名
+	Path     string    // 诊断信息中显示的文件路径
+	Package  string    // 文件以 package 语句声明的包名，没有声明时为空
+	Analyzer *Analyzer // 检查此模块的语义分析器，其顶层区块即模块导出的符号
+
+	key         string            // 文件的绝对路径，同一个文件只加载一次
+	declaration *PackageStatement // 声明包名的语句，用于报告同一目录中包名不一致
+}
+
+// 模块导出的符号：模块顶层定义的全部符号
+func (module *LoadedModule) Lookup(name string) ISymbol {
+	return module.Analyzer.RootScope.SymbolMap[name]
+}
+
+// 模块加载器：将引入路径映射到源文件，每个模块只解析与检查一次，并检测循环引入
+type ModuleLoader struct {
+	SearchPath []string                 // 查找非相对路径（如标准库 "math"）的目录，按顺序查找
+	Modules    map[string]*LoadedModule // 以文件的绝对路径为键的全部模块
+	Order      []*LoadedModule          // 按检查完成的先后排列，被引入的模块总在引入者之前
+
+	loading  []*LoadedModule          // @private 正在检查的模块，由外向内，用于检测循环引入
+	packages map[string]*LoadedModule // @private 以目录的绝对路径为键，该目录中第一个声明了包名的模块
+}
+
+func NewModuleLoader(searchPath []string) *ModuleLoader {
+	return &ModuleLoader{
+		SearchPath: searchPath,
+		Modules:    make(map[string]*LoadedModule),
+		packages:   make(map[string]*LoadedModule),
+	}
+}
+
+// 将引入路径解析为文件路径：以 ./ 或 ../ 开头的路径与绝对路径相对于引入者所在的目录，
+// 其余的依次在搜索路径中查找；没有扩展名时补上 .cr，找不到时返回 false
+func (loader *ModuleLoader) Resolve(importer string, importPath string) (string, bool) {
+	if filepath.Ext(importPath) == "" {
+		importPath += SourceExtension
+	}
+	var candidates []string
+	if filepath.IsAbs(importPath) {
+		candidates = []string{importPath}
+	} else if strings.HasPrefix(importPath, "./") || strings.HasPrefix(importPath, "../") {
+		candidates = []string{filepath.Join(filepath.Dir(importer), importPath)}
+	} else {
+		for _, dir := range loader.SearchPath {
+			candidates = append(candidates, filepath.Join(dir, importPath))
+		}
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// 开始检查一个模块，记录在已加载的模块中，使得检查途中对它的引入被识别为循环
+func (loader *ModuleLoader) enter(analyzer *Analyzer) *LoadedModule {
+	path := analyzer.parser.FileName
+	module := &LoadedModule{
+		Name:     strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Path:     path,
+		Analyzer: analyzer,
+	}
+	if path != "" {
+		if key, err := filepath.Abs(path); err == nil {
+			module.key = key
+			loader.Modules[key] = module
+		}
+	}
+	loader.loading = append(loader.loading, module)
+	return module
+}
+func (loader *ModuleLoader) leave(module *LoadedModule) {
+	loader.loading = loader.loading[:len(loader.loading)-1]
+	loader.Order = append(loader.Order, module)
+}
+
+// 加载 importer 引入的模块，出错时在 node 处报错并返回 nil
+// 新加载的模块与引入者共用类型、常量与符号的记录，其中的错误也一并归入引入者
+func (loader *ModuleLoader) Load(importer *Analyzer, node Node, importPath string) *LoadedModule {
+	path, found := loader.Resolve(importer.parser.FileName, importPath)
+	if !found {
+		importer.ReportError(*node.GetSpan(), NewCoralError("Compile",
+			fmt.Sprintf("cannot find module \"%s\"!", importPath), ModuleNotFound))
+		return nil
+	}
+	key, _ := filepath.Abs(path)
+	for i, module := range loader.loading {
+		if module.key != key {
+			continue
+		}
+		cycle := make([]string, 0, len(loader.loading)-i+1)
+		for _, loading := range loader.loading[i:] {
+			cycle = append(cycle, loading.Path)
+		}
+		importer.ReportError(*node.GetSpan(), NewCoralError("Compile",
+			fmt.Sprintf("import cycle: %s -> %s!", strings.Join(cycle, " -> "), path), ImportCycle))
+		return nil
+	}
+	if module, loaded := loader.Modules[key]; loaded {
+		return module
+	}
+
+	parser := new(Parser)
+	if err := parser.InitFromFile(path); err != nil {
+		importer.ReportError(*node.GetSpan(), err)
+		return nil
+	}
+	analyzer := new(Analyzer)
+	analyzer.InitAnalyzerFromParser(parser)
+	analyzer.Loader = loader
+	analyzer.Types = importer.Types
+	analyzer.Constants = importer.Constants
+	analyzer.Symbols = importer.Symbols
+	analyzer.Imports = importer.Imports
+	analyzer.CheckProgram()
+	importer.Errors = append(importer.Errors, analyzer.Errors...)
+	importer.Diagnostics = append(importer.Diagnostics, analyzer.Diagnostics...)
+	return loader.Modules[key]
+}
+
+// 语义分析器使用的模块加载器，没有指定时以源文件所在的目录作为搜索路径
+func (analyzer *Analyzer) ModuleLoader() *ModuleLoader {
+	if analyzer.Loader == nil {
+		analyzer.Loader = NewModuleLoader([]string{filepath.Dir(analyzer.parser.FileName)})
+	}
+	return analyzer.Loader
+}
+
+// 包名限定此文件顶层定义的函数、类、接口与枚举
+// 同一目录中的文件属于同一个包：程序加载的文件中，同一目录下声明的包名必须一致
+func (analyzer *Analyzer) DeclarePackageStatement(packageStmt *PackageStatement) {
+	analyzer.Package = packageStmt.Name.GetName()
+	loader := analyzer.ModuleLoader()
+	if len(loader.loading) == 0 {
+		return
+	}
+	module := loader.loading[len(loader.loading)-1]
+	module.Package, module.declaration = analyzer.Package, packageStmt
+	if module.key == "" {
+		return
+	}
+	dir := filepath.Dir(module.key)
+	declared, found := loader.packages[dir]
+	if !found {
+		loader.packages[dir] = module
+		return
+	}
+	if declared.Package != module.Package {
+		analyzer.ReportError(packageStmt.Name.Span, NewCoralError("Compile",
+			fmt.Sprintf("package %s conflicts with package %s declared in the same directory!", module.Package, declared.Package),
+			PackageMismatch)).
+			AddNote(declared.Path, toPosition(declared.declaration.Name.Start), toPosition(declared.declaration.Name.End),
+				fmt.Sprintf("package %s is declared here", declared.Package))
+	}
+}
+
+// 定义在顶层的函数、类、接口与枚举属于文件声明的包，嵌套的定义不受包名限定
+func (analyzer *Analyzer) declaringPackage() string {
+	if analyzer.CurrentScope != analyzer.RootScope {
+		return ""
+	}
+	return analyzer.Package
+}
+
+// 引入语句加载模块并以模块名、别名或者引入的名称定义符号
+func (analyzer *Analyzer) DeclareImportStatement(importStmt ImportStatement) {
+	switch importStmt.ImportStatementNodeType() {
+	case ImportStatementTypeSingleGlobal:
+		globalImport := importStmt.(*SingleGlobalImportStatement)
+		module := analyzer.loadModule(globalImport, globalImport.Path)
+		// 以包名作为模块名，没有声明包名时以去掉扩展名的文件名作为模块名：import "lib/animal.cr" 即定义 animal
+		moduleType := &ModuleType{Name: strings.TrimSuffix(filepath.Base(globalImport.Path), filepath.Ext(globalImport.Path)), Module: module}
+		if module != nil && module.Package != "" {
+			moduleType.Name = module.Package
+		}
+		token := &Token{Kind: TokenTypeIdentifier, Str: moduleType.Name, Start: globalImport.Start, End: globalImport.End}
+		if globalImport.As != nil {
+			token = globalImport.As.Token
+		}
+		symbol := &IdSymbol{Symbol: &Symbol{Token: token}, Type: moduleType, Immutable: true}
+		analyzer.Symbols[globalImport] = symbol
+		analyzer.DefineSymbol(token, symbol)
+	case ImportStatementTypeSingleFrom:
+		fromImport := importStmt.(*SingleFromImportStatement)
+		module := analyzer.loadModule(fromImport, fromImport.From)
+		analyzer.DefineImportElement(module, fromImport.Element)
+	case ImportStatementTypeList:
+		listImport := importStmt.(*ListImportStatement)
+		module := analyzer.loadModule(listImport, listImport.From)
+		for _, element := range listImport.Elements {
+			analyzer.DefineImportElement(module, element)
+		}
+	}
+}
+func (analyzer *Analyzer) loadModule(importStmt ImportStatement, importPath string) *LoadedModule {
+	module := analyzer.ModuleLoader().Load(analyzer, importStmt, importPath)
+	if module != nil {
+		analyzer.Imports[importStmt] = module
+	}
+	return module
+}
+
+// 以别名或原名定义从模块中引入的符号，模块加载失败时定义为类型未知的符号以免连锁报错
+func (analyzer *Analyzer) DefineImportElement(module *LoadedModule, element *ImportElement) {
+	token := element.ModuleName.Token
+	if element.As != nil {
+		token = element.As.Token
+	}
+	var symbol ISymbol = &IdSymbol{Symbol: &Symbol{Token: token}, Type: Unknown}
+	if module != nil {
+		if imported := module.Lookup(element.ModuleName.GetName()); imported != nil {
+			analyzer.Symbols[element] = imported
+			analyzer.References[element.ModuleName] = imported
+			symbol = importedSymbol(token, imported)
+		} else {
+			analyzer.ReportTypeError(element.ModuleName, UndeclaredIdentifier, "module %s has no member \"%s\"!",
+				module.Name, element.ModuleName.GetName())
+		}
+	}
+	analyzer.DefineSymbol(token, symbol)
+}
+
+// 引入的符号在引入者中以引入语句中的名称定义：变量与函数引入的是引入时的值，不能再被赋值
+// 类型与枚举须保持同一个符号，以免被视为不同的类型
+func importedSymbol(token *Token, imported ISymbol) ISymbol {
+	if idSymbol, isId := imported.(*IdSymbol); isId {
+		return &IdSymbol{Symbol: &Symbol{Token: token}, Type: idSymbol.Type, Constant: idSymbol.Constant, Immutable: true,
+			Function: idSymbol.Function}
+	}
+	return imported
+}
+
+// 模块成员的类型，与以名称引用该符号时相同
+func (analyzer *Analyzer) LookupModuleMember(moduleType *ModuleType, name *Identifier) Type {
+	if moduleType.Module == nil {
+		return Unknown
+	}
+	symbol := moduleType.Module.Lookup(name.GetName())
+	if symbol == nil {
+		analyzer.ReportTypeError(name, UndeclaredIdentifier, "module %s has no member \"%s\"!", moduleType.Name, name.GetName())
+		return Unknown
+	}
+	analyzer.References[name] = symbol
+	return analyzer.SymbolType(name, symbol)
+}
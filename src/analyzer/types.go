@@ -0,0 +1,528 @@
+package analyzer
+
+import (
+	"strings"
+)
+
+const (
+	TypeKindUnknown      = iota // 无法确定的类型，出错后使用，以免同一个错误被连锁报告
+	TypeKindVoid                // 没有返回值的函数调用
+	TypeKindNil                 // nil 字面量
+	TypeKindUntypedInt          // 尚未确定具体类型的整数常量
+	TypeKindUntypedFloat        // 尚未确定具体类型的浮点数常量
+	TypeKindBasic               // 内建的基本类型
+	TypeKindArray
+	TypeKindTable
+	TypeKindFunction
+	TypeKindTuple // 多返回值函数的调用结果
+	TypeKindClass // 类与接口
+	TypeKindEnum
+	TypeKindTypeParam // 泛型参数
+	TypeKindModule    // 引入的模块
+	TypeKindStatic    // 以类名作为值，只能用于访问类的静态成员
+)
+
+// 语义分析得出的类型
+type Type interface {
+	TypeKind() int
+	String() string
+}
+
+type UnknownType struct{}
+
+func (it *UnknownType) TypeKind() int {
+	return TypeKindUnknown
+}
+func (it *UnknownType) String() string {
+	return "unknown"
+}
+
+type VoidType struct{}
+
+func (it *VoidType) TypeKind() int {
+	return TypeKindVoid
+}
+func (it *VoidType) String() string {
+	return "void"
+}
+
+type NilType struct{}
+
+func (it *NilType) TypeKind() int {
+	return TypeKindNil
+}
+func (it *NilType) String() string {
+	return "nil"
+}
+
+// 未定类型的常量，赋值或参与运算时再转为具体的基本类型
+type UntypedType struct {
+	Kind    int        // TypeKindUntypedInt 或 TypeKindUntypedFloat
+	Default *BasicType // 无从推断时采用的类型
+}
+
+func (it *UntypedType) TypeKind() int {
+	return it.Kind
+}
+func (it *UntypedType) String() string {
+	if it.Kind == TypeKindUntypedInt {
+		return "untyped int"
+	}
+	return "untyped float"
+}
+
+type ArrayType struct {
+	Element Type
+}
+
+func (it *ArrayType) TypeKind() int {
+	return TypeKindArray
+}
+func (it *ArrayType) String() string {
+	return it.Element.String() + "[]"
+}
+
+// 表字面量 {key: value} 的类型
+type TableType struct {
+	Value Type
+}
+
+func (it *TableType) TypeKind() int {
+	return TypeKindTable
+}
+func (it *TableType) String() string {
+	return "{" + it.Value.String() + "}"
+}
+
+type FunctionType struct {
+	TypeParams []*TypeParamType // 泛型函数的泛型参数，调用时由实参推断
+	Params     []Type
+	Returns    []Type
+	Variadic   bool   // 接受任意个数任意类型的实参，仅用于内建函数
+	Throws     []Type // throws 子句中声明的异常类型
+}
+
+func (it *FunctionType) TypeKind() int {
+	return TypeKindFunction
+}
+func (it *FunctionType) String() string {
+	var builder strings.Builder
+	builder.WriteString("fn")
+	if len(it.TypeParams) > 0 {
+		builder.WriteString("<" + typeParamsString(it.TypeParams) + ">")
+	}
+	builder.WriteString("(")
+	if it.Variadic {
+		builder.WriteString("...")
+	} else {
+		builder.WriteString(typeListString(it.Params))
+	}
+	builder.WriteString(")")
+	if len(it.Returns) == 1 {
+		builder.WriteString(" " + it.Returns[0].String())
+	} else if len(it.Returns) > 1 {
+		builder.WriteString(" (" + typeListString(it.Returns) + ")")
+	}
+	if len(it.Throws) > 0 {
+		builder.WriteString(" throws " + typeListString(it.Throws))
+	}
+	return builder.String()
+}
+
+// 函数调用的结果：0 个返回值为 void，1 个为其本身，多个则为元组
+func (it *FunctionType) Result() Type {
+	switch len(it.Returns) {
+	case 0:
+		return Void
+	case 1:
+		return it.Returns[0]
+	}
+	return &TupleType{Types: it.Returns}
+}
+
+type TupleType struct {
+	Types []Type
+}
+
+func (it *TupleType) TypeKind() int {
+	return TypeKindTuple
+}
+func (it *TupleType) String() string {
+	return "(" + typeListString(it.Types) + ")"
+}
+
+// 类或接口的类型，成员记录在其类型符号的 Members 作用域中
+// 泛型类以实参实例化后得到新的 ClassType，其 Origin 指向泛型类本身，成员的类型在访问时再代入实参
+type ClassType struct {
+	Symbol      *TypeSymbol
+	IsInterface bool
+	Base        *ClassType   // 类继承的父类或接口继承的接口，没有时为 nil
+	Interfaces  []*ClassType // 类实现的接口
+	TypeParams  []*TypeParamType
+
+	Origin   *ClassType // 实例化前的泛型类，未实例化时为 nil
+	TypeArgs []Type     // 实例化时的泛型实参
+}
+
+// 以 args 实例化泛型类，实参恰为其自身的泛型参数时即为泛型类本身
+func (it *ClassType) Instantiate(args []Type) *ClassType {
+	generic := it.Generic()
+	for i, param := range generic.TypeParams {
+		if i >= len(args) || args[i] != Type(param) {
+			return &ClassType{Symbol: generic.Symbol, IsInterface: generic.IsInterface, Origin: generic, TypeArgs: args}
+		}
+	}
+	return generic
+}
+
+// 实例化前的泛型类
+func (it *ClassType) Generic() *ClassType {
+	if it.Origin != nil {
+		return it.Origin
+	}
+	return it
+}
+
+// 泛型实参，未实例化的泛型类以其自身的泛型参数作为实参
+func (it *ClassType) Args() []Type {
+	if it.Origin != nil {
+		return it.TypeArgs
+	}
+	args := make([]Type, len(it.TypeParams))
+	for i, param := range it.TypeParams {
+		args[i] = param
+	}
+	return args
+}
+
+// 泛型参数到实参的绑定，用于代入成员、父类等的类型
+func (it *ClassType) Bindings() map[*TypeSymbol]Type {
+	if it.Origin == nil {
+		return nil
+	}
+	bindings := make(map[*TypeSymbol]Type)
+	for i, param := range it.Origin.TypeParams {
+		if i < len(it.TypeArgs) {
+			bindings[param.Symbol] = it.TypeArgs[i]
+		}
+	}
+	return bindings
+}
+
+// 代入实参后的父类
+func (it *ClassType) BaseType() *ClassType {
+	base := it.Generic().Base
+	if base == nil {
+		return nil
+	}
+	return Substitute(base, it.Bindings()).(*ClassType)
+}
+
+// 代入实参后实现的接口
+func (it *ClassType) InterfaceTypes() []*ClassType {
+	interfaces := it.Generic().Interfaces
+	bindings := it.Bindings()
+	if len(bindings) == 0 {
+		return interfaces
+	}
+	substituted := make([]*ClassType, len(interfaces))
+	for i, implemented := range interfaces {
+		substituted[i] = Substitute(implemented, bindings).(*ClassType)
+	}
+	return substituted
+}
+
+// 查找成员，自身没有时沿继承链向上查找；父类的构造方法不会被继承
+func (it *ClassType) Member(name string) *IdSymbol {
+	member, _ := it.MemberOf(name)
+	return member
+}
+
+// 查找成员并返回定义它的类，成员的类型需要以该类的实参代入
+func (it *ClassType) MemberOf(name string) (*IdSymbol, *ClassType) {
+	for current := it; current != nil; current = current.BaseType() {
+		if current.Symbol.Members == nil || (current.Symbol != it.Symbol && name == current.Symbol.Token.Str) {
+			continue
+		}
+		if member, ok := current.Symbol.Members.SymbolMap[name].(*IdSymbol); ok {
+			return member, current
+		}
+	}
+	return nil, nil
+}
+
+// 继承链与实现的接口中与 symbol 对应的类型，即 it 被视为该泛型类时的实参；没有时返回 nil
+func (it *ClassType) Ancestor(symbol *TypeSymbol) *ClassType {
+	for current := it; current != nil; current = current.BaseType() {
+		if current.Symbol == symbol {
+			return current
+		}
+		for _, implemented := range current.InterfaceTypes() {
+			if ancestor := implemented.Ancestor(symbol); ancestor != nil {
+				return ancestor
+			}
+		}
+	}
+	return nil
+}
+
+// 是否为 other 本身、other 的子类，或者实现了接口 other；泛型类的实参须完全相同
+func (it *ClassType) IsSubtypeOf(other *ClassType) bool {
+	ancestor := it.Ancestor(other.Symbol)
+	return ancestor != nil && identicalTypeLists(ancestor.Args(), other.Args())
+}
+
+func (it *ClassType) TypeKind() int {
+	return TypeKindClass
+}
+func (it *ClassType) String() string {
+	if it.Origin != nil {
+		return it.Symbol.QualifiedName() + "<" + typeListString(it.TypeArgs) + ">"
+	}
+	if len(it.TypeParams) > 0 {
+		return it.Symbol.QualifiedName() + "<" + typeParamsString(it.TypeParams) + ">"
+	}
+	return it.Symbol.QualifiedName()
+}
+
+// 以类名作为值时的类型，与类的实例相区分
+type StaticType struct {
+	Class *ClassType
+}
+
+func (it *StaticType) TypeKind() int {
+	return TypeKindStatic
+}
+func (it *StaticType) String() string {
+	if it.Class.IsInterface {
+		return "interface " + it.Class.String()
+	}
+	return "class " + it.Class.String()
+}
+
+type EnumType struct {
+	Symbol *EnumSymbol
+}
+
+func (it *EnumType) TypeKind() int {
+	return TypeKindEnum
+}
+func (it *EnumType) String() string {
+	return it.Symbol.QualifiedName()
+}
+
+// 泛型参数，有约束时只能作为约束的类或接口使用
+type TypeParamType struct {
+	Symbol     *TypeSymbol
+	Constraint *ClassType // 没有约束时为 nil
+}
+
+func (it *TypeParamType) TypeKind() int {
+	return TypeKindTypeParam
+}
+func (it *TypeParamType) String() string {
+	return it.Symbol.Token.Str
+}
+
+type ModuleType struct {
+	Name   string
+	Module *LoadedModule // 引入的模块，加载失败时为 nil
+}
+
+func (it *ModuleType) TypeKind() int {
+	return TypeKindModule
+}
+func (it *ModuleType) String() string {
+	return "module " + it.Name
+}
+
+var (
+	Unknown = &UnknownType{}
+	Void    = &VoidType{}
+	Nil     = &NilType{}
+
+	UntypedInt         = &UntypedType{Kind: TypeKindUntypedInt, Default: IntType}
+	UntypedFloat       = &UntypedType{Kind: TypeKindUntypedFloat, Default: FloatType}
+	UntypedDoubleFloat = &UntypedType{Kind: TypeKindUntypedFloat, Default: DoubleType} // 小数位超过 float 精度的常量
+)
+
+func IsUnknown(t Type) bool {
+	return t == nil || t.TypeKind() == TypeKindUnknown
+}
+
+// 整数类型，字符型 rune 实际上等同于 uint16，也视作整数
+func IsIntegerType(t Type) bool {
+	if basicType, isBasic := t.(*BasicType); isBasic {
+		return basicType.Kind == BasicKindInteger || basicType.Kind == BasicKindRune
+	}
+	return t.TypeKind() == TypeKindUntypedInt
+}
+func IsFloatType(t Type) bool {
+	if basicType, isBasic := t.(*BasicType); isBasic {
+		return basicType.Kind == BasicKindFloat
+	}
+	return t.TypeKind() == TypeKindUntypedFloat
+}
+func IsNumericType(t Type) bool {
+	return IsIntegerType(t) || IsFloatType(t)
+}
+func IsUntyped(t Type) bool {
+	return t.TypeKind() == TypeKindUntypedInt || t.TypeKind() == TypeKindUntypedFloat
+}
+
+// 引用类型的默认值为 nil
+func IsReferenceType(t Type) bool {
+	switch t.TypeKind() {
+	case TypeKindArray, TypeKindTable, TypeKindFunction, TypeKindClass, TypeKindTypeParam:
+		return true
+	}
+	return t == StringType
+}
+
+// 类型的零值：基本类型见 BasicType.Zero，引用类型的零值为 nil
+func ZeroValue(t Type) interface{} {
+	if basicType, isBasic := t.(*BasicType); isBasic {
+		return basicType.Zero
+	}
+	return nil
+}
+
+// 变量以未定类型的常量初始化时，取其默认类型
+func DefaultType(t Type) Type {
+	switch t.TypeKind() {
+	case TypeKindUntypedInt, TypeKindUntypedFloat:
+		return t.(*UntypedType).Default
+	case TypeKindArray:
+		return &ArrayType{Element: DefaultType(t.(*ArrayType).Element)}
+	}
+	return t
+}
+
+// 两个类型是否完全相同
+func IdenticalTypes(a, b Type) bool {
+	if a == b {
+		return true
+	}
+	if a.TypeKind() != b.TypeKind() {
+		return false
+	}
+	switch a.TypeKind() {
+	case TypeKindUnknown, TypeKindVoid, TypeKindNil:
+		return true
+	case TypeKindUntypedInt, TypeKindUntypedFloat:
+		return a.(*UntypedType).Default == b.(*UntypedType).Default
+	case TypeKindArray:
+		return IdenticalTypes(a.(*ArrayType).Element, b.(*ArrayType).Element)
+	case TypeKindTable:
+		return IdenticalTypes(a.(*TableType).Value, b.(*TableType).Value)
+	case TypeKindFunction:
+		fnA, fnB := a.(*FunctionType), b.(*FunctionType)
+		return fnA.Variadic == fnB.Variadic &&
+			identicalTypeLists(fnA.Params, fnB.Params) && identicalTypeLists(fnA.Returns, fnB.Returns) &&
+			ThrowsCovered(fnA.Throws, fnB.Throws) && ThrowsCovered(fnB.Throws, fnA.Throws)
+	case TypeKindTuple:
+		return identicalTypeLists(a.(*TupleType).Types, b.(*TupleType).Types)
+	case TypeKindClass:
+		classA, classB := a.(*ClassType), b.(*ClassType)
+		return classA.Symbol == classB.Symbol && identicalTypeLists(classA.Args(), classB.Args())
+	case TypeKindEnum:
+		return a.(*EnumType).Symbol == b.(*EnumType).Symbol
+	case TypeKindTypeParam:
+		return a.(*TypeParamType).Symbol == b.(*TypeParamType).Symbol
+	case TypeKindModule:
+		return a.(*ModuleType).Name == b.(*ModuleType).Name
+	case TypeKindStatic:
+		return a.(*StaticType).Class.Symbol == b.(*StaticType).Class.Symbol
+	}
+	return false
+}
+
+// 异常类型 thrown 是否是 declared 中某个异常类型或其子类，即 thrown 已被 declared 声明或捕获
+func ExceptionCovered(thrown Type, declared []Type) bool {
+	for _, it := range declared {
+		if AssignableTo(thrown, it) {
+			return true
+		}
+	}
+	return false
+}
+
+// throws 中的每个异常类型是否都已被 declared 声明
+func ThrowsCovered(throws, declared []Type) bool {
+	for _, thrown := range throws {
+		if !ExceptionCovered(thrown, declared) {
+			return false
+		}
+	}
+	return true
+}
+
+func identicalTypeLists(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !IdenticalTypes(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// 类型为 from 的值能否赋给类型为 to 的变量
+func AssignableTo(from, to Type) bool {
+	if IsUnknown(from) || IsUnknown(to) || IdenticalTypes(from, to) {
+		return true
+	}
+	switch from.TypeKind() {
+	case TypeKindUntypedInt:
+		return IsNumericType(to)
+	case TypeKindUntypedFloat:
+		return IsFloatType(to)
+	case TypeKindNil:
+		return IsReferenceType(to)
+	case TypeKindClass:
+		// 子类的实例可以赋给父类与其实现的接口
+		if toClass, ok := to.(*ClassType); ok {
+			return from.(*ClassType).IsSubtypeOf(toClass)
+		}
+	case TypeKindTypeParam:
+		// 泛型参数的值只能赋给其自身或其约束所允许的类型
+		if constraint := from.(*TypeParamType).Constraint; constraint != nil {
+			return AssignableTo(constraint, to)
+		}
+	case TypeKindArray:
+		// 由常量组成的数组字面量可以赋给元素类型兼容的数组，如 var a int8[] = [1, 2]
+		if toArray, ok := to.(*ArrayType); ok {
+			element := from.(*ArrayType).Element
+			return isUntypedValue(element) && AssignableTo(element, toArray.Element)
+		}
+	}
+	return false
+}
+
+// 尚未确定具体类型的值：未定类型的常量、nil 以及由它们组成的数组
+func isUntypedValue(t Type) bool {
+	if IsUnknown(t) || IsUntyped(t) || t.TypeKind() == TypeKindNil {
+		return true
+	}
+	if array, ok := t.(*ArrayType); ok {
+		return isUntypedValue(array.Element)
+	}
+	return false
+}
+
+func typeParamsString(params []*TypeParamType) string {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.String()
+	}
+	return strings.Join(names, ", ")
+}
+func typeListString(types []Type) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.String()
+	}
+	return strings.Join(names, ", ")
+}
@@ -18,15 +18,41 @@ import (
 // Node 为语法树中的所有节点定义了接口
 type Node interface {
 	NodeType() string
+	GetSpan() *Span
+}
+
+// Span 为节点在源代码中的范围，内嵌于每一个语法树节点之中
+// 供诊断信息标出整段代码，也供格式化、重构工具与语言服务把节点映射回源代码
+type Span struct {
+	Start Pos // 节点第一个字符的位置
+	End   Pos // 节点最后一个字符之后的位置
+}
+
+func (span *Span) GetSpan() *Span {
+	return span
+}
+
+// 恰好由一个 Token 构成的范围
+func TokenSpan(token *Token) Span {
+	return Span{Start: token.Start, End: token.End}
+}
+
+// 范围是否包含给定的字节偏移量
+func (span *Span) Contains(offset int) bool {
+	return span.Start.Offset <= offset && offset < span.End.Offset
 }
 
 // Program 为语法树根节点，每一个 .coral 源代码文件都被视为一整段程序，以一个语句的切片表达
 type Program struct {
+	Span
+
 	Root []Statement
 }
 
 // 标识符节点
 type Identifier struct {
+	Span
+
 	Token *Token
 }
 
@@ -36,3 +62,14 @@ func (it *Identifier) NodeType() string {
 func (it *Identifier) GetName() string {
 	return it.Token.Str
 }
+
+// 文档注释节点：紧挨在函数、类、接口或枚举的定义之前的一个 /** */ 块注释，或者连续的几行 /// 行注释
+type DocComment struct {
+	Span
+
+	Text string // 去掉注释符号之后的内容，以 Markdown 书写
+}
+
+func (it *DocComment) NodeType() string {
+	return "Doc_Comment"
+}
@@ -0,0 +1,73 @@
+package ast
+
+import (
+	. "coral-lang/src/lexer"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Fprint 以缩进树的形式打印语法树，供 `coral parse` 等调试场合使用
+// 借助反射遍历节点的导出字段，空指针、空切片一概略过，节点名之后附上其在源代码中的范围
+func Fprint(w io.Writer, node interface{}) {
+	dumpValue(w, "", reflect.ValueOf(node), 0)
+}
+
+func dumpValue(w io.Writer, label string, v reflect.Value, depth int) {
+	if !v.IsValid() {
+		return
+	}
+	indent := strings.Repeat("  ", depth)
+	if label != "" {
+		label += ": "
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		dumpValue(w, strings.TrimSuffix(label, ": "), v.Elem(), depth)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if token, isToken := v.Interface().(*Token); isToken {
+			fmt.Fprintf(w, "%s%s%q [%s-%s]\n", indent, label, token.Str, token.Start, token.End)
+			return
+		}
+		name := v.Elem().Type().Name()
+		if node, isNode := v.Interface().(Node); isNode {
+			span := node.GetSpan()
+			name = fmt.Sprintf("%s [%s-%s]", node.NodeType(), span.Start, span.End)
+		}
+		fmt.Fprintf(w, "%s%s%s\n", indent, label, name)
+		dumpFields(w, v.Elem(), depth+1)
+	case reflect.Struct:
+		dumpFields(w, v, depth)
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s%s\n", indent, strings.TrimSuffix(label, " "))
+		for i := 0; i < v.Len(); i++ {
+			dumpValue(w, fmt.Sprintf("[%d]", i), v.Index(i), depth+1)
+		}
+	default:
+		fmt.Fprintf(w, "%s%s%v\n", indent, label, v.Interface())
+	}
+}
+
+func dumpFields(w io.Writer, v reflect.Value, depth int) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue // 非导出字段
+		}
+		if field.Anonymous && field.Type == reflect.TypeOf(Span{}) {
+			continue // 节点的范围已打印在节点名之后
+		}
+		dumpValue(w, field.Name, v.Field(i), depth)
+	}
+}
@@ -22,6 +22,7 @@ const (
 	StatementTypeFunctionDecl
 	StatementTypeClassDecl
 	StatementTypeInterfaceDecl
+	StatementTypeBad
 
 	// 定义引入外部模块语句的种类来区分
 	ImportStatementTypeSingleGlobal
@@ -52,6 +53,7 @@ const (
 	ExpressionTypeUnary
 	ExpressionTypeBinary
 	ExpressionTypeRange
+	ExpressionTypeCast
 
 	// 定义基本表达式的类型来区分
 	PrimaryExprTypeBasic
@@ -8,6 +8,8 @@ import (
 
 // nil
 type NilLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -23,6 +25,8 @@ func (it *NilLit) OperandNodeType() int {
 
 // true
 type TrueLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -38,6 +42,8 @@ func (it *TrueLit) OperandNodeType() int {
 
 // false
 type FalseLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -53,6 +59,8 @@ func (it *FalseLit) OperandNodeType() int {
 
 // 十进制整数
 type DecimalLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -68,6 +76,8 @@ func (it *DecimalLit) OperandNodeType() int {
 
 // 十六进制整数
 type HexadecimalLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -83,6 +93,8 @@ func (it *HexadecimalLit) OperandNodeType() int {
 
 // 八进制整数
 type OctalLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -98,6 +110,8 @@ func (it *OctalLit) OperandNodeType() int {
 
 // 二进制整数
 type BinaryLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -113,6 +127,8 @@ func (it *BinaryLit) OperandNodeType() int {
 
 // 浮点数
 type FloatLit struct {
+	Span
+
 	Value    *Token
 	Accuracy int
 }
@@ -129,6 +145,8 @@ func (it *FloatLit) OperandNodeType() int {
 
 // 科学记数法
 type ExponentLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -144,6 +162,8 @@ func (it *ExponentLit) OperandNodeType() int {
 
 // 字符
 type RuneLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -159,6 +179,8 @@ func (it *RuneLit) OperandNodeType() int {
 
 // 字符串
 type StringLit struct {
+	Span
+
 	Value *Token
 }
 
@@ -174,6 +196,8 @@ func (it *StringLit) OperandNodeType() int {
 
 // 数组
 type ArrayLit struct {
+	Span
+
 	ValueList []Expression
 }
 
@@ -189,6 +213,8 @@ func (it *ArrayLit) OperandNodeType() int {
 
 // 字典元素
 type TableElement struct {
+	Span
+
 	Key   *Identifier
 	Value Expression
 }
@@ -199,6 +225,8 @@ func (it *TableElement) NodeType() string {
 
 // 字典
 type TableLit struct {
+	Span
+
 	KeyValueList []*TableElement
 }
 
@@ -214,6 +242,8 @@ func (it *TableLit) OperandNodeType() int {
 
 // 箭头函数
 type LambdaLit struct {
+	Span
+
 	Signature *Signature
 	Result    Statement
 }
@@ -230,6 +260,8 @@ func (it *LambdaLit) OperandNodeType() int {
 
 // 自指对象 this
 type ThisLit struct {
+	Span
+
 	Token     *Token
 	BelongsTo *ClassIdentifier // 留给后续语义分析阶段的
 }
@@ -246,6 +278,8 @@ func (it *ThisLit) OperandNodeType() int {
 
 // 父级对象 super
 type SuperLit struct {
+	Span
+
 	Token     *Token
 	BelongsTo *ClassIdentifier // 留给后续语义分析阶段的
 }
@@ -270,6 +304,8 @@ type Literal interface {
 
 // 操作数名节点
 type OperandName struct {
+	Span
+
 	Name *Identifier
 }
 
@@ -286,11 +322,14 @@ func (it *OperandName) OperandNodeType() int {
 // 结合语法定义可知 primaryExpr 有四种可能性
 // 应当抽取出 operand，之后的三种情况可以继承
 type Operand interface {
+	Node
 	OperandNodeType() int
 }
 
 // 只是操作数本身的 primaryExpr
 type BasicPrimaryExpression struct {
+	Span
+
 	It Operand
 }
 
@@ -312,6 +351,8 @@ func (it *BasicPrimaryExpression) StatementNodeType() int {
 
 // 索引访问表达式节点
 type IndexExpression struct {
+	Span
+
 	Operand Expression // 操作数，其他三种下同
 	Index   Expression // 索引表达式
 }
@@ -334,6 +375,8 @@ func (it *IndexExpression) StatementNodeType() int {
 
 // 切片访问表达式节点
 type SliceExpression struct {
+	Span
+
 	Operand Expression
 	Start   Expression // 切片位置起点
 	End     Expression // 切片位置终点
@@ -357,6 +400,8 @@ func (it *SliceExpression) StatementNodeType() int {
 
 // 函数调用表达式节点
 type CallExpression struct {
+	Span
+
 	Operand Expression
 	Params  []Expression // 函数实参列表
 }
@@ -379,6 +424,8 @@ func (it *CallExpression) StatementNodeType() int {
 
 // 成员链表节点 同时也是 AST 节点
 type MemberLinkNode struct {
+	Span
+
 	It         *Identifier
 	MemberNext *MemberLinkNode
 }
@@ -389,6 +436,8 @@ func (it *MemberLinkNode) NodeType() string {
 
 // 成员表达式节点
 type MemberExpression struct {
+	Span
+
 	Operand Expression
 	Member  *MemberLinkNode // 链表
 }
@@ -417,6 +466,8 @@ type PrimaryExpression interface {
 
 // 新建对象实例表达式节点
 type NewInstanceExpression struct {
+	Span
+
 	Class      TypeDescription
 	InitParams []Expression
 }
@@ -436,6 +487,8 @@ func (it *NewInstanceExpression) StatementNodeType() int {
 
 // 一元表达式节点
 type UnaryExpression struct {
+	Span
+
 	Operator *Token
 	Operand  Expression
 }
@@ -455,9 +508,12 @@ func (it *UnaryExpression) StatementNodeType() int {
 
 // 二元表达式节点
 type BinaryExpression struct {
-	Operator *Token
-	Left     Expression
-	Right    Expression
+	Span
+
+	Operator      *Token
+	Left          Expression
+	Right         Expression
+	Parenthesized bool // 源代码中以括号括起，作为一个整体不参与按优先级的结合
 }
 
 func (it *BinaryExpression) ExpressionNodeType() int {
@@ -475,6 +531,8 @@ func (it *BinaryExpression) StatementNodeType() int {
 
 // 区间表达式节点
 type RangeExpression struct {
+	Span
+
 	Start      Expression
 	End        Expression
 	IncludeEnd bool
@@ -495,12 +553,14 @@ func (it *RangeExpression) StatementNodeType() int {
 
 // 强制类型转换表达式节点
 type CastExpression struct {
+	Span
+
 	Source Expression
 	Type   TypeDescription
 }
 
 func (it *CastExpression) ExpressionNodeType() int {
-	return ExpressionTypeRange
+	return ExpressionTypeCast
 }
 func (it *CastExpression) NodeType() string {
 	return "Cast_Expression"
@@ -0,0 +1,362 @@
+package ast
+
+import (
+	"reflect"
+)
+
+// Apply 在进入与离开每个节点时调用的函数
+type ApplyFunc func(cursor *Cursor) bool
+
+// Apply 与 Walk 以同样的顺序遍历语法树，并可以借助 Cursor 替换、删除或插入节点
+// 进入每个节点时先调用 pre，pre 返回 false 时不再深入它的子节点，也不调用 post
+// 子节点都遍历完之后调用 post，post 返回 false 时整个遍历就此结束
+// 为空的子节点不经过 pre 与 post；pre 中替换的节点会代替原节点被继续遍历，新插入的节点则不会
+// 返回值为遍历之后的根节点，根节点被替换时即为新的节点
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+	parent := &struct{ Node Node }{root}
+	defer func() {
+		if r := recover(); r != nil && r != abortApply {
+			panic(r)
+		}
+		result = parent.Node
+	}()
+	applier := &applier{pre: pre, post: post}
+	applier.apply(nil, reflect.ValueOf(parent).Elem(), "Node", nil, root)
+	return
+}
+
+// ApplyProgram 以 Apply 的方式依次遍历整段程序的每一条语句，顶层语句的 Parent 为 nil
+func ApplyProgram(program *Program, pre, post ApplyFunc) {
+	defer func() {
+		if r := recover(); r != nil && r != abortApply {
+			panic(r)
+		}
+	}()
+	applier := &applier{pre: pre, post: post}
+	applier.applyList(nil, reflect.ValueOf(program).Elem(), "Root")
+}
+
+var abortApply = new(int) // post 返回 false 时以此结束遍历
+
+// Cursor 指向 Apply 正在访问的节点，记录它在父节点中的位置
+type Cursor struct {
+	parent Node
+	holder reflect.Value // @private 父节点的结构体，节点即其中名为 name 的字段或者字段的切片中的一项
+	name   string
+	iter   *iterator // 节点在切片中时不为 nil
+	node   Node
+}
+
+// 节点在切片中的位置，以及访问完当前节点之后前进的步数
+type iterator struct {
+	index, step int
+}
+
+func (cursor *Cursor) Node() Node   { return cursor.node }
+func (cursor *Cursor) Parent() Node { return cursor.parent }
+
+// 节点在父节点中的字段名，如 IfStatement 中的 Elif
+func (cursor *Cursor) Name() string { return cursor.name }
+
+// 节点在父节点字段的切片中的下标，不在切片中时为 -1
+func (cursor *Cursor) Index() int {
+	if cursor.iter == nil {
+		return -1
+	}
+	return cursor.iter.index
+}
+
+func (cursor *Cursor) field() reflect.Value {
+	return cursor.holder.FieldByName(cursor.name)
+}
+
+// 以 node 替换当前节点；node 的类型与字段不符时 panic
+func (cursor *Cursor) Replace(node Node) {
+	field := cursor.field()
+	if i := cursor.Index(); i >= 0 {
+		field = field.Index(i)
+	}
+	field.Set(reflect.ValueOf(node))
+	cursor.node = node
+}
+
+// 从切片中删除当前节点，节点不在切片中时 panic
+func (cursor *Cursor) Delete() {
+	i := cursor.Index()
+	if i < 0 {
+		panic("Delete node not contained in slice")
+	}
+	field := cursor.field()
+	length := field.Len()
+	reflect.Copy(field.Slice(i, length), field.Slice(i+1, length))
+	field.Index(length - 1).Set(reflect.Zero(field.Type().Elem()))
+	field.SetLen(length - 1)
+	cursor.iter.step--
+}
+
+// 在切片中当前节点之后插入 node，node 不会被遍历；节点不在切片中时 panic
+func (cursor *Cursor) InsertAfter(node Node) {
+	i := cursor.Index()
+	if i < 0 {
+		panic("InsertAfter node not contained in slice")
+	}
+	cursor.insert(i+1, node)
+	cursor.iter.step++
+}
+
+// 在切片中当前节点之前插入 node，node 不会被遍历；节点不在切片中时 panic
+func (cursor *Cursor) InsertBefore(node Node) {
+	i := cursor.Index()
+	if i < 0 {
+		panic("InsertBefore node not contained in slice")
+	}
+	cursor.insert(i, node)
+	cursor.iter.index++
+}
+
+func (cursor *Cursor) insert(i int, node Node) {
+	field := cursor.field()
+	length := field.Len()
+	field.Set(reflect.Append(field, reflect.Zero(field.Type().Elem())))
+	reflect.Copy(field.Slice(i+1, length+1), field.Slice(i, length))
+	field.Index(i).Set(reflect.ValueOf(node))
+}
+
+type applier struct {
+	pre, post ApplyFunc
+	cursor    Cursor
+	iter      iterator
+}
+
+func (applier *applier) apply(parent Node, holder reflect.Value, name string, iter *iterator, node Node) {
+	if isNilNode(node) {
+		return
+	}
+	saved := applier.cursor
+	applier.cursor = Cursor{parent: parent, holder: holder, name: name, iter: iter, node: node}
+	if applier.pre != nil && !applier.pre(&applier.cursor) {
+		applier.cursor = saved
+		return
+	}
+
+	node = applier.cursor.node
+	if isNilNode(node) {
+		applier.cursor = saved
+		return // pre 中将节点替换为了 nil
+	}
+	fields := reflect.ValueOf(node).Elem()
+	child := func(name string) {
+		if value := fields.FieldByName(name); !value.IsNil() {
+			applier.apply(node, fields, name, nil, value.Interface().(Node))
+		}
+	}
+	list := func(name string) {
+		applier.applyList(node, fields, name)
+	}
+
+	switch node.(type) {
+	case *Identifier, *DocComment, *NilLit, *TrueLit, *FalseLit, *DecimalLit, *HexadecimalLit, *OctalLit,
+		*BinaryLit, *FloatLit, *ExponentLit, *RuneLit, *StringLit, *ThisLit, *SuperLit,
+		*BreakStatement, *ContinueStatement, *BadStatement:
+
+	case *ArrayLit:
+		list("ValueList")
+	case *TableElement:
+		child("Key")
+		child("Value")
+	case *TableLit:
+		list("KeyValueList")
+	case *LambdaLit:
+		child("Signature")
+		child("Result")
+	case *OperandName:
+		child("Name")
+	case *BasicPrimaryExpression:
+		child("It")
+	case *IndexExpression:
+		child("Operand")
+		child("Index")
+	case *SliceExpression:
+		child("Operand")
+		child("Start")
+		child("End")
+	case *CallExpression:
+		child("Operand")
+		list("Params")
+	case *MemberLinkNode:
+		child("It")
+		child("MemberNext")
+	case *MemberExpression:
+		child("Operand")
+		child("Member")
+	case *NewInstanceExpression:
+		child("Class")
+		list("InitParams")
+	case *UnaryExpression:
+		child("Operand")
+	case *BinaryExpression:
+		child("Left")
+		child("Right")
+	case *RangeExpression:
+		child("Start")
+		child("End")
+	case *CastExpression:
+		child("Source")
+		child("Type")
+
+	case *TypeName:
+		child("Identifier")
+	case *FuncType:
+		list("ArgTypes")
+		list("ReturnTypes")
+	case *ArrayTypeLit:
+		child("ElementType")
+	case *GenericsTypeLit:
+		child("BasicType")
+		list("GenericsArgs")
+
+	case *ReturnStatement:
+		list("Expression")
+	case *ThrowStatement:
+		child("Value")
+	case *IncDecStatement:
+		child("Expression")
+	case *VarDeclElement:
+		child("Type")
+		child("InitValue")
+	case *VarDeclStatement:
+		list("Declarations")
+	case *AssignListStatement:
+		list("Targets")
+		list("Values")
+
+	case *BlockStatement:
+		list("Statements")
+	case *PackageStatement:
+		child("Name")
+	case *ImportElement:
+		child("ModuleName")
+		child("As")
+	case *SingleGlobalImportStatement:
+		child("As")
+	case *SingleFromImportStatement:
+		child("Element")
+	case *ListImportStatement:
+		list("Elements")
+	case *EnumElement:
+		child("Name")
+		child("Value")
+	case *EnumStatement:
+		child("Doc")
+		child("Name")
+		list("Elements")
+	case *IfElement:
+		child("Condition")
+		child("Block")
+	case *IfStatement:
+		child("If")
+		list("Elif")
+		child("Else")
+	case *SwitchStatementNormalCase:
+		list("Conditions")
+		child("Block")
+	case *SwitchStatementRangeCase:
+		child("Range")
+		child("Block")
+	case *SwitchStatement:
+		child("Entry")
+		list("Cases")
+		child("Default")
+	case *WhileStatement:
+		child("Condition")
+		child("Block")
+	case *ForStatement:
+		child("Initial")
+		child("Condition")
+		list("Appendix")
+		child("Block")
+	case *EachStatement:
+		child("Element")
+		child("Key")
+		child("Target")
+		child("Block")
+	case *TryCatchStatement:
+		child("TryBlock")
+		list("Handlers")
+		child("Finally")
+	case *ErrorCatchHandler:
+		child("Name")
+		child("ErrorType")
+		child("Handler")
+
+	case *Argument:
+		child("Name")
+		child("Type")
+	case *Signature:
+		child("Generics")
+		list("Arguments")
+		list("Returns")
+		list("Throws")
+	case *GenericsArgElement:
+		child("ArgName")
+		child("Generics")
+		child("Constraint")
+	case *GenericArgs:
+		list("Args")
+	case *FunctionDeclarationStatement:
+		child("Doc")
+		child("Name")
+		child("Signature")
+		child("Block")
+	case *ClassIdentifier:
+		child("Name")
+		child("Generics")
+	case *ClassMemberVar:
+		child("VarDecl")
+	case *ClassMemberMethod:
+		child("MethodDecl")
+	case *ClassDeclarationStatement:
+		child("Doc")
+		child("Definition")
+		child("Extends")
+		list("Implements")
+		list("Members")
+	case *InterfaceMethodDeclaration:
+		child("Name")
+		child("Generics")
+		child("Signature")
+	case *InterfaceDeclarationStatement:
+		child("Doc")
+		child("Definition")
+		child("Extends")
+		list("Methods")
+
+	default:
+		panic("ast.Apply: unexpected node type " + reflect.TypeOf(node).String())
+	}
+
+	if applier.post != nil && !applier.post(&applier.cursor) {
+		panic(abortApply)
+	}
+	applier.cursor = saved
+}
+
+// 逐个访问切片中的节点；访问途中删除或插入的节点会相应地调整下标
+func (applier *applier) applyList(parent Node, holder reflect.Value, name string) {
+	saved := applier.iter
+	applier.iter.index = 0
+	for {
+		field := holder.FieldByName(name)
+		if applier.iter.index >= field.Len() {
+			break
+		}
+		var node Node
+		if element := field.Index(applier.iter.index); !element.IsNil() {
+			node = element.Interface().(Node)
+		}
+		applier.iter.step = 1
+		applier.apply(parent, holder, name, &applier.iter, node)
+		applier.iter.index += applier.iter.step
+	}
+	applier.iter = saved
+}
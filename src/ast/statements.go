@@ -8,6 +8,8 @@ type ClassMemberScopeType int
 
 // 返回语句节点
 type ReturnStatement struct {
+	Span
+
 	Token      *Token
 	Expression []Expression
 }
@@ -21,6 +23,8 @@ func (it *ReturnStatement) StatementNodeType() int {
 
 // 循环中断语句节点
 type BreakStatement struct {
+	Span
+
 	Token *Token
 }
 
@@ -33,6 +37,8 @@ func (it *BreakStatement) StatementNodeType() int {
 
 // 循环继续语句节点
 type ContinueStatement struct {
+	Span
+
 	Token *Token
 }
 
@@ -43,8 +49,25 @@ func (it *ContinueStatement) StatementNodeType() int {
 	return StatementTypeSimple
 }
 
+// 抛出异常语句节点
+type ThrowStatement struct {
+	Span
+
+	Token *Token
+	Value Expression
+}
+
+func (it *ThrowStatement) NodeType() string {
+	return "Simple_Statement_Throw"
+}
+func (it *ThrowStatement) StatementNodeType() int {
+	return StatementTypeSimple
+}
+
 // 自增或自减语句节点
 type IncDecStatement struct {
+	Span
+
 	Expression Expression
 	Operator   *Token
 }
@@ -59,7 +82,7 @@ func (it *IncDecStatement) NodeType() string {
 	return stmtType
 }
 func (it *IncDecStatement) SimpleStatementNodeType() int {
-	return SimpleStmtIncDecStmt
+	return SimpleStmtTypeIncDecStmt
 }
 func (it *IncDecStatement) StatementNodeType() int {
 	return StatementTypeSimple
@@ -67,13 +90,21 @@ func (it *IncDecStatement) StatementNodeType() int {
 
 // 单个变量定义的赋值部分
 type VarDeclElement struct {
+	Span
+
 	VarName   *Token // 定义的变量标识符 identifier token
 	Type      TypeDescription
 	InitValue Expression // 赋予的初始值（是个表达式）
 }
 
+func (it *VarDeclElement) NodeType() string {
+	return "Variable_Declaration_Element"
+}
+
 // 变量定义语句节点
 type VarDeclStatement struct {
+	Span
+
 	Mutable      bool              // 用于区分 var 和 val
 	Declarations []*VarDeclElement // 可能有多个变量定义
 }
@@ -94,6 +125,8 @@ func (it *VarDeclStatement) StatementNodeType() int {
 
 // 同句多赋值语句定义的
 type AssignListStatement struct {
+	Span
+
 	Token   *Token // Token: '='
 	Targets []PrimaryExpression
 	Values  []Expression
@@ -117,6 +150,8 @@ type SimpleStatement interface {
 
 // 块语句节点
 type BlockStatement struct {
+	Span
+
 	Statements []Statement
 }
 
@@ -129,6 +164,8 @@ func (it *BlockStatement) StatementNodeType() int {
 
 // 引入语句单元
 type ImportElement struct {
+	Span
+
 	ModuleName *Identifier
 	As         *Identifier
 }
@@ -139,6 +176,8 @@ func (it *ImportElement) NodeType() string {
 
 // 直接 import 模块整体的语句
 type SingleGlobalImportStatement struct {
+	Span
+
 	Path string
 	As   *Identifier
 }
@@ -155,6 +194,8 @@ func (it *SingleGlobalImportStatement) StatementNodeType() int {
 
 // from 引入单个的语句
 type SingleFromImportStatement struct {
+	Span
+
 	From    string
 	Element *ImportElement
 }
@@ -171,6 +212,8 @@ func (it *SingleFromImportStatement) StatementNodeType() int {
 
 // 集合引入语句
 type ListImportStatement struct {
+	Span
+
 	From     string
 	Elements []*ImportElement
 }
@@ -194,6 +237,8 @@ type ImportStatement interface {
 
 // 枚举单元
 type EnumElement struct {
+	Span
+
 	Name  *Identifier
 	Value *DecimalLit
 }
@@ -204,6 +249,9 @@ func (it *EnumElement) NodeType() string {
 
 // 枚举语句节点
 type EnumStatement struct {
+	Span
+
+	Doc      *DocComment
 	Name     *Identifier
 	Elements []*EnumElement
 }
@@ -217,6 +265,8 @@ func (it *EnumStatement) StatementNodeType() int {
 
 // 条件语句单元
 type IfElement struct {
+	Span
+
 	Condition Expression
 	Block     *BlockStatement
 }
@@ -227,6 +277,8 @@ func (it *IfElement) NodeType() string {
 
 // 条件语句节点
 type IfStatement struct {
+	Span
+
 	If   *IfElement
 	Elif []*IfElement
 	Else *BlockStatement
@@ -247,6 +299,8 @@ type SwitchStatementCase interface {
 
 // 分支语句匹配条件单元
 type SwitchStatementNormalCase struct {
+	Span
+
 	Conditions []Expression
 	Block      *BlockStatement
 }
@@ -260,6 +314,8 @@ func (it *SwitchStatementNormalCase) SwitchStatementCaseNodeType() int {
 
 // 分支语句匹配条件范围
 type SwitchStatementRangeCase struct {
+	Span
+
 	Range *RangeExpression
 	Block *BlockStatement
 }
@@ -273,6 +329,8 @@ func (it *SwitchStatementRangeCase) SwitchStatementCaseNodeType() int {
 
 // 条件语句节点
 type SwitchStatement struct {
+	Span
+
 	Entry   Expression
 	Default *BlockStatement
 	Cases   []SwitchStatementCase
@@ -287,6 +345,8 @@ func (it *SwitchStatement) StatementNodeType() int {
 
 // while 语句
 type WhileStatement struct {
+	Span
+
 	Condition Expression
 	Block     *BlockStatement
 }
@@ -300,6 +360,8 @@ func (it *WhileStatement) StatementNodeType() int {
 
 // for 语句
 type ForStatement struct {
+	Span
+
 	Initial   SimpleStatement
 	Condition Expression
 	Appendix  []SimpleStatement
@@ -315,6 +377,8 @@ func (it *ForStatement) StatementNodeType() int {
 
 // each 语句
 type EachStatement struct {
+	Span
+
 	Element *Identifier
 	Key     *Identifier
 	Target  Expression
@@ -330,6 +394,8 @@ func (it *EachStatement) StatementNodeType() int {
 
 // 函数形参节点
 type Argument struct {
+	Span
+
 	Name *Identifier
 	Type TypeDescription
 }
@@ -340,6 +406,8 @@ func (it *Argument) NodeType() string {
 
 // 函数签名
 type Signature struct {
+	Span
+
 	Generics  *GenericArgs
 	Arguments []*Argument
 	Returns   []TypeDescription
@@ -352,6 +420,9 @@ func (it *Signature) NodeType() string {
 
 // 函数定义语句
 type FunctionDeclarationStatement struct {
+	Span
+
+	Doc       *DocComment
 	Name      *Identifier
 	Signature *Signature
 	Block     *BlockStatement
@@ -372,7 +443,10 @@ type ClassMember interface {
 
 // 类成员变量定义节点
 type ClassMemberVar struct {
+	Span
+
 	Scope   ClassMemberScopeType
+	Static  bool // 以 static 修饰的成员属于类本身，通过类名访问
 	VarDecl *VarDeclStatement
 }
 
@@ -385,7 +459,10 @@ func (it *ClassMemberVar) ClassMemberNodeType() int {
 
 // 类成员方法定义节点
 type ClassMemberMethod struct {
+	Span
+
 	Scope      ClassMemberScopeType
+	Static     bool // 以 static 修饰的成员属于类本身，通过类名访问
 	MethodDecl *FunctionDeclarationStatement
 }
 
@@ -397,8 +474,11 @@ func (it *ClassMemberMethod) ClassMemberNodeType() int {
 }
 
 type GenericsArgElement struct {
-	ArgName  *Identifier
-	Generics *GenericArgs
+	Span
+
+	ArgName    *Identifier
+	Generics   *GenericArgs
+	Constraint TypeDescription // 泛型参数的约束，如 <T : Comparable>，没有时为 nil
 }
 
 func (it *GenericsArgElement) NodeType() string {
@@ -407,6 +487,8 @@ func (it *GenericsArgElement) NodeType() string {
 
 // 泛型参数列表
 type GenericArgs struct {
+	Span
+
 	Args []*GenericsArgElement
 }
 
@@ -415,6 +497,8 @@ func (it *GenericArgs) NodeType() string {
 }
 
 type ClassIdentifier struct {
+	Span
+
 	Name     *Identifier
 	Generics *GenericArgs
 }
@@ -425,6 +509,9 @@ func (it *ClassIdentifier) NodeType() string {
 
 // 类定义语句节点
 type ClassDeclarationStatement struct {
+	Span
+
+	Doc        *DocComment
 	Definition *ClassIdentifier
 	Extends    *ClassIdentifier
 	Implements []*ClassIdentifier
@@ -440,14 +527,23 @@ func (it *ClassDeclarationStatement) StatementNodeType() int {
 
 // 接口方法声明
 type InterfaceMethodDeclaration struct {
+	Span
+
 	Scope     ClassMemberScopeType
 	Name      *Identifier
 	Generics  *GenericArgs
 	Signature *Signature
 }
 
+func (it *InterfaceMethodDeclaration) NodeType() string {
+	return "Interface_Method_Declaration"
+}
+
 // 接口定义语句节点
 type InterfaceDeclarationStatement struct {
+	Span
+
+	Doc        *DocComment
 	Definition *ClassIdentifier
 	Extends    *ClassIdentifier
 	Methods    []*InterfaceMethodDeclaration
@@ -462,6 +558,8 @@ func (it *InterfaceDeclarationStatement) StatementNodeType() int {
 
 // catch 错误捕获单元节点
 type ErrorCatchHandler struct {
+	Span
+
 	Name      *Identifier
 	ErrorType TypeDescription
 	Handler   *BlockStatement
@@ -473,6 +571,8 @@ func (it *ErrorCatchHandler) NodeType() string {
 
 // try/catch 异常捕获语句节点
 type TryCatchStatement struct {
+	Span
+
 	TryBlock *BlockStatement
 	Handlers []*ErrorCatchHandler
 	Finally  *BlockStatement
@@ -487,6 +587,8 @@ func (it *TryCatchStatement) StatementNodeType() int {
 
 // 定义包名
 type PackageStatement struct {
+	Span
+
 	Name *Identifier
 }
 
@@ -497,6 +599,21 @@ func (it *PackageStatement) StatementNodeType() int {
 	return StatementTypePackage
 }
 
+// 错误语句节点：语法解析出错后被跳过的一段源代码，使出错的文件仍能得到一棵完整的语法树
+type BadStatement struct {
+	Span
+
+	From *Token // 被跳过的第一个 Token
+	To   *Token // 被跳过的最后一个 Token
+}
+
+func (it *BadStatement) NodeType() string {
+	return "Bad_Statement"
+}
+func (it *BadStatement) StatementNodeType() int {
+	return StatementTypeBad
+}
+
 // Statement 为所有语句节点定义了接口
 type Statement interface {
 	Node
@@ -8,6 +8,8 @@ type TypeDescription interface {
 
 // 类型的名称节点
 type TypeName struct {
+	Span
+
 	Identifier *Identifier
 }
 
@@ -19,6 +21,8 @@ func (it *TypeName) TypeDescriptionNode() int {
 }
 
 type FuncType struct {
+	Span
+
 	ArgTypes    []TypeDescription
 	ReturnTypes []TypeDescription
 }
@@ -32,6 +36,8 @@ func (it *FuncType) TypeDescriptionNode() int {
 
 // 数组类型标识 eg: T[]
 type ArrayTypeLit struct {
+	Span
+
 	ElementType TypeDescription
 	ArrayLength int
 }
@@ -45,6 +51,8 @@ func (it *ArrayTypeLit) TypeDescriptionNode() int {
 
 // 带泛型参数的标识 eg: A<B,C>
 type GenericsTypeLit struct {
+	Span
+
 	BasicType    *TypeName
 	GenericsArgs []TypeDescription
 }
@@ -0,0 +1,290 @@
+package ast
+
+import (
+	"reflect"
+)
+
+// Visitor 的 Visit 方法在 Walk 遇到每一个节点时被调用
+// 返回的 w 不为 nil 时，Walk 以 w 依次访问该节点的子节点，最后再调用一次 w.Visit(nil)
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk 以深度优先的顺序遍历语法树，子节点按其在源代码中的先后访问
+// 为空的子节点一概略过；几个形参共用的同一个类型节点会被访问多次
+// 语义分析时填入的字段（如 ThisLit.BelongsTo）不属于语法树，不会被访问
+func Walk(v Visitor, node Node) {
+	if isNilNode(node) {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch it := node.(type) {
+	// 标识符、字面量等没有子节点
+	case *Identifier, *DocComment, *NilLit, *TrueLit, *FalseLit, *DecimalLit, *HexadecimalLit, *OctalLit,
+		*BinaryLit, *FloatLit, *ExponentLit, *RuneLit, *StringLit, *ThisLit, *SuperLit,
+		*BreakStatement, *ContinueStatement, *BadStatement:
+
+	// 表达式
+	case *ArrayLit:
+		walkExpressionList(v, it.ValueList)
+	case *TableElement:
+		Walk(v, it.Key)
+		Walk(v, it.Value)
+	case *TableLit:
+		for _, element := range it.KeyValueList {
+			Walk(v, element)
+		}
+	case *LambdaLit:
+		Walk(v, it.Signature)
+		Walk(v, it.Result)
+	case *OperandName:
+		Walk(v, it.Name)
+	case *BasicPrimaryExpression:
+		Walk(v, it.It)
+	case *IndexExpression:
+		Walk(v, it.Operand)
+		Walk(v, it.Index)
+	case *SliceExpression:
+		Walk(v, it.Operand)
+		Walk(v, it.Start)
+		Walk(v, it.End)
+	case *CallExpression:
+		Walk(v, it.Operand)
+		walkExpressionList(v, it.Params)
+	case *MemberLinkNode:
+		Walk(v, it.It)
+		Walk(v, it.MemberNext)
+	case *MemberExpression:
+		Walk(v, it.Operand)
+		Walk(v, it.Member)
+	case *NewInstanceExpression:
+		Walk(v, it.Class)
+		walkExpressionList(v, it.InitParams)
+	case *UnaryExpression:
+		Walk(v, it.Operand)
+	case *BinaryExpression:
+		Walk(v, it.Left)
+		Walk(v, it.Right)
+	case *RangeExpression:
+		Walk(v, it.Start)
+		Walk(v, it.End)
+	case *CastExpression:
+		Walk(v, it.Source)
+		Walk(v, it.Type)
+
+	// 类型标注
+	case *TypeName:
+		Walk(v, it.Identifier)
+	case *FuncType:
+		walkTypeList(v, it.ArgTypes)
+		walkTypeList(v, it.ReturnTypes)
+	case *ArrayTypeLit:
+		Walk(v, it.ElementType)
+	case *GenericsTypeLit:
+		Walk(v, it.BasicType)
+		walkTypeList(v, it.GenericsArgs)
+
+	// 简单语句
+	case *ReturnStatement:
+		walkExpressionList(v, it.Expression)
+	case *ThrowStatement:
+		Walk(v, it.Value)
+	case *IncDecStatement:
+		Walk(v, it.Expression)
+	case *VarDeclElement:
+		Walk(v, it.Type)
+		Walk(v, it.InitValue)
+	case *VarDeclStatement:
+		for _, element := range it.Declarations {
+			Walk(v, element)
+		}
+	case *AssignListStatement:
+		for _, target := range it.Targets {
+			Walk(v, target)
+		}
+		walkExpressionList(v, it.Values)
+
+	// 语句
+	case *BlockStatement:
+		walkStatementList(v, it.Statements)
+	case *PackageStatement:
+		Walk(v, it.Name)
+	case *ImportElement:
+		Walk(v, it.ModuleName)
+		Walk(v, it.As)
+	case *SingleGlobalImportStatement:
+		Walk(v, it.As)
+	case *SingleFromImportStatement:
+		Walk(v, it.Element)
+	case *ListImportStatement:
+		for _, element := range it.Elements {
+			Walk(v, element)
+		}
+	case *EnumElement:
+		Walk(v, it.Name)
+		Walk(v, it.Value)
+	case *EnumStatement:
+		Walk(v, it.Doc)
+		Walk(v, it.Name)
+		for _, element := range it.Elements {
+			Walk(v, element)
+		}
+	case *IfElement:
+		Walk(v, it.Condition)
+		Walk(v, it.Block)
+	case *IfStatement:
+		Walk(v, it.If)
+		for _, elif := range it.Elif {
+			Walk(v, elif)
+		}
+		Walk(v, it.Else)
+	case *SwitchStatementNormalCase:
+		walkExpressionList(v, it.Conditions)
+		Walk(v, it.Block)
+	case *SwitchStatementRangeCase:
+		Walk(v, it.Range)
+		Walk(v, it.Block)
+	case *SwitchStatement:
+		Walk(v, it.Entry)
+		for _, switchCase := range it.Cases {
+			Walk(v, switchCase)
+		}
+		Walk(v, it.Default)
+	case *WhileStatement:
+		Walk(v, it.Condition)
+		Walk(v, it.Block)
+	case *ForStatement:
+		Walk(v, it.Initial)
+		Walk(v, it.Condition)
+		for _, appendix := range it.Appendix {
+			Walk(v, appendix)
+		}
+		Walk(v, it.Block)
+	case *EachStatement:
+		Walk(v, it.Element)
+		Walk(v, it.Key)
+		Walk(v, it.Target)
+		Walk(v, it.Block)
+	case *TryCatchStatement:
+		Walk(v, it.TryBlock)
+		for _, handler := range it.Handlers {
+			Walk(v, handler)
+		}
+		Walk(v, it.Finally)
+	case *ErrorCatchHandler:
+		Walk(v, it.Name)
+		Walk(v, it.ErrorType)
+		Walk(v, it.Handler)
+
+	// 函数、类与接口
+	case *Argument:
+		Walk(v, it.Name)
+		Walk(v, it.Type)
+	case *Signature:
+		Walk(v, it.Generics)
+		for _, argument := range it.Arguments {
+			Walk(v, argument)
+		}
+		walkTypeList(v, it.Returns)
+		walkTypeList(v, it.Throws)
+	case *GenericsArgElement:
+		Walk(v, it.ArgName)
+		Walk(v, it.Generics)
+		Walk(v, it.Constraint)
+	case *GenericArgs:
+		for _, element := range it.Args {
+			Walk(v, element)
+		}
+	case *FunctionDeclarationStatement:
+		Walk(v, it.Doc)
+		Walk(v, it.Name)
+		Walk(v, it.Signature)
+		Walk(v, it.Block)
+	case *ClassIdentifier:
+		Walk(v, it.Name)
+		Walk(v, it.Generics)
+	case *ClassMemberVar:
+		Walk(v, it.VarDecl)
+	case *ClassMemberMethod:
+		Walk(v, it.MethodDecl)
+	case *ClassDeclarationStatement:
+		Walk(v, it.Doc)
+		Walk(v, it.Definition)
+		Walk(v, it.Extends)
+		for _, impl := range it.Implements {
+			Walk(v, impl)
+		}
+		for _, member := range it.Members {
+			Walk(v, member)
+		}
+	case *InterfaceMethodDeclaration:
+		Walk(v, it.Name)
+		Walk(v, it.Generics)
+		Walk(v, it.Signature)
+	case *InterfaceDeclarationStatement:
+		Walk(v, it.Doc)
+		Walk(v, it.Definition)
+		Walk(v, it.Extends)
+		for _, method := range it.Methods {
+			Walk(v, method)
+		}
+
+	default:
+		panic("ast.Walk: unexpected node type " + reflect.TypeOf(node).String())
+	}
+
+	v.Visit(nil)
+}
+
+// WalkProgram 依次遍历整段程序的每一条语句
+func WalkProgram(v Visitor, program *Program) {
+	walkStatementList(v, program.Root)
+}
+
+func walkExpressionList(v Visitor, list []Expression) {
+	for _, expr := range list {
+		Walk(v, expr)
+	}
+}
+func walkStatementList(v Visitor, list []Statement) {
+	for _, stmt := range list {
+		Walk(v, stmt)
+	}
+}
+func walkTypeList(v Visitor, list []TypeDescription) {
+	for _, typeDescription := range list {
+		Walk(v, typeDescription)
+	}
+}
+
+// 接口中的空指针，如值为 (*BlockStatement)(nil) 的 Node
+func isNilNode(node Node) bool {
+	if node == nil {
+		return true
+	}
+	value := reflect.ValueOf(node)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect 以 Walk 的顺序遍历语法树，对每个节点调用 f(node)
+// f 返回 false 时不再深入该节点的子节点；每个节点的子节点都访问完毕之后会调用一次 f(nil)
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// InspectProgram 以 Inspect 的方式依次遍历整段程序的每一条语句
+func InspectProgram(program *Program, f func(Node) bool) {
+	WalkProgram(inspector(f), program)
+}
@@ -0,0 +1,204 @@
+package bytecode
+
+import (
+	. "coral-lang/src/exception"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// .cbytes 文件的格式，所有整数均为小端序：
+//
+//	magic      4 字节 "CBYT"
+//	version    u16，格式不兼容时递增
+//	source     字符串
+//	constants  u32 个数，每个常量为 u8 种类 + 内容（int: i64，float: f64，String: 字符串）
+//	globals    u32 个数，每个为字符串
+//	functions  u32 个数，每个函数为：
+//	           名称字符串、源文件字符串、u8 形参个数、u16 局部变量槽位数、u8 返回值个数、
+//	           u32 代码长度 + 代码、u32 行号表项数 + 每项 u32 PC 与 u32 行号
+//	entry      u32 入口函数下标
+//
+// 字符串以 u32 字节数 + UTF-8 内容存放
+
+// 字节码文件的扩展名
+const FileExtension = ".cbytes"
+
+var Magic = [4]byte{'C', 'B', 'Y', 'T'}
+
+// 当前的字节码格式版本，读取时版本不符即报错
+const Version = 2
+
+// 将模块序列化为 .cbytes 文件的内容
+func (module *Module) Encode() []byte {
+	var buf []byte
+	putU8 := func(v int) {
+		buf = append(buf, byte(v))
+	}
+	putU16 := func(v int) {
+		buf = append(buf, 0, 0)
+		binary.LittleEndian.PutUint16(buf[len(buf)-2:], uint16(v))
+	}
+	putU32 := func(v int) {
+		buf = append(buf, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(buf[len(buf)-4:], uint32(v))
+	}
+	putU64 := func(v uint64) {
+		buf = append(buf, make([]byte, 8)...)
+		binary.LittleEndian.PutUint64(buf[len(buf)-8:], v)
+	}
+	putString := func(s string) {
+		putU32(len(s))
+		buf = append(buf, s...)
+	}
+
+	buf = append(buf, Magic[:]...)
+	putU16(Version)
+	putString(module.Source)
+
+	putU32(len(module.Constants))
+	for _, constant := range module.Constants {
+		putU8(constant.Kind)
+		switch constant.Kind {
+		case ConstantInt:
+			putU64(uint64(constant.Int))
+		case ConstantFloat:
+			putU64(math.Float64bits(constant.Float))
+		case ConstantString:
+			putString(constant.Str)
+		}
+	}
+
+	putU32(len(module.Globals))
+	for _, name := range module.Globals {
+		putString(name)
+	}
+
+	putU32(len(module.Functions))
+	for _, function := range module.Functions {
+		putString(function.Name)
+		putString(function.Source)
+		putU8(function.Params)
+		putU16(function.Locals)
+		putU8(function.Returns)
+		putU32(len(function.Code))
+		buf = append(buf, function.Code...)
+		putU32(len(function.Lines))
+		for _, entry := range function.Lines {
+			putU32(entry.PC)
+			putU32(entry.Line)
+		}
+	}
+
+	putU32(module.Entry)
+	return buf
+}
+
+// 读取 .cbytes 文件内容时的游标，出错后的读取一律返回零值，由调用方在最后统一检查
+type decoder struct {
+	data []byte
+	pos  int
+	err  *CoralCompileError
+}
+
+func (decoder *decoder) fail(format string, args ...interface{}) {
+	if decoder.err == nil {
+		decoder.err = NewCoralError("Bytecode", fmt.Sprintf(format, args...), BytecodeFormatError)
+	}
+}
+func (decoder *decoder) take(n int) []byte {
+	if decoder.err != nil {
+		return nil
+	}
+	if n < 0 || decoder.pos+n > len(decoder.data) {
+		decoder.fail("unexpected end of bytecode at offset %d!", decoder.pos)
+		return nil
+	}
+	b := decoder.data[decoder.pos : decoder.pos+n]
+	decoder.pos += n
+	return b
+}
+func (decoder *decoder) u8() int {
+	if b := decoder.take(1); b != nil {
+		return int(b[0])
+	}
+	return 0
+}
+func (decoder *decoder) u16() int {
+	if b := decoder.take(2); b != nil {
+		return int(binary.LittleEndian.Uint16(b))
+	}
+	return 0
+}
+func (decoder *decoder) u32() int {
+	if b := decoder.take(4); b != nil {
+		return int(binary.LittleEndian.Uint32(b))
+	}
+	return 0
+}
+func (decoder *decoder) u64() uint64 {
+	if b := decoder.take(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+func (decoder *decoder) string() string {
+	return string(decoder.take(decoder.u32()))
+}
+
+// 读取 .cbytes 文件的内容，格式或版本不符时返回错误
+func Decode(data []byte) (*Module, *CoralCompileError) {
+	decoder := &decoder{data: data}
+	if magic := decoder.take(len(Magic)); decoder.err != nil || string(magic) != string(Magic[:]) {
+		return nil, NewCoralError("Bytecode", "not a Coral bytecode file!", BytecodeFormatError)
+	}
+	if version := decoder.u16(); decoder.err == nil && version != Version {
+		return nil, NewCoralError("Bytecode",
+			fmt.Sprintf("unsupported bytecode version %d, expected %d!", version, Version), BytecodeFormatError)
+	}
+
+	module := &Module{Source: decoder.string()}
+	for i, count := 0, decoder.u32(); i < count && decoder.err == nil; i++ {
+		constant := Constant{Kind: decoder.u8()}
+		switch constant.Kind {
+		case ConstantInt:
+			constant.Int = int64(decoder.u64())
+		case ConstantFloat:
+			constant.Float = math.Float64frombits(decoder.u64())
+		case ConstantString:
+			constant.Str = decoder.string()
+		default:
+			decoder.fail("unknown constant kind %d!", constant.Kind)
+		}
+		module.Constants = append(module.Constants, constant)
+	}
+	for i, count := 0, decoder.u32(); i < count && decoder.err == nil; i++ {
+		module.Globals = append(module.Globals, decoder.string())
+	}
+	for i, count := 0, decoder.u32(); i < count && decoder.err == nil; i++ {
+		function := &Function{
+			Name:    decoder.string(),
+			Source:  decoder.string(),
+			Params:  decoder.u8(),
+			Locals:  decoder.u16(),
+			Returns: decoder.u8(),
+		}
+		function.Code = append([]byte(nil), decoder.take(decoder.u32())...)
+		for j, lines := 0, decoder.u32(); j < lines && decoder.err == nil; j++ {
+			function.Lines = append(function.Lines, LineEntry{PC: decoder.u32(), Line: decoder.u32()})
+		}
+		module.Functions = append(module.Functions, function)
+	}
+	module.Entry = decoder.u32()
+
+	if decoder.err == nil && decoder.pos != len(data) {
+		decoder.fail("unexpected trailing data at offset %d!", decoder.pos)
+	}
+	if decoder.err == nil && module.Entry >= len(module.Functions) {
+		decoder.fail("entry function %d does not exist!", module.Entry)
+	}
+	if decoder.err != nil {
+		return nil, decoder.err
+	}
+	return module, nil
+}
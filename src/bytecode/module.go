@@ -0,0 +1,212 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// 常量池中常量的种类
+const (
+	ConstantInt = iota + 1
+	ConstantFloat
+	ConstantString
+)
+
+// 常量池中的一个常量，根据 Kind 只有其中一个字段有意义
+type Constant struct {
+	Kind  int
+	Int   int64
+	Float float64
+	Str   string
+}
+
+func (constant Constant) String() string {
+	switch constant.Kind {
+	case ConstantInt:
+		return strconv.FormatInt(constant.Int, 10)
+	case ConstantFloat:
+		return strconv.FormatFloat(constant.Float, 'g', -1, 64)
+	case ConstantString:
+		return strconv.Quote(constant.Str)
+	}
+	return "<invalid>"
+}
+
+// 行号表中的一项：从 PC 起的指令都来自源代码的第 Line 行，直到下一项为止
+type LineEntry struct {
+	PC   int
+	Line int
+}
+
+// 函数表中的一个函数
+type Function struct {
+	Name    string
+	Source  string      // 函数所在的源文件，引入的模块中的函数与程序本身不在同一个文件中
+	Params  int         // 形参个数，形参占据前若干个局部变量槽位
+	Locals  int         // 局部变量槽位总数，包括形参
+	Returns int         // 返回值个数
+	Code    []byte      // 指令序列
+	Lines   []LineEntry // 行号表，按 PC 升序排列
+}
+
+// 在函数末尾追加一条指令，返回指令的起始偏移量
+func (function *Function) Emit(op Opcode, operands ...int) int {
+	definition := Lookup(op)
+	if definition == nil || len(operands) != len(definition.OperandWidths) {
+		panic(fmt.Sprintf("bytecode: malformed instruction %s %v", op, operands))
+	}
+	offset := len(function.Code)
+	function.Code = append(function.Code, byte(op))
+	for i, width := range definition.OperandWidths {
+		function.Code = append(function.Code, make([]byte, width)...)
+		PutOperand(function.Code[len(function.Code)-width:], width, operands[i])
+	}
+	return offset
+}
+
+// 改写位于 offset 的指令的第一个操作数，用于回填跳转目标
+func (function *Function) Patch(offset int, operand int) {
+	width := Lookup(Opcode(function.Code[offset])).OperandWidths[0]
+	PutOperand(function.Code[offset+1:], width, operand)
+}
+
+// 记录此后的指令所在的源代码行，与上一项同一行时不重复记录
+func (function *Function) MarkLine(line int) {
+	pc := len(function.Code)
+	if count := len(function.Lines); count > 0 {
+		last := &function.Lines[count-1]
+		if last.Line == line {
+			return
+		}
+		if last.PC == pc {
+			last.Line = line // 上一行没有产生任何指令
+			return
+		}
+	}
+	function.Lines = append(function.Lines, LineEntry{PC: pc, Line: line})
+}
+
+// 查找位于 pc 的指令所在的源代码行，找不到时返回 0
+func (function *Function) LineOf(pc int) int {
+	line := 0
+	for _, entry := range function.Lines {
+		if entry.PC > pc {
+			break
+		}
+		line = entry.Line
+	}
+	return line
+}
+
+// 一个编译完成的程序，即一个 .cbytes 文件的内容
+type Module struct {
+	Source    string     // 源文件名，供运行时错误定位
+	Constants []Constant // 常量池
+	Functions []*Function
+	Globals   []string // 全局变量的名称，下标即 OpGetGlobal 等指令的操作数
+	Entry     int      // 入口函数在函数表中的下标：脚本的顶层语句被编译为此函数
+}
+
+// 向常量池中添加常量，相同的常量只保存一份，返回其下标
+func (module *Module) AddConstant(constant Constant) int {
+	for i, existing := range module.Constants {
+		if existing == constant {
+			return i
+		}
+	}
+	module.Constants = append(module.Constants, constant)
+	return len(module.Constants) - 1
+}
+
+// 以指定宽度写入、读出操作数
+func PutOperand(b []byte, width int, operand int) {
+	switch width {
+	case WidthU8:
+		b[0] = byte(operand)
+	case WidthU16:
+		binary.LittleEndian.PutUint16(b, uint16(operand))
+	case WidthU32:
+		binary.LittleEndian.PutUint32(b, uint32(operand))
+	}
+}
+func ReadOperand(b []byte, width int) int {
+	switch width {
+	case WidthU8:
+		return int(b[0])
+	case WidthU16:
+		return int(binary.LittleEndian.Uint16(b))
+	case WidthU32:
+		return int(binary.LittleEndian.Uint32(b))
+	}
+	return 0
+}
+
+// 以可读的汇编形式打印整个模块，供 `coral build -S` 与调试使用
+func (module *Module) Disassemble(w io.Writer) {
+	fmt.Fprintf(w, "module %q entry %d\n", module.Source, module.Entry)
+	fmt.Fprintln(w, "constants:")
+	for i, constant := range module.Constants {
+		fmt.Fprintf(w, "  %4d  %s\n", i, constant)
+	}
+	fmt.Fprintln(w, "globals:")
+	for i, name := range module.Globals {
+		fmt.Fprintf(w, "  %4d  %s\n", i, name)
+	}
+	for i, function := range module.Functions {
+		fmt.Fprintf(w, "function %d %s (params %d, locals %d, returns %d):\n",
+			i, function.Name, function.Params, function.Locals, function.Returns)
+		module.disassembleCode(w, function)
+	}
+}
+
+func (module *Module) disassembleCode(w io.Writer, function *Function) {
+	lastLine := 0
+	for pc := 0; pc < len(function.Code); {
+		op := Opcode(function.Code[pc])
+		definition := Lookup(op)
+		if definition == nil {
+			fmt.Fprintf(w, "  %04d  <unknown opcode %d>\n", pc, op)
+			return
+		}
+		lineColumn := "   |"
+		if line := function.LineOf(pc); line != lastLine {
+			lineColumn, lastLine = fmt.Sprintf("%4d", line), line
+		}
+		fmt.Fprintf(w, "  %04d %s  %-20s", pc, lineColumn, definition.Name)
+		offset := pc + 1
+		for _, width := range definition.OperandWidths {
+			if offset+width > len(function.Code) {
+				fmt.Fprintln(w, " <truncated>")
+				return
+			}
+			operand := ReadOperand(function.Code[offset:], width)
+			fmt.Fprintf(w, " %d", operand)
+			offset += width
+			module.annotate(w, op, operand)
+		}
+		fmt.Fprintln(w)
+		pc = offset
+	}
+}
+
+// 在操作数之后注明其所指的常量、全局变量、函数或类型
+func (module *Module) annotate(w io.Writer, op Opcode, operand int) {
+	switch op {
+	case OpConstant, OpGetBuiltin:
+		if operand < len(module.Constants) {
+			fmt.Fprintf(w, " (%s)", module.Constants[operand])
+		}
+	case OpGetGlobal, OpSetGlobal:
+		if operand < len(module.Globals) {
+			fmt.Fprintf(w, " (%s)", module.Globals[operand])
+		}
+	case OpFunction:
+		if operand < len(module.Functions) {
+			fmt.Fprintf(w, " (%s)", module.Functions[operand].Name)
+		}
+	case OpConvert:
+		fmt.Fprintf(w, " (%s)", TypeCode(operand))
+	}
+}
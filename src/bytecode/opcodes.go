@@ -0,0 +1,235 @@
+package bytecode
+
+// Package bytecode 定义了 CVM 的指令集与 .cbytes 字节码文件的格式
+// 上承代码生成，下启虚拟机，两者只通过此包中的数据结构交流
+// 指令集的完整说明见 docs_src/bytecode.md
+
+// 操作码，每条指令由一个字节的操作码与若干个定长的操作数组成，操作数以小端序存放
+type Opcode byte
+
+const (
+	OpNop Opcode = iota
+
+	// 常量与字面值
+	OpConstant // u16 常量池下标：压入常量
+	OpNil      // 压入 nil
+	OpTrue     // 压入 true
+	OpFalse    // 压入 false
+
+	// 栈操作
+	OpPop  // 弹出栈顶
+	OpDup  // 复制栈顶
+	OpDup2 // 复制栈顶的两个值，保持其顺序
+
+	// 变量：写入指令不弹出栈顶，因而赋值表达式仍然有值
+	OpGetLocal   // u16 槽位：压入当前调用帧中的局部变量
+	OpSetLocal   // u16 槽位：以栈顶的值写入局部变量
+	OpGetGlobal  // u16 全局变量下标：压入全局变量
+	OpSetGlobal  // u16 全局变量下标：以栈顶的值写入全局变量
+	OpGetBuiltin // u16 常量池下标：以常量池中的名称压入内建函数
+
+	// 算术与位运算：弹出右、左操作数，压入结果
+	// 两侧都是整数时按整数运算，其一为浮点数时按浮点数运算，两个字符串相加即拼接
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpRem
+	OpPow
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShl
+	OpShr
+	OpNeg    // 取负
+	OpBitNot // 按位取反
+	OpNot    // 逻辑非
+
+	// 比较：弹出右、左操作数，压入 bool
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpLessEqual
+	OpGreater
+	OpGreaterEqual
+
+	// 类型转换
+	OpConvert // u8 类型编码：将栈顶转换为该基本类型的值，整数按其位数截断
+
+	// 跳转：目标为所在函数代码中的绝对偏移量
+	OpJump             // u32 目标：无条件跳转
+	OpJumpIfFalse      // u32 目标：弹出栈顶，为 false 时跳转
+	OpJumpIfFalseOrPop // u32 目标：栈顶为 false 时保留并跳转，否则弹出，用于 &&
+	OpJumpIfTrueOrPop  // u32 目标：栈顶为 true 时保留并跳转，否则弹出，用于 ||
+
+	// 函数
+	OpFunction // u16 函数表下标：压入该函数
+	OpCall     // u8 实参个数：被调用者位于实参之下，调用后二者均被替换为全部返回值
+	OpReturn   // u8 返回值个数：弹出返回值，结束当前调用帧并将返回值压入调用方的栈
+
+	// 数组、表与字符串
+	OpArray    // u16 元素个数：以栈顶的若干个值依次组成数组
+	OpTable    // u16 键值对个数：以栈顶的若干对键、值组成表
+	OpRange    // u8 是否包含终点：弹出终点、起点，压入由区间内的整数组成的数组
+	OpIndex    // 弹出下标、被索引者，压入元素
+	OpSetIndex // 弹出值、下标、被索引者，写入元素后压回该值
+	OpSlice    // u8 标记：1 表示有起点，2 表示有终点，弹出终点、起点与被切片者，压入切片
+	OpLength   // 弹出数组、字符串或表，压入其长度
+	OpKeys     // 弹出表，压入由其全部键按字典序排列组成的数组
+
+	// 异常：处理入口记录登记时的调用帧与操作数栈高度，抛出时回退到该处
+	OpTry        // u32 目标：登记异常处理入口，其后抛出的异常将跳转到目标处
+	OpEndTry     // 撤销最近登记的异常处理入口
+	OpThrow      // 弹出异常并抛出：回退到最近登记的处理入口，压入异常后跳转；没有处理入口时以未捕获的异常结束运行
+	OpException  // u16 常量池下标：弹出消息，以常量池中的类名创建异常
+	OpIsInstance // u16 常量池下标：弹出值，压入它是否为常量池中所命名的类的异常
+	OpGetField   // u16 常量池下标：弹出对象，压入以常量池中的名称命名的字段
+)
+
+// 操作数的宽度，以字节计
+const (
+	WidthU8  = 1
+	WidthU16 = 2
+	WidthU32 = 4
+)
+
+// 指令的定义：名称与各个操作数的宽度
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpNop:      {"NOP", nil},
+	OpConstant: {"CONSTANT", []int{WidthU16}},
+	OpNil:      {"NIL", nil},
+	OpTrue:     {"TRUE", nil},
+	OpFalse:    {"FALSE", nil},
+
+	OpPop:  {"POP", nil},
+	OpDup:  {"DUP", nil},
+	OpDup2: {"DUP2", nil},
+
+	OpGetLocal:   {"GET_LOCAL", []int{WidthU16}},
+	OpSetLocal:   {"SET_LOCAL", []int{WidthU16}},
+	OpGetGlobal:  {"GET_GLOBAL", []int{WidthU16}},
+	OpSetGlobal:  {"SET_GLOBAL", []int{WidthU16}},
+	OpGetBuiltin: {"GET_BUILTIN", []int{WidthU16}},
+
+	OpAdd:    {"ADD", nil},
+	OpSub:    {"SUB", nil},
+	OpMul:    {"MUL", nil},
+	OpDiv:    {"DIV", nil},
+	OpRem:    {"REM", nil},
+	OpPow:    {"POW", nil},
+	OpBitAnd: {"BIT_AND", nil},
+	OpBitOr:  {"BIT_OR", nil},
+	OpBitXor: {"BIT_XOR", nil},
+	OpShl:    {"SHL", nil},
+	OpShr:    {"SHR", nil},
+	OpNeg:    {"NEG", nil},
+	OpBitNot: {"BIT_NOT", nil},
+	OpNot:    {"NOT", nil},
+
+	OpEqual:        {"EQUAL", nil},
+	OpNotEqual:     {"NOT_EQUAL", nil},
+	OpLess:         {"LESS", nil},
+	OpLessEqual:    {"LESS_EQUAL", nil},
+	OpGreater:      {"GREATER", nil},
+	OpGreaterEqual: {"GREATER_EQUAL", nil},
+
+	OpConvert: {"CONVERT", []int{WidthU8}},
+
+	OpJump:             {"JUMP", []int{WidthU32}},
+	OpJumpIfFalse:      {"JUMP_IF_FALSE", []int{WidthU32}},
+	OpJumpIfFalseOrPop: {"JUMP_IF_FALSE_OR_POP", []int{WidthU32}},
+	OpJumpIfTrueOrPop:  {"JUMP_IF_TRUE_OR_POP", []int{WidthU32}},
+
+	OpFunction: {"FUNCTION", []int{WidthU16}},
+	OpCall:     {"CALL", []int{WidthU8}},
+	OpReturn:   {"RETURN", []int{WidthU8}},
+
+	OpArray:    {"ARRAY", []int{WidthU16}},
+	OpTable:    {"TABLE", []int{WidthU16}},
+	OpRange:    {"RANGE", []int{WidthU8}},
+	OpIndex:    {"INDEX", nil},
+	OpSetIndex: {"SET_INDEX", nil},
+	OpSlice:    {"SLICE", []int{WidthU8}},
+	OpLength:   {"LENGTH", nil},
+	OpKeys:     {"KEYS", nil},
+
+	OpTry:        {"TRY", []int{WidthU32}},
+	OpEndTry:     {"END_TRY", nil},
+	OpThrow:      {"THROW", nil},
+	OpException:  {"EXCEPTION", []int{WidthU16}},
+	OpIsInstance: {"IS_INSTANCE", []int{WidthU16}},
+	OpGetField:   {"GET_FIELD", []int{WidthU16}},
+}
+
+// 查找操作码的定义，未知的操作码返回 nil
+func Lookup(op Opcode) *Definition {
+	return definitions[op]
+}
+
+func (op Opcode) String() string {
+	if definition := Lookup(op); definition != nil {
+		return definition.Name
+	}
+	return "UNKNOWN"
+}
+
+// 指令的总长度：操作码本身加上全部操作数
+func (definition *Definition) Length() int {
+	length := 1
+	for _, width := range definition.OperandWidths {
+		length += width
+	}
+	return length
+}
+
+// OpSlice 的标记位
+const (
+	SliceHasStart = 1 << iota
+	SliceHasEnd
+)
+
+// OpConvert 所用的基本类型编码，顺序与语义分析中预定义的基本类型一致
+type TypeCode byte
+
+const (
+	TypeInt TypeCode = iota
+	TypeInt8
+	TypeInt16
+	TypeInt64
+	TypeUint
+	TypeUint8
+	TypeUint16
+	TypeUint64
+	TypeFloat
+	TypeDouble
+	TypeRune
+	TypeBool
+	TypeString
+)
+
+var typeCodeNames = []string{
+	"int", "int8", "int16", "int64", "uint", "uint8", "uint16", "uint64",
+	"float", "double", "rune", "bool", "String",
+}
+
+func (code TypeCode) String() string {
+	if int(code) < len(typeCodeNames) {
+		return typeCodeNames[code]
+	}
+	return "unknown"
+}
+
+// 以基本类型的名称查找其类型编码
+func TypeCodeOf(name string) (TypeCode, bool) {
+	for i, typeName := range typeCodeNames {
+		if typeName == name {
+			return TypeCode(i), true
+		}
+	}
+	return 0, false
+}
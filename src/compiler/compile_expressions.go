@@ -0,0 +1,438 @@
+package compiler
+
+import (
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/lexer"
+	"fmt"
+	"go/constant"
+	"math"
+)
+
+var (
+	constantZero = constant.MakeInt64(0)
+	constantOne  = constant.MakeInt64(1)
+)
+
+// 二元运算符对应的指令，复合赋值运算符对应其中的运算
+var binaryOpcodes = map[TokenType]Opcode{
+	TokenTypePlus:             OpAdd,
+	TokenTypeMinus:            OpSub,
+	TokenTypeStar:             OpMul,
+	TokenTypeSlash:            OpDiv,
+	TokenTypePercent:          OpRem,
+	TokenTypeDoubleStar:       OpPow,
+	TokenTypeAmpersand:        OpBitAnd,
+	TokenTypeVertical:         OpBitOr,
+	TokenTypeCaret:            OpBitXor,
+	TokenTypeDoubleLeftAngle:  OpShl,
+	TokenTypeDoubleRightAngle: OpShr,
+
+	TokenTypeDoubleEqual:     OpEqual,
+	TokenTypeBangEqual:       OpNotEqual,
+	TokenTypeLeftAngle:       OpLess,
+	TokenTypeLeftAngleEqual:  OpLessEqual,
+	TokenTypeRightAngle:      OpGreater,
+	TokenTypeRightAngleEqual: OpGreaterEqual,
+}
+var compoundAssignOpcodes = map[TokenType]Opcode{
+	TokenTypePlusEqual:             OpAdd,
+	TokenTypeMinusEqual:            OpSub,
+	TokenTypeStarEqual:             OpMul,
+	TokenTypeSlashEqual:            OpDiv,
+	TokenTypePercentEqual:          OpRem,
+	TokenTypeAmpersandEqual:        OpBitAnd,
+	TokenTypeVerticalEqual:         OpBitOr,
+	TokenTypeCaretEqual:            OpBitXor,
+	TokenTypeDoubleLeftAngleEqual:  OpShl,
+	TokenTypeDoubleRightAngleEqual: OpShr,
+}
+
+// 编译表达式，其结果留在栈顶：没有返回值的调用不留下值，多返回值的调用留下全部返回值
+// 编译期已经求出值的常量表达式直接压入常量
+func (compiler *Compiler) compileExpression(expression Expression) {
+	exprType := compiler.analyzer.Types[expression]
+	if value, isConstant := compiler.analyzer.Constants[expression]; isConstant {
+		compiler.emitConstantValue(value, DefaultType(exprType))
+		return
+	}
+	switch expression.ExpressionNodeType() {
+	case ExpressionTypePrimary:
+		compiler.compilePrimaryExpression(expression.(PrimaryExpression))
+	case ExpressionTypeNewInstance:
+		compiler.compileNewInstanceExpression(expression.(*NewInstanceExpression))
+	case ExpressionTypeUnary:
+		compiler.compileUnaryExpression(expression.(*UnaryExpression))
+	case ExpressionTypeBinary:
+		compiler.compileBinaryExpression(expression.(*BinaryExpression))
+	case ExpressionTypeRange:
+		rangeExpr := expression.(*RangeExpression)
+		elementType := exprType.(*ArrayType).Element
+		compiler.compileValue(rangeExpr.Start, elementType)
+		compiler.compileValue(rangeExpr.End, elementType)
+		includeEnd := 0
+		if rangeExpr.IncludeEnd {
+			includeEnd = 1
+		}
+		compiler.emit(OpRange, includeEnd)
+	case ExpressionTypeCast:
+		castExpr := expression.(*CastExpression)
+		compiler.compileValue(castExpr.Source, nil)
+		if code, isBasic := typeCodeOf(exprType); isBasic && !IdenticalTypes(DefaultType(compiler.analyzer.Types[castExpr.Source]), exprType) {
+			compiler.emit(OpConvert, int(code))
+		}
+	}
+}
+
+// 编译用作类型 target 的单个值：未定类型的值在此转为 target，target 为 nil 时取其默认类型
+func (compiler *Compiler) compileValue(expression Expression, target Type) {
+	exprType := compiler.analyzer.Types[expression]
+	if _, isBasic := target.(*BasicType); !isBasic {
+		target = DefaultType(exprType)
+	}
+	if value, isConstant := compiler.analyzer.Constants[expression]; isConstant {
+		compiler.emitConstantValue(value, target)
+		return
+	}
+	compiler.compileExpression(expression)
+	if code, isBasic := typeCodeOf(target); isBasic && IsNumericType(exprType) && !IdenticalTypes(exprType, target) {
+		compiler.emit(OpConvert, int(code))
+	}
+}
+
+// 编译一组值，若仅有一个多返回值的函数调用，则留下其全部返回值
+func (compiler *Compiler) compileValueList(expressions []Expression, types []Type) {
+	if len(expressions) == 1 {
+		if _, isTuple := compiler.analyzer.Types[expressions[0]].(*TupleType); isTuple {
+			compiler.compileExpression(expressions[0])
+			return
+		}
+	}
+	for i, expression := range expressions {
+		var target Type
+		if i < len(types) {
+			target = types[i]
+		}
+		compiler.compileValue(expression, target)
+	}
+}
+
+// 一组值展开后的个数
+func (compiler *Compiler) listLength(expressions []Expression) int {
+	if len(expressions) == 1 {
+		if tuple, isTuple := compiler.analyzer.Types[expressions[0]].(*TupleType); isTuple {
+			return len(tuple.Types)
+		}
+	}
+	return len(expressions)
+}
+
+func (compiler *Compiler) compilePrimaryExpression(primaryExpr PrimaryExpression) {
+	switch primaryExpr.PrimaryExpressionNode() {
+	case PrimaryExprTypeBasic:
+		compiler.compileOperand(primaryExpr, primaryExpr.(*BasicPrimaryExpression).It)
+	case PrimaryExprTypeIndex:
+		indexExpr := primaryExpr.(*IndexExpression)
+		compiler.compileValue(indexExpr.Operand, nil)
+		compiler.compileValue(indexExpr.Index, nil)
+		compiler.emit(OpIndex)
+	case PrimaryExprTypeSlice:
+		sliceExpr := primaryExpr.(*SliceExpression)
+		compiler.compileValue(sliceExpr.Operand, nil)
+		flags := 0
+		if sliceExpr.Start != nil {
+			compiler.compileValue(sliceExpr.Start, nil)
+			flags |= SliceHasStart
+		}
+		if sliceExpr.End != nil {
+			compiler.compileValue(sliceExpr.End, nil)
+			flags |= SliceHasEnd
+		}
+		compiler.emit(OpSlice, flags)
+	case PrimaryExprTypeCall:
+		compiler.compileCallExpression(primaryExpr.(*CallExpression))
+	case PrimaryExprTypeMember:
+		compiler.compileMemberExpression(primaryExpr.(*MemberExpression))
+	}
+}
+
+// 实参转换为形参的类型，可变参数的内建函数则取实参的默认类型
+func (compiler *Compiler) compileCallExpression(callExpr *CallExpression) {
+	compiler.compileValue(callExpr.Operand, nil)
+	var paramTypes []Type
+	if fnType, isFunction := compiler.analyzer.Types[callExpr.Operand].(*FunctionType); isFunction && !fnType.Variadic {
+		paramTypes = fnType.Params
+	}
+	compiler.compileValueList(callExpr.Params, paramTypes)
+	argc := compiler.listLength(callExpr.Params)
+	if argc > math.MaxUint8 {
+		compiler.ReportError(callExpr, UnsupportedFeature, "too many arguments in call: %d, at most %d!", argc, math.MaxUint8)
+		return
+	}
+	compiler.emit(OpCall, argc)
+}
+
+// 枚举元素即其整数值；数组与字符串只有 length 一个成员；模块的成员为模块顶层的变量、函数与枚举
+func (compiler *Compiler) compileMemberExpression(memberExpr *MemberExpression) {
+	ownerType := compiler.analyzer.Types[memberExpr.Operand]
+	member := memberExpr.Member
+	loaded := false // 是否已经压入了成员所属的值
+	for moduleType, isModule := ownerType.(*ModuleType); isModule && member != nil; moduleType, isModule = ownerType.(*ModuleType) {
+		switch symbol := moduleType.Module.Lookup(member.It.GetName()).(type) {
+		case *IdSymbol:
+			if _, isModule := symbol.Type.(*ModuleType); !isModule {
+				compiler.emitGet(compiler.modules[moduleType.Module].bindings[member.It.GetName()])
+				loaded = true
+			}
+			ownerType = symbol.Type
+		case *EnumSymbol:
+			ownerType = &EnumType{Symbol: symbol}
+		default:
+			compiler.reportUnsupported(member.It, "member access on "+ownerType.String())
+			return
+		}
+		member = member.MemberNext
+	}
+	if enumType, isEnum := ownerType.(*EnumType); isEnum && !loaded && member != nil {
+		element := enumType.Symbol.ElementsMap[member.It.GetName()]
+		compiler.emitConstant(Constant{Kind: ConstantInt, Int: compiler.enumValues[element]})
+		return
+	}
+	if member == memberExpr.Member {
+		compiler.compileValue(memberExpr.Operand, nil)
+	}
+	for ; member != nil; member = member.MemberNext {
+		_, isArray := ownerType.(*ArrayType)
+		if (isArray || ownerType == StringType) && member.It.GetName() == "length" {
+			compiler.emit(OpLength)
+			ownerType = IntType
+			continue
+		}
+		if isExceptionClass(ownerType) && member.It.GetName() == "message" {
+			compiler.emit(OpGetField, compiler.module.AddConstant(Constant{Kind: ConstantString, Str: "message"}))
+			ownerType = StringType
+			continue
+		}
+		compiler.reportUnsupported(member.It, "member access on "+ownerType.String())
+		return
+	}
+}
+
+// 自定义的类尚不支持，只有内建的 Exception 类可以实例化
+func (compiler *Compiler) compileNewInstanceExpression(newInstanceExpr *NewInstanceExpression) {
+	classType := compiler.analyzer.Types[newInstanceExpr]
+	if !isExceptionClass(classType) {
+		compiler.reportUnsupported(newInstanceExpr, "class instantiation")
+		return
+	}
+	compiler.compileValue(newInstanceExpr.InitParams[0], StringType)
+	compiler.emit(OpException, compiler.module.AddConstant(Constant{Kind: ConstantString, Str: "Exception"}))
+}
+func isExceptionClass(t Type) bool {
+	classType, isClass := t.(*ClassType)
+	return isClass && classType.Symbol == ExceptionType.Symbol
+}
+
+func (compiler *Compiler) compileOperand(expression Expression, operand Operand) {
+	switch operand.OperandNodeType() {
+	case OperandTypeName:
+		name := operand.(*OperandName).GetFullName()
+		if b := compiler.resolve(operand, name); b != nil {
+			compiler.emitGet(b)
+			return
+		}
+		if _, isBuiltin := compiler.analyzer.BuiltinScope.SymbolMap[name].(*IdSymbol); isBuiltin {
+			compiler.emit(OpGetBuiltin, compiler.module.AddConstant(Constant{Kind: ConstantString, Str: name}))
+			return
+		}
+		compiler.reportUnsupported(operand, fmt.Sprintf("using \"%s\" as a value", name))
+	case OperandTypeLiteral:
+		compiler.compileLiteral(expression, operand.(Literal))
+	}
+}
+
+// 数字、字符与字符串字面量都是常量，已经在 compileExpression 中处理
+func (compiler *Compiler) compileLiteral(expression Expression, literal Literal) {
+	switch literal.LiteralNodeType() {
+	case LiteralNodeTypeNil:
+		compiler.emit(OpNil)
+	case LiteralNodeTypeArray:
+		values := literal.(*ArrayLit).ValueList
+		elementType := compiler.analyzer.Types[expression].(*ArrayType).Element
+		for _, value := range values {
+			compiler.compileValue(value, elementType)
+		}
+		compiler.emitCollection(literal, OpArray, len(values))
+	case LiteralNodeTypeMap:
+		elements := literal.(*TableLit).KeyValueList
+		valueType := compiler.analyzer.Types[expression].(*TableType).Value
+		for _, element := range elements {
+			compiler.emitConstant(Constant{Kind: ConstantString, Str: element.Key.GetName()})
+			compiler.compileValue(element.Value, valueType)
+		}
+		compiler.emitCollection(literal, OpTable, len(elements))
+	case LiteralNodeTypeLambda:
+		compiler.compileLambda(literal.(*LambdaLit), compiler.analyzer.Types[expression].(*FunctionType))
+	case LiteralNodeTypeThis:
+		compiler.reportUnsupported(literal, "this")
+	case LiteralNodeTypeSuper:
+		compiler.reportUnsupported(literal, "super")
+	}
+}
+func (compiler *Compiler) emitCollection(literal Literal, op Opcode, count int) {
+	if count > math.MaxUint16 {
+		compiler.ReportError(literal, UnsupportedFeature, "too many elements in literal: %d, at most %d!", count, math.MaxUint16)
+		return
+	}
+	compiler.emit(op, count)
+}
+
+// lambda 编译为函数表中的一个新函数，以表达式为结果时返回表达式的值
+func (compiler *Compiler) compileLambda(lambda *LambdaLit, fnType *FunctionType) {
+	index := compiler.reserveFunction(lambda)
+	compiler.compileFunction(index, "<lambda>", fnType, lambda.Signature, func() {
+		compiler.markLine(lambda)
+		switch result := lambda.Result.(type) {
+		case *BlockStatement:
+			compiler.compileStatementList(result.Statements)
+		case Expression:
+			if len(fnType.Returns) == 1 {
+				compiler.compileValue(result, fnType.Returns[0])
+				compiler.emit(OpReturn, 1)
+			} else {
+				compiler.compileExpression(result)
+				compiler.emitPops(valueCount(compiler.analyzer.Types[result]))
+			}
+		default:
+			compiler.compileStatement(result)
+		}
+	})
+	compiler.emit(OpFunction, index)
+}
+
+// 运算的结果按类型截断，因此 int8 的 127 + 1 得到 -128
+func (compiler *Compiler) compileUnaryExpression(unaryExpr *UnaryExpression) {
+	resultType := DefaultType(compiler.analyzer.Types[unaryExpr])
+	switch unaryExpr.Operator.Kind {
+	case TokenTypeMinus:
+		compiler.compileValue(unaryExpr.Operand, resultType)
+		compiler.emit(OpNeg)
+		compiler.emitTruncation(resultType)
+	case TokenTypeWavy:
+		compiler.compileValue(unaryExpr.Operand, resultType)
+		compiler.emit(OpBitNot)
+		compiler.emitTruncation(resultType)
+	case TokenTypeBang:
+		compiler.compileValue(unaryExpr.Operand, BoolType)
+		compiler.emit(OpNot)
+	}
+}
+
+func (compiler *Compiler) compileBinaryExpression(binaryExpr *BinaryExpression) {
+	operator := binaryExpr.Operator.Kind
+	leftType := compiler.analyzer.Types[binaryExpr.Left]
+	rightType := compiler.analyzer.Types[binaryExpr.Right]
+	if operator == TokenTypeEqual {
+		compiler.compileStore(binaryExpr.Left, func() {
+			compiler.compileValue(binaryExpr.Right, leftType)
+		})
+		return
+	}
+	if op, isCompound := compoundAssignOpcodes[operator]; isCompound {
+		compiler.compileUpdate(binaryExpr.Left, func() {
+			compiler.compileOperation(op, binaryExpr.Right, leftType)
+			compiler.emitTruncation(leftType)
+		})
+		return
+	}
+
+	switch operator {
+	case TokenTypeDoubleAmpersand, TokenTypeDoubleVertical:
+		// 短路求值：左侧已经能确定结果时保留左侧的值，不再求右侧
+		jumpOp := OpJumpIfFalseOrPop
+		if operator == TokenTypeDoubleVertical {
+			jumpOp = OpJumpIfTrueOrPop
+		}
+		compiler.compileValue(binaryExpr.Left, BoolType)
+		end := compiler.emitJump(jumpOp)
+		compiler.compileValue(binaryExpr.Right, BoolType)
+		compiler.patchJump(end)
+	case TokenTypeDoubleEqual, TokenTypeBangEqual, TokenTypeLeftAngle, TokenTypeLeftAngleEqual,
+		TokenTypeRightAngle, TokenTypeRightAngleEqual:
+		// 比较两侧转为相同的类型，未定类型的一侧取另一侧的类型
+		operandType := leftType
+		if IsUntyped(leftType) {
+			operandType = rightType
+		}
+		compiler.compileValue(binaryExpr.Left, operandType)
+		compiler.compileValue(binaryExpr.Right, operandType)
+		compiler.emit(binaryOpcodes[operator])
+	default:
+		resultType := DefaultType(compiler.analyzer.Types[binaryExpr])
+		compiler.compileValue(binaryExpr.Left, resultType)
+		compiler.compileOperation(binaryOpcodes[operator], binaryExpr.Right, resultType)
+		compiler.emitTruncation(resultType)
+	}
+}
+
+// 以栈顶的值为左操作数进行运算，移位运算的右操作数保持其自身的类型
+func (compiler *Compiler) compileOperation(op Opcode, right Expression, operandType Type) {
+	if op == OpShl || op == OpShr {
+		compiler.compileValue(right, nil)
+	} else {
+		compiler.compileValue(right, operandType)
+	}
+	compiler.emit(op)
+}
+
+// 以 value 压入的值写入赋值目标，写入的值留在栈顶
+func (compiler *Compiler) compileStore(target Expression, value func()) {
+	switch target := target.(type) {
+	case *BasicPrimaryExpression:
+		if b := compiler.assignedBinding(target); b != nil {
+			value()
+			compiler.emitSet(b)
+		}
+	case *IndexExpression:
+		compiler.compileValue(target.Operand, nil)
+		compiler.compileValue(target.Index, nil)
+		value()
+		compiler.emit(OpSetIndex)
+	default:
+		compiler.reportUnsupported(target, "assigning to a class member")
+	}
+}
+
+// 以赋值目标的当前值为左操作数，经 update 运算后写回，新的值留在栈顶
+func (compiler *Compiler) compileUpdate(target Expression, update func()) {
+	switch target := target.(type) {
+	case *BasicPrimaryExpression:
+		if b := compiler.assignedBinding(target); b != nil {
+			compiler.emitGet(b)
+			update()
+			compiler.emitSet(b)
+		}
+	case *IndexExpression:
+		compiler.compileValue(target.Operand, nil)
+		compiler.compileValue(target.Index, nil)
+		compiler.emit(OpDup2)
+		compiler.emit(OpIndex)
+		update()
+		compiler.emit(OpSetIndex)
+	default:
+		compiler.reportUnsupported(target, "assigning to a class member")
+	}
+}
+
+// 赋值目标所指的变量，函数名不能被重新赋值
+func (compiler *Compiler) assignedBinding(target *BasicPrimaryExpression) *binding {
+	operandName := target.It.(*OperandName)
+	b := compiler.resolve(operandName, operandName.GetFullName())
+	if b == nil || b.kind == bindingFunction {
+		compiler.reportUnsupported(target, fmt.Sprintf("assigning to \"%s\"", operandName.GetFullName()))
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,538 @@
+package compiler
+
+import (
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/exception"
+	"strconv"
+)
+
+// 编译一组同一区块中的语句：与语义分析一致，先定义其中的函数与枚举，使得它们可以在定义之前被引用
+func (compiler *Compiler) compileStatementList(stmts []Statement) {
+	for _, stmt := range stmts {
+		switch stmt.StatementNodeType() {
+		case StatementTypeFunctionDecl:
+			fnStmt := stmt.(*FunctionDeclarationStatement)
+			index := compiler.reserveFunction(fnStmt)
+			compiler.functions[fnStmt] = index
+			compiler.scope.bindings[fnStmt.Name.GetName()] = &binding{kind: bindingFunction, index: index}
+		case StatementTypeEnum:
+			compiler.declareEnum(stmt.(*EnumStatement))
+		}
+	}
+	for _, stmt := range stmts {
+		compiler.compileStatement(stmt)
+	}
+}
+
+// 枚举元素的值：给出值的元素取其值，其余元素为上一个元素的值加一，第一个元素默认为 0
+func (compiler *Compiler) declareEnum(enumStmt *EnumStatement) {
+	next := int64(0)
+	for _, element := range enumStmt.Elements {
+		if element.Value != nil {
+			if value, err := strconv.ParseInt(element.Value.Value.Str, 0, 64); err == nil {
+				next = value
+			}
+		}
+		compiler.enumValues[element] = next
+		next++
+	}
+}
+
+func (compiler *Compiler) compileStatement(stmt Statement) {
+	compiler.stmt = stmt
+	compiler.markLine(stmt)
+	switch stmt.StatementNodeType() {
+	case StatementTypeSimple:
+		switch simpleStmt := stmt.(type) {
+		case *ReturnStatement:
+			compiler.compileReturnStatement(simpleStmt)
+		case *ThrowStatement:
+			compiler.compileValue(simpleStmt.Value, nil)
+			compiler.emit(OpThrow)
+		case *BreakStatement:
+			compiler.compileBranch(simpleStmt, "break")
+		case *ContinueStatement:
+			compiler.compileBranch(simpleStmt, "continue")
+		case SimpleStatement:
+			compiler.compileSimpleStatement(simpleStmt)
+		}
+	case StatementTypeBlock:
+		compiler.compileScopedBlock(stmt.(*BlockStatement))
+	case StatementTypeIf:
+		compiler.compileIfStatement(stmt.(*IfStatement))
+	case StatementTypeSwitch:
+		compiler.compileSwitchStatement(stmt.(*SwitchStatement))
+	case StatementTypeWhile:
+		compiler.compileWhileStatement(stmt.(*WhileStatement))
+	case StatementTypeFor:
+		compiler.compileForStatement(stmt.(*ForStatement))
+	case StatementTypeEach:
+		compiler.compileEachStatement(stmt.(*EachStatement))
+	case StatementTypeFunctionDecl:
+		fnStmt := stmt.(*FunctionDeclarationStatement)
+		fnSymbol := compiler.analyzer.Symbols[fnStmt].(*IdSymbol)
+		fnType := fnSymbol.Type.(*FunctionType)
+		// 函数以包名限定的名称记录在字节码中，调用栈据此区分不同包中的同名函数
+		compiler.compileFunction(compiler.functions[fnStmt], fnSymbol.QualifiedName(), fnType, fnStmt.Signature, func() {
+			if fnStmt.Block != nil {
+				compiler.compileStatementList(fnStmt.Block.Statements)
+			}
+		})
+	case StatementTypeTryCatch:
+		compiler.compileTryCatchStatement(stmt.(*TryCatchStatement))
+	case StatementTypeImport:
+		compiler.compileImportStatement(stmt.(ImportStatement))
+	case StatementTypeClassDecl:
+		compiler.reportUnsupported(stmt, "class")
+	case StatementTypePackage, StatementTypeEnum, StatementTypeInterfaceDecl, StatementTypeBad:
+		// 枚举已经在 compileStatementList 中求出了值，其余语句不产生任何指令
+	}
+}
+
+// 模块已经在入口函数的开头初始化，引入整个模块不产生任何指令，其成员在访问时直接引用
+// 从模块中引入的变量取引入时的值，函数则直接引用函数本身；类型与枚举不需要存储
+func (compiler *Compiler) compileImportStatement(importStmt ImportStatement) {
+	var elements []*ImportElement
+	switch importStmt := importStmt.(type) {
+	case *SingleFromImportStatement:
+		elements = []*ImportElement{importStmt.Element}
+	case *ListImportStatement:
+		elements = importStmt.Elements
+	}
+	moduleScope := compiler.modules[compiler.analyzer.Imports[importStmt]]
+	for _, element := range elements {
+		if _, isId := compiler.analyzer.Symbols[element].(*IdSymbol); !isId || moduleScope == nil {
+			continue
+		}
+		name := element.ModuleName.GetName()
+		if element.As != nil {
+			name = element.As.GetName()
+		}
+		imported := moduleScope.bindings[element.ModuleName.GetName()]
+		if imported == nil {
+			continue // 模块引入的模块，其成员在访问时直接引用
+		}
+		if imported.kind == bindingFunction {
+			compiler.scope.bindings[name] = imported
+			continue
+		}
+		compiler.emitGet(imported)
+		compiler.emitSet(compiler.declareVariable(name))
+		compiler.emit(OpPop)
+	}
+}
+
+func (compiler *Compiler) compileSimpleStatement(simpleStmt SimpleStatement) {
+	switch simpleStmt.SimpleStatementNodeType() {
+	case SimpleStmtTypeExpression:
+		expression := simpleStmt.(Expression)
+		compiler.compileExpression(expression)
+		compiler.emitPops(valueCount(compiler.analyzer.Types[expression]))
+	case SimpleStmtTypeVariableDecl:
+		compiler.compileVarDeclStatement(simpleStmt.(*VarDeclStatement))
+	case SimpleStmtTypeAssignList:
+		compiler.compileAssignListStatement(simpleStmt.(*AssignListStatement))
+	case SimpleStmtTypeIncDecStmt:
+		incDecStmt := simpleStmt.(*IncDecStatement)
+		op := OpAdd
+		if incDecStmt.Operator.Str == "--" {
+			op = OpSub
+		}
+		targetType := compiler.analyzer.Types[incDecStmt.Expression]
+		compiler.compileUpdate(incDecStmt.Expression, func() {
+			compiler.emitConstantValue(constantOne, targetType)
+			compiler.emit(op)
+			compiler.emitTruncation(targetType)
+		})
+		compiler.emit(OpPop)
+	}
+}
+
+// 表达式语句的结果个数：没有返回值的调用为 0，多返回值的调用为返回值个数
+func valueCount(t Type) int {
+	switch t.TypeKind() {
+	case TypeKindVoid:
+		return 0
+	case TypeKindTuple:
+		return len(t.(*TupleType).Types)
+	}
+	return 1
+}
+func (compiler *Compiler) emitPops(count int) {
+	for i := 0; i < count; i++ {
+		compiler.emit(OpPop)
+	}
+}
+
+// 先求初始值再定义变量，没有初始值的变量取其类型的零值
+func (compiler *Compiler) compileVarDeclStatement(varDeclStmt *VarDeclStatement) {
+	for _, element := range varDeclStmt.Declarations {
+		varType := compiler.analyzer.Symbols[element].(*IdSymbol).Type
+		if element.InitValue != nil {
+			compiler.compileValue(element.InitValue, varType)
+		} else {
+			compiler.emitZero(varType)
+		}
+		compiler.emitSet(compiler.declareVariable(element.VarName.Str))
+		compiler.emit(OpPop)
+	}
+}
+
+// 先求出全部的值并暂存在临时变量中，再依次赋值，因此 a, b = b, a 可以交换两个变量
+func (compiler *Compiler) compileAssignListStatement(assignListStmt *AssignListStatement) {
+	compiler.enterScope()
+	temporaries := make([]*binding, len(assignListStmt.Targets))
+	for i := range temporaries {
+		temporaries[i] = compiler.declareLocal("")
+	}
+	compiler.compileValueList(assignListStmt.Values, compiler.targetTypes(assignListStmt.Targets))
+	for i := len(temporaries) - 1; i >= 0; i-- {
+		compiler.emitSet(temporaries[i])
+		compiler.emit(OpPop)
+	}
+	for i, target := range assignListStmt.Targets {
+		temporary := temporaries[i]
+		compiler.compileStore(target, func() {
+			compiler.emitGet(temporary)
+		})
+		compiler.emit(OpPop)
+	}
+	compiler.leaveScope()
+}
+func (compiler *Compiler) targetTypes(targets []PrimaryExpression) []Type {
+	var types []Type
+	for _, target := range targets {
+		types = append(types, compiler.analyzer.Types[target])
+	}
+	return types
+}
+
+// 返回值转换为函数声明的返回值类型；脚本的顶层没有返回值，return 只结束脚本
+func (compiler *Compiler) compileReturnStatement(returnStmt *ReturnStatement) {
+	if compiler.current.outer == nil {
+		compiler.compileValueList(returnStmt.Expression, nil)
+		compiler.emitPops(compiler.listLength(returnStmt.Expression))
+		compiler.exitTries(0)
+		compiler.emit(OpReturn, 0)
+		return
+	}
+	// 返回值先于 finally 块求值，finally 块执行时它们留在操作数栈上
+	compiler.compileValueList(returnStmt.Expression, compiler.current.fnType.Returns)
+	compiler.exitTries(0)
+	compiler.emit(OpReturn, len(compiler.current.fnType.Returns))
+}
+
+// break 与 continue 先跳转到未知的位置，待所在循环编译完毕后回填
+func (compiler *Compiler) compileBranch(stmt Statement, keyword string) {
+	loops := compiler.current.loops
+	if len(loops) == 0 {
+		compiler.ReportError(stmt, InvalidOperation, "%s is not in a loop!", keyword)
+		return
+	}
+	loop := loops[len(loops)-1]
+	compiler.exitTries(loop.tries)
+	jump := compiler.emitJump(OpJump)
+	if keyword == "break" {
+		loop.breaks = append(loop.breaks, jump)
+	} else {
+		loop.continues = append(loop.continues, jump)
+	}
+}
+
+// 编译循环体，body 返回 continue 应当跳转到的位置，break 则跳转到循环之后
+func (compiler *Compiler) compileLoop(body func() int) {
+	loop := &loopState{tries: len(compiler.current.tries)}
+	compiler.current.loops = append(compiler.current.loops, loop)
+	continueTarget := body()
+	compiler.current.loops = compiler.current.loops[:len(compiler.current.loops)-1]
+	for _, jump := range loop.continues {
+		compiler.current.function.Patch(jump, continueTarget)
+	}
+	for _, jump := range loop.breaks {
+		compiler.patchJump(jump)
+	}
+}
+
+// 由内向外退出第 depth 层以内的 try 语句：撤销其处理入口并执行其 finally 块
+// finally 块中的 return 等语句只需退出更外层的 try 语句
+func (compiler *Compiler) exitTries(depth int) {
+	tries := compiler.current.tries
+	for i := len(tries) - 1; i >= depth; i-- {
+		compiler.emit(OpEndTry)
+		compiler.current.tries = tries[:i]
+		compiler.compileScopedBlock(tries[i].finally)
+	}
+	compiler.current.tries = tries
+}
+
+// try 块在处理入口的保护下执行，抛出的异常依次与各个 catch 的类型比较，都不匹配时重新抛出
+// 有 finally 块时，catch 块同样在另一个处理入口的保护下执行，使得其中以及重新抛出的异常都先经过 finally 块：
+//
+//	    TRY catch; <try 块>; END_TRY; <finally>; JUMP end
+//	catch:
+//	    SET_LOCAL e; POP; TRY finally
+//	    GET_LOCAL e; IS_INSTANCE T1; JUMP_IF_FALSE next; ...; <catch 块>; END_TRY; <finally>; JUMP end
+//	    ...
+//	    GET_LOCAL e; THROW
+//	finally:
+//	    <finally>; THROW
+//	end:
+func (compiler *Compiler) compileTryCatchStatement(tryCatchStmt *TryCatchStatement) {
+	state := &tryState{finally: tryCatchStmt.Finally}
+	var exits []int
+	// 离开受保护的代码：撤销处理入口，执行 finally 块后跳转到整个语句之后
+	leave := func() {
+		compiler.emit(OpEndTry)
+		compiler.current.tries = compiler.current.tries[:len(compiler.current.tries)-1]
+		compiler.compileScopedBlock(tryCatchStmt.Finally)
+		exits = append(exits, compiler.emitJump(OpJump))
+	}
+
+	catch := compiler.emitJump(OpTry)
+	compiler.current.tries = append(compiler.current.tries, state)
+	compiler.compileScopedBlock(tryCatchStmt.TryBlock)
+	leave()
+
+	compiler.patchJump(catch)
+	compiler.enterScope()
+	thrown := compiler.declareLocal("")
+	compiler.emitSet(thrown)
+	compiler.emit(OpPop)
+	finally := -1
+	if tryCatchStmt.Finally != nil {
+		finally = compiler.emitJump(OpTry)
+		compiler.current.tries = append(compiler.current.tries, state)
+	}
+	for _, handler := range tryCatchStmt.Handlers {
+		compiler.emitGet(thrown)
+		class := compiler.analyzer.Symbols[handler].(*IdSymbol).Type.(*ClassType)
+		compiler.emit(OpIsInstance, compiler.module.AddConstant(Constant{Kind: ConstantString, Str: class.Symbol.QualifiedName()}))
+		next := compiler.emitJump(OpJumpIfFalse)
+		compiler.enterScope()
+		compiler.emitGet(thrown)
+		compiler.emitSet(compiler.declareLocal(handler.Name.GetName()))
+		compiler.emit(OpPop)
+		compiler.compileStatementList(handler.Handler.Statements)
+		compiler.leaveScope()
+		if finally >= 0 {
+			leave()
+			compiler.current.tries = append(compiler.current.tries, state)
+		} else {
+			exits = append(exits, compiler.emitJump(OpJump))
+		}
+		compiler.patchJump(next)
+	}
+	compiler.emitGet(thrown)
+	compiler.emit(OpThrow)
+	compiler.leaveScope()
+
+	if finally >= 0 {
+		compiler.current.tries = compiler.current.tries[:len(compiler.current.tries)-1]
+		compiler.patchJump(finally)
+		compiler.compileScopedBlock(tryCatchStmt.Finally)
+		compiler.emit(OpThrow)
+	}
+	for _, exit := range exits {
+		compiler.patchJump(exit)
+	}
+}
+
+func (compiler *Compiler) compileScopedBlock(blockStmt *BlockStatement) {
+	if blockStmt == nil {
+		return
+	}
+	compiler.enterScope()
+	compiler.compileStatementList(blockStmt.Statements)
+	compiler.leaveScope()
+}
+
+func (compiler *Compiler) compileIfStatement(ifStmt *IfStatement) {
+	var exits []int
+	branches := append([]*IfElement{ifStmt.If}, ifStmt.Elif...)
+	for i, ifElement := range branches {
+		compiler.compileValue(ifElement.Condition, BoolType)
+		next := compiler.emitJump(OpJumpIfFalse)
+		compiler.compileScopedBlock(ifElement.Block)
+		if i < len(branches)-1 || ifStmt.Else != nil {
+			exits = append(exits, compiler.emitJump(OpJump))
+		}
+		compiler.patchJump(next)
+	}
+	compiler.compileScopedBlock(ifStmt.Else)
+	for _, exit := range exits {
+		compiler.patchJump(exit)
+	}
+}
+
+// 入口表达式只求值一次，存放在临时变量中，再依次与各个匹配条件比较
+func (compiler *Compiler) compileSwitchStatement(switchStmt *SwitchStatement) {
+	compiler.enterScope()
+	entryType := DefaultType(compiler.analyzer.Types[switchStmt.Entry])
+	entry := compiler.declareLocal("")
+	compiler.compileValue(switchStmt.Entry, entryType)
+	compiler.emitSet(entry)
+	compiler.emit(OpPop)
+
+	var exits []int
+	for _, switchCase := range switchStmt.Cases {
+		var matched []int
+		var block *BlockStatement
+		switch switchCase.SwitchStatementCaseNodeType() {
+		case SwitchStatementTypeNormal:
+			// 任意一个条件与入口相等即匹配：c1 || c2 || ...
+			normalCase := switchCase.(*SwitchStatementNormalCase)
+			for i, condition := range normalCase.Conditions {
+				compiler.emitGet(entry)
+				compiler.compileValue(condition, entryType)
+				compiler.emit(OpEqual)
+				if i < len(normalCase.Conditions)-1 {
+					matched = append(matched, compiler.emitJump(OpJumpIfTrueOrPop))
+				}
+			}
+			block = normalCase.Block
+		case SwitchStatementTypeRange:
+			// 入口位于区间之内即匹配：entry >= start && entry < end
+			rangeCase := switchCase.(*SwitchStatementRangeCase)
+			compiler.emitGet(entry)
+			compiler.compileValue(rangeCase.Range.Start, entryType)
+			compiler.emit(OpGreaterEqual)
+			matched = append(matched, compiler.emitJump(OpJumpIfFalseOrPop))
+			compiler.emitGet(entry)
+			compiler.compileValue(rangeCase.Range.End, entryType)
+			if rangeCase.Range.IncludeEnd {
+				compiler.emit(OpLessEqual)
+			} else {
+				compiler.emit(OpLess)
+			}
+			block = rangeCase.Block
+		}
+		for _, jump := range matched {
+			compiler.patchJump(jump)
+		}
+		next := compiler.emitJump(OpJumpIfFalse)
+		compiler.compileScopedBlock(block)
+		exits = append(exits, compiler.emitJump(OpJump))
+		compiler.patchJump(next)
+	}
+	compiler.compileScopedBlock(switchStmt.Default)
+	for _, exit := range exits {
+		compiler.patchJump(exit)
+	}
+	compiler.leaveScope()
+}
+
+func (compiler *Compiler) compileWhileStatement(whileStmt *WhileStatement) {
+	start := compiler.here()
+	compiler.compileValue(whileStmt.Condition, BoolType)
+	exit := compiler.emitJump(OpJumpIfFalse)
+	compiler.compileLoop(func() int {
+		compiler.compileScopedBlock(whileStmt.Block)
+		compiler.emit(OpJump, start)
+		return start
+	})
+	compiler.patchJump(exit)
+}
+
+// for 语句的初始化部分自成一个作用域，continue 跳转到每轮循环之后执行的部分
+func (compiler *Compiler) compileForStatement(forStmt *ForStatement) {
+	compiler.enterScope()
+	if forStmt.Initial != nil {
+		compiler.compileSimpleStatement(forStmt.Initial)
+	}
+	start := compiler.here()
+	exit := -1
+	if forStmt.Condition != nil {
+		compiler.compileValue(forStmt.Condition, BoolType)
+		exit = compiler.emitJump(OpJumpIfFalse)
+	}
+	compiler.compileLoop(func() int {
+		compiler.compileScopedBlock(forStmt.Block)
+		appendix := compiler.here()
+		for _, stmt := range forStmt.Appendix {
+			compiler.compileSimpleStatement(stmt)
+		}
+		compiler.emit(OpJump, start)
+		return appendix
+	})
+	if exit >= 0 {
+		compiler.patchJump(exit)
+	}
+	compiler.leaveScope()
+}
+
+// each 语句以临时变量保存被遍历的值与当前下标，表则先取出按字典序排列的全部键再逐个遍历
+func (compiler *Compiler) compileEachStatement(eachStmt *EachStatement) {
+	compiler.enterScope()
+	targetType := compiler.analyzer.Types[eachStmt.Target]
+	_, isTable := targetType.(*TableType)
+
+	target := compiler.declareLocal("")
+	compiler.compileValue(eachStmt.Target, targetType)
+	compiler.emitSet(target)
+	compiler.emit(OpPop)
+	sequence := target
+	if isTable {
+		sequence = compiler.declareLocal("")
+		compiler.emitGet(target)
+		compiler.emit(OpKeys)
+		compiler.emitSet(sequence)
+		compiler.emit(OpPop)
+	}
+	index := compiler.declareLocal("")
+	compiler.emitConstantValue(constantZero, IntType)
+	compiler.emitSet(index)
+	compiler.emit(OpPop)
+	element := compiler.declareLocal(eachStmt.Element.GetName())
+	var key *binding
+	if eachStmt.Key != nil {
+		key = compiler.declareLocal(eachStmt.Key.GetName())
+	}
+
+	start := compiler.here()
+	compiler.emitGet(index)
+	compiler.emitGet(sequence)
+	compiler.emit(OpLength)
+	compiler.emit(OpLess)
+	exit := compiler.emitJump(OpJumpIfFalse)
+	if isTable {
+		// key = keys[i]; element = table[keys[i]]
+		compiler.emitGet(target)
+		compiler.emitGet(sequence)
+		compiler.emitGet(index)
+		compiler.emit(OpIndex)
+		if key != nil {
+			compiler.emitSet(key)
+		}
+		compiler.emit(OpIndex)
+	} else {
+		// key = i; element = sequence[i]
+		if key != nil {
+			compiler.emitGet(index)
+			compiler.emitSet(key)
+			compiler.emit(OpPop)
+		}
+		compiler.emitGet(sequence)
+		compiler.emitGet(index)
+		compiler.emit(OpIndex)
+	}
+	compiler.emitSet(element)
+	compiler.emit(OpPop)
+
+	compiler.compileLoop(func() int {
+		compiler.enterScope()
+		compiler.compileStatementList(eachStmt.Block.Statements)
+		compiler.leaveScope()
+		increment := compiler.here()
+		compiler.emitGet(index)
+		compiler.emitConstantValue(constantOne, IntType)
+		compiler.emit(OpAdd)
+		compiler.emitSet(index)
+		compiler.emit(OpPop)
+		compiler.emit(OpJump, start)
+		return increment
+	})
+	compiler.patchJump(exit)
+	compiler.leaveScope()
+}
@@ -0,0 +1,374 @@
+package compiler
+
+import (
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/exception"
+	"fmt"
+	"go/constant"
+	"math"
+)
+
+// Package compiler 将通过语义检查的语法树编译为 CVM 字节码
+// 上承语义分析，借助其推断出的类型与常量值选择指令，下启虚拟机
+
+// 名称绑定的种类
+const (
+	bindingLocal    = iota // 当前调用帧中的局部变量槽位
+	bindingGlobal          // 全局变量
+	bindingFunction        // 函数表中的函数，函数名不可被重新赋值，因而直接引用函数本身
+)
+
+type binding struct {
+	kind  int
+	index int
+}
+
+// 编译时的作用域，与语义分析的区块作用域一一对应
+type scope struct {
+	outer    *scope
+	owner    *functionState // 作用域所属的函数
+	bindings map[string]*binding
+}
+
+// 循环中 break 与 continue 的跳转指令，在目标位置确定后回填
+type loopState struct {
+	breaks    []int
+	continues []int
+	tries     int // 进入循环时所在的 try 语句的层数，break 与 continue 须先退出循环内的 try 语句
+}
+
+// 已经以 OpTry 登记了处理入口的 try 语句，return、break 与 continue 离开它时须先撤销入口并执行 finally 块
+type tryState struct {
+	finally *BlockStatement
+}
+
+// 正在编译的函数
+type functionState struct {
+	outer    *functionState
+	function *Function
+	fnType   *FunctionType
+	nextSlot int // 下一个空闲的局部变量槽位，离开区块后其中的槽位可以复用
+	loops    []*loopState
+	tries    []*tryState // 由外向内包围着正在编译的语句的 try 语句
+}
+
+type Compiler struct {
+	analyzer *Analyzer // @private 提供类型与常量值的语义分析器
+	module   *Module   // @private 编译结果
+	current  *functionState
+	scope    *scope
+	stmt     Statement // @private 正在编译的语句，用于报告没有对应节点的错误
+	source   string    // @private 正在编译的代码所在的源文件
+
+	functions  map[*FunctionDeclarationStatement]int // @private 函数定义在函数表中的下标
+	enumValues map[*EnumElement]int64                // @private 枚举元素的值
+	modules    map[*LoadedModule]*scope              // @private 引入的模块的顶层作用域
+
+	Errors      []*CoralCompileError // 编译过程中的所有错误
+	Diagnostics []*Diagnostic        // 编译过程中带位置的错误
+}
+
+// 以已经完成语义检查的分析器创建编译器
+func NewCompiler(analyzer *Analyzer) *Compiler {
+	return &Compiler{
+		analyzer:   analyzer,
+		module:     &Module{Source: analyzer.GetParser().FileName},
+		source:     analyzer.GetParser().FileName,
+		functions:  make(map[*FunctionDeclarationStatement]int),
+		enumValues: make(map[*EnumElement]int64),
+		modules:    make(map[*LoadedModule]*scope),
+	}
+}
+
+// 将整个程序编译为模块：顶层语句编译为入口函数，顶层的变量为全局变量
+// 引入的模块按被引入的先后各自编译为一个函数，由入口函数在执行程序本身之前依次调用
+// 有错误时返回的模块不完整，不应被执行
+func (compiler *Compiler) CompileProgram() (*Module, []*CoralCompileError) {
+	compiler.module.Entry = len(compiler.module.Functions)
+	compiler.module.Functions = append(compiler.module.Functions, &Function{})
+	script := compiler.beginFunction(compiler.module.Entry, "<script>", &FunctionType{})
+	if loader := compiler.analyzer.Loader; loader != nil {
+		for _, imported := range loader.Order {
+			if imported.Analyzer != compiler.analyzer {
+				compiler.emit(OpFunction, compiler.compileModule(imported))
+				compiler.emit(OpCall, 0)
+			}
+		}
+	}
+	compiler.compileStatementList(compiler.analyzer.Ast.Root)
+	compiler.emit(OpReturn, 0)
+	compiler.endFunction(script)
+	return compiler.module, compiler.Errors
+}
+
+// 将引入的模块的顶层语句编译为一个函数，返回其在函数表中的下标
+// 模块的顶层与入口函数的顶层一样，其中的变量为全局变量
+func (compiler *Compiler) compileModule(imported *LoadedModule) int {
+	current, outerScope, source := compiler.current, compiler.scope, compiler.source
+	compiler.current, compiler.scope, compiler.source = nil, nil, imported.Path
+	index := len(compiler.module.Functions)
+	compiler.module.Functions = append(compiler.module.Functions, &Function{})
+	state := compiler.beginFunction(index, "<module "+imported.Name+">", &FunctionType{})
+	compiler.modules[imported] = compiler.scope
+	compiler.compileStatementList(imported.Analyzer.Ast.Root)
+	compiler.emit(OpReturn, 0)
+	compiler.endFunction(state)
+	compiler.current, compiler.scope, compiler.source = current, outerScope, source
+	return index
+}
+
+// 报告一个编译错误并标出相应的范围
+func (compiler *Compiler) ReportError(node Node, errEnum int, format string, args ...interface{}) {
+	err := NewCoralError("Compile", fmt.Sprintf(format, args...), errEnum)
+	compiler.Errors = append(compiler.Errors, err)
+	span := node.GetSpan()
+	compiler.Diagnostics = append(compiler.Diagnostics, NewDiagnostic(compiler.source,
+		Position{Line: span.Start.Line, Col: span.Start.Col}, Position{Line: span.End.Line, Col: span.End.Col}, err))
+}
+
+// 报告字节码后端尚不支持的语言特性
+func (compiler *Compiler) reportUnsupported(node Node, feature string) {
+	compiler.ReportError(node, UnsupportedFeature, "%s is not supported by the bytecode compiler yet!", feature)
+}
+
+// 在函数表中为函数预留位置，函数可以在编译其函数体之前被引用
+func (compiler *Compiler) reserveFunction(node Node) int {
+	index := len(compiler.module.Functions)
+	if index > math.MaxUint16 {
+		compiler.ReportError(node, UnsupportedFeature, "too many functions in one module!")
+	}
+	compiler.module.Functions = append(compiler.module.Functions, &Function{})
+	return index
+}
+
+// 开始编译函数表中第 index 个函数，其作用域是当前作用域的内层
+func (compiler *Compiler) beginFunction(index int, name string, fnType *FunctionType) *functionState {
+	state := &functionState{
+		outer:    compiler.current,
+		function: &Function{Name: name, Source: compiler.source, Params: len(fnType.Params), Returns: len(fnType.Returns)},
+		fnType:   fnType,
+	}
+	compiler.module.Functions[index] = state.function
+	compiler.current = state
+	compiler.enterScope()
+	return state
+}
+func (compiler *Compiler) endFunction(state *functionState) {
+	compiler.leaveScope()
+	compiler.current = state.outer
+}
+
+// 编译函数体：形参依次占据前几个槽位，执行到末尾时返回
+// 有返回值的函数不会执行到末尾（由语义检查保证），末尾以各返回值类型的零值返回只是为了指令序列完整
+func (compiler *Compiler) compileFunction(index int, name string, fnType *FunctionType, signature *Signature, body func()) {
+	if len(signature.Arguments) > math.MaxUint8 || len(fnType.Returns) > math.MaxUint8 {
+		compiler.ReportError(signature, UnsupportedFeature, "too many parameters or return values!")
+	}
+	state := compiler.beginFunction(index, name, fnType)
+	for _, argument := range signature.Arguments {
+		compiler.declareLocal(argument.Name.GetName())
+	}
+	body()
+	for _, returnType := range fnType.Returns {
+		compiler.emitZero(returnType)
+	}
+	compiler.emit(OpReturn, len(fnType.Returns))
+	compiler.endFunction(state)
+}
+
+func (compiler *Compiler) enterScope() {
+	compiler.scope = &scope{outer: compiler.scope, owner: compiler.current, bindings: make(map[string]*binding)}
+}
+
+// 离开作用域，其中的局部变量槽位可以被之后的变量复用
+func (compiler *Compiler) leaveScope() {
+	for _, b := range compiler.scope.bindings {
+		if b.kind == bindingLocal && b.index < compiler.current.nextSlot {
+			compiler.current.nextSlot = b.index
+		}
+	}
+	compiler.scope = compiler.scope.outer
+}
+
+// 是否在入口函数的顶层作用域中：此处定义的变量为全局变量
+func (compiler *Compiler) atTopLevel() bool {
+	return compiler.current.outer == nil && compiler.scope.outer == nil
+}
+
+// 在当前作用域中为变量分配存储：顶层为全局变量，其余为局部变量
+func (compiler *Compiler) declareVariable(name string) *binding {
+	if compiler.atTopLevel() {
+		b := &binding{kind: bindingGlobal, index: len(compiler.module.Globals)}
+		compiler.module.Globals = append(compiler.module.Globals, name)
+		compiler.scope.bindings[name] = b
+		return b
+	}
+	return compiler.declareLocal(name)
+}
+
+// 在当前作用域中分配一个局部变量槽位，name 为空时即编译器使用的临时变量
+func (compiler *Compiler) declareLocal(name string) *binding {
+	state := compiler.current
+	b := &binding{kind: bindingLocal, index: state.nextSlot}
+	state.nextSlot++
+	if state.nextSlot > state.function.Locals {
+		state.function.Locals = state.nextSlot
+	}
+	if name == "" {
+		name = fmt.Sprintf("$%d", b.index) // 不可能与标识符重名
+	}
+	compiler.scope.bindings[name] = b
+	return b
+}
+
+// 由内向外查找名称的绑定，找不到时返回 nil，此时名称为内建符号或者类型
+// 外层函数的局部变量需要以闭包捕获，暂不支持，此时报错
+func (compiler *Compiler) resolve(node Node, name string) *binding {
+	for current := compiler.scope; current != nil; current = current.outer {
+		b, ok := current.bindings[name]
+		if !ok {
+			continue
+		}
+		if b.kind == bindingLocal && current.owner != compiler.current {
+			compiler.reportUnsupported(node, fmt.Sprintf("capturing the local variable \"%s\" of an enclosing function", name))
+		}
+		return b
+	}
+	return nil
+}
+
+// 以当前语句的行号记录此后的指令
+func (compiler *Compiler) markLine(node Node) {
+	compiler.current.function.MarkLine(node.GetSpan().Start.Line)
+}
+
+func (compiler *Compiler) emit(op Opcode, operands ...int) int {
+	return compiler.current.function.Emit(op, operands...)
+}
+
+// 发出目标待定的跳转指令，返回其偏移量以便回填
+func (compiler *Compiler) emitJump(op Opcode) int {
+	return compiler.emit(op, 0)
+}
+
+// 将跳转指令的目标回填为当前位置
+func (compiler *Compiler) patchJump(offset int) {
+	compiler.current.function.Patch(offset, len(compiler.current.function.Code))
+}
+
+// 当前位置，即下一条指令的偏移量
+func (compiler *Compiler) here() int {
+	return len(compiler.current.function.Code)
+}
+
+func (compiler *Compiler) emitConstant(c Constant) {
+	index := compiler.module.AddConstant(c)
+	if index > math.MaxUint16 {
+		compiler.ReportError(compiler.stmt, UnsupportedFeature, "too many constants in one module!")
+		return
+	}
+	compiler.emit(OpConstant, index)
+}
+
+func (compiler *Compiler) emitGet(b *binding) {
+	switch b.kind {
+	case bindingLocal:
+		compiler.emit(OpGetLocal, b.index)
+	case bindingGlobal:
+		compiler.emit(OpGetGlobal, b.index)
+	case bindingFunction:
+		compiler.emit(OpFunction, b.index)
+	}
+}
+
+// 以栈顶的值写入变量，值仍保留在栈顶
+func (compiler *Compiler) emitSet(b *binding) {
+	switch b.kind {
+	case bindingLocal:
+		compiler.emit(OpSetLocal, b.index)
+	case bindingGlobal:
+		compiler.emit(OpSetGlobal, b.index)
+	}
+}
+
+// 压入类型 t 的零值：基本类型见 BasicType.Zero，引用类型为 nil
+func (compiler *Compiler) emitZero(t Type) {
+	switch zero := ZeroValue(t).(type) {
+	case int64:
+		compiler.emitConstant(Constant{Kind: ConstantInt, Int: zero})
+	case uint64:
+		compiler.emitConstant(Constant{Kind: ConstantInt, Int: int64(zero)})
+	case float64:
+		compiler.emitConstant(Constant{Kind: ConstantFloat, Float: zero})
+	case bool:
+		compiler.emit(OpFalse)
+	case string:
+		compiler.emitConstant(Constant{Kind: ConstantString, Str: zero})
+	default:
+		compiler.emit(OpNil)
+	}
+	compiler.emitRepresentation(t)
+}
+
+// 以类型 t 的表示压入常量：整数类型为整数，浮点数类型为浮点数
+func (compiler *Compiler) emitConstantValue(value constant.Value, t Type) {
+	switch value.Kind() {
+	case constant.Bool:
+		if constant.BoolVal(value) {
+			compiler.emit(OpTrue)
+		} else {
+			compiler.emit(OpFalse)
+		}
+	case constant.String:
+		compiler.emitConstant(Constant{Kind: ConstantString, Str: constant.StringVal(value)})
+	case constant.Int, constant.Float:
+		if IsFloatType(t) {
+			float, _ := constant.Float64Val(value)
+			compiler.emitConstant(Constant{Kind: ConstantFloat, Float: float})
+			compiler.emitRepresentation(t)
+			return
+		}
+		integer := constant.ToInt(value)
+		if number, exact := constant.Int64Val(integer); exact {
+			compiler.emitConstant(Constant{Kind: ConstantInt, Int: number})
+		} else {
+			number, _ := constant.Uint64Val(integer) // 超出 int64 范围的 uint64 以其补码存放
+			compiler.emitConstant(Constant{Kind: ConstantInt, Int: int64(number)})
+		}
+		compiler.emitRepresentation(t)
+	}
+}
+
+// 常量池中的整数都是 int64、浮点数都是 float64，uint64、rune 与 float 在虚拟机中另有表示，需要再转换一次
+func (compiler *Compiler) emitRepresentation(t Type) {
+	switch t {
+	case Uint64Type:
+		compiler.emit(OpConvert, int(TypeUint64))
+	case RuneType:
+		compiler.emit(OpConvert, int(TypeRune))
+	case FloatType:
+		compiler.emit(OpConvert, int(TypeFloat))
+	}
+}
+
+// 基本类型对应的类型编码，其余类型返回 false
+func typeCodeOf(t Type) (TypeCode, bool) {
+	if basicType, isBasic := t.(*BasicType); isBasic {
+		return TypeCodeOf(basicType.Name)
+	}
+	return 0, false
+}
+
+// 算术运算的结果是否需要按类型的位数截断：int64 与 double 即虚拟机中值的原生表示，无需截断
+func needsTruncation(t Type) bool {
+	return IsNumericType(t) && !IsUntyped(t) && t != Int64Type && t != DoubleType
+}
+
+// 在算术运算之后按结果类型截断
+func (compiler *Compiler) emitTruncation(t Type) {
+	if code, ok := typeCodeOf(t); ok && needsTruncation(t) {
+		compiler.emit(OpConvert, int(code))
+	}
+}
@@ -0,0 +1,153 @@
+package doc
+
+import (
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/formatter"
+	"path/filepath"
+	"strings"
+)
+
+// Package doc 实现了 coral doc：以定义之前的文档注释为每个包生成一页 Markdown
+// 生成的页面可以直接放进 docs_src 目录，并在 mkdocs.yml 的 nav 中列出
+
+// 一个包的文档页面
+type Page struct {
+	Package  string // 包名，没有 package 声明的文件以模块名为包名
+	Markdown string
+}
+
+// 一个包中的全部模块，按检查完成的先后排列
+type packageModules struct {
+	name    string
+	modules []*LoadedModule
+}
+
+// 为入口文件以及它引入的模块生成文档，每个包一页，按首次出现的先后排列
+// 只收录 dir 目录之中的模块，从 CORAL_PATH 等处引入的库不在此列
+func Generate(analyzer *Analyzer, dir string) []*Page {
+	var packages []*packageModules
+	for _, module := range analyzer.Loader.Order {
+		if !insideDir(module.Path, dir) {
+			continue
+		}
+		name := module.Package
+		if name == "" {
+			name = module.Name
+		}
+		var found *packageModules
+		for _, pkg := range packages {
+			if pkg.name == name {
+				found = pkg
+			}
+		}
+		if found == nil {
+			found = &packageModules{name: name}
+			packages = append(packages, found)
+		}
+		found.modules = append(found.modules, module)
+	}
+
+	pages := make([]*Page, len(packages))
+	for i, pkg := range packages {
+		pages[i] = &Page{Package: pkg.name, Markdown: renderPackage(pkg)}
+	}
+	return pages
+}
+
+func insideDir(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// 包中的定义按种类分节，节内按源代码中的先后排列
+func renderPackage(pkg *packageModules) string {
+	var out strings.Builder
+	out.WriteString("# " + pkg.name + "\n")
+
+	files := make([]string, len(pkg.modules))
+	for i, module := range pkg.modules {
+		files[i] = "`" + filepath.Base(module.Path) + "`"
+	}
+	out.WriteString("\n源文件：" + strings.Join(files, "、") + "\n")
+
+	sections := []struct {
+		title string
+		match func(Statement) bool
+	}{
+		{"枚举", func(stmt Statement) bool { _, ok := stmt.(*EnumStatement); return ok }},
+		{"接口", func(stmt Statement) bool { _, ok := stmt.(*InterfaceDeclarationStatement); return ok }},
+		{"类", func(stmt Statement) bool { _, ok := stmt.(*ClassDeclarationStatement); return ok }},
+		{"函数", func(stmt Statement) bool { _, ok := stmt.(*FunctionDeclarationStatement); return ok }},
+	}
+	for _, section := range sections {
+		var body strings.Builder
+		for _, module := range pkg.modules {
+			source := module.Analyzer.GetParser().Lexer.Content
+			for _, stmt := range module.Analyzer.Ast.Root {
+				if section.match(stmt) {
+					renderDeclaration(&body, source, stmt)
+				}
+			}
+		}
+		if body.Len() > 0 {
+			out.WriteString("\n## " + section.title + "\n")
+			out.WriteString(body.String())
+		}
+	}
+	return out.String()
+}
+
+func renderDeclaration(out *strings.Builder, source []byte, stmt Statement) {
+	switch it := stmt.(type) {
+	case *EnumStatement:
+		renderEntry(out, "###", it.Name.GetName(), FormatDeclaration(source, it), it.Doc)
+	case *InterfaceDeclarationStatement:
+		renderEntry(out, "###", it.Definition.Name.GetName(), FormatDeclaration(source, it), it.Doc)
+	case *FunctionDeclarationStatement:
+		renderEntry(out, "###", it.Name.GetName(), FormatDeclaration(source, it), it.Doc)
+	case *ClassDeclarationStatement:
+		className := it.Definition.Name.GetName()
+		renderEntry(out, "###", className, FormatDeclaration(source, it), it.Doc)
+		renderClassMembers(out, source, className, it.Members)
+	}
+}
+
+// 类的 public 成员，构造方法总是 public，标题写作 new 类名
+func renderClassMembers(out *strings.Builder, source []byte, className string, members []ClassMember) {
+	for _, member := range members {
+		switch it := member.(type) {
+		case *ClassMemberMethod:
+			name := it.MethodDecl.Name.GetName()
+			if name == className {
+				renderEntry(out, "####", "new "+className, FormatDeclaration(source, it.MethodDecl), it.MethodDecl.Doc)
+			} else if it.Scope == ClassMemberScopePublic {
+				renderEntry(out, "####", className+"."+name, FormatDeclaration(source, it), it.MethodDecl.Doc)
+			}
+		case *ClassMemberVar:
+			if it.Scope != ClassMemberScopePublic {
+				continue
+			}
+			for _, element := range it.VarDecl.Declarations {
+				renderEntry(out, "####", className+"."+element.VarName.Str, FormatDeclaration(source, it), nil)
+			}
+		}
+	}
+}
+
+// 标题、以代码块展示的定义，以及文档注释的内容
+func renderEntry(out *strings.Builder, level, title, declaration string, doc *DocComment) {
+	out.WriteString("\n" + level + " " + title + "\n\n")
+	out.WriteString("```coral\n" + declaration + "\n```\n")
+	if doc != nil && doc.Text != "" {
+		out.WriteString("\n" + doc.Text + "\n")
+	}
+}
@@ -0,0 +1,435 @@
+package driver
+
+import (
+	"bytes"
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/compiler"
+	"coral-lang/src/doc"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/formatter"
+	. "coral-lang/src/interp"
+	. "coral-lang/src/lexer"
+	"coral-lang/src/lsp"
+	. "coral-lang/src/parser"
+	. "coral-lang/src/repl"
+	. "coral-lang/src/vm"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Package driver 实现了 coral 命令行工具的各个子命令
+// 与 main 包分离，使得命令行的行为可以直接在测试中调用与断言
+
+const usage = `Usage: coral <command> [options] <file>
+
+Commands:
+  lex    <file>   print the token stream of a source file
+  parse  <file>   print the abstract syntax tree of a source file
+  check  <file>   parse and run semantic analysis on a source file
+  build  <file>   compile a source file to a .cbytes bytecode file
+  run    <file>   compile and execute a source file, or execute a .cbytes file
+  fmt    <file>   print a source file in the canonical layout
+  doc    <file>   generate Markdown API documentation for a source file and the modules it imports
+  repl            start an interactive session that evaluates statements as they are typed
+  lsp             start a Language Server Protocol server that talks over stdin and stdout
+  help            show this message
+
+Options:
+  -format terminal|plain|json   how diagnostics are reported (default "terminal")
+
+Imports that are not relative paths are looked up in the directory of the source file,
+then in the directories listed in the CORAL_PATH environment variable.
+
+Lex options:
+  -trivia     also print the whitespace and comments attached to each token
+
+Build options:
+  -o <file>   where to write the bytecode (default: the source file with a .cbytes extension)
+  -S          also print the disassembled bytecode
+
+Run options:
+  -interp     execute the syntax tree directly with the interpreter instead of compiling it
+
+Fmt options:
+  -w          write the result back to the source file instead of printing it
+  -check      print nothing and exit with a non-zero code if the file is not formatted
+
+Doc options:
+  -o <dir>    write one <package>.md page per package into the directory instead of printing them
+`
+
+// 单次命令行调用的上下文
+type invocation struct {
+	filePath string
+	stdout   io.Writer
+	stderr   io.Writer
+	renderer DiagnosticRenderer
+	sources  map[string][]byte // 已读入的源代码，供终端渲染器展示出错的代码行
+
+	output    string // build：字节码的输出路径
+	assembly  bool   // build：是否打印反汇编
+	trivia    bool   // lex：是否同时打印 Token 前后的空白与注释
+	interpret bool   // run：是否以解释器直接执行语法树
+	write     bool   // fmt：是否将结果写回源文件
+	check     bool   // fmt：只检查源文件是否已经格式化
+	docDir    string // doc：文档页面的输出目录，为空时打印到标准输出
+}
+
+type command struct {
+	name   string
+	run    func(inv *invocation) int
+	flags  func(flags *flag.FlagSet, inv *invocation) // 子命令特有的选项，没有时为 nil
+	noFile bool                                       // 子命令不接受源文件参数
+}
+
+// 交互式子命令读取输入的来源，测试中可以替换
+var Stdin io.Reader = os.Stdin
+
+var commands = []*command{
+	{name: "lex", run: runLex, flags: lexFlags},
+	{name: "parse", run: runParse},
+	{name: "check", run: runCheck},
+	{name: "build", run: runBuild, flags: buildFlags},
+	{name: "run", run: runRun, flags: runFlags},
+	{name: "fmt", run: runFmt, flags: fmtFlags},
+	{name: "doc", run: runDoc, flags: docFlags},
+	{name: "repl", run: runRepl, noFile: true},
+	{name: "lsp", run: runLsp, noFile: true},
+}
+
+// Run 执行一次命令行调用，args 不含程序名本身，返回值即为进程退出码
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return CommandLineUsageError
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stdout, usage)
+		return NormalError
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		inv := &invocation{
+			stdout:  stdout,
+			stderr:  stderr,
+			sources: make(map[string][]byte),
+		}
+		flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		flags.SetOutput(ioutil.Discard)
+		format := flags.String("format", "terminal", "")
+		if cmd.flags != nil {
+			cmd.flags(flags, inv)
+		}
+		if err := flags.Parse(args[1:]); err != nil {
+			fmt.Fprintf(stderr, "coral %s: %s\n\n", cmd.name, err)
+			fmt.Fprint(stderr, usage)
+			return CommandLineUsageError
+		}
+		if cmd.noFile && flags.NArg() != 0 {
+			fmt.Fprintf(stderr, "coral %s: unexpected argument \"%s\"\n\n", cmd.name, flags.Arg(0))
+			fmt.Fprint(stderr, usage)
+			return CommandLineUsageError
+		}
+		if !cmd.noFile && flags.NArg() != 1 {
+			fmt.Fprintf(stderr, "coral %s: expected exactly one source file\n\n", cmd.name)
+			fmt.Fprint(stderr, usage)
+			return CommandLineUsageError
+		}
+
+		inv.filePath = flags.Arg(0)
+		renderer, ok := NewDiagnosticRenderer(*format, inv.sources)
+		if !ok {
+			fmt.Fprintf(stderr, "coral %s: unknown diagnostics format \"%s\"\n", cmd.name, *format)
+			return CommandLineUsageError
+		}
+		inv.renderer = renderer
+		return cmd.run(inv)
+	}
+
+	fmt.Fprintf(stderr, "coral: unknown command \"%s\"\n\n", args[0])
+	fmt.Fprint(stderr, usage)
+	return CommandLineUsageError
+}
+
+// 读入源文件，打开失败时输出诊断信息
+func (inv *invocation) readSource() ([]byte, int) {
+	content, err := OpenSourceFile(inv.filePath)
+	if err != nil {
+		return nil, inv.report([]*Diagnostic{NewDiagnostic(inv.filePath, Position{}, Position{}, err)})
+	}
+	inv.sources[inv.filePath] = content
+	return content, NormalError
+}
+
+// 输出诊断信息，返回首个错误的错误码作为退出码
+func (inv *invocation) report(diagnostics []*Diagnostic) int {
+	if len(diagnostics) > 0 {
+		inv.renderer.Render(inv.stderr, diagnostics)
+	}
+	return FirstErrorCode(diagnostics)
+}
+
+func runLex(inv *invocation) int {
+	content, exitCode := inv.readSource()
+	if content == nil {
+		return exitCode
+	}
+	lexer := new(Lexer)
+	lexer.KeepTrivia = inv.trivia
+	lexer.InitFromBytes(content)
+
+	for {
+		token, err := lexer.GetNextToken(false)
+		if err != nil {
+			pos := Position{Line: lexer.Line, Col: lexer.Col}
+			return inv.report([]*Diagnostic{NewDiagnostic(inv.filePath, pos, pos, err)})
+		}
+		if token == nil {
+			inv.printTrivia(lexer.EndTrivia)
+			return NormalError
+		}
+		inv.printTrivia(token.LeadingTrivia)
+		fmt.Fprintf(inv.stdout, "%s\t%s\t%q\n", token.Start, token.KindName(), token.Str)
+		inv.printTrivia(token.TrailingTrivia)
+	}
+}
+
+func lexFlags(flags *flag.FlagSet, inv *invocation) {
+	flags.BoolVar(&inv.trivia, "trivia", false, "")
+}
+
+func (inv *invocation) printTrivia(trivia []*Trivia) {
+	for _, piece := range trivia {
+		fmt.Fprintf(inv.stdout, "%s\t%s\t%q\n", piece.Start, piece.KindName(), piece.Text)
+	}
+}
+
+func runParse(inv *invocation) int {
+	content, exitCode := inv.readSource()
+	if content == nil {
+		return exitCode
+	}
+	parser := new(Parser)
+	parser.FileName = inv.filePath
+	parser.InitFromBytes(content)
+	program, _ := parser.ParseProgram()
+
+	Fprint(inv.stdout, program)
+	return inv.report(parser.Diagnostics)
+}
+
+func runCheck(inv *invocation) int {
+	_, exitCode := checkSourceFile(inv)
+	return exitCode
+}
+
+func buildFlags(flags *flag.FlagSet, inv *invocation) {
+	flags.StringVar(&inv.output, "o", "", "")
+	flags.BoolVar(&inv.assembly, "S", false, "")
+}
+
+// 编译源文件并写出 .cbytes 文件，默认与源文件同名同目录
+func runBuild(inv *invocation) int {
+	analyzer, exitCode := checkSourceFile(inv)
+	if analyzer == nil {
+		return exitCode
+	}
+	compiler := NewCompiler(analyzer)
+	module, _ := compiler.CompileProgram()
+	if exitCode := inv.report(compiler.Diagnostics); exitCode != NormalError {
+		return exitCode
+	}
+
+	output := inv.output
+	if output == "" {
+		output = strings.TrimSuffix(inv.filePath, filepath.Ext(inv.filePath)) + FileExtension
+	}
+	if err := ioutil.WriteFile(output, module.Encode(), 0644); err != nil {
+		fmt.Fprintf(inv.stderr, "coral build: cannot write %s: %s\n", output, err)
+		return FileSystemOpenFileError
+	}
+	if inv.assembly {
+		module.Disassemble(inv.stdout)
+	}
+	return NormalError
+}
+
+func runFlags(flags *flag.FlagSet, inv *invocation) {
+	flags.BoolVar(&inv.interpret, "interp", false, "")
+}
+
+// 执行一个源文件或者 .cbytes 文件，源文件先检查并编译为字节码
+func runRun(inv *invocation) int {
+	if inv.interpret {
+		return runInterpreter(inv)
+	}
+	var module *Module
+	if filepath.Ext(inv.filePath) == FileExtension {
+		data, err := ioutil.ReadFile(inv.filePath)
+		if err != nil {
+			fmt.Fprintf(inv.stderr, "coral run: cannot read %s: %s\n", inv.filePath, err)
+			return FileSystemOpenFileError
+		}
+		var loadErr *CoralCompileError
+		if module, loadErr = Load(data); loadErr != nil {
+			return inv.report([]*Diagnostic{NewDiagnostic(inv.filePath, Position{}, Position{}, loadErr)})
+		}
+		// 源文件存在时读入，以便终端渲染器展示运行时出错的代码行
+		for _, function := range module.Functions {
+			if _, read := inv.sources[function.Source]; read || function.Source == "" {
+				continue
+			}
+			if content, err := ioutil.ReadFile(function.Source); err == nil {
+				inv.sources[function.Source] = content
+			}
+		}
+	} else {
+		analyzer, exitCode := checkSourceFile(inv)
+		if analyzer == nil {
+			return exitCode
+		}
+		compiler := NewCompiler(analyzer)
+		module, _ = compiler.CompileProgram()
+		if exitCode := inv.report(compiler.Diagnostics); exitCode != NormalError {
+			return exitCode
+		}
+	}
+
+	machine := NewVM(module, inv.stdout)
+	if err := machine.Run(); err != nil {
+		return inv.report([]*Diagnostic{machine.Diagnostic(err)})
+	}
+	return NormalError
+}
+
+// 不经编译，以解释器直接执行源文件的语法树
+func runInterpreter(inv *invocation) int {
+	analyzer, exitCode := checkSourceFile(inv)
+	if analyzer == nil {
+		return exitCode
+	}
+	interpreter := NewInterpreter(analyzer, inv.stdout)
+	interpreter.Run()
+	return inv.report(interpreter.Diagnostics)
+}
+
+func fmtFlags(flags *flag.FlagSet, inv *invocation) {
+	flags.BoolVar(&inv.write, "w", false, "")
+	flags.BoolVar(&inv.check, "check", false, "")
+}
+
+// 按统一的风格格式化源文件，有语法错误时不做任何改动
+func runFmt(inv *invocation) int {
+	content, exitCode := inv.readSource()
+	if content == nil {
+		return exitCode
+	}
+	formatted, diagnostics := FormatSource(inv.filePath, content)
+	if formatted == nil {
+		return inv.report(diagnostics)
+	}
+
+	switch {
+	case inv.check:
+		if !bytes.Equal(formatted, content) {
+			fmt.Fprintf(inv.stderr, "coral fmt: %s is not formatted\n", inv.filePath)
+			return SourceNotFormatted
+		}
+	case inv.write:
+		if bytes.Equal(formatted, content) {
+			return NormalError
+		}
+		if err := ioutil.WriteFile(inv.filePath, formatted, 0644); err != nil {
+			fmt.Fprintf(inv.stderr, "coral fmt: cannot write %s: %s\n", inv.filePath, err)
+			return FileSystemOpenFileError
+		}
+	default:
+		inv.stdout.Write(formatted)
+	}
+	return NormalError
+}
+
+func docFlags(flags *flag.FlagSet, inv *invocation) {
+	flags.StringVar(&inv.docDir, "o", "", "")
+}
+
+// 以文档注释为源文件所在目录中的各个包生成 Markdown 文档，从 CORAL_PATH 引入的库不在其中
+func runDoc(inv *invocation) int {
+	analyzer, exitCode := checkSourceFile(inv)
+	if analyzer == nil {
+		return exitCode
+	}
+	pages := doc.Generate(analyzer, filepath.Dir(inv.filePath))
+	if inv.docDir == "" {
+		for i, page := range pages {
+			if i > 0 {
+				fmt.Fprintln(inv.stdout)
+			}
+			fmt.Fprint(inv.stdout, page.Markdown)
+		}
+		return NormalError
+	}
+
+	if err := os.MkdirAll(inv.docDir, 0755); err != nil {
+		fmt.Fprintf(inv.stderr, "coral doc: cannot create %s: %s\n", inv.docDir, err)
+		return FileSystemOpenFileError
+	}
+	for _, page := range pages {
+		output := filepath.Join(inv.docDir, page.Package+".md")
+		if err := ioutil.WriteFile(output, []byte(page.Markdown), 0644); err != nil {
+			fmt.Fprintf(inv.stderr, "coral doc: cannot write %s: %s\n", output, err)
+			return FileSystemOpenFileError
+		}
+	}
+	return NormalError
+}
+
+// 交互式地逐条执行语句，直到输入结束；出错只输出诊断信息，不会结束会话
+func runRepl(inv *invocation) int {
+	NewREPL(inv.stdout, inv.stderr, inv.renderer, inv.sources).Run(Stdin)
+	return NormalError
+}
+
+// 以标准输入输出与编辑器通信，直到编辑器通知退出
+func runLsp(inv *invocation) int {
+	return lsp.NewServer(inv.stdout, inv.stderr).Run(Stdin)
+}
+
+// 查找引入的模块的目录：先是源文件所在的目录，然后是环境变量 CORAL_PATH 中列出的目录
+func searchPath(filePath string) []string {
+	return append([]string{filepath.Dir(filePath)}, filepath.SplitList(os.Getenv("CORAL_PATH"))...)
+}
+
+// 语法解析并语义检查一个源文件，有错误时返回 nil 以及首个错误的错误码
+func checkSourceFile(inv *invocation) (*Analyzer, int) {
+	content, exitCode := inv.readSource()
+	if content == nil {
+		return nil, exitCode
+	}
+	parser := new(Parser)
+	parser.FileName = inv.filePath
+	parser.InitFromBytes(content)
+	analyzer := new(Analyzer)
+	analyzer.InitAnalyzerFromParser(parser)
+	analyzer.Loader = NewModuleLoader(searchPath(inv.filePath))
+	analyzer.CheckProgram()
+	for _, module := range analyzer.Loader.Order {
+		inv.sources[module.Path] = module.Analyzer.GetParser().Lexer.Content
+	}
+
+	if exitCode := inv.report(analyzer.Diagnostics); exitCode != NormalError {
+		return nil, exitCode
+	}
+	return analyzer, NormalError
+}
@@ -0,0 +1,148 @@
+package exception
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 诊断信息的严重程度
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (severity Severity) String() string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "unknown"
+}
+
+// 以 "error"/"warning" 文本的形式序列化，便于其他工具消费
+func (severity Severity) MarshalText() ([]byte, error) {
+	return []byte(severity.String()), nil
+}
+
+func (severity *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "error":
+		*severity = SeverityError
+	case "warning":
+		*severity = SeverityWarning
+	default:
+		return fmt.Errorf("unknown severity %q", text)
+	}
+	return nil
+}
+
+// 源代码中的一个位置，行号与列号均从 1 开始
+type Position struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Col)
+}
+
+// 附着在诊断信息上的关联说明，如「此前在这里定义过」
+type DiagnosticNote struct {
+	File    string   `json:"file,omitempty"`
+	Start   Position `json:"start"`
+	End     Position `json:"end"`
+	Message string   `json:"message"`
+}
+
+// 诊断信息：编译过程中产出的一条带位置的错误或警告
+type Diagnostic struct {
+	File     string            `json:"file,omitempty"`
+	Start    Position          `json:"start"`
+	End      Position          `json:"end"` // 不含终点字符，与 Start 相同时表示一个插入点
+	Severity Severity          `json:"severity"`
+	Code     int               `json:"code"`     // 即 error_enum.go 中的错误码
+	Category string            `json:"category"` // 如 Syntax、Compile
+	Message  string            `json:"message"`
+	Notes    []*DiagnosticNote `json:"notes,omitempty"`
+}
+
+// 由编译错误构造一条诊断信息
+func NewDiagnostic(file string, start, end Position, err *CoralCompileError) *Diagnostic {
+	return &Diagnostic{
+		File:     file,
+		Start:    start,
+		End:      end,
+		Severity: SeverityError,
+		Code:     err.ErrEnum,
+		Category: err.Category,
+		Message:  err.Message,
+	}
+}
+
+// 构造一条警告
+func NewWarningDiagnostic(file string, start, end Position, msg string) *Diagnostic {
+	return &Diagnostic{
+		File:     file,
+		Start:    start,
+		End:      end,
+		Severity: SeverityWarning,
+		Code:     CompileWarning,
+		Category: "Compile",
+		Message:  msg,
+	}
+}
+
+// 为诊断信息追加一条关联说明
+func (diagnostic *Diagnostic) AddNote(file string, start, end Position, msg string) *Diagnostic {
+	diagnostic.Notes = append(diagnostic.Notes, &DiagnosticNote{
+		File:    file,
+		Start:   start,
+		End:     end,
+		Message: msg,
+	})
+	return diagnostic
+}
+
+func (diagnostic *Diagnostic) IsError() bool {
+	return diagnostic.Severity == SeverityError
+}
+
+// 形如 "file:1:2: error: message" 的单行文本
+func (diagnostic *Diagnostic) String() string {
+	var builder strings.Builder
+	if diagnostic.File != "" {
+		builder.WriteString(diagnostic.File + ":")
+	}
+	builder.WriteString(fmt.Sprintf("%s: %s", diagnostic.Start, diagnostic.Severity))
+	if diagnostic.IsError() {
+		builder.WriteString(fmt.Sprintf("[%d]", diagnostic.Code))
+	}
+	builder.WriteString(": " + diagnostic.Message)
+	return builder.String()
+}
+
+// 统计诊断信息中错误与警告的数量
+func CountDiagnostics(diagnostics []*Diagnostic) (errCount int, warnCount int) {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.IsError() {
+			errCount++
+		} else {
+			warnCount++
+		}
+	}
+	return
+}
+
+// 取第一条错误的错误码，没有错误则为 NormalError
+func FirstErrorCode(diagnostics []*Diagnostic) int {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.IsError() {
+			return diagnostic.Code
+		}
+	}
+	return NormalError
+}
@@ -18,4 +18,38 @@ const (
 	NoConstructorMethod
 	EmptyInterfaceDeclaration
 	MethodNameSameWithInterfaceName
+	CommandLineUsageError
+	RuntimeUnavailable
+	LexStringUnclosed
+	LexRuneUnclosed
+	LexBlockCommentUnclosed
+	LexUnknownEscapeCharacter
+	CompileWarning
+	DuplicateDefinition
+	UndeclaredIdentifier
+	TypeMismatch
+	InvalidOperation
+	ValueCountMismatch
+	ConstantOverflow
+	ConstantDivisionByZero
+	ImmutableAssignment
+	UninitializedValue
+	InvalidValueType
+	UnsupportedFeature
+	BytecodeFormatError
+	RuntimeError
+	ModuleNotFound
+	ImportCycle
+	PackageMismatch
+	InheritanceCycle
+	InterfaceNotImplemented
+	PrivateMemberAccess
+	StaticMemberAccess
+	TypeArgumentMismatch
+	ConstraintNotSatisfied
+	UnhandledException
+	UnreachableCatch
+	SourceNotFormatted
+	LanguageServerError
+	MissingReturn
 )
@@ -9,17 +9,27 @@ import (
 )
 
 type CoralCompileError struct {
-	Err     error
-	ErrEnum int
+	Err      error
+	ErrEnum  int
+	Category string // 错误的类别，如 Syntax、Compile
+	Message  string // 不带颜色与前缀的错误描述，供诊断信息使用
 }
 
 func NewCoralError(prefixDescription string, msg string, errEnum int) *CoralCompileError {
 	return &CoralCompileError{
-		errors.New("\n* " + Bold(Red(prefixDescription+" Error: ")) + msg),
-		errEnum,
+		Err:      errors.New("\n* " + Bold(Red(prefixDescription+" Error: ")) + msg),
+		ErrEnum:  errEnum,
+		Category: prefixDescription,
+		Message:  msg,
 	}
 }
 
+// 实现 error 接口，使编译错误可以作为普通的 Go 错误值向上传递
+func (c *CoralCompileError) Error() string {
+	return c.Err.Error()
+}
+
+// 打印错误并以错误码退出进程，仅供命令行入口与测试使用，各编译阶段都应返回错误值
 func CoralErrorCrashHandler(c *CoralCompileError) {
 	fmt.Println(c.Err)
 	fmt.Println(Cyan(fmt.Sprintf("* Error code: %d", c.ErrEnum)))
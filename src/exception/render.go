@@ -0,0 +1,146 @@
+package exception
+
+import (
+	. "coral-lang/src/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 诊断信息渲染器：同一组诊断信息可以输出为终端彩色文本、无颜色的纯文本或 JSON
+type DiagnosticRenderer interface {
+	Render(w io.Writer, diagnostics []*Diagnostic) error
+}
+
+// 根据格式名称获取渲染器，sources 为文件名到源代码的映射，供终端渲染器展示出错的代码行
+func NewDiagnosticRenderer(format string, sources map[string][]byte) (DiagnosticRenderer, bool) {
+	switch format {
+	case "terminal":
+		return &TerminalRenderer{Sources: sources}, true
+	case "plain":
+		return &PlainRenderer{}, true
+	case "json":
+		return &JSONRenderer{}, true
+	}
+	return nil, false
+}
+
+// 终端渲染器：带颜色，打印出错位置附近的代码行并以 ^ 标出出错的位置
+type TerminalRenderer struct {
+	Sources map[string][]byte
+}
+
+func (renderer *TerminalRenderer) Render(w io.Writer, diagnostics []*Diagnostic) error {
+	for _, diagnostic := range diagnostics {
+		location := fmt.Sprintf("line %s", diagnostic.Start)
+		if diagnostic.File != "" {
+			location = fmt.Sprintf("%s line %s", diagnostic.File, diagnostic.Start)
+		}
+
+		if diagnostic.IsError() {
+			fmt.Fprint(w, "\n"+Bold(Green("* "+location+" ")))
+			fmt.Fprintln(w, "\n* "+Bold(Red(diagnostic.Category+" Error: "))+diagnostic.Message)
+		} else {
+			fmt.Fprint(w, "\n"+Green("* "+location+" "))
+			fmt.Fprintln(w, "\n"+Bold(Yellow("* Warning: ")))
+			for _, str := range strings.Split(diagnostic.Message, "\n") {
+				fmt.Fprintln(w, "\t"+str)
+			}
+		}
+
+		if source, ok := renderer.Sources[diagnostic.File]; ok {
+			renderer.renderSourceLines(w, string(source), diagnostic)
+		}
+		for _, note := range diagnostic.Notes {
+			fmt.Fprintln(w, Cyan(fmt.Sprintf("  note: %s %s", noteLocation(note), note.Message)))
+		}
+	}
+
+	errCount, warnCount := CountDiagnostics(diagnostics)
+	_, err := fmt.Fprintln(w, "\n"+Yellow(fmt.Sprintf("(%d error, %d warning)", errCount, warnCount)))
+	return err
+}
+
+// 打印错误代码所在行以及附近两行
+func (renderer *TerminalRenderer) renderSourceLines(w io.Writer, source string, diagnostic *Diagnostic) {
+	lines := strings.Split(source, "\n")
+	errLineIndex := diagnostic.Start.Line - 1
+	if errLineIndex < 0 || errLineIndex >= len(lines) {
+		return
+	}
+
+	startLineIndex := errLineIndex - 1
+	if startLineIndex < 0 {
+		startLineIndex = 0
+	}
+	for i := startLineIndex; i < startLineIndex+3 && i < len(lines); i++ {
+		fmt.Fprint(w, Yellow(fmt.Sprintf("%4d", i+1)))
+		fmt.Fprintf(w, "| %s\n", lines[i])
+		if i == errLineIndex {
+			fmt.Fprintln(w, "      "+caretLine(lines[i], diagnostic))
+		}
+	}
+}
+
+// 生成标记行：行首空白原样保留，其余字符以 . 占位，直到出错位置以 ^ 标出
+func caretLine(line string, diagnostic *Diagnostic) string {
+	var builder strings.Builder
+	leading := true
+	col := 1
+	for _, r := range line {
+		if col >= diagnostic.Start.Col {
+			break
+		}
+		if leading && (r == ' ' || r == '\t') {
+			builder.WriteRune(r)
+		} else {
+			leading = false
+			builder.WriteString(Yellow("."))
+		}
+		col++
+	}
+
+	width := 1
+	if diagnostic.End.Line == diagnostic.Start.Line && diagnostic.End.Col > diagnostic.Start.Col {
+		width = diagnostic.End.Col - diagnostic.Start.Col
+	}
+	builder.WriteString(Red(strings.Repeat("^", width)))
+	return builder.String()
+}
+
+func noteLocation(note *DiagnosticNote) string {
+	if note.File != "" {
+		return fmt.Sprintf("%s:%s:", note.File, note.Start)
+	}
+	return note.Start.String() + ":"
+}
+
+// 纯文本渲染器：每条诊断信息一行，不带任何颜色，适合日志与 CI
+type PlainRenderer struct{}
+
+func (renderer *PlainRenderer) Render(w io.Writer, diagnostics []*Diagnostic) error {
+	for _, diagnostic := range diagnostics {
+		if _, err := fmt.Fprintln(w, diagnostic.String()); err != nil {
+			return err
+		}
+		for _, note := range diagnostic.Notes {
+			if _, err := fmt.Fprintf(w, "  note: %s %s\n", noteLocation(note), note.Message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// JSON 渲染器：输出诊断信息数组，供编辑器与其他工具解析
+type JSONRenderer struct{}
+
+func (renderer *JSONRenderer) Render(w io.Writer, diagnostics []*Diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []*Diagnostic{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diagnostics)
+}
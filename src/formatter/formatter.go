@@ -0,0 +1,236 @@
+package formatter
+
+import (
+	"bytes"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/lexer"
+	. "coral-lang/src/parser"
+	"strings"
+)
+
+// Package formatter 将语法树按统一的风格重新输出为源代码，即 coral fmt
+// 缩进为 4 个空格，左花括号不换行，运算符两侧各一个空格；注释与语句之间的单个空行被保留下来
+// 格式化的结果再格式化一次不会有任何变化
+
+const indentUnit = "    "
+
+// 两个相邻的语句（或类成员等）之间是否空一行
+const (
+	keepBlank  = iota // 与源代码一致：源代码中隔有空行时空一行
+	forceBlank        // 总是空一行，如 package 声明、import 语句之后
+)
+
+type Printer struct {
+	source   []byte
+	comments []*Comment
+	next     int // @private 下一条尚未输出的注释
+
+	out         bytes.Buffer
+	indent      int
+	atLineStart bool
+	first       bool // @private 是否尚未在当前区块中输出任何一行，区块开头不空行
+	spacing     int  // @private 下一行与上一行之间的空行规则
+	lastLine    int  // @private 已输出的内容在源代码中的末行，据此判断源代码中是否隔有空行
+}
+
+// 格式化一段源代码，有语法错误时不做格式化，返回解析时的诊断信息
+func FormatSource(fileName string, content []byte) ([]byte, []*Diagnostic) {
+	parser := new(Parser)
+	parser.FileName = fileName
+	parser.InitFromBytes(content)
+	program, _ := parser.ParseProgram()
+	if parser.ErrCount > 0 {
+		return nil, parser.Diagnostics
+	}
+	printer := NewPrinter(content, parser.Lexer.Comments)
+	printer.PrintProgram(program)
+	return printer.Bytes(), nil
+}
+
+// 定义的概要，见 PrintDeclaration；source 是定义所在的源代码，其中的注释不会输出
+func FormatDeclaration(source []byte, node Node) string {
+	printer := NewPrinter(source, nil)
+	printer.PrintDeclaration(node)
+	return string(printer.Bytes())
+}
+
+func NewPrinter(source []byte, comments []*Comment) *Printer {
+	return &Printer{
+		source:      source,
+		comments:    comments,
+		atLineStart: true,
+		first:       true,
+	}
+}
+
+func (printer *Printer) Bytes() []byte {
+	return printer.out.Bytes()
+}
+
+func (printer *Printer) write(s string) {
+	if printer.atLineStart {
+		printer.out.WriteString(strings.Repeat(indentUnit, printer.indent))
+		printer.atLineStart = false
+	}
+	printer.out.WriteString(s)
+}
+func (printer *Printer) newline() {
+	printer.out.WriteByte('\n')
+	printer.atLineStart = true
+}
+
+// 即将输出源代码第 line 行开始的一行内容，按空行规则决定是否先空一行
+func (printer *Printer) beginLine(line int) {
+	if !printer.first && (printer.spacing == forceBlank ||
+		printer.spacing == keepBlank && line > printer.lastLine+1) {
+		printer.newline()
+	}
+	printer.first = false
+	printer.spacing = keepBlank
+}
+func (printer *Printer) advanceLine(line int) {
+	if line > printer.lastLine {
+		printer.lastLine = line
+	}
+}
+
+// 输出位于 offset 之前、尚未输出的注释，每条注释独占一行
+func (printer *Printer) flushComments(offset int) {
+	for printer.hasCommentBefore(offset) {
+		comment := printer.comments[printer.next]
+		printer.next++
+		printer.beginLine(comment.Start.Line)
+		printer.write(commentText(comment))
+		printer.newline()
+		printer.advanceLine(comment.End.Line)
+	}
+}
+func (printer *Printer) hasCommentBefore(offset int) bool {
+	return printer.next < len(printer.comments) && printer.comments[printer.next].Start.Offset < offset
+}
+
+// 与刚输出的内容末尾同一行的注释，接在该行之后
+func (printer *Printer) trailingComments(line int) {
+	for printer.next < len(printer.comments) && printer.comments[printer.next].Start.Line == line {
+		comment := printer.comments[printer.next]
+		printer.next++
+		printer.write(" " + commentText(comment))
+		printer.advanceLine(comment.End.Line)
+	}
+}
+
+// 表达式中位于 offset 之前、尚未输出的注释原地输出，与前后的内容以空格隔开
+// leading 表示注释之后紧接着表达式；行注释之后只能换行，下一行多缩进一级
+func (printer *Printer) inlineComments(offset int, leading bool) {
+	for printer.hasCommentBefore(offset) {
+		comment := printer.comments[printer.next]
+		printer.next++
+		if out := printer.out.Bytes(); !printer.atLineStart && len(out) > 0 &&
+			!strings.ContainsRune(" ([{", rune(out[len(out)-1])) {
+			printer.write(" ")
+		}
+		printer.write(commentText(comment))
+		printer.advanceLine(comment.End.Line)
+		if strings.HasPrefix(comment.Text, "//") {
+			printer.newline()
+			printer.write(indentUnit)
+		} else if leading {
+			printer.write(" ")
+		}
+	}
+}
+
+// 行注释末尾的空白被去掉
+func commentText(comment *Comment) string {
+	return strings.TrimRight(comment.Text, " \t\r")
+}
+
+// 逐个输出区块中的语句、类成员等，每个各占一行，其前的注释与空行随之输出
+func (printer *Printer) printItems(items []Node, end Pos, print func(Node)) {
+	for _, item := range items {
+		printer.printItem(item, print)
+	}
+	printer.flushComments(end.Offset)
+}
+func (printer *Printer) printItem(item Node, print func(Node)) {
+	span := item.GetSpan()
+	printer.flushComments(span.Start.Offset)
+	printer.beginLine(span.Start.Line)
+	print(item)
+	printer.advanceLine(span.End.Line)
+	printer.trailingComments(span.End.Line)
+	printer.newline()
+}
+
+// 以花括号括起的一组语句或成员，其中没有任何内容时输出为 {}
+func (printer *Printer) printBody(items []Node, end Pos, print func(Node)) {
+	if len(items) == 0 && !printer.hasCommentBefore(end.Offset) {
+		printer.write("{}")
+		return
+	}
+	printer.write("{")
+	printer.newline()
+	printer.indent++
+	printer.first = true
+	printer.printItems(items, end, print)
+	printer.indent--
+	printer.write("}")
+}
+
+// 文件开头的 package 声明与 import 语句之后各空一行
+// import 语句保持源代码中的顺序，模块按导入的顺序执行，调换顺序会改变程序的行为
+func (printer *Printer) PrintProgram(program *Program) {
+	root := program.Root
+	if len(root) > 0 {
+		if _, isPackage := root[0].(*PackageStatement); isPackage {
+			printer.printItem(root[0], printer.printStatementNode)
+			root = root[1:]
+			printer.spacing = forceBlank
+		}
+	}
+
+	count := 0
+	for count < len(root) {
+		if _, isImport := root[count].(ImportStatement); !isImport {
+			break
+		}
+		printer.printItem(root[count], printer.printStatementNode)
+		count++
+	}
+	if count > 0 {
+		root = root[count:]
+		printer.spacing = forceBlank
+	}
+
+	for _, stmt := range root {
+		printer.printItem(stmt, printer.printStatementNode)
+	}
+	printer.flushComments(len(printer.source))
+}
+
+func toNodes(stmts []Statement) []Node {
+	nodes := make([]Node, len(stmts))
+	for i, stmt := range stmts {
+		nodes[i] = stmt
+	}
+	return nodes
+}
+
+// Token 在源代码中的原文，字面量据此保持原样，如字符串中的转义字符、数字的写法
+func (printer *Printer) tokenText(token *Token) string {
+	return string(printer.source[token.Start.Offset:token.End.Offset])
+}
+
+// offset 之后第一个字符串字面量的原文，import 语句的节点中只记录了转义后的路径
+func (printer *Printer) stringLitAfter(offset int) string {
+	start := bytes.IndexByte(printer.source[offset:], '"') + offset
+	end := start + 1
+	for end < len(printer.source) && printer.source[end] != '"' {
+		if printer.source[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	return string(printer.source[start : end+1])
+}
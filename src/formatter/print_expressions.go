@@ -0,0 +1,241 @@
+package formatter
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/lexer"
+	"sort"
+)
+
+// 括号表达式没有单独的节点，但其范围包括括号，因而起点先于其最左侧的部分
+// 源代码中写出的括号原样保留，不增不减，重新解析得到的语法树也就与原来相同
+func (printer *Printer) parenthesized(expr Expression) bool {
+	return printer.parens(expr) > 0
+}
+
+// 表达式外面括号的层数，即范围的起点到其最左侧的部分之间的左括号个数
+func (printer *Printer) parens(expr Expression) int {
+	start := expr.GetSpan().Start.Offset
+	if start >= len(printer.source) || printer.source[start] != '(' {
+		return 0
+	}
+	var leftmost Node
+	switch it := expr.(type) {
+	case *BasicPrimaryExpression:
+		leftmost = it.It
+	case *IndexExpression:
+		leftmost = it.Operand
+	case *SliceExpression:
+		leftmost = it.Operand
+	case *CallExpression:
+		leftmost = it.Operand
+	case *MemberExpression:
+		leftmost = it.Operand
+	case *BinaryExpression:
+		leftmost = it.Left
+	case *CastExpression:
+		leftmost = it.Source
+	case *RangeExpression:
+		leftmost = it.Start
+	case *UnaryExpression:
+		return printer.countParens(start, it.Operator.Start.Offset)
+	default:
+		return printer.countParens(start, len(printer.source)) // new 表达式以关键字开头
+	}
+	return printer.countParens(start, leftmost.GetSpan().Start.Offset)
+}
+
+// 从 offset 起到 end 为止连续的左括号个数，其间的空白与注释被跳过
+func (printer *Printer) countParens(offset, end int) int {
+	count := 0
+	for offset < end {
+		if comment := printer.commentAt(offset, false); comment != nil {
+			offset = comment.End.Offset
+		} else if printer.source[offset] == '(' {
+			count++
+			offset++
+		} else if isSpace(printer.source[offset]) {
+			offset++
+		} else {
+			break
+		}
+	}
+	return count
+}
+
+// 去掉外面的括号之后表达式的终点，其后的注释在右括号之前输出
+func (printer *Printer) innerEnd(expr Expression) int {
+	end := expr.GetSpan().End.Offset
+	parens := printer.parens(expr)
+	for parens > 0 {
+		if comment := printer.commentAt(end, true); comment != nil {
+			end = comment.Start.Offset
+		} else if printer.source[end-1] == ')' {
+			parens--
+			end--
+		} else {
+			end--
+		}
+	}
+	for printer.commentAt(end, true) != nil || isSpace(printer.source[end-1]) {
+		if comment := printer.commentAt(end, true); comment != nil {
+			end = comment.Start.Offset
+		} else {
+			end--
+		}
+	}
+	return end
+}
+
+// 起点（before 为 false）或终点（before 为 true）恰好在 offset 的注释
+func (printer *Printer) commentAt(offset int, before bool) *Comment {
+	i := sort.Search(len(printer.comments), func(i int) bool {
+		return printer.comments[i].Start.Offset >= offset
+	})
+	if before && i > 0 && printer.comments[i-1].End.Offset == offset {
+		return printer.comments[i-1]
+	} else if !before && i < len(printer.comments) && printer.comments[i].Start.Offset == offset {
+		return printer.comments[i]
+	}
+	return nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func (printer *Printer) PrintExpression(expr Expression) {
+	span := expr.GetSpan()
+	printer.inlineComments(span.Start.Offset, true)
+	if printer.parenthesized(expr) {
+		printer.write("(")
+		defer func() {
+			printer.inlineComments(span.End.Offset, false)
+			printer.write(")")
+		}()
+	}
+
+	switch it := expr.(type) {
+	case *BasicPrimaryExpression:
+		printer.printOperand(it.It)
+	case *IndexExpression:
+		printer.PrintExpression(it.Operand)
+		printer.write("[")
+		printer.PrintExpression(it.Index)
+		printer.closing("]", printer.innerEnd(expr))
+	case *SliceExpression:
+		printer.PrintExpression(it.Operand)
+		printer.write("[")
+		if it.Start != nil {
+			printer.PrintExpression(it.Start)
+		}
+		printer.write(":")
+		printer.PrintExpression(it.End)
+		printer.closing("]", printer.innerEnd(expr))
+	case *CallExpression:
+		printer.PrintExpression(it.Operand)
+		printer.write("(")
+		printer.printExpressionList(it.Params)
+		printer.closing(")", printer.innerEnd(expr))
+	case *MemberExpression:
+		printer.PrintExpression(it.Operand)
+		for member := it.Member; member != nil; member = member.MemberNext {
+			printer.write("." + member.It.GetName())
+		}
+	case *NewInstanceExpression:
+		printer.write("new ")
+		printer.PrintType(it.Class)
+		printer.write("(")
+		printer.printExpressionList(it.InitParams)
+		printer.closing(")", printer.innerEnd(expr))
+	case *UnaryExpression:
+		printer.write(it.Operator.Str)
+		printer.PrintExpression(it.Operand)
+	case *BinaryExpression:
+		printer.PrintExpression(it.Left)
+		printer.write(" " + it.Operator.Str + " ")
+		printer.PrintExpression(it.Right)
+	case *RangeExpression:
+		printer.PrintExpression(it.Start)
+		if it.IncludeEnd {
+			printer.write("...")
+		} else {
+			printer.write("..")
+		}
+		printer.PrintExpression(it.End)
+	case *CastExpression:
+		printer.PrintExpression(it.Source)
+		printer.write(" as ")
+		printer.PrintType(it.Type)
+	}
+}
+
+// 结尾的右括号等，其前的注释先输出；end 是该符号之后的位置
+func (printer *Printer) closing(s string, end int) {
+	printer.inlineComments(end, false)
+	printer.write(s)
+}
+
+func (printer *Printer) printExpressionList(exprList []Expression) {
+	for i, expr := range exprList {
+		if i > 0 {
+			printer.write(", ")
+		}
+		printer.PrintExpression(expr)
+	}
+}
+
+func (printer *Printer) printOperand(operand Operand) {
+	switch it := operand.(type) {
+	case *OperandName:
+		printer.write(it.Name.GetName())
+	case *NilLit:
+		printer.write(printer.tokenText(it.Value))
+	case *TrueLit:
+		printer.write(printer.tokenText(it.Value))
+	case *FalseLit:
+		printer.write(printer.tokenText(it.Value))
+	case *DecimalLit:
+		printer.write(printer.tokenText(it.Value))
+	case *HexadecimalLit:
+		printer.write(printer.tokenText(it.Value))
+	case *OctalLit:
+		printer.write(printer.tokenText(it.Value))
+	case *BinaryLit:
+		printer.write(printer.tokenText(it.Value))
+	case *FloatLit:
+		printer.write(printer.tokenText(it.Value))
+	case *ExponentLit:
+		printer.write(printer.tokenText(it.Value))
+	case *RuneLit:
+		printer.write(printer.tokenText(it.Value))
+	case *StringLit:
+		printer.write(printer.tokenText(it.Value))
+	case *ThisLit:
+		printer.write("this")
+	case *SuperLit:
+		printer.write("super")
+	case *ArrayLit:
+		printer.write("[")
+		printer.printExpressionList(it.ValueList)
+		printer.closing("]", it.End.Offset)
+	case *TableLit:
+		printer.write("{")
+		for i, element := range it.KeyValueList {
+			if i > 0 {
+				printer.write(", ")
+			}
+			printer.inlineComments(element.Start.Offset, true)
+			printer.write(element.Key.GetName() + ": ")
+			printer.PrintExpression(element.Value)
+		}
+		printer.closing("}", it.End.Offset)
+	case *LambdaLit:
+		printer.printSignature(it.Signature)
+		printer.write(" -> ")
+		if block, isBlock := it.Result.(*BlockStatement); isBlock {
+			printer.printBlock(block)
+		} else {
+			printer.PrintExpression(it.Result.(Expression))
+		}
+	}
+}
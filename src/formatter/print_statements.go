@@ -0,0 +1,430 @@
+package formatter
+
+import (
+	. "coral-lang/src/ast"
+	"strconv"
+)
+
+func (printer *Printer) printStatementNode(node Node) {
+	printer.PrintStatement(node.(Statement))
+}
+
+func (printer *Printer) PrintStatement(stmt Statement) {
+	switch it := stmt.(type) {
+	case SimpleStatement:
+		printer.printSimpleStatement(it, true)
+	case *BreakStatement:
+		printer.write("break;")
+	case *ContinueStatement:
+		printer.write("continue;")
+	case *ReturnStatement:
+		printer.write("return ")
+		printer.printExpressionList(it.Expression)
+		printer.write(";")
+	case *ThrowStatement:
+		printer.write("throw ")
+		printer.PrintExpression(it.Value)
+		printer.write(";")
+	case *PackageStatement:
+		printer.write("package " + it.Name.GetName() + ";")
+	case *SingleGlobalImportStatement:
+		printer.write("import " + printer.stringLitAfter(it.Start.Offset))
+		if it.As != nil {
+			printer.write(" as " + it.As.GetName())
+		}
+		printer.write(";")
+	case *SingleFromImportStatement:
+		printer.write("from " + printer.stringLitAfter(it.Start.Offset) + " import ")
+		printer.printImportElement(it.Element)
+		printer.write(";")
+	case *ListImportStatement:
+		printer.write("from " + printer.stringLitAfter(it.Start.Offset) + " import { ")
+		for i, element := range it.Elements {
+			if i > 0 {
+				printer.write(", ")
+			}
+			printer.printImportElement(element)
+		}
+		printer.write(" }")
+	case *EnumStatement:
+		printer.printEnumStatement(it)
+	case *BlockStatement:
+		printer.printBlock(it)
+	case *IfStatement:
+		printer.printIfStatement(it)
+	case *SwitchStatement:
+		printer.printSwitchStatement(it)
+	case *WhileStatement:
+		printer.write("while ")
+		printer.PrintExpression(it.Condition)
+		printer.write(" ")
+		printer.printBlock(it.Block)
+	case *ForStatement:
+		printer.printForStatement(it)
+	case *EachStatement:
+		printer.write("each " + it.Element.GetName())
+		if it.Key != nil {
+			printer.write(", " + it.Key.GetName())
+		}
+		printer.write(" in ")
+		printer.PrintExpression(it.Target)
+		printer.write(" ")
+		printer.printBlock(it.Block)
+	case *FunctionDeclarationStatement:
+		printer.printFunction(it)
+	case *ClassDeclarationStatement:
+		printer.printClassStatement(it)
+	case *InterfaceDeclarationStatement:
+		printer.printInterfaceStatement(it)
+	case *TryCatchStatement:
+		printer.printTryCatchStatement(it)
+	case *BadStatement:
+		printer.write(string(printer.source[it.Start.Offset:it.End.Offset]))
+	}
+}
+
+// 简单语句出现在 for 子句中时不以分号结尾
+func (printer *Printer) printSimpleStatement(stmt SimpleStatement, semi bool) {
+	switch it := stmt.(type) {
+	case *VarDeclStatement:
+		printer.printVarDecl(it)
+	case *AssignListStatement:
+		for i, target := range it.Targets {
+			if i > 0 {
+				printer.write(", ")
+			}
+			printer.PrintExpression(target)
+		}
+		printer.write(" = ")
+		printer.printExpressionList(it.Values)
+	case *IncDecStatement:
+		printer.PrintExpression(it.Expression)
+		printer.write(it.Operator.Str)
+	case Expression:
+		printer.PrintExpression(it)
+	}
+	if semi {
+		printer.write(";")
+	}
+}
+
+func (printer *Printer) printVarDecl(varDecl *VarDeclStatement) {
+	if varDecl.Mutable {
+		printer.write("var ")
+	} else {
+		printer.write("val ")
+	}
+	for i, element := range varDecl.Declarations {
+		if i > 0 {
+			printer.write(", ")
+		}
+		printer.write(element.VarName.Str)
+		if element.Type != nil {
+			printer.write(" ")
+			printer.PrintType(element.Type)
+		}
+		if element.InitValue != nil {
+			printer.write(" = ")
+			printer.PrintExpression(element.InitValue)
+		}
+	}
+}
+
+func (printer *Printer) printImportElement(element *ImportElement) {
+	printer.write(element.ModuleName.GetName())
+	if element.As != nil {
+		printer.write(" as " + element.As.GetName())
+	}
+}
+
+// 枚举的每个元素各占一行，都以逗号结尾
+func (printer *Printer) printEnumStatement(enumStmt *EnumStatement) {
+	printer.write("enum " + enumStmt.Name.GetName() + " ")
+	elements := make([]Node, len(enumStmt.Elements))
+	for i, element := range enumStmt.Elements {
+		elements[i] = element
+	}
+	printer.printBody(elements, enumStmt.End, func(node Node) {
+		element := node.(*EnumElement)
+		printer.write(element.Name.GetName())
+		if element.Value != nil {
+			printer.write(" = " + printer.tokenText(element.Value.Value))
+		}
+		printer.write(",")
+	})
+}
+
+func (printer *Printer) printBlock(block *BlockStatement) {
+	printer.printBody(toNodes(block.Statements), block.End, printer.printStatementNode)
+}
+
+func (printer *Printer) printIfStatement(ifStmt *IfStatement) {
+	printer.write("if ")
+	printer.printIfElement(ifStmt.If)
+	for _, elif := range ifStmt.Elif {
+		printer.write(" elif ")
+		printer.printIfElement(elif)
+	}
+	if ifStmt.Else != nil {
+		printer.write(" else ")
+		printer.printBlock(ifStmt.Else)
+	}
+}
+func (printer *Printer) printIfElement(element *IfElement) {
+	printer.PrintExpression(element.Condition)
+	printer.write(" ")
+	printer.printBlock(element.Block)
+}
+
+// 各个 case 缩进一层，default 总是放在最后
+func (printer *Printer) printSwitchStatement(switchStmt *SwitchStatement) {
+	printer.write("switch ")
+	printer.PrintExpression(switchStmt.Entry)
+	printer.write(" ")
+	var cases []Node
+	for _, switchCase := range switchStmt.Cases {
+		cases = append(cases, switchCase)
+	}
+	if switchStmt.Default != nil {
+		cases = append(cases, switchStmt.Default)
+	}
+	printer.printBody(cases, switchStmt.End, func(node Node) {
+		switch it := node.(type) {
+		case *SwitchStatementNormalCase:
+			printer.write("case ")
+			printer.printExpressionList(it.Conditions)
+			printer.write(" ")
+			printer.printBlock(it.Block)
+		case *SwitchStatementRangeCase:
+			printer.write("case ")
+			printer.PrintExpression(it.Range)
+			printer.write(" ")
+			printer.printBlock(it.Block)
+		case *BlockStatement:
+			printer.write("default ")
+			printer.printBlock(it)
+		}
+	})
+}
+
+func (printer *Printer) printForStatement(forStmt *ForStatement) {
+	printer.write("for ")
+	if forStmt.Initial != nil {
+		printer.printSimpleStatement(forStmt.Initial, false)
+	}
+	printer.write("; ")
+	printer.PrintExpression(forStmt.Condition)
+	printer.write(";")
+	for i, appendix := range forStmt.Appendix {
+		if i > 0 {
+			printer.write(",")
+		}
+		printer.write(" ")
+		printer.printSimpleStatement(appendix, false)
+	}
+	printer.write(" ")
+	printer.printBlock(forStmt.Block)
+}
+
+func (printer *Printer) printFunction(fnStmt *FunctionDeclarationStatement) {
+	printer.write("fn " + fnStmt.Name.GetName())
+	printer.printSignature(fnStmt.Signature)
+	printer.write(" ")
+	printer.printBlock(fnStmt.Block)
+}
+
+// 连续几个形参共用同一个类型节点时，说明源代码中是 a, b int 的简写，输出时保持简写
+func (printer *Printer) printSignature(signature *Signature) {
+	printer.printGenericArgs(signature.Generics)
+	printer.write("(")
+	for i, argument := range signature.Arguments {
+		if i > 0 {
+			printer.write(", ")
+		}
+		printer.write(argument.Name.GetName())
+		if argument.Type == nil {
+			continue
+		}
+		if i+1 < len(signature.Arguments) && signature.Arguments[i+1].Type == argument.Type {
+			continue
+		}
+		printer.write(" ")
+		printer.PrintType(argument.Type)
+	}
+	printer.write(")")
+	if len(signature.Returns) > 0 {
+		printer.write(" ")
+		printer.printTypeList(signature.Returns)
+	}
+	if len(signature.Throws) > 0 {
+		printer.write(" throws ")
+		printer.printTypeList(signature.Throws)
+	}
+}
+
+func (printer *Printer) printGenericArgs(generics *GenericArgs) {
+	if generics == nil {
+		return
+	}
+	printer.write("<")
+	for i, element := range generics.Args {
+		if i > 0 {
+			printer.write(", ")
+		}
+		printer.write(element.ArgName.GetName())
+		printer.printGenericArgs(element.Generics)
+		if element.Constraint != nil {
+			printer.write(" : ")
+			printer.PrintType(element.Constraint)
+		}
+	}
+	printer.write(">")
+}
+
+func (printer *Printer) printClassIdentifier(classId *ClassIdentifier) {
+	printer.write(classId.Name.GetName())
+	printer.printGenericArgs(classId.Generics)
+}
+
+// 成员默认为 private，构造方法总是 public，这两种情况都不写出访问修饰符
+func (printer *Printer) printClassStatement(classStmt *ClassDeclarationStatement) {
+	printer.printClassHeader(classStmt)
+	printer.write(" ")
+
+	members := make([]Node, len(classStmt.Members))
+	for i, member := range classStmt.Members {
+		members[i] = member
+	}
+	className := classStmt.Definition.Name.GetName()
+	printer.printBody(members, classStmt.End, func(node Node) {
+		switch member := node.(type) {
+		case *ClassMemberVar:
+			printer.printModifiers(member.Scope, member.Static)
+			printer.printVarDecl(member.VarDecl)
+			printer.write(";")
+		case *ClassMemberMethod:
+			if member.MethodDecl.Name.GetName() != className {
+				printer.printModifiers(member.Scope, member.Static)
+			}
+			printer.printFunction(member.MethodDecl)
+		}
+	})
+}
+
+// 类的定义行：类名、父类与实现的接口，不含成员
+func (printer *Printer) printClassHeader(classStmt *ClassDeclarationStatement) {
+	printer.write("class ")
+	printer.printClassIdentifier(classStmt.Definition)
+	if classStmt.Extends != nil {
+		printer.write(" : ")
+		printer.printClassIdentifier(classStmt.Extends)
+	}
+	if len(classStmt.Implements) > 0 {
+		printer.write(" <- ")
+		for i, impl := range classStmt.Implements {
+			if i > 0 {
+				printer.write(", ")
+			}
+			printer.printClassIdentifier(impl)
+		}
+	}
+}
+func (printer *Printer) printModifiers(scope ClassMemberScopeType, static bool) {
+	if scope == ClassMemberScopePublic {
+		printer.write("public ")
+	}
+	if static {
+		printer.write("static ")
+	}
+}
+
+// 定义的概要，即 coral doc 中展示的代码：函数与类成员只有签名，类只有定义行，接口与枚举则是全部内容
+func (printer *Printer) PrintDeclaration(node Node) {
+	switch it := node.(type) {
+	case *FunctionDeclarationStatement:
+		printer.write("fn " + it.Name.GetName())
+		printer.printSignature(it.Signature)
+	case *ClassDeclarationStatement:
+		printer.printClassHeader(it)
+	case *InterfaceDeclarationStatement:
+		printer.printInterfaceStatement(it)
+	case *EnumStatement:
+		printer.printEnumStatement(it)
+	case *ClassMemberVar:
+		printer.printModifiers(it.Scope, it.Static)
+		printer.printVarDecl(it.VarDecl)
+	case *ClassMemberMethod:
+		printer.printModifiers(it.Scope, it.Static)
+		printer.PrintDeclaration(it.MethodDecl)
+	}
+}
+
+func (printer *Printer) printInterfaceStatement(interfaceStmt *InterfaceDeclarationStatement) {
+	printer.write("interface ")
+	printer.printClassIdentifier(interfaceStmt.Definition)
+	if interfaceStmt.Extends != nil {
+		printer.write(" : ")
+		printer.printClassIdentifier(interfaceStmt.Extends)
+	}
+	printer.write(" ")
+
+	methods := make([]Node, len(interfaceStmt.Methods))
+	for i, method := range interfaceStmt.Methods {
+		methods[i] = method
+	}
+	printer.printBody(methods, interfaceStmt.End, func(node Node) {
+		method := node.(*InterfaceMethodDeclaration)
+		printer.printModifiers(method.Scope, false)
+		printer.write("fn " + method.Name.GetName())
+		printer.printGenericArgs(method.Generics)
+		printer.printSignature(method.Signature)
+		printer.write(";")
+	})
+}
+
+func (printer *Printer) printTryCatchStatement(tryCatchStmt *TryCatchStatement) {
+	printer.write("try ")
+	printer.printBlock(tryCatchStmt.TryBlock)
+	for _, handler := range tryCatchStmt.Handlers {
+		printer.write(" catch " + handler.Name.GetName() + " ")
+		printer.PrintType(handler.ErrorType)
+		printer.write(" ")
+		printer.printBlock(handler.Handler)
+	}
+	if tryCatchStmt.Finally != nil {
+		printer.write(" finally ")
+		printer.printBlock(tryCatchStmt.Finally)
+	}
+}
+
+func (printer *Printer) PrintType(typeDescription TypeDescription) {
+	switch it := typeDescription.(type) {
+	case *TypeName:
+		printer.write(it.Identifier.GetName())
+	case *ArrayTypeLit:
+		printer.PrintType(it.ElementType)
+		if it.ArrayLength > 0 {
+			printer.write("[" + strconv.Itoa(it.ArrayLength) + "]")
+		} else {
+			printer.write("[]")
+		}
+	case *GenericsTypeLit:
+		printer.PrintType(it.BasicType)
+		printer.write("<")
+		printer.printTypeList(it.GenericsArgs)
+		printer.write(">")
+	case *FuncType:
+		printer.write("(")
+		printer.printTypeList(it.ArgTypes)
+		printer.write(") -> ")
+		printer.printTypeList(it.ReturnTypes)
+	}
+}
+func (printer *Printer) printTypeList(types []TypeDescription) {
+	for i, typeDescription := range types {
+		if i > 0 {
+			printer.write(", ")
+		}
+		printer.PrintType(typeDescription)
+	}
+}
@@ -0,0 +1,551 @@
+package interp
+
+import (
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/lexer"
+	. "coral-lang/src/vm"
+	"fmt"
+	"go/constant"
+)
+
+// 二元运算符对应的运算，与字节码编译器选择的指令相同
+var binaryOperations = map[TokenType]Opcode{
+	TokenTypePlus:             OpAdd,
+	TokenTypeMinus:            OpSub,
+	TokenTypeStar:             OpMul,
+	TokenTypeSlash:            OpDiv,
+	TokenTypePercent:          OpRem,
+	TokenTypeDoubleStar:       OpPow,
+	TokenTypeAmpersand:        OpBitAnd,
+	TokenTypeVertical:         OpBitOr,
+	TokenTypeCaret:            OpBitXor,
+	TokenTypeDoubleLeftAngle:  OpShl,
+	TokenTypeDoubleRightAngle: OpShr,
+
+	TokenTypeLeftAngle:       OpLess,
+	TokenTypeLeftAngleEqual:  OpLessEqual,
+	TokenTypeRightAngle:      OpGreater,
+	TokenTypeRightAngleEqual: OpGreaterEqual,
+}
+var compoundAssignOperations = map[TokenType]Opcode{
+	TokenTypePlusEqual:             OpAdd,
+	TokenTypeMinusEqual:            OpSub,
+	TokenTypeStarEqual:             OpMul,
+	TokenTypeSlashEqual:            OpDiv,
+	TokenTypePercentEqual:          OpRem,
+	TokenTypeAmpersandEqual:        OpBitAnd,
+	TokenTypeVerticalEqual:         OpBitOr,
+	TokenTypeCaretEqual:            OpBitXor,
+	TokenTypeDoubleLeftAngleEqual:  OpShl,
+	TokenTypeDoubleRightAngleEqual: OpShr,
+}
+
+// 对表达式求值：没有返回值的调用得到 nil，多返回值的调用得到第一个返回值
+func (interp *Interpreter) evalExpression(expression Expression) (Value, error) {
+	results, err := interp.evalResults(expression)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// 对表达式求值并得到全部结果，只有函数调用的结果个数可能不为一
+func (interp *Interpreter) evalResults(expression Expression) ([]Value, error) {
+	exprType := interp.analyzer.Types[expression]
+	if value, isConstant := interp.analyzer.Constants[expression]; isConstant {
+		return []Value{constantValue(value, DefaultType(exprType))}, nil
+	}
+	if callExpr, isCall := expression.(*CallExpression); isCall {
+		return interp.evalCallExpression(callExpr)
+	}
+	var value Value
+	var err error
+	switch expression.ExpressionNodeType() {
+	case ExpressionTypePrimary:
+		value, err = interp.evalPrimaryExpression(expression.(PrimaryExpression))
+	case ExpressionTypeNewInstance:
+		value, err = interp.evalNewInstanceExpression(expression.(*NewInstanceExpression))
+	case ExpressionTypeUnary:
+		value, err = interp.evalUnaryExpression(expression.(*UnaryExpression))
+	case ExpressionTypeBinary:
+		value, err = interp.evalBinaryExpression(expression.(*BinaryExpression))
+	case ExpressionTypeRange:
+		rangeExpr := expression.(*RangeExpression)
+		elementType := exprType.(*ArrayType).Element
+		var start, end Value
+		if start, err = interp.evalValue(rangeExpr.Start, elementType); err != nil {
+			return nil, err
+		}
+		if end, err = interp.evalValue(rangeExpr.End, elementType); err != nil {
+			return nil, err
+		}
+		value, err = Range(start, end, rangeExpr.IncludeEnd)
+	case ExpressionTypeCast:
+		castExpr := expression.(*CastExpression)
+		if value, err = interp.evalValue(castExpr.Source, nil); err != nil {
+			return nil, err
+		}
+		if code, isBasic := typeCodeOf(exprType); isBasic && !IdenticalTypes(DefaultType(interp.analyzer.Types[castExpr.Source]), exprType) {
+			value = Convert(value, code)
+		}
+	}
+	if err != nil {
+		return nil, interp.errorAt(expression, err)
+	}
+	return []Value{value}, nil
+}
+
+// 对用作类型 target 的单个值求值：未定类型的值在此转为 target，target 为 nil 时取其默认类型
+func (interp *Interpreter) evalValue(expression Expression, target Type) (Value, error) {
+	exprType := interp.analyzer.Types[expression]
+	if _, isBasic := target.(*BasicType); !isBasic {
+		target = DefaultType(exprType)
+	}
+	if value, isConstant := interp.analyzer.Constants[expression]; isConstant {
+		return constantValue(value, target), nil
+	}
+	value, err := interp.evalExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	if code, isBasic := typeCodeOf(target); isBasic && IsNumericType(exprType) && !IdenticalTypes(exprType, target) {
+		value = Convert(value, code)
+	}
+	return value, nil
+}
+
+// 对一组值求值，若仅有一个多返回值的函数调用，则得到其全部返回值
+func (interp *Interpreter) evalValueList(expressions []Expression, types []Type) ([]Value, error) {
+	if len(expressions) == 1 {
+		if _, isTuple := interp.analyzer.Types[expressions[0]].(*TupleType); isTuple {
+			return interp.evalResults(expressions[0])
+		}
+	}
+	values := make([]Value, len(expressions))
+	for i, expression := range expressions {
+		var target Type
+		if i < len(types) {
+			target = types[i]
+		}
+		value, err := interp.evalValue(expression, target)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (interp *Interpreter) evalPrimaryExpression(primaryExpr PrimaryExpression) (Value, error) {
+	switch primaryExpr.PrimaryExpressionNode() {
+	case PrimaryExprTypeBasic:
+		return interp.evalOperand(primaryExpr, primaryExpr.(*BasicPrimaryExpression).It)
+	case PrimaryExprTypeIndex:
+		indexExpr := primaryExpr.(*IndexExpression)
+		operand, err := interp.evalValue(indexExpr.Operand, nil)
+		if err != nil {
+			return nil, err
+		}
+		index, err := interp.evalValue(indexExpr.Index, nil)
+		if err != nil {
+			return nil, err
+		}
+		return Index(operand, index)
+	case PrimaryExprTypeSlice:
+		sliceExpr := primaryExpr.(*SliceExpression)
+		operand, err := interp.evalValue(sliceExpr.Operand, nil)
+		if err != nil {
+			return nil, err
+		}
+		var start, end Value
+		if sliceExpr.Start != nil {
+			if start, err = interp.evalValue(sliceExpr.Start, nil); err != nil {
+				return nil, err
+			}
+		}
+		if sliceExpr.End != nil {
+			if end, err = interp.evalValue(sliceExpr.End, nil); err != nil {
+				return nil, err
+			}
+		}
+		return Slice(operand, start, end)
+	case PrimaryExprTypeMember:
+		return interp.evalMemberExpression(primaryExpr.(*MemberExpression))
+	}
+	return nil, interp.unsupported(primaryExpr, primaryExpr.NodeType())
+}
+
+// 实参转换为形参的类型，可变参数的内建函数则取实参的默认类型
+func (interp *Interpreter) evalCallExpression(callExpr *CallExpression) ([]Value, error) {
+	callee, err := interp.evalValue(callExpr.Operand, nil)
+	if err != nil {
+		return nil, err
+	}
+	var paramTypes []Type
+	if fnType, isFunction := interp.analyzer.Types[callExpr.Operand].(*FunctionType); isFunction && !fnType.Variadic {
+		paramTypes = fnType.Params
+	}
+	args, err := interp.evalValueList(callExpr.Params, paramTypes)
+	if err != nil {
+		return nil, err
+	}
+	return interp.call(callExpr, callee, args)
+}
+
+// 枚举元素即其整数值；数组与字符串只有 length 一个成员；模块的成员为模块顶层的变量、函数与枚举
+func (interp *Interpreter) evalMemberExpression(memberExpr *MemberExpression) (Value, error) {
+	ownerType := interp.analyzer.Types[memberExpr.Operand]
+	member := memberExpr.Member
+	var value Value
+	loaded := false // 是否已经求出了成员所属的值
+	for moduleType, isModule := ownerType.(*ModuleType); isModule && member != nil; moduleType, isModule = ownerType.(*ModuleType) {
+		switch symbol := moduleType.Module.Lookup(member.It.GetName()).(type) {
+		case *IdSymbol:
+			if _, isModule := symbol.Type.(*ModuleType); !isModule {
+				value, loaded = interp.modules[moduleType.Module].values[member.It.GetName()], true
+			}
+			ownerType = symbol.Type
+		case *EnumSymbol:
+			ownerType = &EnumType{Symbol: symbol}
+		default:
+			return nil, interp.unsupported(member.It, "member access on "+ownerType.String())
+		}
+		member = member.MemberNext
+	}
+	if enumType, isEnum := ownerType.(*EnumType); isEnum && !loaded && member != nil {
+		return interp.enumValues[enumType.Symbol.ElementsMap[member.It.GetName()]], nil
+	}
+	var err error
+	if member == memberExpr.Member {
+		if value, err = interp.evalValue(memberExpr.Operand, nil); err != nil {
+			return nil, err
+		}
+	}
+	for ; member != nil; member = member.MemberNext {
+		_, isArray := ownerType.(*ArrayType)
+		if (isArray || ownerType == StringType) && member.It.GetName() == "length" {
+			if value, err = Length(value); err != nil {
+				return nil, err
+			}
+			ownerType = IntType
+			continue
+		}
+		if exception, isException := value.(*Exception); isException && member.It.GetName() == "message" {
+			value, ownerType = exception.Message, StringType
+			continue
+		}
+		return nil, interp.unsupported(member.It, "member access on "+ownerType.String())
+	}
+	return value, nil
+}
+
+// 自定义的类尚不支持，只有内建的 Exception 类可以实例化
+func (interp *Interpreter) evalNewInstanceExpression(newInstanceExpr *NewInstanceExpression) (Value, error) {
+	classType, isClass := interp.analyzer.Types[newInstanceExpr].(*ClassType)
+	if !isClass || classType.Symbol != ExceptionType.Symbol {
+		return nil, interp.unsupported(newInstanceExpr, "class instantiation")
+	}
+	message, err := interp.evalValue(newInstanceExpr.InitParams[0], StringType)
+	if err != nil {
+		return nil, err
+	}
+	return &Exception{Class: "Exception", Message: message.(string)}, nil
+}
+
+func (interp *Interpreter) evalOperand(expression Expression, operand Operand) (Value, error) {
+	switch operand.OperandNodeType() {
+	case OperandTypeName:
+		name := operand.(*OperandName).GetFullName()
+		if env, ok := interp.env.lookup(name); ok {
+			return env.values[name], nil
+		}
+		if builtin, isBuiltin := Builtins[name]; isBuiltin {
+			return builtin, nil
+		}
+		return nil, interp.unsupported(operand, fmt.Sprintf("using \"%s\" as a value", name))
+	case OperandTypeLiteral:
+		return interp.evalLiteral(expression, operand.(Literal))
+	}
+	return nil, nil
+}
+
+// 数字、字符与字符串字面量都是常量，已经在 evalResults 中处理
+func (interp *Interpreter) evalLiteral(expression Expression, literal Literal) (Value, error) {
+	switch literal.LiteralNodeType() {
+	case LiteralNodeTypeArray:
+		elementType := interp.analyzer.Types[expression].(*ArrayType).Element
+		values := literal.(*ArrayLit).ValueList
+		elements := make([]Value, len(values))
+		for i, value := range values {
+			element, err := interp.evalValue(value, elementType)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &Array{Elements: elements}, nil
+	case LiteralNodeTypeMap:
+		valueType := interp.analyzer.Types[expression].(*TableType).Value
+		table := &Table{Entries: make(map[string]Value)}
+		for _, element := range literal.(*TableLit).KeyValueList {
+			value, err := interp.evalValue(element.Value, valueType)
+			if err != nil {
+				return nil, err
+			}
+			table.Entries[element.Key.GetName()] = value
+		}
+		return table, nil
+	case LiteralNodeTypeLambda:
+		lambda := literal.(*LambdaLit)
+		return &FunctionValue{
+			Name:      "<lambda>",
+			Signature: lambda.Signature,
+			Type:      interp.analyzer.Types[expression].(*FunctionType),
+			Body:      lambda.Result,
+			env:       interp.env,
+			file:      interp.file,
+		}, nil
+	case LiteralNodeTypeThis:
+		return nil, interp.unsupported(literal, "this")
+	case LiteralNodeTypeSuper:
+		return nil, interp.unsupported(literal, "super")
+	}
+	return nil, nil
+}
+
+// 运算的结果按类型截断，因此 int8 的 127 + 1 得到 -128
+func (interp *Interpreter) evalUnaryExpression(unaryExpr *UnaryExpression) (Value, error) {
+	resultType := DefaultType(interp.analyzer.Types[unaryExpr])
+	var op Opcode
+	switch unaryExpr.Operator.Kind {
+	case TokenTypeMinus:
+		op = OpNeg
+	case TokenTypeWavy:
+		op = OpBitNot
+	case TokenTypeBang:
+		op, resultType = OpNot, BoolType
+	}
+	operand, err := interp.evalValue(unaryExpr.Operand, resultType)
+	if err != nil {
+		return nil, err
+	}
+	result, err := Unary(op, operand)
+	if err != nil {
+		return nil, err
+	}
+	return truncate(result, resultType), nil
+}
+
+func (interp *Interpreter) evalBinaryExpression(binaryExpr *BinaryExpression) (Value, error) {
+	operator := binaryExpr.Operator.Kind
+	leftType := interp.analyzer.Types[binaryExpr.Left]
+	rightType := interp.analyzer.Types[binaryExpr.Right]
+	if operator == TokenTypeEqual {
+		return interp.store(binaryExpr.Left, func() (Value, error) {
+			return interp.evalValue(binaryExpr.Right, leftType)
+		})
+	}
+	if op, isCompound := compoundAssignOperations[operator]; isCompound {
+		return interp.update(binaryExpr.Left, func(current Value) (Value, error) {
+			result, err := interp.operate(op, current, binaryExpr.Right, leftType)
+			if err != nil {
+				return nil, err
+			}
+			return truncate(result, leftType), nil
+		})
+	}
+
+	switch operator {
+	case TokenTypeDoubleAmpersand, TokenTypeDoubleVertical:
+		// 短路求值：左侧已经能确定结果时不再求右侧
+		left, err := interp.evalValue(binaryExpr.Left, BoolType)
+		if err != nil || left == (operator == TokenTypeDoubleVertical) {
+			return left, err
+		}
+		return interp.evalValue(binaryExpr.Right, BoolType)
+	case TokenTypeDoubleEqual, TokenTypeBangEqual, TokenTypeLeftAngle, TokenTypeLeftAngleEqual,
+		TokenTypeRightAngle, TokenTypeRightAngleEqual:
+		// 比较两侧转为相同的类型，未定类型的一侧取另一侧的类型
+		operandType := leftType
+		if IsUntyped(leftType) {
+			operandType = rightType
+		}
+		left, err := interp.evalValue(binaryExpr.Left, operandType)
+		if err != nil {
+			return nil, err
+		}
+		right, err := interp.evalValue(binaryExpr.Right, operandType)
+		if err != nil {
+			return nil, err
+		}
+		switch operator {
+		case TokenTypeDoubleEqual:
+			return ValuesEqual(left, right), nil
+		case TokenTypeBangEqual:
+			return !ValuesEqual(left, right), nil
+		}
+		return Compare(binaryOperations[operator], left, right)
+	}
+	resultType := DefaultType(interp.analyzer.Types[binaryExpr])
+	left, err := interp.evalValue(binaryExpr.Left, resultType)
+	if err != nil {
+		return nil, err
+	}
+	result, err := interp.operate(binaryOperations[operator], left, binaryExpr.Right, resultType)
+	if err != nil {
+		return nil, err
+	}
+	return truncate(result, resultType), nil
+}
+
+// 以 left 为左操作数进行运算，移位运算的右操作数保持其自身的类型
+func (interp *Interpreter) operate(op Opcode, left Value, right Expression, operandType Type) (Value, error) {
+	if op == OpShl || op == OpShr {
+		operandType = nil
+	}
+	rightValue, err := interp.evalValue(right, operandType)
+	if err != nil {
+		return nil, err
+	}
+	return Arithmetic(op, left, rightValue)
+}
+
+// 以 value 求出的值写入赋值目标，并得到写入的值
+func (interp *Interpreter) store(target Expression, value func() (Value, error)) (Value, error) {
+	switch target := target.(type) {
+	case *BasicPrimaryExpression:
+		name := target.It.(*OperandName).GetFullName()
+		result, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if env, ok := interp.env.lookup(name); ok {
+			env.values[name] = result
+			return result, nil
+		}
+		return nil, interp.unsupported(target, fmt.Sprintf("assigning to \"%s\"", name))
+	case *IndexExpression:
+		operand, err := interp.evalValue(target.Operand, nil)
+		if err != nil {
+			return nil, err
+		}
+		index, err := interp.evalValue(target.Index, nil)
+		if err != nil {
+			return nil, err
+		}
+		result, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if err := SetIndex(operand, index, result); err != nil {
+			return nil, interp.errorAt(target, err)
+		}
+		return result, nil
+	}
+	return nil, interp.unsupported(target, "assigning to a class member")
+}
+
+// 以赋值目标的当前值求出新的值后写回，并得到新的值
+func (interp *Interpreter) update(target Expression, update func(current Value) (Value, error)) (Value, error) {
+	switch target := target.(type) {
+	case *BasicPrimaryExpression:
+		name := target.It.(*OperandName).GetFullName()
+		env, ok := interp.env.lookup(name)
+		if !ok {
+			return nil, interp.unsupported(target, fmt.Sprintf("assigning to \"%s\"", name))
+		}
+		result, err := update(env.values[name])
+		if err != nil {
+			return nil, err
+		}
+		env.values[name] = result
+		return result, nil
+	case *IndexExpression:
+		operand, err := interp.evalValue(target.Operand, nil)
+		if err != nil {
+			return nil, err
+		}
+		index, err := interp.evalValue(target.Index, nil)
+		if err != nil {
+			return nil, err
+		}
+		current, err := Index(operand, index)
+		if err != nil {
+			return nil, interp.errorAt(target, err)
+		}
+		result, err := update(current)
+		if err != nil {
+			return nil, err
+		}
+		if err := SetIndex(operand, index, result); err != nil {
+			return nil, interp.errorAt(target, err)
+		}
+		return result, nil
+	}
+	return nil, interp.unsupported(target, "assigning to a class member")
+}
+
+// 以类型 t 的表示得到常量的值：整数类型为整数，浮点数类型为浮点数
+func constantValue(value constant.Value, t Type) Value {
+	switch value.Kind() {
+	case constant.Bool:
+		return constant.BoolVal(value)
+	case constant.String:
+		return constant.StringVal(value)
+	case constant.Int, constant.Float:
+		if IsFloatType(t) {
+			float, _ := constant.Float64Val(value)
+			return representation(float, t)
+		}
+		integer := constant.ToInt(value)
+		number, exact := constant.Int64Val(integer)
+		if !exact {
+			unsigned, _ := constant.Uint64Val(integer)
+			number = int64(unsigned)
+		}
+		return representation(number, t)
+	}
+	return nil
+}
+
+// 类型 t 的零值：基本类型见 BasicType.Zero，引用类型为 nil
+func zeroValue(t Type) Value {
+	switch zero := ZeroValue(t).(type) {
+	case int64, float64, bool, string:
+		return representation(zero, t)
+	case uint64:
+		return representation(int64(zero), t)
+	}
+	return nil
+}
+
+// uint64、rune 与 float 的值另有表示，其余整数均以 int64、浮点数以 float64 表示
+func representation(value Value, t Type) Value {
+	switch t {
+	case Uint64Type:
+		return Convert(value, TypeUint64)
+	case RuneType:
+		return Convert(value, TypeRune)
+	case FloatType:
+		return Convert(value, TypeFloat)
+	}
+	return value
+}
+
+// 基本类型对应的类型编码，其余类型返回 false
+func typeCodeOf(t Type) (TypeCode, bool) {
+	if basicType, isBasic := t.(*BasicType); isBasic {
+		return TypeCodeOf(basicType.Name)
+	}
+	return 0, false
+}
+
+// 在算术运算之后按结果类型截断：int64 与 double 即值的原生表示，无需截断
+func truncate(value Value, t Type) Value {
+	if code, ok := typeCodeOf(t); ok && IsNumericType(t) && !IsUntyped(t) && t != Int64Type && t != DoubleType {
+		return Convert(value, code)
+	}
+	return value
+}
@@ -0,0 +1,438 @@
+package interp
+
+import (
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/vm"
+	"fmt"
+	"go/constant"
+	"strconv"
+)
+
+var constantOne = constant.MakeInt64(1)
+
+// 执行一组同一区块中的语句：与语义分析一致，先定义其中的函数与枚举，使得它们可以在定义之前被引用
+func (interp *Interpreter) execStatementList(stmts []Statement) (int, error) {
+	for _, stmt := range stmts {
+		switch stmt.StatementNodeType() {
+		case StatementTypeFunctionDecl:
+			fnStmt := stmt.(*FunctionDeclarationStatement)
+			fnSymbol := interp.analyzer.Symbols[fnStmt].(*IdSymbol)
+			interp.env.values[fnStmt.Name.GetName()] = &FunctionValue{
+				Name:      fnSymbol.QualifiedName(),
+				Signature: fnStmt.Signature,
+				Type:      fnSymbol.Type.(*FunctionType),
+				Body:      fnStmt.Block,
+				env:       interp.env,
+				file:      interp.file,
+			}
+		case StatementTypeEnum:
+			interp.declareEnum(stmt.(*EnumStatement))
+		}
+	}
+	for _, stmt := range stmts {
+		if flow, err := interp.execStatement(stmt); err != nil || flow != flowNormal {
+			return flow, err
+		}
+	}
+	return flowNormal, nil
+}
+
+// 枚举元素的值：给出值的元素取其值，其余元素为上一个元素的值加一，第一个元素默认为 0
+func (interp *Interpreter) declareEnum(enumStmt *EnumStatement) {
+	next := int64(0)
+	for _, element := range enumStmt.Elements {
+		if element.Value != nil {
+			if value, err := strconv.ParseInt(element.Value.Value.Str, 0, 64); err == nil {
+				next = value
+			}
+		}
+		interp.enumValues[element] = next
+		next++
+	}
+}
+
+func (interp *Interpreter) execStatement(stmt Statement) (int, error) {
+	switch stmt.StatementNodeType() {
+	case StatementTypeSimple:
+		switch simpleStmt := stmt.(type) {
+		case *ReturnStatement:
+			return interp.execReturnStatement(simpleStmt)
+		case *ThrowStatement:
+			return flowNormal, interp.execThrowStatement(simpleStmt)
+		case *BreakStatement:
+			return flowBreak, nil
+		case *ContinueStatement:
+			return flowContinue, nil
+		case SimpleStatement:
+			return flowNormal, interp.execSimpleStatement(simpleStmt)
+		}
+	case StatementTypeBlock:
+		return interp.execScopedBlock(stmt.(*BlockStatement))
+	case StatementTypeIf:
+		return interp.execIfStatement(stmt.(*IfStatement))
+	case StatementTypeSwitch:
+		return interp.execSwitchStatement(stmt.(*SwitchStatement))
+	case StatementTypeWhile:
+		return interp.execWhileStatement(stmt.(*WhileStatement))
+	case StatementTypeFor:
+		return interp.execForStatement(stmt.(*ForStatement))
+	case StatementTypeEach:
+		return interp.execEachStatement(stmt.(*EachStatement))
+	case StatementTypeTryCatch:
+		return interp.execTryCatchStatement(stmt.(*TryCatchStatement))
+	case StatementTypeImport:
+		interp.execImportStatement(stmt.(ImportStatement))
+		return flowNormal, nil
+	case StatementTypeClassDecl:
+		return flowNormal, interp.unsupported(stmt, "class")
+	}
+	// 函数与枚举已经在 execStatementList 中定义，其余语句不需要执行
+	return flowNormal, nil
+}
+
+// 模块已经在执行程序本身之前初始化，引入整个模块时其成员在访问时直接读取
+// 从模块中引入的变量取引入时的值，类型与枚举则不需要存储
+func (interp *Interpreter) execImportStatement(importStmt ImportStatement) {
+	var elements []*ImportElement
+	switch importStmt := importStmt.(type) {
+	case *SingleFromImportStatement:
+		elements = []*ImportElement{importStmt.Element}
+	case *ListImportStatement:
+		elements = importStmt.Elements
+	}
+	moduleEnv := interp.modules[interp.analyzer.Imports[importStmt]]
+	for _, element := range elements {
+		value, defined := moduleEnv.values[element.ModuleName.GetName()]
+		if _, isId := interp.analyzer.Symbols[element].(*IdSymbol); !isId || !defined {
+			continue
+		}
+		name := element.ModuleName.GetName()
+		if element.As != nil {
+			name = element.As.GetName()
+		}
+		interp.env.values[name] = value
+	}
+}
+
+func (interp *Interpreter) execSimpleStatement(simpleStmt SimpleStatement) error {
+	switch simpleStmt.SimpleStatementNodeType() {
+	case SimpleStmtTypeExpression:
+		expression := simpleStmt.(Expression)
+		results, err := interp.evalResults(expression)
+		if err == nil && interp.Echo != nil && interp.env == interp.globals {
+			interp.Echo(expression, results)
+		}
+		return err
+	case SimpleStmtTypeVariableDecl:
+		return interp.execVarDeclStatement(simpleStmt.(*VarDeclStatement))
+	case SimpleStmtTypeAssignList:
+		return interp.execAssignListStatement(simpleStmt.(*AssignListStatement))
+	case SimpleStmtTypeIncDecStmt:
+		incDecStmt := simpleStmt.(*IncDecStatement)
+		op := OpAdd
+		if incDecStmt.Operator.Str == "--" {
+			op = OpSub
+		}
+		targetType := interp.analyzer.Types[incDecStmt.Expression]
+		_, err := interp.update(incDecStmt.Expression, func(current Value) (Value, error) {
+			result, err := Arithmetic(op, current, constantValue(constantOne, targetType))
+			if err != nil {
+				return nil, interp.errorAt(incDecStmt, err)
+			}
+			return truncate(result, targetType), nil
+		})
+		return err
+	}
+	return nil
+}
+
+// 先求初始值再定义变量，没有初始值的变量取其类型的零值
+func (interp *Interpreter) execVarDeclStatement(varDeclStmt *VarDeclStatement) error {
+	for _, element := range varDeclStmt.Declarations {
+		varType := interp.analyzer.Symbols[element].(*IdSymbol).Type
+		value := zeroValue(varType)
+		if element.InitValue != nil {
+			var err error
+			if value, err = interp.evalValue(element.InitValue, varType); err != nil {
+				return err
+			}
+		}
+		interp.env.values[element.VarName.Str] = value
+	}
+	return nil
+}
+
+// 先求出全部的值，再依次赋值，因此 a, b = b, a 可以交换两个变量
+func (interp *Interpreter) execAssignListStatement(assignListStmt *AssignListStatement) error {
+	var types []Type
+	for _, target := range assignListStmt.Targets {
+		types = append(types, interp.analyzer.Types[target])
+	}
+	values, err := interp.evalValueList(assignListStmt.Values, types)
+	if err != nil {
+		return err
+	}
+	for i, target := range assignListStmt.Targets {
+		value := values[i]
+		if _, err := interp.store(target, func() (Value, error) { return value, nil }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 返回值转换为函数声明的返回值类型；脚本的顶层没有返回值，return 只结束脚本
+func (interp *Interpreter) execReturnStatement(returnStmt *ReturnStatement) (int, error) {
+	var returns []Type
+	if len(interp.calls) > 0 {
+		returns = interp.calls[len(interp.calls)-1].function.Type.Returns
+	}
+	results, err := interp.evalValueList(returnStmt.Expression, returns)
+	if err != nil {
+		return flowNormal, err
+	}
+	if returns == nil {
+		results = nil
+	}
+	interp.results = results
+	return flowReturn, nil
+}
+
+// 在新的作用域中执行区块
+// 抛出的异常作为带位置的运行时错误向外传递，直到被 catch 捕获；没有被捕获时即为运行时错误
+func (interp *Interpreter) execThrowStatement(throwStmt *ThrowStatement) error {
+	value, err := interp.evalValue(throwStmt.Value, nil)
+	if err != nil {
+		return err
+	}
+	exception := value.(*Exception)
+	failure := interp.locate(throwStmt, NewCoralError("Runtime",
+		fmt.Sprintf("uncaught exception %s!", FormatValue(exception)), RuntimeError))
+	failure.thrown = exception
+	return failure
+}
+
+// try 块中抛出的异常由第一个类型匹配的 catch 块处理；finally 块在 try 与 catch 块正常结束、
+// 以 return 等语句离开或抛出异常之后执行，其自身以 return 等语句离开或抛出异常时取代此前的结果
+// 其余的运行时错误不能被捕获，也不执行 finally 块，与虚拟机一致
+func (interp *Interpreter) execTryCatchStatement(tryCatchStmt *TryCatchStatement) (int, error) {
+	env := interp.env
+	flow, err := interp.execScopedBlock(tryCatchStmt.TryBlock)
+	if failure, isFailure := err.(*runtimeError); isFailure && failure.thrown != nil {
+		for _, handler := range tryCatchStmt.Handlers {
+			class := interp.analyzer.Symbols[handler].(*IdSymbol).Type.(*ClassType)
+			if failure.thrown.IsInstance(class.Symbol.QualifiedName()) {
+				interp.env = newEnvironment(env)
+				interp.env.values[handler.Name.GetName()] = failure.thrown
+				flow, err = interp.execStatementList(handler.Handler.Statements)
+				interp.env = env
+				break
+			}
+		}
+	}
+	if failure, isFailure := err.(*runtimeError); isFailure && failure.thrown == nil {
+		return flow, err
+	}
+	results := interp.results
+	if finallyFlow, finallyErr := interp.execScopedBlock(tryCatchStmt.Finally); finallyErr != nil || finallyFlow != flowNormal {
+		return finallyFlow, finallyErr
+	}
+	interp.results = results
+	return flow, err
+}
+
+func (interp *Interpreter) execScopedBlock(blockStmt *BlockStatement) (int, error) {
+	if blockStmt == nil {
+		return flowNormal, nil
+	}
+	outer := interp.env
+	interp.env = newEnvironment(outer)
+	flow, err := interp.execStatementList(blockStmt.Statements)
+	interp.env = outer
+	return flow, err
+}
+
+// 求出条件的值，条件必然是 bool
+func (interp *Interpreter) condition(expression Expression) (bool, error) {
+	value, err := interp.evalValue(expression, BoolType)
+	if err != nil {
+		return false, err
+	}
+	return value == true, nil
+}
+
+func (interp *Interpreter) execIfStatement(ifStmt *IfStatement) (int, error) {
+	for _, ifElement := range append([]*IfElement{ifStmt.If}, ifStmt.Elif...) {
+		matched, err := interp.condition(ifElement.Condition)
+		if err != nil {
+			return flowNormal, err
+		}
+		if matched {
+			return interp.execScopedBlock(ifElement.Block)
+		}
+	}
+	return interp.execScopedBlock(ifStmt.Else)
+}
+
+// 入口表达式只求值一次，再依次与各个匹配条件比较
+func (interp *Interpreter) execSwitchStatement(switchStmt *SwitchStatement) (int, error) {
+	entryType := DefaultType(interp.analyzer.Types[switchStmt.Entry])
+	entry, err := interp.evalValue(switchStmt.Entry, entryType)
+	if err != nil {
+		return flowNormal, err
+	}
+	for _, switchCase := range switchStmt.Cases {
+		matched := false
+		var block *BlockStatement
+		switch switchCase.SwitchStatementCaseNodeType() {
+		case SwitchStatementTypeNormal:
+			// 任意一个条件与入口相等即匹配
+			normalCase := switchCase.(*SwitchStatementNormalCase)
+			for _, condition := range normalCase.Conditions {
+				value, err := interp.evalValue(condition, entryType)
+				if err != nil {
+					return flowNormal, err
+				}
+				if matched = ValuesEqual(entry, value); matched {
+					break
+				}
+			}
+			block = normalCase.Block
+		case SwitchStatementTypeRange:
+			// 入口位于区间之内即匹配：entry >= start && entry < end
+			rangeCase := switchCase.(*SwitchStatementRangeCase)
+			if matched, err = interp.inRange(entry, rangeCase.Range, entryType); err != nil {
+				return flowNormal, err
+			}
+			block = rangeCase.Block
+		}
+		if matched {
+			return interp.execScopedBlock(block)
+		}
+	}
+	return interp.execScopedBlock(switchStmt.Default)
+}
+func (interp *Interpreter) inRange(entry Value, rangeExpr *RangeExpression, entryType Type) (bool, error) {
+	start, err := interp.evalValue(rangeExpr.Start, entryType)
+	if err != nil {
+		return false, err
+	}
+	if aboveStart, err := Compare(OpGreaterEqual, entry, start); err != nil || !aboveStart {
+		return false, interp.locateError(rangeExpr, err)
+	}
+	end, err := interp.evalValue(rangeExpr.End, entryType)
+	if err != nil {
+		return false, err
+	}
+	op := OpLess
+	if rangeExpr.IncludeEnd {
+		op = OpLessEqual
+	}
+	belowEnd, err := Compare(op, entry, end)
+	return belowEnd, interp.locateError(rangeExpr, err)
+}
+
+// 为可能为 nil 的错误标记位置
+func (interp *Interpreter) locateError(node Node, err error) error {
+	if err == nil {
+		return nil
+	}
+	return interp.errorAt(node, err)
+}
+
+// 执行一轮循环体，返回是否应当结束循环；return 与错误都会结束循环
+func (interp *Interpreter) execLoopBody(block *BlockStatement) (stop bool, flow int, err error) {
+	flow, err = interp.execScopedBlock(block)
+	switch {
+	case err != nil || flow == flowReturn:
+		return true, flow, err
+	case flow == flowBreak:
+		return true, flowNormal, nil
+	}
+	return false, flowNormal, nil
+}
+
+func (interp *Interpreter) execWhileStatement(whileStmt *WhileStatement) (int, error) {
+	for {
+		matched, err := interp.condition(whileStmt.Condition)
+		if err != nil || !matched {
+			return flowNormal, err
+		}
+		if stop, flow, err := interp.execLoopBody(whileStmt.Block); stop {
+			return flow, err
+		}
+	}
+}
+
+// for 语句的初始化部分自成一个作用域，continue 之后仍然执行每轮循环之后的部分
+func (interp *Interpreter) execForStatement(forStmt *ForStatement) (int, error) {
+	outer := interp.env
+	interp.env = newEnvironment(outer)
+	defer func() { interp.env = outer }()
+	if forStmt.Initial != nil {
+		if err := interp.execSimpleStatement(forStmt.Initial); err != nil {
+			return flowNormal, err
+		}
+	}
+	for {
+		if forStmt.Condition != nil {
+			matched, err := interp.condition(forStmt.Condition)
+			if err != nil || !matched {
+				return flowNormal, err
+			}
+		}
+		if stop, flow, err := interp.execLoopBody(forStmt.Block); stop {
+			return flow, err
+		}
+		for _, stmt := range forStmt.Appendix {
+			if err := interp.execSimpleStatement(stmt); err != nil {
+				return flowNormal, err
+			}
+		}
+	}
+}
+
+// each 语句逐个取出数组的元素或者字符串中的字符，键为下标；表则按字典序逐个取出键与值
+func (interp *Interpreter) execEachStatement(eachStmt *EachStatement) (int, error) {
+	targetType := interp.analyzer.Types[eachStmt.Target]
+	target, err := interp.evalValue(eachStmt.Target, targetType)
+	if err != nil {
+		return flowNormal, err
+	}
+	var keys []Value
+	if table, isTable := target.(*Table); isTable {
+		for _, key := range table.Keys() {
+			keys = append(keys, key)
+		}
+	} else {
+		length, err := Length(target)
+		if err != nil {
+			return flowNormal, interp.errorAt(eachStmt.Target, err)
+		}
+		for i := int64(0); i < length.(int64); i++ {
+			keys = append(keys, i)
+		}
+	}
+
+	outer := interp.env
+	defer func() { interp.env = outer }()
+	for _, key := range keys {
+		element, err := Index(target, key)
+		if err != nil {
+			// 遍历的过程中数组可能被改短
+			return flowNormal, interp.errorAt(eachStmt.Target, err)
+		}
+		interp.env = newEnvironment(outer)
+		interp.env.values[eachStmt.Element.GetName()] = element
+		if eachStmt.Key != nil {
+			interp.env.values[eachStmt.Key.GetName()] = key
+		}
+		if stop, flow, err := interp.execLoopBody(eachStmt.Block); stop {
+			return flow, err
+		}
+	}
+	return flowNormal, nil
+}
@@ -0,0 +1,252 @@
+package interp
+
+import (
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/vm"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Package interp 是直接遍历语法树求值的解释器
+// 借助语义分析推断出的类型与常量值，其行为与字节码编译器加上虚拟机的结果一致，
+// 因此既可以在不编译的情况下快速运行程序，也可以作为对照来检验字节码编译器
+
+// 语句执行完毕后的去向
+const (
+	flowNormal   = iota // 继续执行下一条语句
+	flowBreak           // 跳出所在的循环
+	flowContinue        // 进入所在循环的下一轮
+	flowReturn          // 从所在的函数返回，返回值见 Interpreter.results
+)
+
+// 变量的作用域，与语义分析的区块作用域一一对应
+// lambda 与函数保留定义处的作用域，因此可以引用外层函数的局部变量
+type environment struct {
+	outer  *environment
+	values map[string]Value
+}
+
+func newEnvironment(outer *environment) *environment {
+	return &environment{outer: outer, values: make(map[string]Value)}
+}
+
+// 由内向外查找名称所在的作用域
+func (env *environment) lookup(name string) (*environment, bool) {
+	for current := env; current != nil; current = current.outer {
+		if _, ok := current.values[name]; ok {
+			return current, true
+		}
+	}
+	return nil, false
+}
+
+// 以语法树表示的函数，lambda 的名称为 <lambda>
+type FunctionValue struct {
+	Name      string
+	Signature *Signature
+	Type      *FunctionType
+	Body      Statement // 函数定义为其函数体；lambda 为其结果，可以是区块、表达式或者语句
+	env       *environment
+	file      string // 定义所在的源文件
+}
+
+func (function *FunctionValue) FunctionName() string {
+	return function.Name
+}
+
+// 调用链上的一层：被调用的函数以及调用发生的位置
+type callSite struct {
+	function *FunctionValue
+	node     Node
+	file     string
+}
+
+// 带出错位置的运行时错误，沿调用链向外传递
+type runtimeError struct {
+	node    Node
+	file    string
+	err     *CoralCompileError
+	callers []*callSite // 由内向外
+	thrown  *Exception  // throw 语句抛出的异常，可以被 catch 捕获；其余运行时错误为 nil
+}
+
+func (it *runtimeError) Error() string {
+	return it.err.Message
+}
+
+type Interpreter struct {
+	analyzer   *Analyzer // @private 提供类型与常量值的语义分析器
+	globals    *environment
+	env        *environment // @private 当前的作用域
+	calls      []*callSite  // @private 当前的调用链
+	results    []Value      // @private return 语句的返回值
+	file       string       // @private 正在执行的代码所在的源文件
+	enumValues map[*EnumElement]int64
+	modules    map[*LoadedModule]*environment // @private 已经初始化的模块的顶层作用域
+
+	Stdout      io.Writer     // 内建函数 print 等的输出
+	Diagnostics []*Diagnostic // 运行时错误，带有出错的位置与调用链
+
+	// 顶层的表达式语句求值之后的回调，交互式环境借此输出表达式的值，为 nil 时不回调
+	Echo func(expression Expression, results []Value)
+}
+
+// 以已经完成语义检查的分析器创建解释器
+func NewInterpreter(analyzer *Analyzer, stdout io.Writer) *Interpreter {
+	globals := newEnvironment(nil)
+	return &Interpreter{
+		analyzer:   analyzer,
+		globals:    globals,
+		env:        globals,
+		enumValues: make(map[*EnumElement]int64),
+		modules:    make(map[*LoadedModule]*environment),
+		Stdout:     stdout,
+	}
+}
+
+// 从上到下执行整个程序，出错时返回运行时错误，并在 Diagnostics 中记录出错的位置
+func (interp *Interpreter) Run() *CoralCompileError {
+	return interp.Execute(interp.analyzer.Ast.Root)
+}
+
+// 在全局作用域中执行一组已经检查过的顶层语句，此前定义的全局变量与函数依然可见
+// 尚未初始化的引入的模块按被引入的先后先行初始化
+func (interp *Interpreter) Execute(stmts []Statement) *CoralCompileError {
+	interp.calls = nil
+	interp.Diagnostics = nil
+	err := interp.initModules()
+	if err == nil {
+		interp.env = interp.globals
+		interp.file = interp.analyzer.GetParser().FileName
+		_, err = interp.execStatementList(stmts)
+	}
+	if err == nil {
+		return nil
+	}
+	failure := err.(*runtimeError)
+	diagnostic := NewDiagnostic(failure.file, startOf(failure.node), endOf(failure.node), failure.err)
+	for _, caller := range failure.callers {
+		diagnostic.AddNote(caller.file, startOf(caller.node), endOf(caller.node),
+			fmt.Sprintf("%s called from here", caller.function.Name))
+	}
+	interp.Diagnostics = append(interp.Diagnostics, diagnostic)
+	return failure.err
+}
+
+// 在各自的顶层作用域中执行引入的模块，每个模块只执行一次
+func (interp *Interpreter) initModules() error {
+	if interp.analyzer.Loader == nil {
+		return nil
+	}
+	for _, imported := range interp.analyzer.Loader.Order {
+		if _, initialized := interp.modules[imported]; initialized || imported.Analyzer == interp.analyzer {
+			continue
+		}
+		interp.env = newEnvironment(nil)
+		interp.file = imported.Path
+		interp.modules[imported] = interp.env
+		if _, err := interp.execStatementList(imported.Analyzer.Ast.Root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startOf(node Node) Position {
+	span := node.GetSpan()
+	return Position{Line: span.Start.Line, Col: span.Start.Col}
+}
+func endOf(node Node) Position {
+	span := node.GetSpan()
+	return Position{Line: span.End.Line, Col: span.End.Col}
+}
+
+// 将求值中的错误标记为在 node 处发生，已经带有位置的错误原样返回
+func (interp *Interpreter) errorAt(node Node, err error) error {
+	if _, located := err.(*runtimeError); located {
+		return err
+	}
+	return interp.locate(node, NewCoralError("Runtime", err.Error()+"!", RuntimeError))
+}
+
+// 报告解释器尚不支持的语言特性
+func (interp *Interpreter) unsupported(node Node, feature string) error {
+	return interp.locate(node, NewCoralError("Runtime", feature+" is not supported by the interpreter yet!", UnsupportedFeature))
+}
+
+// 以当前的调用链记录错误发生的位置
+func (interp *Interpreter) locate(node Node, err *CoralCompileError) *runtimeError {
+	callers := make([]*callSite, len(interp.calls))
+	for i, call := range interp.calls {
+		callers[len(callers)-1-i] = call
+	}
+	return &runtimeError{node: node, file: interp.file, err: err, callers: callers}
+}
+
+// 调用函数值：内建函数直接执行，语法树中的函数在其定义处作用域的内层执行
+func (interp *Interpreter) call(node Node, callee Value, args []Value) ([]Value, error) {
+	switch callee := callee.(type) {
+	case *Builtin:
+		results, err := callee.Fn(interp.Stdout, args)
+		if err != nil {
+			return nil, interp.errorAt(node, err)
+		}
+		return results, nil
+	case *FunctionValue:
+		if len(interp.calls) >= MaxFrames {
+			return nil, interp.errorAt(node, errors.New("stack overflow"))
+		}
+		env := newEnvironment(callee.env)
+		for i, argument := range callee.Signature.Arguments {
+			env.values[argument.Name.GetName()] = args[i]
+		}
+		outerEnv, outerFile := interp.env, interp.file
+		interp.env, interp.file = env, callee.file
+		interp.calls = append(interp.calls, &callSite{function: callee, node: node, file: outerFile})
+		results, err := interp.execFunctionBody(callee)
+		interp.calls = interp.calls[:len(interp.calls)-1]
+		interp.env, interp.file = outerEnv, outerFile
+		return results, err
+	}
+	return nil, interp.errorAt(node, fmt.Errorf("cannot call value of type %s", ValueTypeName(callee)))
+}
+
+// 执行函数体；有返回值的函数不会执行到末尾（由语义检查保证），末尾的零值返回只是兜底
+func (interp *Interpreter) execFunctionBody(function *FunctionValue) ([]Value, error) {
+	returns := function.Type.Returns
+	switch body := function.Body.(type) {
+	case *BlockStatement:
+		flow, err := interp.execStatementList(body.Statements)
+		if err != nil {
+			return nil, err
+		}
+		if flow == flowReturn {
+			return interp.results, nil
+		}
+	case Expression:
+		if len(returns) == 1 {
+			value, err := interp.evalValue(body, returns[0])
+			return []Value{value}, err
+		}
+		_, err := interp.evalResults(body)
+		if err != nil {
+			return nil, err
+		}
+	case Statement:
+		flow, err := interp.execStatement(body)
+		if err != nil {
+			return nil, err
+		}
+		if flow == flowReturn {
+			return interp.results, nil
+		}
+	}
+	results := make([]Value, len(returns))
+	for i, returnType := range returns {
+		results[i] = zeroValue(returnType)
+	}
+	return results, nil
+}
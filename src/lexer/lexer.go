@@ -5,8 +5,8 @@ import (
 	"coral-lang/src/utils"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"regexp"
+	"sort"
 	"unicode/utf8"
 )
 
@@ -46,6 +46,7 @@ const (
 	TokenTypeTry
 	TokenTypeCatch
 	TokenTypeFinally
+	TokenTypeThrow
 	TokenTypeThrows
 
 	TokenTypeSemi                  // ;
@@ -112,9 +113,33 @@ const (
 
 type TokenType = int
 type Token struct {
-	Line, Col int
+	Line, Col int // Token 末尾之后的位置，即 End 的行号列号
 	Kind      TokenType
 	Str       string
+
+	Start, End Pos // Token 在源代码中的范围，End 不含在内
+
+	// 以下仅在词法分析器保留 trivia 时记录，见 trivia.go
+	Raw            string    // Token 在源代码中的原文，如字符串字面量转义之前的写法
+	LeadingTrivia  []*Trivia // Token 之前的空白、换行与注释
+	TrailingTrivia []*Trivia // Token 之后、同一行中的空白与注释
+}
+
+// 源代码中的一个位置
+type Pos struct {
+	Offset int // 字节偏移量，从 0 开始
+	Line   int // 行号，从 1 开始
+	Col    int // 列号，以 UTF-8 字符计，从 1 开始
+}
+
+func (pos Pos) String() string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Col)
+}
+
+// 源代码中的一条注释，词法分析时不产出 Token，只记录下来供格式化工具保留
+type Comment struct {
+	Text       string // 注释的原文，包括 // 或 /* */ 本身
+	Start, End Pos
 }
 
 type UTF8Char struct {
@@ -132,21 +157,26 @@ type Lexer struct {
 
 	Line, Col int // 记录行号列号
 	BytePos   int // 当前游标位置
+
+	Comments []*Comment // 已读过的注释，按在源代码中出现的先后排列
+
+	KeepTrivia bool      // 是否保留 trivia，使得由 Token 流能够还原出源代码，须在初始化之前设置
+	EndTrivia  []*Trivia // 最后一个 Token 之后的 trivia
+
+	pendingTrivia []*Trivia // @private 已读过、尚未归属于某个 Token 的 trivia
+
+	lineStarts []int // @private 每一行起始处的字节偏移量，用于由偏移量换算行号列号
+	tokenStart int   // @private 正在读取的 Token 的起始字节偏移量
 }
 
 // 给出路径，打开源代码文件
-func OpenSourceFile(filePath string) []byte {
-	file, err := os.Open(filePath)
+func OpenSourceFile(filePath string) ([]byte, *CoralCompileError) {
+	content, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		CoralErrorCrashHandler(NewCoralError("FileSystem",
-			"Can't open source file: "+filePath, FileSystemOpenFileError))
-	}
-	if file != nil {
-		defer file.Close()
+		return nil, NewCoralError("FileSystem",
+			"Can't open source file: "+filePath, FileSystemOpenFileError)
 	}
-
-	content, err := ioutil.ReadAll(file)
-	return content
+	return content, nil
 }
 
 // 初始化词法分析器
@@ -159,6 +189,16 @@ func (lexer *Lexer) InitLexerCommonOperations() {
 	lexer.BraceCount = 0
 	lexer.BracketCount = 0
 
+	lexer.Comments = nil
+	lexer.EndTrivia = nil
+	lexer.pendingTrivia = nil
+	lexer.lineStarts = []int{0}
+	for i, b := range lexer.Content {
+		if b == '\n' {
+			lexer.lineStarts = append(lexer.lineStarts, i+1)
+		}
+	}
+
 	lexer.KeywordMap = map[string]TokenType{
 		"import":    TokenTypeImport,
 		"package":   TokenTypePackage,
@@ -195,6 +235,7 @@ func (lexer *Lexer) InitLexerCommonOperations() {
 		"try":       TokenTypeTry,
 		"catch":     TokenTypeCatch,
 		"finally":   TokenTypeFinally,
+		"throw":     TokenTypeThrow,
 		"throws":    TokenTypeThrows,
 	}
 }
@@ -211,11 +252,102 @@ func (lexer *Lexer) ResetBytePos(i int) {
 	lexer.BytePos = i
 }
 
+// 由字节偏移量换算出源代码中的位置
+func (lexer *Lexer) PositionOf(offset int) Pos {
+	line := sort.Search(len(lexer.lineStarts), func(i int) bool {
+		return lexer.lineStarts[i] > offset
+	}) // 第一个起始位置在 offset 之后的行，其上一行即 offset 所在行
+	lineStart := lexer.lineStarts[line-1]
+	return Pos{
+		Offset: offset,
+		Line:   line,
+		Col:    utf8.RuneCount(lexer.Content[lineStart:offset]) + 1,
+	}
+}
+
+// 词法分析器游标当前所在的位置
+func (lexer *Lexer) CurrentPos() Pos {
+	return lexer.PositionOf(lexer.BytePos)
+}
+
 // Token 的 ToString() 方法
 func (token *Token) ToString() string {
 	return fmt.Sprintf("Line %d:%d  Type: %d, Str: %s", token.Line, token.Col, token.Kind, token.Str)
 }
 
+// 各类 Token 可读的名称，供命令行等工具输出
+var tokenTypeNames = map[TokenType]string{
+	TokenTypeSemi:                  "Semi",
+	TokenTypeComma:                 "Comma",
+	TokenTypeColon:                 "Colon",
+	TokenTypeLeftParen:             "LeftParen",
+	TokenTypeRightParen:            "RightParen",
+	TokenTypeLeftBrace:             "LeftBrace",
+	TokenTypeRightBrace:            "RightBrace",
+	TokenTypeLeftBracket:           "LeftBracket",
+	TokenTypeRightBracket:          "RightBracket",
+	TokenTypeDot:                   "Dot",
+	TokenTypeEqual:                 "Equal",
+	TokenTypeDoubleEqual:           "DoubleEqual",
+	TokenTypeBangEqual:             "BangEqual",
+	TokenTypePlus:                  "Plus",
+	TokenTypeMinus:                 "Minus",
+	TokenTypeStar:                  "Star",
+	TokenTypeDoubleStar:            "DoubleStar",
+	TokenTypeSlash:                 "Slash",
+	TokenTypePercent:               "Percent",
+	TokenTypeAlpha:                 "Alpha",
+	TokenTypeWavy:                  "Wavy",
+	TokenTypeCaret:                 "Caret",
+	TokenTypeAmpersand:             "Ampersand",
+	TokenTypeBang:                  "Bang",
+	TokenTypeVertical:              "Vertical",
+	TokenTypeLeftAngle:             "LeftAngle",
+	TokenTypeRightAngle:            "RightAngle",
+	TokenTypeDoubleLeftAngle:       "DoubleLeftAngle",
+	TokenTypeDoubleRightAngle:      "DoubleRightAngle",
+	TokenTypeDoubleAmpersand:       "DoubleAmpersand",
+	TokenTypeDoubleVertical:        "DoubleVertical",
+	TokenTypeLeftAngleEqual:        "LeftAngleEqual",
+	TokenTypeRightAngleEqual:       "RightAngleEqual",
+	TokenTypeLeftArrow:             "LeftArrow",
+	TokenTypeRightArrow:            "RightArrow",
+	TokenTypeDoublePlus:            "DoublePlus",
+	TokenTypeDoubleMinus:           "DoubleMinus",
+	TokenTypePlusEqual:             "PlusEqual",
+	TokenTypeMinusEqual:            "MinusEqual",
+	TokenTypeStarEqual:             "StarEqual",
+	TokenTypeSlashEqual:            "SlashEqual",
+	TokenTypePercentEqual:          "PercentEqual",
+	TokenTypeDoubleLeftAngleEqual:  "DoubleLeftAngleEqual",
+	TokenTypeDoubleRightAngleEqual: "DoubleRightAngleEqual",
+	TokenTypeAmpersandEqual:        "AmpersandEqual",
+	TokenTypeVerticalEqual:         "VerticalEqual",
+	TokenTypeCaretEqual:            "CaretEqual",
+	TokenTypeEllipsis:              "Ellipsis",
+	TokenTypeDoubleDot:             "DoubleDot",
+	TokenTypeDecimalInteger:        "DecimalInteger",
+	TokenTypeOctalInteger:          "OctalInteger",
+	TokenTypeHexadecimalInteger:    "HexadecimalInteger",
+	TokenTypeBinaryInteger:         "BinaryInteger",
+	TokenTypeExponent:              "Exponent",
+	TokenTypeFloat:                 "Float",
+	TokenTypeRune:                  "Rune",
+	TokenTypeString:                "String",
+	TokenTypeIdentifier:            "Identifier",
+}
+
+// 获取 Token 种类的可读名称，关键字统一以 Keyword 表示
+func (token *Token) KindName() string {
+	if name, ok := tokenTypeNames[token.Kind]; ok {
+		return name
+	}
+	if token.Kind <= TokenTypeThrows {
+		return "Keyword"
+	}
+	return fmt.Sprintf("Unknown(%d)", token.Kind)
+}
+
 // 拾取当前游标所在位置的字符
 func (lexer *Lexer) PeekChar() *UTF8Char {
 	r, byteLength := utf8.DecodeRune(lexer.Content[lexer.BytePos:])
@@ -407,6 +539,9 @@ func (lexer *Lexer) ReadString() (*Token, *CoralCompileError) {
 	lexer.GoNextChar() // 移过当前的 '"' 双引号
 
 	for !lexer.PeekChar().MatchRune('"') {
+		if lexer.BytePos >= len(lexer.Content) {
+			return nil, NewCoralError("Syntax", "unclosed string literal!", LexStringUnclosed)
+		}
 		if lexer.PeekChar().MatchRune('\\') { // 可能遇到转义字符
 			switch lexer.PeekNextChar(lexer.PeekChar().ByteLength).Rune {
 			case 'a':
@@ -433,6 +568,12 @@ func (lexer *Lexer) ReadString() (*Token, *CoralCompileError) {
 			case '"':
 				str += "\""
 				lexer.GoNextCharByStep(2)
+			case '\'':
+				str += "'"
+				lexer.GoNextCharByStep(2)
+			case '\\':
+				str += "\\"
+				lexer.GoNextCharByStep(2)
 			case 'u':
 				// Unicode 需要是：\uXXXX 格式：
 				lexer.GoNextCharByStep(2) // 移过当前的 '\u'
@@ -463,6 +604,10 @@ func (lexer *Lexer) ReadString() (*Token, *CoralCompileError) {
 				}
 				gotUTF8Decoded := utils.UnicodeToUTF8(sUnicode, 2)
 				str += gotUTF8Decoded
+			default:
+				lexer.GoNextChar() // 移过 '\\'，避免停留在原地
+				return nil, NewCoralError("Syntax",
+					fmt.Sprintf("unknown escape character '\\%c'!", lexer.PeekChar().Rune), LexUnknownEscapeCharacter)
 			}
 		} else {
 			// 正常添加字符
@@ -476,11 +621,14 @@ func (lexer *Lexer) ReadString() (*Token, *CoralCompileError) {
 }
 
 // 读出一个字符，含转义字符的处理
-func (lexer *Lexer) ReadRune() (*Token, *CoralCompileError) {
+func (lexer *Lexer) ReadRuneLit() (*Token, *CoralCompileError) {
 	var str string
 	lexer.GoNextChar() // 移过当前的 ' 双引号
 
 	for !lexer.PeekChar().MatchRune('\'') {
+		if lexer.BytePos >= len(lexer.Content) {
+			return nil, NewCoralError("Syntax", "unclosed rune literal!", LexRuneUnclosed)
+		}
 		if lexer.PeekChar().MatchRune('\\') { // 可能遇到转义字符
 			switch lexer.PeekNextChar(lexer.PeekChar().ByteLength).Rune {
 			case 'a':
@@ -507,6 +655,12 @@ func (lexer *Lexer) ReadRune() (*Token, *CoralCompileError) {
 			case '"':
 				str += "\""
 				lexer.GoNextCharByStep(2)
+			case '\'':
+				str += "'"
+				lexer.GoNextCharByStep(2)
+			case '\\':
+				str += "\\"
+				lexer.GoNextCharByStep(2)
 			case 'u', 'U':
 				// Unicode 需要是：\uXXXX 格式：
 				lexer.GoNextCharByStep(2) // 移过当前的 '\u'
@@ -536,6 +690,10 @@ func (lexer *Lexer) ReadRune() (*Token, *CoralCompileError) {
 				}
 				gotUTF8Decoded := utils.UnicodeToUTF8(sUnicode, 2)
 				str += gotUTF8Decoded
+			default:
+				lexer.GoNextChar() // 移过 '\\'，避免停留在原地
+				return nil, NewCoralError("Syntax",
+					fmt.Sprintf("unknown escape character '\\%c'!", lexer.PeekChar().Rune), LexUnknownEscapeCharacter)
 			}
 
 		} else {
@@ -583,6 +741,9 @@ func (lexer *Lexer) SkipBlockComment() *CoralCompileError {
 	current := lexer.PeekChar()
 	next := lexer.PeekNextChar(current.ByteLength)
 	for {
+		if lexer.BytePos >= len(lexer.Content) {
+			return NewCoralError("Syntax", "unclosed block comment!", LexBlockCommentUnclosed)
+		}
 		lexer.GoNextChar() // 首要任务 跳过当前注释内容
 
 		// 然后更新 current 和 next
@@ -610,26 +771,49 @@ func (lexer *Lexer) SkipBlockComment() *CoralCompileError {
 // 跳过行注释
 func (lexer *Lexer) SkipLineComment() {
 	// 直到换行符
-	for !lexer.PeekChar().MatchRune('\n') {
+	for lexer.BytePos < len(lexer.Content) && !lexer.PeekChar().MatchRune('\n') {
 		lexer.GoNextChar()
 	}
 }
 
-// 产出 Token，词法分析器的行号也移动字面值 s 的长度
+// 记录刚刚跳过的注释，解析器回溯后重新读到的注释不会重复记录
+func (lexer *Lexer) recordComment() {
+	if n := len(lexer.Comments); n > 0 && lexer.Comments[n-1].Start.Offset >= lexer.tokenStart {
+		return
+	}
+	lexer.Comments = append(lexer.Comments, &Comment{
+		Text:  string(lexer.Content[lexer.tokenStart:lexer.BytePos]),
+		Start: lexer.PositionOf(lexer.tokenStart),
+		End:   lexer.CurrentPos(),
+	})
+}
+
+// 产出 Token，Token 的范围由起止的字节偏移量换算而来，词法分析器的行号列号也随之移到 Token 末尾
+// 这样字符串里的转义字符、跨行的块注释都不会让位置出现偏差
 func (lexer *Lexer) makeToken(t TokenType, s string) *Token {
-	lexer.Col += utf8.RuneCountInString(s)
-	// s 这个字符串的长度就是其中 UTF8 字符个数的长度
+	start, end := lexer.PositionOf(lexer.tokenStart), lexer.CurrentPos()
+	lexer.Line, lexer.Col = end.Line, end.Col
 	return &Token{
-		Line: lexer.Line,
-		Col:  lexer.Col,
-		Kind: t,
-		Str:  s,
+		Line:  end.Line,
+		Col:   end.Col,
+		Kind:  t,
+		Str:   s,
+		Start: start,
+		End:   end,
 	}
 }
 
 // 词法分析器获取下一个 Token
 func (lexer *Lexer) GetNextToken(avoidAngleConfusing bool) (*Token, *CoralCompileError) {
+	token, err := lexer.readToken(avoidAngleConfusing)
+	if lexer.KeepTrivia {
+		lexer.attachTrivia(token)
+	}
+	return token, err
+}
+func (lexer *Lexer) readToken(avoidAngleConfusing bool) (*Token, *CoralCompileError) {
 	for lexer.BytePos < len(lexer.Content) {
+		lexer.tokenStart = lexer.BytePos
 		c := lexer.PeekChar()
 		switch c.Rune {
 		default:
@@ -640,10 +824,12 @@ func (lexer *Lexer) GetNextToken(avoidAngleConfusing bool) (*Token, *CoralCompil
 		case '\t', ' ':
 			lexer.Col += 1
 			lexer.GoNextChar() // skip whitespace
+			lexer.recordTrivia(TriviaWhitespace)
 		case '\n':
 			lexer.Line++
 			lexer.Col = 1
 			lexer.GoNextChar() // skip
+			lexer.recordTrivia(TriviaNewline)
 		case ';':
 			lexer.GoNextChar()
 			return lexer.makeToken(TokenTypeSemi, ";"), nil
@@ -727,9 +913,13 @@ func (lexer *Lexer) GetNextToken(avoidAngleConfusing bool) (*Token, *CoralCompil
 				if err != nil {
 					return nil, err // 可能的块注释略过时出错
 				}
+				lexer.recordComment()
+				lexer.recordTrivia(TriviaBlockComment)
 				continue
 			} else if lexer.PeekNextChar(c.ByteLength).MatchRune('/') {
 				lexer.SkipLineComment()
+				lexer.recordComment()
+				lexer.recordTrivia(TriviaLineComment)
 				continue
 			}
 			lexer.GoNextChar()
@@ -834,17 +1024,18 @@ func (lexer *Lexer) GetNextToken(avoidAngleConfusing bool) (*Token, *CoralCompil
 		case '"':
 			return lexer.ReadString()
 		case '\'':
-			return lexer.ReadRune()
+			return lexer.ReadRuneLit()
 		}
 	}
 
+	lexer.Line, lexer.Col = lexer.CurrentPos().Line, lexer.CurrentPos().Col
 	if lexer.ParenCount > 0 {
 		return nil, NewCoralError("Syntax", "Unclosed parentheses '(' !", LexParenthesesUnclosed)
 	}
 	if lexer.BracketCount > 0 {
 		return nil, NewCoralError("Syntax", "Unclosed bracket '[' !", LexBracketUnclosed)
 	}
-	if lexer.ParenCount > 0 {
+	if lexer.BraceCount > 0 {
 		return nil, NewCoralError("Syntax", "Unclosed brace '{' !", LexBraceUnclosed)
 	}
 
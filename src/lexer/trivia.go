@@ -0,0 +1,132 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+)
+
+/* trivia 是源代码中不构成 Token 的部分：空白、换行与注释
+ * 保留 trivia 时，Token 之前的 trivia 归于该 Token 的 LeadingTrivia，其后直到行末（不含换行符）的空白与注释归于 TrailingTrivia，
+ * 最后一个 Token 之后的则归于 Lexer.EndTrivia
+ * 依次写出每个 Token 的 LeadingTrivia、原文与 TrailingTrivia，最后写出 EndTrivia，即得到与源代码逐字节相同的内容
+ */
+
+type TriviaKind = int
+
+const (
+	TriviaWhitespace   = iota // 连续的空格与制表符
+	TriviaNewline             // 一个换行符
+	TriviaLineComment         // 行注释，不含末尾的换行符
+	TriviaBlockComment        // 块注释
+)
+
+type Trivia struct {
+	Kind       TriviaKind
+	Text       string // 在源代码中的原文
+	Start, End Pos
+}
+
+var triviaKindNames = map[TriviaKind]string{
+	TriviaWhitespace:   "Whitespace",
+	TriviaNewline:      "Newline",
+	TriviaLineComment:  "LineComment",
+	TriviaBlockComment: "BlockComment",
+}
+
+func (trivia *Trivia) KindName() string {
+	if name, ok := triviaKindNames[trivia.Kind]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", trivia.Kind)
+}
+
+// 记录刚刚读过的一段 trivia，即从 tokenStart 到游标处，相邻的空白合并为一段
+func (lexer *Lexer) recordTrivia(kind TriviaKind) {
+	if !lexer.KeepTrivia {
+		return
+	}
+	if n := len(lexer.pendingTrivia); n > 0 && kind == TriviaWhitespace {
+		last := lexer.pendingTrivia[n-1]
+		if last.Kind == TriviaWhitespace && last.End.Offset == lexer.tokenStart {
+			last.Text = string(lexer.Content[last.Start.Offset:lexer.BytePos])
+			last.End = lexer.CurrentPos()
+			return
+		}
+	}
+	lexer.pendingTrivia = append(lexer.pendingTrivia, &Trivia{
+		Kind:  kind,
+		Text:  string(lexer.Content[lexer.tokenStart:lexer.BytePos]),
+		Start: lexer.PositionOf(lexer.tokenStart),
+		End:   lexer.CurrentPos(),
+	})
+}
+
+// 将此前读过的 trivia 归于刚读出的 Token，并读取其后同一行中的 trivia
+// 读到文件末尾时，剩余的 trivia 归于 EndTrivia；词法错误跳过的字符不会被记录
+func (lexer *Lexer) attachTrivia(token *Token) {
+	if token == nil {
+		if lexer.BytePos >= len(lexer.Content) {
+			lexer.EndTrivia = append(lexer.EndTrivia, lexer.pendingTrivia...)
+			lexer.pendingTrivia = nil
+		}
+		return
+	}
+	token.Raw = string(lexer.Content[token.Start.Offset:token.End.Offset])
+	token.LeadingTrivia, lexer.pendingTrivia = lexer.pendingTrivia, nil
+	lexer.readTrailingTrivia()
+	lexer.Line, lexer.Col = lexer.CurrentPos().Line, lexer.CurrentPos().Col
+	token.TrailingTrivia, lexer.pendingTrivia = lexer.pendingTrivia, nil
+}
+
+// 读取 Token 之后同一行中的空白与注释，遇到换行符或其他 Token 时停下
+func (lexer *Lexer) readTrailingTrivia() {
+	for lexer.BytePos < len(lexer.Content) {
+		lexer.tokenStart = lexer.BytePos
+		c := lexer.PeekChar()
+		next := lexer.PeekNextChar(c.ByteLength)
+		switch {
+		case c.MatchRune(' ') || c.MatchRune('\t'):
+			lexer.GoNextChar()
+			lexer.recordTrivia(TriviaWhitespace)
+		case c.MatchRune('/') && next.MatchRune('/'):
+			lexer.SkipLineComment()
+			lexer.recordComment()
+			lexer.recordTrivia(TriviaLineComment)
+		case c.MatchRune('/') && next.MatchRune('*'):
+			if err := lexer.SkipBlockComment(); err != nil {
+				lexer.BytePos = lexer.tokenStart // 出错的块注释留待读取下一个 Token 时报错
+				return
+			}
+			lexer.recordComment()
+			lexer.recordTrivia(TriviaBlockComment)
+		default:
+			return
+		}
+	}
+}
+
+// 依次写出 Token 连同其前后的 trivia，最后写出 endTrivia
+// 保留 trivia 时读出的完整 Token 流由此还原出与源代码逐字节相同的内容；Token 被修改后则得到修改后的源代码
+func WriteTokens(w io.Writer, tokens []*Token, endTrivia []*Trivia) error {
+	writeTrivia := func(trivia []*Trivia) error {
+		for _, piece := range trivia {
+			if _, err := io.WriteString(w, piece.Text); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, token := range tokens {
+		if err := writeTrivia(token.LeadingTrivia); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, token.Raw); err != nil {
+			return err
+		}
+		if err := writeTrivia(token.TrailingTrivia); err != nil {
+			return err
+		}
+	}
+	return writeTrivia(endTrivia)
+}
@@ -0,0 +1,218 @@
+package lsp
+
+import (
+	. "coral-lang/src/analyzer"
+	"coral-lang/src/exception"
+	. "coral-lang/src/lexer"
+	. "coral-lang/src/parser"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 编辑器中打开的一个文档，其内容以编辑器中的为准，不必已经保存
+type Document struct {
+	URI     string
+	Path    string // URI 所对应的文件路径，引入的模块相对于它查找；不是 file URI 时即 URI 本身
+	Version int
+	Content []byte
+
+	Analyzer *Analyzer // 最近一次解析与检查的结果，有语法错误时也照常检查能解析出的部分
+
+	lineStarts []int // @private 每一行起始处的字节偏移量
+}
+
+func NewDocument(uri string, version int, content []byte) *Document {
+	document := &Document{URI: uri, Path: uriToPath(uri), Version: version}
+	document.setContent(content)
+	document.Analyze()
+	return document
+}
+
+func uriToPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(parsed.Path)
+}
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func (document *Document) setContent(content []byte) {
+	document.Content = content
+	document.lineStarts = []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			document.lineStarts = append(document.lineStarts, i+1)
+		}
+	}
+}
+
+// 以改动更新文档内容，改动的范围以改动之前的内容计
+func (document *Document) ApplyChange(change TextDocumentContentChangeEvent) {
+	if change.Range == nil {
+		document.setContent([]byte(change.Text))
+		return
+	}
+	start, end := document.OffsetOf(change.Range.Start), document.OffsetOf(change.Range.End)
+	if end < start {
+		start, end = end, start
+	}
+	content := make([]byte, 0, len(document.Content)-(end-start)+len(change.Text))
+	content = append(content, document.Content[:start]...)
+	content = append(content, change.Text...)
+	content = append(content, document.Content[end:]...)
+	document.setContent(content)
+}
+
+// 重新解析与检查整个文档，引入的模块从磁盘上读入，查找方式与 coral check 相同
+func (document *Document) Analyze() {
+	parser := new(Parser)
+	parser.FileName = document.Path
+	parser.InitFromBytes(document.Content)
+	analyzer := new(Analyzer)
+	analyzer.InitAnalyzerFromParser(parser)
+	analyzer.Loader = NewModuleLoader(append([]string{filepath.Dir(document.Path)}, filepath.SplitList(os.Getenv("CORAL_PATH"))...))
+	analyzer.CheckProgram()
+	document.Analyzer = analyzer
+}
+
+// 本文档中的诊断信息，引入的模块中的错误报告在引入语句处，不在此列出
+func (document *Document) Diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, diagnostic := range document.Analyzer.Diagnostics {
+		if diagnostic.File != document.Path {
+			continue
+		}
+		converted := Diagnostic{
+			Range:    Range{Start: document.positionAt(diagnostic.Start), End: document.positionAt(diagnostic.End)},
+			Severity: DiagnosticSeverityError,
+			Code:     diagnostic.Code,
+			Source:   "coral",
+			Message:  diagnostic.Message,
+		}
+		if diagnostic.Severity == exception.SeverityWarning {
+			converted.Severity = DiagnosticSeverityWarning
+		}
+		for _, note := range diagnostic.Notes {
+			location := Location{URI: pathToURI(note.File)}
+			if note.File == document.Path {
+				location.URI = document.URI
+				location.Range = Range{Start: document.positionAt(note.Start), End: document.positionAt(note.End)}
+			} else {
+				// 其他文件的内容不在手边，列号按字符计，只在非 ASCII 字符之后有偏差
+				location.Range = Range{
+					Start: Position{Line: note.Start.Line - 1, Character: note.Start.Col - 1},
+					End:   Position{Line: note.End.Line - 1, Character: note.End.Col - 1},
+				}
+			}
+			converted.RelatedInformation = append(converted.RelatedInformation, DiagnosticRelatedInformation{
+				Location: location, Message: note.Message,
+			})
+		}
+		diagnostics = append(diagnostics, converted)
+	}
+	return diagnostics
+}
+
+// 协议中的位置对应的字节偏移量，超出行尾或文档末尾时取行尾或文档末尾
+func (document *Document) OffsetOf(position Position) int {
+	if position.Line < 0 {
+		return 0
+	}
+	if position.Line >= len(document.lineStarts) {
+		return len(document.Content)
+	}
+	offset, end := document.lineStarts[position.Line], document.lineEnd(position.Line)
+	for units := 0; offset < end && units < position.Character; {
+		r, size := utf8.DecodeRune(document.Content[offset:])
+		units += utf16.RuneLen(r)
+		if units > position.Character && r >= 0x10000 {
+			break // 不落在代理对的中间
+		}
+		offset += size
+	}
+	return offset
+}
+
+// 字节偏移量在协议中的位置
+func (document *Document) PositionOf(offset int) Position {
+	line := sort.Search(len(document.lineStarts), func(i int) bool {
+		return document.lineStarts[i] > offset
+	}) - 1
+	character := 0
+	for _, r := range string(document.Content[document.lineStarts[line]:offset]) {
+		character += utf16.RuneLen(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// 诊断信息中的位置（行号列号从 1 开始，列号以字符计）在协议中的位置
+func (document *Document) positionAt(position exception.Position) Position {
+	line := position.Line - 1
+	if line < 0 || line >= len(document.lineStarts) {
+		return Position{Line: line, Character: position.Col - 1}
+	}
+	offset, end := document.lineStarts[line], document.lineEnd(line)
+	for col := 1; col < position.Col && offset < end; col++ {
+		_, size := utf8.DecodeRune(document.Content[offset:])
+		offset += size
+	}
+	return document.PositionOf(offset)
+}
+
+// 第 line 行（从 0 开始）行尾的字节偏移量，不含换行符
+func (document *Document) lineEnd(line int) int {
+	if line+1 < len(document.lineStarts) {
+		return document.lineStarts[line+1] - 1
+	}
+	return len(document.Content)
+}
+
+func (document *Document) rangeOf(start, end Pos) Range {
+	return Range{Start: document.PositionOf(start.Offset), End: document.PositionOf(end.Offset)}
+}
+
+// 光标所在处的标识符前缀，即光标之前紧挨着的标识符字符
+func (document *Document) wordBefore(offset int) string {
+	start := offset
+	for start > 0 {
+		r, size := utf8.DecodeLastRune(document.Content[:start])
+		if !isIdentifierRune(r) {
+			break
+		}
+		start -= size
+	}
+	return string(document.Content[start:offset])
+}
+func isIdentifierRune(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= utf8.RuneSelf
+}
+
+// 以 . 分隔的一串名称，如 this.a.b；中间夹有调用、下标等时返回 nil
+func (document *Document) nameChainBefore(offset int) []string {
+	var chain []string
+	for {
+		name := document.wordBefore(offset)
+		if name == "" {
+			return nil
+		}
+		chain = append([]string{name}, chain...)
+		offset -= len(name)
+		before := strings.TrimRight(string(document.Content[:offset]), " \t")
+		if !strings.HasSuffix(before, ".") {
+			return chain
+		}
+		offset = len(before) - 1
+		offset = len(strings.TrimRight(string(document.Content[:offset]), " \t"))
+	}
+}
@@ -0,0 +1,546 @@
+package lsp
+
+import (
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/lexer"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// 跳转到定义：光标处的名称所指的符号在哪里定义，内建符号没有定义的位置，返回 null
+func (server *Server) definition(params json.RawMessage) (interface{}, error) {
+	var position TextDocumentPositionParams
+	if err := json.Unmarshal(params, &position); err != nil {
+		return nil, err
+	}
+	document, err := server.document(position.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	symbol, _ := document.symbolAt(document.OffsetOf(position.Position))
+	if symbol == nil {
+		return nil, nil
+	}
+	return document.definitionOf(symbol), nil
+}
+
+// 查找引用：此文档及其引入的模块中的引用，引入了此文档的其他文件不在此列
+func (server *Server) references(params json.RawMessage) (interface{}, error) {
+	var reference ReferenceParams
+	if err := json.Unmarshal(params, &reference); err != nil {
+		return nil, err
+	}
+	document, err := server.document(reference.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	locations := []Location{}
+	symbol, _ := document.symbolAt(document.OffsetOf(reference.Position))
+	if symbol == nil {
+		return locations, nil
+	}
+	if reference.Context.IncludeDeclaration {
+		if definition := document.definitionOf(symbol); definition != nil {
+			locations = append(locations, *definition)
+		}
+	}
+	for _, module := range document.modules() {
+		text := document.textOf(module)
+		for identifier, referred := range module.Analyzer.References {
+			if sameSymbol(referred, symbol) {
+				locations = append(locations, Location{URI: text.URI, Range: text.rangeOf(identifier.Start, identifier.End)})
+			}
+		}
+	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		if locations[i].URI != locations[j].URI {
+			return locations[i].URI < locations[j].URI
+		}
+		a, b := locations[i].Range.Start, locations[j].Range.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+	})
+	return locations, nil
+}
+
+// 悬停提示：以 Coral 的写法展示光标处的名称所指的符号
+func (server *Server) hover(params json.RawMessage) (interface{}, error) {
+	var position TextDocumentPositionParams
+	if err := json.Unmarshal(params, &position); err != nil {
+		return nil, err
+	}
+	document, err := server.document(position.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	symbol, name := document.symbolAt(document.OffsetOf(position.Position))
+	if symbol == nil {
+		return nil, nil
+	}
+	nameRange := document.rangeOf(name.Start, name.End)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```coral\n" + document.describe(symbol, name.Str) + "\n```"},
+		Range:    &nameRange,
+	}, nil
+}
+
+// 补全：. 之后补全成员，否则补全光标处可见的名称与关键字
+func (server *Server) completion(params json.RawMessage) (interface{}, error) {
+	var position TextDocumentPositionParams
+	if err := json.Unmarshal(params, &position); err != nil {
+		return nil, err
+	}
+	document, err := server.document(position.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	offset := document.OffsetOf(position.Position)
+	prefix := document.wordBefore(offset)
+	var items []CompletionItem
+	before := strings.TrimRight(string(document.Content[:offset-len(prefix)]), " \t")
+	if strings.HasSuffix(before, ".") {
+		if chain := document.nameChainBefore(len(before) - 1); chain != nil {
+			items = document.memberCompletions(chain, offset)
+		}
+	} else {
+		items = append(document.scopeCompletions(offset), keywordCompletions()...)
+	}
+
+	list := &CompletionList{Items: []CompletionItem{}}
+	for _, item := range items {
+		if strings.HasPrefix(item.Label, prefix) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	sort.SliceStable(list.Items, func(i, j int) bool {
+		return list.Items[i].Label < list.Items[j].Label
+	})
+	return list, nil
+}
+
+// 光标处的名称所指的符号，以及该名称的 Token；光标在名称的末尾也算在其中
+func (document *Document) symbolAt(offset int) (ISymbol, *Token) {
+	for identifier, symbol := range document.Analyzer.References {
+		if identifier.Start.Offset <= offset && offset <= identifier.End.Offset {
+			return symbol, identifier.Token
+		}
+	}
+	for token, symbol := range document.Analyzer.Definitions {
+		if token.Start.Offset <= offset && offset <= token.End.Offset {
+			return symbol, token
+		}
+	}
+	return nil, nil
+}
+
+// 两个符号是否为同一个：继承的泛型成员是代入了实参的副本，与原来的符号有着相同的 Token
+func sameSymbol(a, b ISymbol) bool {
+	return a == b || a.GetToken() != nil && a.GetToken() == b.GetToken()
+}
+
+// 符号定义的位置，可能在引入的模块中
+func (document *Document) definitionOf(symbol ISymbol) *Location {
+	token := symbol.GetToken()
+	if token == nil {
+		return nil
+	}
+	for _, module := range document.modules() {
+		if _, defined := module.Analyzer.Definitions[token]; defined {
+			text := document.textOf(module)
+			return &Location{URI: text.URI, Range: text.rangeOf(token.Start, token.End)}
+		}
+	}
+	return nil
+}
+
+// 本次检查所加载的全部模块，包括此文档本身，按路径排列
+func (document *Document) modules() []*LoadedModule {
+	var modules []*LoadedModule
+	loaded := false
+	for _, module := range document.Analyzer.Loader.Modules {
+		modules = append(modules, module)
+		loaded = loaded || module.Analyzer == document.Analyzer
+	}
+	if !loaded {
+		modules = append(modules, &LoadedModule{Path: document.Path, Analyzer: document.Analyzer})
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Path < modules[j].Path
+	})
+	return modules
+}
+
+// 模块的源代码，用于换算位置；此文档本身即编辑器中的内容
+func (document *Document) textOf(module *LoadedModule) *Document {
+	if module.Analyzer == document.Analyzer {
+		return document
+	}
+	text := &Document{URI: pathToURI(module.Path), Path: module.Path}
+	text.setContent(module.Analyzer.GetParser().Lexer.Content)
+	return text
+}
+
+// 以 Coral 的写法描述符号，name 为引用它时所用的名称
+func (document *Document) describe(symbol ISymbol, name string) string {
+	switch symbol := symbol.(type) {
+	case *IdSymbol:
+		var modifiers string
+		if symbol.Private {
+			modifiers += "private "
+		}
+		if symbol.Static {
+			modifiers += "static "
+		}
+		if symbol.Owner != nil {
+			name = symbol.Owner.Token.Str + "." + name
+		}
+		if moduleType, isModule := symbol.Type.(*ModuleType); isModule {
+			return moduleType.String()
+		}
+		if fnType, isFunction := symbol.Type.(*FunctionType); isFunction && document.isFunction(symbol) {
+			return modifiers + "fn " + name + strings.TrimPrefix(fnType.String(), "fn")
+		}
+		typeName := Unknown.String()
+		if symbol.Type != nil {
+			typeName = symbol.Type.String()
+		}
+		if symbol.Immutable {
+			if symbol.Constant != nil {
+				return modifiers + "val " + name + " " + typeName + " = " + symbol.Constant.String()
+			}
+			return modifiers + "val " + name + " " + typeName
+		}
+		return modifiers + "var " + name + " " + typeName
+	case *TypeSymbol:
+		switch symbolType := symbol.Type.(type) {
+		case *ClassType:
+			description := (&StaticType{Class: symbolType}).String()
+			if symbolType.Base != nil {
+				description += " : " + symbolType.Base.String()
+			}
+			return description
+		case *TypeParamType:
+			if symbolType.Constraint != nil {
+				return "type " + name + " : " + symbolType.Constraint.String()
+			}
+			return "type " + name
+		}
+		return "type " + symbol.Type.String()
+	case *EnumSymbol:
+		elements := make([]*EnumElement, 0, len(symbol.ElementsMap))
+		for _, element := range symbol.ElementsMap {
+			elements = append(elements, element)
+		}
+		sort.Slice(elements, func(i, j int) bool {
+			return elements[i].Name.Start.Offset < elements[j].Name.Start.Offset
+		})
+		names := make([]string, len(elements))
+		for i, element := range elements {
+			names[i] = element.Name.GetName()
+		}
+		return "enum " + symbol.QualifiedName() + " { " + strings.Join(names, ", ") + " }"
+	}
+	return name
+}
+
+// 以 fn 定义的函数与方法，区别于类型为函数的变量；内建函数没有 Token
+func (document *Document) isFunction(symbol *IdSymbol) bool {
+	if symbol.Token == nil {
+		return true
+	}
+	for node, defined := range document.Analyzer.Symbols {
+		switch node.(type) {
+		case *FunctionDeclarationStatement, *InterfaceMethodDeclaration:
+			if sameSymbol(defined, symbol) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 光标所在的最内层作用域
+func (document *Document) scopeAt(offset int) *BlockScope {
+	scope, size := document.Analyzer.RootScope, -1
+	for node, nodeScope := range document.Analyzer.Scopes {
+		span := node.GetSpan()
+		inside := span.Start.Offset < offset && offset <= span.End.Offset
+		if _, isBlock := node.(*BlockStatement); isBlock {
+			inside = span.Start.Offset < offset && offset < span.End.Offset // 不在右花括号之后
+		}
+		if inside && (size < 0 || span.End.Offset-span.Start.Offset < size) {
+			scope, size = nodeScope, span.End.Offset-span.Start.Offset
+		}
+	}
+	return scope
+}
+
+// 成员作用域为 scope 的类或接口，scope 不是成员作用域时返回 nil
+func (document *Document) classOf(scope *BlockScope) *TypeSymbol {
+	for _, symbol := range document.Analyzer.Symbols {
+		if typeSymbol, isType := symbol.(*TypeSymbol); isType && typeSymbol.Members == scope {
+			return typeSymbol
+		}
+	}
+	return nil
+}
+
+// 光标处可见的名称：由内向外各层作用域中定义的符号，类的方法中还有继承自父类的成员
+func (document *Document) scopeCompletions(offset int) []CompletionItem {
+	var items []CompletionItem
+	seen := make(map[string]bool)
+	add := func(name string, symbol ISymbol) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		items = append(items, CompletionItem{Label: name, Kind: completionKind(symbol), Detail: document.describe(symbol, name)})
+	}
+	for scope := document.scopeAt(offset); scope != nil; scope = scope.OuterScope {
+		class := document.classOf(scope)
+		for _, name := range sortedNames(scope) {
+			symbol := scope.SymbolMap[name]
+			if document.declaredAfter(symbol, scope, offset) || class != nil && name == class.Token.Str {
+				continue // 尚未定义的局部变量，以及构造方法
+			}
+			add(name, symbol)
+		}
+		if class == nil {
+			continue
+		}
+		if classType, isClass := class.Type.(*ClassType); isClass {
+			for base := classType.BaseType(); base != nil; base = base.BaseType() {
+				for _, member := range document.members(base, false, false) {
+					add(member.Token.Str, member)
+				}
+			}
+		}
+	}
+	return items
+}
+
+// 局部变量在定义之后才可见，而函数、类与顶层的定义在整个区块中可见
+func (document *Document) declaredAfter(symbol ISymbol, scope *BlockScope, offset int) bool {
+	idSymbol, isId := symbol.(*IdSymbol)
+	if !isId || idSymbol.Owner != nil || scope == document.Analyzer.RootScope || idSymbol.Token == nil {
+		return false
+	}
+	if _, isFunction := idSymbol.Type.(*FunctionType); isFunction && document.isFunction(idSymbol) {
+		return false
+	}
+	_, inDocument := document.Analyzer.Definitions[idSymbol.Token]
+	return inDocument && idSymbol.Token.Start.Offset > offset
+}
+
+// 补全 a.b. 之后的成员：沿着名称链逐个查找成员的类型
+func (document *Document) memberCompletions(chain []string, offset int) []CompletionItem {
+	scope := document.scopeAt(offset)
+	var enclosing *TypeSymbol // 光标所在的类，其私有成员可见
+	for current := scope; current != nil && enclosing == nil; current = current.OuterScope {
+		enclosing = document.classOf(current)
+	}
+
+	var ownerType Type
+	switch chain[0] {
+	case "this", "super":
+		if enclosing == nil {
+			return nil
+		}
+		classType, isClass := enclosing.Type.(*ClassType)
+		if !isClass {
+			return nil
+		}
+		ownerType = classType
+		if chain[0] == "super" {
+			if classType.BaseType() == nil {
+				return nil
+			}
+			ownerType = classType.BaseType()
+		}
+	default:
+		symbol := document.lookup(scope, chain[0])
+		if symbol == nil {
+			return nil
+		}
+		ownerType = valueType(symbol)
+	}
+	for _, name := range chain[1:] {
+		if ownerType == nil {
+			return nil
+		}
+		ownerType = memberValueType(ownerType, name)
+	}
+
+	var items []CompletionItem
+	addSymbol := func(name string, symbol ISymbol) {
+		items = append(items, CompletionItem{Label: name, Kind: completionKind(symbol), Detail: document.describe(symbol, name)})
+	}
+	switch ownerType := ownerType.(type) {
+	case *ClassType:
+		for _, member := range document.members(ownerType, false, ownerType.Symbol == enclosing) {
+			addSymbol(member.Token.Str, member)
+		}
+	case *StaticType:
+		for _, member := range document.members(ownerType.Class, true, ownerType.Class.Symbol == enclosing) {
+			addSymbol(member.Token.Str, member)
+		}
+	case *TypeParamType:
+		if ownerType.Constraint != nil {
+			for _, member := range document.members(ownerType.Constraint, false, false) {
+				addSymbol(member.Token.Str, member)
+			}
+		}
+	case *EnumType:
+		for name := range ownerType.Symbol.ElementsMap {
+			items = append(items, CompletionItem{Label: name, Kind: CompletionKindEnumMember, Detail: ownerType.String()})
+		}
+	case *ArrayType:
+		items = append(items, CompletionItem{Label: "length", Kind: CompletionKindField, Detail: "var length int"})
+	case *ModuleType:
+		if ownerType.Module != nil {
+			for _, name := range sortedNames(ownerType.Module.Analyzer.RootScope) {
+				addSymbol(name, ownerType.Module.Analyzer.RootScope.SymbolMap[name])
+			}
+		}
+	default:
+		if ownerType == StringType {
+			items = append(items, CompletionItem{Label: "length", Kind: CompletionKindField, Detail: "var length int"})
+		}
+	}
+	return items
+}
+
+// 由内向外查找名称，类的成员作用域中还要查找继承自父类的成员
+func (document *Document) lookup(scope *BlockScope, name string) ISymbol {
+	for current := scope; current != nil; current = current.OuterScope {
+		class := document.classOf(current)
+		if symbol, ok := current.SymbolMap[name]; ok && (class == nil || name != class.Token.Str) {
+			return symbol
+		}
+		if class == nil {
+			continue
+		}
+		if classType, isClass := class.Type.(*ClassType); isClass && classType.BaseType() != nil {
+			if member := classType.BaseType().Member(name); member != nil {
+				return member
+			}
+		}
+	}
+	return nil
+}
+
+// 类型的成员（包括继承的成员），不含构造方法；static 指定只取静态成员还是只取实例成员
+func (document *Document) members(classType *ClassType, static bool, private bool) []*IdSymbol {
+	var members []*IdSymbol
+	seen := make(map[string]bool)
+	for current := classType; current != nil; current = current.BaseType() {
+		if current.Symbol.Members == nil {
+			continue
+		}
+		for _, name := range sortedNames(current.Symbol.Members) {
+			member, isId := current.Symbol.Members.SymbolMap[name].(*IdSymbol)
+			if !isId || seen[name] || name == current.Symbol.Token.Str || member.Static != static ||
+				member.Private && !(private && current == classType) {
+				continue
+			}
+			seen[name] = true
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// 以名称引用符号时所得的值的类型：类名即其静态成员所在的类型
+func valueType(symbol ISymbol) Type {
+	switch symbol := symbol.(type) {
+	case *IdSymbol:
+		return symbol.Type
+	case *TypeSymbol:
+		if classType, isClass := symbol.Type.(*ClassType); isClass {
+			return &StaticType{Class: classType}
+		}
+	case *EnumSymbol:
+		return &EnumType{Symbol: symbol}
+	}
+	return nil
+}
+func memberValueType(ownerType Type, name string) Type {
+	switch ownerType := ownerType.(type) {
+	case *ClassType:
+		if member := ownerType.Member(name); member != nil {
+			return member.Type
+		}
+	case *StaticType:
+		if member := ownerType.Class.Member(name); member != nil {
+			return member.Type
+		}
+	case *TypeParamType:
+		if ownerType.Constraint != nil {
+			if member := ownerType.Constraint.Member(name); member != nil {
+				return member.Type
+			}
+		}
+	case *ModuleType:
+		if ownerType.Module != nil {
+			if symbol := ownerType.Module.Lookup(name); symbol != nil {
+				return valueType(symbol)
+			}
+		}
+	}
+	return nil
+}
+
+func sortedNames(scope *BlockScope) []string {
+	names := make([]string, 0, len(scope.SymbolMap))
+	for name := range scope.SymbolMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func completionKind(symbol ISymbol) int {
+	switch symbol := symbol.(type) {
+	case *IdSymbol:
+		_, isFunction := symbol.Type.(*FunctionType)
+		_, isModule := symbol.Type.(*ModuleType)
+		switch {
+		case isFunction && symbol.Owner != nil:
+			return CompletionKindMethod
+		case isFunction:
+			return CompletionKindFunction
+		case isModule:
+			return CompletionKindModule
+		case symbol.Owner != nil:
+			return CompletionKindField
+		case symbol.Immutable:
+			return CompletionKindConstant
+		}
+		return CompletionKindVariable
+	case *TypeSymbol:
+		switch symbolType := symbol.Type.(type) {
+		case *ClassType:
+			if symbolType.IsInterface {
+				return CompletionKindInterface
+			}
+		case *TypeParamType:
+			return CompletionKindTypeParameter
+		}
+		return CompletionKindClass
+	case *EnumSymbol:
+		return CompletionKindEnum
+	}
+	return CompletionKindVariable
+}
+
+// 关键字即词法分析器的关键字映射表中的全部名称
+func keywordCompletions() []CompletionItem {
+	lexer := new(Lexer)
+	lexer.InitFromString("")
+	var items []CompletionItem
+	for keyword := range lexer.KeywordMap {
+		items = append(items, CompletionItem{Label: keyword, Kind: CompletionKindKeyword})
+	}
+	return items
+}
@@ -0,0 +1,234 @@
+package lsp
+
+import (
+	"bufio"
+	"coral-lang/src/exception"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// Package lsp 实现了 coral lsp：以标准输入输出通信的 Language Server Protocol 服务器
+// 文档每次改动后重新解析与检查并发布诊断信息，并以语义分析得出的符号表回答跳转到定义、查找引用、悬停提示与补全请求
+
+const ServerName = "coral-lsp"
+
+type Server struct {
+	out io.Writer // 发往客户端的消息
+	log io.Writer // 服务器自身的日志，不属于协议的一部分
+
+	documents   map[string]*Document // 以 URI 为键的已打开的文档
+	initialized bool                 // @private 是否已经收到 initialize 请求
+	shutdown    bool                 // @private 是否已经收到 shutdown 请求，此后只接受 exit
+}
+
+func NewServer(out, log io.Writer) *Server {
+	return &Server{
+		out:       out,
+		log:       log,
+		documents: make(map[string]*Document),
+	}
+}
+
+// 从 in 中逐条读入消息并处理，直到收到 exit 通知或者输入结束
+// 先收到 shutdown 请求再退出时返回 NormalError，否则返回 LanguageServerError
+func (server *Server) Run(in io.Reader) int {
+	reader := bufio.NewReader(in)
+	for {
+		content, err := readMessage(reader)
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(server.log, "%s: %s\n", ServerName, err)
+			}
+			return exception.LanguageServerError
+		}
+		var request requestMessage
+		if err := json.Unmarshal(content, &request); err != nil {
+			server.replyError(nil, ParseErrorCode, err.Error())
+			continue
+		}
+		if request.Method == "exit" {
+			if server.shutdown {
+				return exception.NormalError
+			}
+			return exception.LanguageServerError
+		}
+		server.handle(&request)
+	}
+}
+
+// 读入一条以 Content-Length 头部标明长度的消息
+func readMessage(reader *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header %q", header.Get("Content-Length"))
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (server *Server) writeMessage(message interface{}) {
+	content, err := json.Marshal(message)
+	if err != nil {
+		fmt.Fprintf(server.log, "%s: %s\n", ServerName, err)
+		return
+	}
+	fmt.Fprintf(server.out, "Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+func (server *Server) reply(id json.RawMessage, result interface{}) {
+	content, err := json.Marshal(result)
+	if err != nil {
+		server.replyError(id, InternalErrorCode, err.Error())
+		return
+	}
+	server.writeMessage(&responseMessage{JSONRPC: "2.0", ID: id, Result: content})
+}
+func (server *Server) replyError(id json.RawMessage, code int, message string) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	server.writeMessage(&responseMessage{JSONRPC: "2.0", ID: id, Error: &ResponseError{Code: code, Message: message}})
+}
+func (server *Server) notify(method string, params interface{}) {
+	server.writeMessage(&notificationMessage{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// 请求的处理函数，返回值即响应的 result
+type requestHandler func(server *Server, params json.RawMessage) (interface{}, error)
+
+// 通知的处理函数，通知没有响应
+type notificationHandler func(server *Server, params json.RawMessage) error
+
+var requestHandlers = map[string]requestHandler{
+	"initialize":              (*Server).initialize,
+	"shutdown":                (*Server).shutdownRequest,
+	"textDocument/definition": (*Server).definition,
+	"textDocument/references": (*Server).references,
+	"textDocument/hover":      (*Server).hover,
+	"textDocument/completion": (*Server).completion,
+}
+
+var notificationHandlers = map[string]notificationHandler{
+	"initialized":            nil,
+	"textDocument/didOpen":   (*Server).didOpen,
+	"textDocument/didChange": (*Server).didChange,
+	"textDocument/didClose":  (*Server).didClose,
+}
+
+// 按方法名分派消息；处理途中的错误与崩溃只影响这一条消息，服务器照常运行
+func (server *Server) handle(request *requestMessage) {
+	isRequest := request.ID != nil
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(server.log, "%s: panic while handling %s: %v\n", ServerName, request.Method, r)
+			if isRequest {
+				server.replyError(request.ID, InternalErrorCode, fmt.Sprint(r))
+			}
+		}
+	}()
+
+	if !isRequest {
+		handler, ok := notificationHandlers[request.Method]
+		if !ok || handler == nil || !server.initialized {
+			return // 不认识的通知以及初始化之前的通知一律忽略
+		}
+		if err := handler(server, request.Params); err != nil {
+			fmt.Fprintf(server.log, "%s: %s: %s\n", ServerName, request.Method, err)
+		}
+		return
+	}
+
+	handler, ok := requestHandlers[request.Method]
+	switch {
+	case !ok:
+		server.replyError(request.ID, MethodNotFoundCode, fmt.Sprintf("method not found: %s", request.Method))
+	case !server.initialized && request.Method != "initialize":
+		server.replyError(request.ID, ServerNotInitializedCode, "server not initialized")
+	case server.shutdown:
+		server.replyError(request.ID, InvalidRequestCode, "server is shutting down")
+	default:
+		result, err := handler(server, request.Params)
+		if err != nil {
+			server.replyError(request.ID, InvalidParamsCode, err.Error())
+			return
+		}
+		server.reply(request.ID, result)
+	}
+}
+
+func (server *Server) initialize(params json.RawMessage) (interface{}, error) {
+	server.initialized = true
+	result := new(InitializeResult)
+	result.ServerInfo.Name = ServerName
+	result.Capabilities.TextDocumentSync = TextDocumentSyncOptions{OpenClose: true, Change: TextDocumentSyncIncremental}
+	result.Capabilities.DefinitionProvider = true
+	result.Capabilities.ReferencesProvider = true
+	result.Capabilities.HoverProvider = true
+	result.Capabilities.CompletionProvider.TriggerCharacters = []string{"."}
+	return result, nil
+}
+func (server *Server) shutdownRequest(params json.RawMessage) (interface{}, error) {
+	server.shutdown = true
+	return nil, nil
+}
+
+func (server *Server) didOpen(params json.RawMessage) error {
+	var open DidOpenTextDocumentParams
+	if err := json.Unmarshal(params, &open); err != nil {
+		return err
+	}
+	document := NewDocument(open.TextDocument.URI, open.TextDocument.Version, []byte(open.TextDocument.Text))
+	server.documents[document.URI] = document
+	server.publishDiagnostics(document)
+	return nil
+}
+func (server *Server) didChange(params json.RawMessage) error {
+	var change DidChangeTextDocumentParams
+	if err := json.Unmarshal(params, &change); err != nil {
+		return err
+	}
+	document, err := server.document(change.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	for _, event := range change.ContentChanges {
+		document.ApplyChange(event)
+	}
+	document.Version = change.TextDocument.Version
+	document.Analyze()
+	server.publishDiagnostics(document)
+	return nil
+}
+
+// 关闭文档时清除其诊断信息
+func (server *Server) didClose(params json.RawMessage) error {
+	var close DidCloseTextDocumentParams
+	if err := json.Unmarshal(params, &close); err != nil {
+		return err
+	}
+	delete(server.documents, close.TextDocument.URI)
+	server.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: close.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	return nil
+}
+func (server *Server) publishDiagnostics(document *Document) {
+	server.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: document.URI, Diagnostics: document.Diagnostics()})
+}
+
+// 请求所涉及的文档须已打开
+func (server *Server) document(uri string) (*Document, error) {
+	document, ok := server.documents[uri]
+	if !ok {
+		return nil, fmt.Errorf("document %s is not open", uri)
+	}
+	return document, nil
+}
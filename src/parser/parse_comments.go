@@ -0,0 +1,88 @@
+package parser
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/lexer"
+	"strings"
+)
+
+// 文档注释以 /// 或 /** 开头，//// 与 /*** 之类只是普通的注释
+func isDocLineComment(text string) bool {
+	return strings.HasPrefix(text, "///") && !strings.HasPrefix(text, "////")
+}
+func isDocBlockComment(text string) bool {
+	return strings.HasPrefix(text, "/**") && !strings.HasPrefix(text, "/***") && text != "/**/"
+}
+
+// 紧挨在 start 之前的文档注释，没有时返回 nil
+// 注释须独占一行或几行，与 start 之间没有空行；连续的几行 /// 行注释合为一个文档注释
+func (parser *Parser) DocCommentBefore(start Pos) *DocComment {
+	comments, content := parser.Lexer.Comments, parser.Lexer.Content
+	i := len(comments) - 1
+	for i >= 0 && comments[i].Start.Offset >= start.Offset {
+		i-- // 定义之中的注释
+	}
+
+	var lines []*Comment
+	for end := start.Offset; i >= 0; i-- {
+		comment := comments[i]
+		if !adjacentComment(content, comment, end) {
+			break
+		}
+		if isDocBlockComment(comment.Text) && len(lines) == 0 {
+			return &DocComment{Span: Span{Start: comment.Start, End: comment.End}, Text: blockDocText(comment.Text)}
+		}
+		if !isDocLineComment(comment.Text) {
+			break
+		}
+		lines = append([]*Comment{comment}, lines...)
+		end = comment.Start.Offset
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		text := strings.TrimPrefix(strings.TrimRight(line.Text, " \t\r"), "///")
+		texts[i] = strings.TrimPrefix(text, " ")
+	}
+	return &DocComment{
+		Span: Span{Start: lines[0].Start, End: lines[len(lines)-1].End},
+		Text: strings.Join(trimBlankLines(texts), "\n"),
+	}
+}
+
+// 注释独占一行，且与 end 之间只有空白、至多一个换行
+func adjacentComment(content []byte, comment *Comment, end int) bool {
+	between := string(content[comment.End.Offset:end])
+	if strings.TrimSpace(between) != "" || strings.Count(between, "\n") > 1 {
+		return false
+	}
+	lineStart := strings.LastIndexByte(string(content[:comment.Start.Offset]), '\n') + 1
+	return strings.TrimSpace(string(content[lineStart:comment.Start.Offset])) == ""
+}
+
+// 去掉块注释的 /** 与 */，以及每行开头对齐用的 *
+func blockDocText(text string) string {
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "/**"), "*/")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(strings.TrimLeft(line, " \t"), " \t\r")
+		if strings.HasPrefix(line, "*") {
+			line = strings.TrimPrefix(line[1:], " ")
+		}
+		lines[i] = line
+	}
+	return strings.Join(trimBlankLines(lines), "\n")
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -51,7 +51,7 @@ func (parser *Parser) ParseIdentifier(avoidAngleConfusingLater bool) *Identifier
 		return nil
 	}
 
-	identifier := &Identifier{Token: parser.CurrentToken} // 以当前标识符为 operand
+	identifier := &Identifier{Span: TokenSpan(parser.CurrentToken), Token: parser.CurrentToken} // 以当前标识符为 operand
 	if avoidAngleConfusingLater {
 		parser.PeekNextTokenAvoidAngleConfusing()
 	} else {
@@ -68,7 +68,7 @@ func (parser *Parser) ParseIdentifierList() []*Identifier {
 
 	// 先添加传入的 token，已确定其为 identifier
 	var identifierList []*Identifier
-	identifierList = append(identifierList, &Identifier{Token: parser.CurrentToken})
+	identifierList = append(identifierList, &Identifier{Span: TokenSpan(parser.CurrentToken), Token: parser.CurrentToken})
 
 	for {
 		parser.PeekNextToken()
@@ -79,7 +79,7 @@ func (parser *Parser) ParseIdentifierList() []*Identifier {
 		if !parser.MatchCurrentTokenType(TokenTypeIdentifier) { // '.' 后的 Identifier
 			break
 		}
-		identifierList = append(identifierList, &Identifier{Token: parser.CurrentToken})
+		identifierList = append(identifierList, &Identifier{Span: TokenSpan(parser.CurrentToken), Token: parser.CurrentToken})
 	}
 	return identifierList
 }
@@ -87,22 +87,34 @@ func (parser *Parser) ParseIdentifierList() []*Identifier {
 func (parser *Parser) ParseExpression() Expression {
 	// 括号表达式优先级最高
 	if parser.MatchCurrentTokenType(TokenTypeLeftParen) {
-		currentLexerPos := parser.Lexer.BytePos
+		start := parser.startPos()
+		state := parser.saveState()
+		errCount := parser.ErrCount
 		tryLambdaLitExpression := parser.ParsePrimaryExpression() // 由于左圆括号的特殊性 先尝试解析 lambdaLit
 		if tryLambdaLitExpression != nil {
-			if _, isLambda := tryLambdaLitExpression.(*BasicPrimaryExpression).It.(*LambdaLit); isLambda {
-				return parser.TryParseBinaryExpression(tryLambdaLitExpression)
-			}
-		} else {
-			parser.Lexer.BytePos = currentLexerPos // 如果不是 lambda 恢复词法器位置
-			parser.PeekNextToken()                 // 移过左括号
-			inParenExpression := parser.ParseExpression()
-			if !parser.AssertCurrentTokenIs(TokenTypeRightParen,
-				"right parenthesis", "to close a parenthesis expression!") {
-				return nil
-			}
-			return parser.TryParseBinaryExpression(inParenExpression)
+			return parser.TryParseBinaryExpression(tryLambdaLitExpression)
+		}
+		if parser.ErrCount > errCount {
+			return nil // 已经确认是 lambda，只是其中有错
+		}
+
+		parser.restoreState(state) // 不是 lambda，回溯到左括号处
+		parser.PeekNextToken()     // 移过左括号
+		inParenExpression := parser.ParseExpression()
+		if inParenExpression == nil {
+			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+				"expected an expression in parenthesis!", ParsingUnexpected))
+			return nil
+		}
+		if !parser.AssertCurrentTokenIs(TokenTypeRightParen,
+			"right parenthesis", "to close a parenthesis expression!") {
+			return nil
 		}
+		parser.finishNode(inParenExpression, start) // 括号表达式没有单独的节点，括号计入内部表达式的范围
+		if binary, isBinary := inParenExpression.(*BinaryExpression); isBinary {
+			binary.Parenthesized = true
+		}
+		return parser.TryParseBinaryExpression(inParenExpression)
 	}
 
 	if unaryExpression := parser.ParseUnaryExpression(); unaryExpression != nil {
@@ -122,12 +134,12 @@ func (parser *Parser) ParseExpression() Expression {
 func (parser *Parser) ParsePrimaryExpression() PrimaryExpression {
 	literal := parser.ParseLiteral()
 	if literal != nil {
-		return parser.TryEnhancePrimaryExpression(&BasicPrimaryExpression{It: literal})
+		return parser.TryEnhancePrimaryExpression(&BasicPrimaryExpression{Span: *literal.GetSpan(), It: literal})
 	} // 如果 literal 为空则另一种情况
 
 	operandName := parser.ParseOperandName()
 	if operandName != nil {
-		return parser.TryEnhancePrimaryExpression(&BasicPrimaryExpression{It: operandName})
+		return parser.TryEnhancePrimaryExpression(&BasicPrimaryExpression{Span: *operandName.GetSpan(), It: operandName})
 	}
 
 	return nil
@@ -136,6 +148,7 @@ func (parser *Parser) ParsePrimaryExpression() PrimaryExpression {
 // 探寻基本表达式的其他可能性 index/slice/call/member
 func (parser *Parser) TryEnhancePrimaryExpression(basic PrimaryExpression) PrimaryExpression {
 	_, isSlice := basic.(*SliceExpression)
+	start := basic.GetSpan().Start // 增强后的表达式与原表达式起点相同
 
 	// try: slice/index
 	if parser.MatchCurrentTokenType(TokenTypeLeftBracket) {
@@ -152,55 +165,67 @@ func (parser *Parser) TryEnhancePrimaryExpression(basic PrimaryExpression) Prima
 			parser.PeekNextToken()
 			end := parser.ParseExpression()
 			if end == nil {
-				CoralErrorCrashHandler(NewCoralError(parser.GetCurrentTokenPos(),
+				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
 					"expected an expression to be end position for slice!", ParsingUnexpected))
+				return nil
 			}
 			sliceExpr.End = end
 
 			if parser.MatchCurrentTokenType(TokenTypeRightBracket) {
 				parser.PeekNextToken() // 移过 ']'
+				parser.finishNode(sliceExpr, start)
 				return parser.TryEnhancePrimaryExpression(sliceExpr)
 			} else {
-				CoralErrorCrashHandler(NewCoralError(parser.GetCurrentTokenPos(),
+				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
 					"expected an close bracket for slice expression!", ParsingUnexpected))
+				return nil
 			}
 		}
 
-		start := parser.ParseExpression()
-		if start == nil {
-			CoralErrorCrashHandler(NewCoralError(parser.GetCurrentTokenPos(),
+		startExpr := parser.ParseExpression()
+		if startExpr == nil {
+			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
 				"expected an expression to be an index/key or a start position for slice!", ParsingUnexpected))
+			return nil
 		}
 
 		if parser.MatchCurrentTokenType(TokenTypeColon) {
 			sliceExpr := new(SliceExpression)
 			sliceExpr.Operand = basic
-			sliceExpr.Start = start
+			sliceExpr.Start = startExpr
 
 			// 解析切片终点表达式
 			parser.PeekNextToken() // 移过冒号 ':'
 			end := parser.ParseExpression()
 			if end == nil {
-				CoralErrorCrashHandler(NewCoralError(parser.GetCurrentTokenPos(),
+				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
 					"expected an expression to be an end position for slice!", ParsingUnexpected))
+				return nil
 			}
 			sliceExpr.End = end
 
 			if parser.MatchCurrentTokenType(TokenTypeRightBracket) {
 				parser.PeekNextToken()
+				parser.finishNode(sliceExpr, start)
 				return parser.TryEnhancePrimaryExpression(sliceExpr)
 			} else {
-				CoralErrorCrashHandler(NewCoralError(parser.GetCurrentTokenPos(),
+				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
 					"expected an close bracket for slice expression!", ParsingUnexpected))
+				return nil
 			}
 		} else if parser.MatchCurrentTokenType(TokenTypeRightBracket) {
 			// 只有一个表达式就遇到了右括号
 			indexExpr := new(IndexExpression)
 			indexExpr.Operand = basic
-			indexExpr.Index = start
+			indexExpr.Index = startExpr
 
 			parser.PeekNextToken()
+			parser.finishNode(indexExpr, start)
 			return parser.TryEnhancePrimaryExpression(indexExpr)
+		} else {
+			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+				"expected a right bracket for index expression!", ParsingUnexpected))
+			return nil
 		}
 	} else if parser.MatchCurrentTokenType(TokenTypeLeftParen) {
 		// try: call
@@ -224,6 +249,7 @@ func (parser *Parser) TryEnhancePrimaryExpression(basic PrimaryExpression) Prima
 		// 结束循环时，检测是否停留于 token ')'
 		if parser.MatchCurrentTokenType(TokenTypeRightParen) {
 			parser.PeekNextToken()
+			parser.finishNode(callExpression, start)
 			return parser.TryEnhancePrimaryExpression(callExpression)
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -239,12 +265,14 @@ func (parser *Parser) TryEnhancePrimaryExpression(basic PrimaryExpression) Prima
 			cursor := memberExpression.Member // 开始根据得到的 标识符列表构建成员链
 			for i, id := range idList {
 				cursor.It = id
+				cursor.Span = Span{Start: id.Start, End: idList[len(idList)-1].End} // 由此往后的整段成员链
 				if i != len(idList)-1 {
 					cursor.MemberNext = new(MemberLinkNode) // 结链
 					cursor = cursor.MemberNext              // -> next
 				}
 			}
 
+			parser.finishNode(memberExpression, start)
 			return parser.TryEnhancePrimaryExpression(memberExpression)
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -258,8 +286,9 @@ func (parser *Parser) TryEnhancePrimaryExpression(basic PrimaryExpression) Prima
 
 func (parser *Parser) ParseTableElement() *TableElement {
 	if parser.MatchCurrentTokenType(TokenTypeIdentifier) {
+		start := parser.startPos()
 		tableElement := new(TableElement)
-		tableElement.Key = &Identifier{Token: parser.CurrentToken}
+		tableElement.Key = &Identifier{Span: TokenSpan(parser.CurrentToken), Token: parser.CurrentToken}
 		parser.PeekNextToken() // 移过标识符
 
 		if !parser.AssertCurrentTokenIs(TokenTypeColon, "a colon",
@@ -268,6 +297,7 @@ func (parser *Parser) ParseTableElement() *TableElement {
 		}
 		if value := parser.ParseExpression(); value != nil {
 			tableElement.Value = value
+			parser.finishNode(tableElement, start)
 			return tableElement
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -282,29 +312,31 @@ func (parser *Parser) ParseTableElement() *TableElement {
 // 解析 operand 的 literal 情况
 func (parser *Parser) ParseLiteral() Literal {
 	if parser.CurrentToken != nil {
+		start := parser.startPos()
 		switch parser.CurrentToken.Kind {
 		case TokenTypeString:
 			defer parser.PeekNextToken()
-			return &StringLit{Value: parser.CurrentToken}
+			return &StringLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeRune:
 			defer parser.PeekNextToken()
-			return &RuneLit{Value: parser.CurrentToken}
+			return &RuneLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeDecimalInteger:
 			defer parser.PeekNextToken()
-			return &DecimalLit{Value: parser.CurrentToken}
+			return &DecimalLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeHexadecimalInteger:
 			defer parser.PeekNextToken()
-			return &HexadecimalLit{Value: parser.CurrentToken}
+			return &HexadecimalLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeOctalInteger:
 			defer parser.PeekNextToken()
-			return &OctalLit{Value: parser.CurrentToken}
+			return &OctalLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeBinaryInteger:
 			defer parser.PeekNextToken()
-			return &BinaryLit{Value: parser.CurrentToken}
+			return &BinaryLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeFloat:
 			defer parser.PeekNextToken()
 			valueToken := parser.CurrentToken
 			floatLit := new(FloatLit)
+			floatLit.Span = TokenSpan(valueToken)
 			floatLit.Value = valueToken
 			if reg := regexp.MustCompile(`\.(\d+)$`); len(reg.FindString(parser.CurrentToken.Str))-1 > 6 && len(reg.FindString(parser.CurrentToken.Str))-1 <= 15 {
 				floatLit.Accuracy = 15
@@ -314,16 +346,16 @@ func (parser *Parser) ParseLiteral() Literal {
 			return floatLit
 		case TokenTypeExponent:
 			defer parser.PeekNextToken()
-			return &ExponentLit{Value: parser.CurrentToken}
+			return &ExponentLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeNil:
 			defer parser.PeekNextToken()
-			return &NilLit{Value: parser.CurrentToken}
+			return &NilLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeTrue:
 			defer parser.PeekNextToken()
-			return &TrueLit{Value: parser.CurrentToken}
+			return &TrueLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeFalse:
 			defer parser.PeekNextToken()
-			return &FalseLit{Value: parser.CurrentToken}
+			return &FalseLit{Span: TokenSpan(parser.CurrentToken), Value: parser.CurrentToken}
 		case TokenTypeLeftBracket:
 			parser.PeekNextToken() // 移过 '['
 			var expressionList []Expression
@@ -340,7 +372,9 @@ func (parser *Parser) ParseLiteral() Literal {
 				"to close the array literal value!") {
 				return nil
 			}
-			return &ArrayLit{ValueList: expressionList}
+			arrayLit := &ArrayLit{ValueList: expressionList}
+			parser.finishNode(arrayLit, start)
+			return arrayLit
 		case TokenTypeLeftBrace:
 			parser.PeekNextToken() // 移过 '{'
 			var elements []*TableElement
@@ -357,7 +391,9 @@ func (parser *Parser) ParseLiteral() Literal {
 				"in map literal definition!") {
 				return nil
 			}
-			return &TableLit{KeyValueList: elements}
+			tableLit := &TableLit{KeyValueList: elements}
+			parser.finishNode(tableLit, start)
+			return tableLit
 		case TokenTypeLeftParen, TokenTypeLeftAngle:
 			if signature := parser.ParseSignature(true, true); signature != nil {
 				lambdaLit := new(LambdaLit)
@@ -366,31 +402,32 @@ func (parser *Parser) ParseLiteral() Literal {
 					parser.PeekNextToken() // 移过尖头
 					if lambdaBlock := parser.ParseBlockStatement(); lambdaBlock != nil {
 						lambdaLit.Result = lambdaBlock
+						parser.finishNode(lambdaLit, start)
 						return lambdaLit
 					} else if lambdaExpr := parser.ParseExpression(); lambdaExpr != nil {
 						lambdaLit.Result = lambdaExpr
+						parser.finishNode(lambdaLit, start)
 						return lambdaLit
 					} else {
 						CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
 							"expected a block for lambda lambda function!", ParsingUnexpected))
 						return nil
 					}
-				} else {
-					CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
-						"expected a right arrow '->' for lambda function!", ParsingUnexpected))
-					return nil
 				}
+				// 没有右箭头：只是形似函数签名（如括号表达式），并非 lambda
 			}
 			return nil
 		case TokenTypeThis:
 			defer parser.PeekNextToken()
 			return &ThisLit{
+				Span:      TokenSpan(parser.CurrentToken),
 				Token:     parser.CurrentToken,
 				BelongsTo: nil,
 			}
 		case TokenTypeSuper:
 			defer parser.PeekNextToken()
 			return &SuperLit{
+				Span:      TokenSpan(parser.CurrentToken),
 				Token:     parser.CurrentToken,
 				BelongsTo: nil,
 			}
@@ -410,6 +447,7 @@ func (parser *Parser) ParseOperandName() *OperandName {
 	operandName := new(OperandName)
 	identifier := parser.ParseIdentifier(false)
 	operandName.Name = identifier
+	operandName.Span = identifier.Span
 
 	return operandName
 }
@@ -419,6 +457,7 @@ func (parser *Parser) ParseNewInstanceExpression() *NewInstanceExpression {
 	if !parser.MatchCurrentTokenType(TokenTypeNew) {
 		return nil
 	}
+	start := parser.startPos()
 
 	parser.PeekNextToken()
 	typeDescription := parser.ParseTypeDescription()
@@ -445,6 +484,7 @@ func (parser *Parser) ParseNewInstanceExpression() *NewInstanceExpression {
 		// 结束循环时，检测是否停留于 token ')'
 		if parser.MatchCurrentTokenType(TokenTypeRightParen) {
 			parser.PeekNextToken() // 移过 ')'
+			parser.finishNode(newInstanceExpression, start)
 			return newInstanceExpression
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -463,12 +503,14 @@ func (parser *Parser) ParseUnaryExpression() *UnaryExpression {
 	if parser.CurrentToken != nil {
 		switch parser.CurrentToken.Kind {
 		case TokenTypeMinus, TokenTypeBang, TokenTypeWavy:
+			start := parser.startPos()
 			unaryExpression := new(UnaryExpression)
 			unaryExpression.Operator = parser.CurrentToken
 			parser.PeekNextToken() // 移过该单目运算符
 
 			if operand := parser.ParsePrimaryExpression(); operand != nil {
 				unaryExpression.Operand = operand
+				parser.finishNode(unaryExpression, start)
 				return unaryExpression
 			} else {
 				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -483,6 +525,8 @@ func (parser *Parser) ParseUnaryExpression() *UnaryExpression {
 
 // 递归尝试解析 二元表达式
 func (parser *Parser) TryParseBinaryExpression(left Expression) Expression {
+	start := left.GetSpan().Start
+	parser.MergeShiftOperator()
 	if parser.MatchCurrentTokenType(TokenTypeAs) {
 		parser.PeekNextToken() // 移过 'as'
 		castExpression := new(CastExpression)
@@ -490,6 +534,7 @@ func (parser *Parser) TryParseBinaryExpression(left Expression) Expression {
 
 		if typeDescription := parser.ParseTypeDescription(); typeDescription != nil {
 			castExpression.Type = typeDescription
+			parser.finishNode(castExpression, start)
 			return castExpression
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -504,6 +549,7 @@ func (parser *Parser) TryParseBinaryExpression(left Expression) Expression {
 		parser.PeekNextToken()                                                     // 移动过 三点或两点 符号
 		if right := parser.ParsePrimaryExpression(); right != nil {
 			rangeExpression.End = right
+			parser.finishNode(rangeExpression, start)
 			return rangeExpression // 区间表达式比较独立，不需要再额外操作
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -512,26 +558,11 @@ func (parser *Parser) TryParseBinaryExpression(left Expression) Expression {
 		}
 	} else if priority := GetBinaryOperatorPriority(parser.CurrentToken); priority != 99 {
 		// 证明是 二元运算符
-		binaryExpression := new(BinaryExpression)
-		binaryExpression.Operator = parser.CurrentToken
-		binaryExpression.Left = left
-
+		operator := parser.CurrentToken
 		parser.PeekNextToken() // 移过当前操作符节点
 
-		// 先暂时形成 左结构，然后解析右边节点（即体现：自左向右结合）
-		if r := parser.ParseExpression(); r != nil {
-			right, rightIsBinary := r.(*BinaryExpression)
-			if rightIsBinary && GetBinaryOperatorPriority(right.Operator) > priority {
-				// 右边节点也是二元表达式，需要进行优先级判断、旋转树
-				// 即 右边节点的 priority 数值大，反而优先级别低，应作父节点
-				binaryExpression.Right = right.Left // 补充原树的右节点
-				right.Left = binaryExpression       // 而原树的成为左节点
-
-				return right
-			}
-			// 否则就正常补充右节点
-			binaryExpression.Right = r
-			return binaryExpression
+		if right := parser.ParseExpression(); right != nil {
+			return CombineBinaryExpression(left, operator, right)
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
 				"expected a right node for binary expression!", ParsingUnexpected))
@@ -541,6 +572,50 @@ func (parser *Parser) TryParseBinaryExpression(left Expression) Expression {
 	return left // 即一个基本的表达式而已
 }
 
+// 右侧的表达式是递归解析出来的，先形成了自右向左结合的树
+// 需要把左操作数与运算符结合进右侧树中最左边、且优先级不高于当前运算符的位置：
+// 1. 右侧运算符优先级更低时（priority 数值更大），应作父节点
+// 2. 优先级相同时，除赋值运算符自右向左结合外，其余运算符都自左向右结合
+// 带括号的二元表达式视为一个整体，不参与旋转
+func CombineBinaryExpression(left Expression, operator *Token, right Expression) Expression {
+	priority := GetBinaryOperatorPriority(operator)
+	if rightBinary, isBinary := right.(*BinaryExpression); isBinary && !rightBinary.Parenthesized {
+		rightPriority := GetBinaryOperatorPriority(rightBinary.Operator)
+		if rightPriority > priority || (rightPriority == priority && priority != assignOperatorPriority) {
+			rightBinary.Left = CombineBinaryExpression(left, operator, rightBinary.Left)
+			rightBinary.Start = left.GetSpan().Start
+			return rightBinary
+		}
+	}
+	return &BinaryExpression{
+		Span:     Span{Start: left.GetSpan().Start, End: right.GetSpan().End},
+		Operator: operator,
+		Left:     left,
+		Right:    right,
+	}
+}
+
+// 赋值运算符的优先级，见 GetBinaryOperatorPriority
+const assignOperatorPriority = 14
+
+// 词法分析时 << 与 >> 默认被拆为两个尖括号，以免与嵌套的泛型参数混淆
+// 二元表达式中遇到紧挨着的两个尖括号时，从当前 Token 起重新读取，合并为移位运算符
+func (parser *Parser) MergeShiftOperator() {
+	token := parser.CurrentToken
+	if token == nil || (token.Kind != TokenTypeLeftAngle && token.Kind != TokenTypeRightAngle) {
+		return
+	}
+	lexer := parser.Lexer
+	if lexer.BytePos != token.End.Offset || lexer.BytePos >= len(lexer.Content) ||
+		lexer.PeekChar().Rune != rune(token.Str[0]) {
+		return
+	}
+	lexer.BytePos = token.Start.Offset
+	if merged, err := lexer.GetNextToken(false); err == nil && merged != nil {
+		parser.CurrentToken = merged
+	}
+}
+
 // 解析一个以逗号分隔的 表达式 列表
 func (parser *Parser) ParseExpressionList() []Expression {
 	var exprList []Expression
@@ -4,58 +4,102 @@ import (
 	. "coral-lang/src/ast"
 	. "coral-lang/src/exception"
 	. "coral-lang/src/lexer"
-	. "coral-lang/src/utils"
 	"fmt"
 )
 
+// 依次尝试各种语句；某种语句已经报错时，其后的 Token 仍属于这条出错的语句
+// 此时不再尝试其余的语句，以免把出错的部分略过，交由 ParseStatementWithRecovery 以 BadStatement 占位
 func (parser *Parser) ParseStatement() Statement {
+	errCount := parser.ErrCount
 	if simpleStmt := parser.ParseSimpleStatement(true); simpleStmt != nil {
 		return simpleStmt
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if breakStatement := parser.ParseBreakStatement(); breakStatement != nil {
 		return breakStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if continueStatement := parser.ParseContinueStatement(); continueStatement != nil {
 		return continueStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if returnStatement := parser.ParseReturnStatement(); returnStatement != nil {
 		return returnStatement
+	} else if parser.ErrCount > errCount {
+		return nil
+	}
+	if throwStatement := parser.ParseThrowStatement(); throwStatement != nil {
+		return throwStatement
+	} else if parser.ErrCount > errCount {
+		return nil
+	}
+	if packageStatement := parser.ParsePackageStatement(); packageStatement != nil {
+		return packageStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if importStatement := parser.ParseImportStatement(); importStatement != nil {
 		return importStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if enumStatement := parser.ParseEnumStatement(); enumStatement != nil {
 		return enumStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if blockStatement := parser.ParseBlockStatement(); blockStatement != nil {
 		return blockStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if ifStatement := parser.ParseIfStatement(); ifStatement != nil {
 		return ifStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if switchStatement := parser.ParseSwitchStatement(); switchStatement != nil {
 		return switchStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if whileStatement := parser.ParseWhileStatement(); whileStatement != nil {
 		return whileStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if forStatement := parser.ParseForStatement(); forStatement != nil {
 		return forStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if eachStatement := parser.ParseEachStatement(); eachStatement != nil {
 		return eachStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if fnStatement := parser.ParseFnStatement(); fnStatement != nil {
 		return fnStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if classStatement := parser.ParseClassStatement(); classStatement != nil {
 		return classStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if interfaceStatement := parser.ParseInterfaceStatement(); interfaceStatement != nil {
 		return interfaceStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 	if tryCatchStatement := parser.ParseTryCatchStatement(); tryCatchStatement != nil {
 		return tryCatchStatement
+	} else if parser.ErrCount > errCount {
+		return nil
 	}
 
 	return nil
@@ -63,9 +107,10 @@ func (parser *Parser) ParseStatement() Statement {
 
 func (parser *Parser) ParseSimpleStatement(needSemiEnd bool) SimpleStatement {
 	if expression := parser.ParseExpression(); expression != nil {
-		if expression, isPrimary := expression.(PrimaryExpression); isPrimary && parser.MatchCurrentTokenType(TokenTypeComma) {
+		primary, isPrimary := expression.(PrimaryExpression)
+		if isPrimary && parser.MatchCurrentTokenType(TokenTypeComma) {
 			parser.PeekNextToken() // 移过 ','
-			primaryExprList := []PrimaryExpression{expression}
+			primaryExprList := []PrimaryExpression{primary}
 			for primaryExpr := parser.ParsePrimaryExpression(); primaryExpr != nil; primaryExpr = parser.ParsePrimaryExpression() {
 				primaryExprList = append(primaryExprList, primaryExpr)
 				if parser.MatchCurrentTokenType(TokenTypeComma) {
@@ -91,6 +136,7 @@ func (parser *Parser) ParseSimpleStatement(needSemiEnd bool) SimpleStatement {
 					"to terminate a assignment list!") {
 					return nil
 				}
+				parser.finishNode(assignListStatement, expression.GetSpan().Start)
 				return assignListStatement
 			} else {
 				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -99,6 +145,11 @@ func (parser *Parser) ParseSimpleStatement(needSemiEnd bool) SimpleStatement {
 			}
 
 		} else if parser.MatchCurrentTokenType(TokenTypeDoublePlus) || parser.MatchCurrentTokenType(TokenTypeDoubleMinus) {
+			if !isPrimary {
+				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+					"the operand of increase/decrease statement must be a primary expression!", ParsingUnexpected))
+				return nil
+			}
 			incDecStatement := new(IncDecStatement)
 			incDecStatement.Expression = expression
 			incDecStatement.Operator = parser.CurrentToken
@@ -111,6 +162,7 @@ func (parser *Parser) ParseSimpleStatement(needSemiEnd bool) SimpleStatement {
 					return nil
 				}
 			}
+			parser.finishNode(incDecStatement, expression.GetSpan().Start)
 			return incDecStatement
 		}
 
@@ -124,7 +176,8 @@ func (parser *Parser) ParseSimpleStatement(needSemiEnd bool) SimpleStatement {
 	}
 	if varDeclStatement := parser.ParseVarDeclStatement(); varDeclStatement != nil {
 		if needSemiEnd {
-			parser.PeekNextToken() // 移过 ';'
+			parser.PeekNextToken() // 移过 ';'，语句的范围也包括这个分号
+			parser.finishNode(varDeclStatement, varDeclStatement.Start)
 		}
 		return varDeclStatement
 	}
@@ -132,6 +185,7 @@ func (parser *Parser) ParseSimpleStatement(needSemiEnd bool) SimpleStatement {
 }
 
 func (parser *Parser) ParseVarDeclElement(mutable bool) *VarDeclElement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeIdentifier) {
 		varNameToken := parser.CurrentToken
 		varDeclElement := new(VarDeclElement)
@@ -149,6 +203,7 @@ func (parser *Parser) ParseVarDeclElement(mutable bool) *VarDeclElement {
 				if initValue := parser.ParseExpression(); initValue != nil {
 					varDeclElement.InitValue = initValue
 					// 一个变量定义元素完成，此时 token 应为 ',' 会在外部循环断言
+					parser.finishNode(varDeclElement, start)
 					return varDeclElement
 				} else {
 					CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -158,14 +213,12 @@ func (parser *Parser) ParseVarDeclElement(mutable bool) *VarDeclElement {
 				}
 
 			} else if parser.MatchCurrentTokenType(TokenTypeComma) || parser.MatchCurrentTokenType(TokenTypeSemi) {
-				// 此时即没有给出初始值
-				if !mutable {
-					CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
-						"no initial value for \"val\" declaration is not allowed!", ParsingUnexpected))
-					return nil
+				// 此时即没有给出初始值，val 必须有初始值，由语义分析报错
+				if mutable {
+					CoralCompileWarningWithPos(parser, fmt.Sprintf(`no initial value for variable: "%s".`, varNameToken.Str))
 				}
-				CoralCompileWarningWithPos(parser, fmt.Sprintf(`no initial value for variable: "%s".`, Blue(varNameToken.Str)))
 				// 那么一个变量定义元素可以结束了，不移过逗号 ','、分号';' 而等待外部断言
+				parser.finishNode(varDeclElement, start)
 				return varDeclElement
 			}
 		}
@@ -175,6 +228,7 @@ func (parser *Parser) ParseVarDeclElement(mutable bool) *VarDeclElement {
 			parser.PeekNextToken() // 移过 '='
 			if initValue := parser.ParseExpression(); initValue != nil {
 				varDeclElement.InitValue = initValue
+				parser.finishNode(varDeclElement, start)
 				return varDeclElement
 			} else {
 				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -193,7 +247,7 @@ func (parser *Parser) ParseVarDeclElement(mutable bool) *VarDeclElement {
 
 			// 其他不正确的 token
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
-				fmt.Sprintf("unexpected token '%s' for variabel declaration!", parser.CurrentToken.Str),
+				fmt.Sprintf("unexpected %s for variable declaration!", parser.describeCurrentToken()),
 				ParsingUnexpected))
 			return nil
 		}
@@ -203,7 +257,9 @@ func (parser *Parser) ParseVarDeclElement(mutable bool) *VarDeclElement {
 }
 
 func (parser *Parser) ParseVarDeclStatement() *VarDeclStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeVar) || parser.MatchCurrentTokenType(TokenTypeVal) {
+		errCount := parser.ErrCount
 		varDeclStatement := new(VarDeclStatement)
 		varDeclStatement.Mutable = parser.CurrentToken.Kind == TokenTypeVar
 		parser.PeekNextToken() // 移过 'var'/'val'
@@ -214,6 +270,7 @@ func (parser *Parser) ParseVarDeclStatement() *VarDeclStatement {
 
 			if parser.MatchCurrentTokenType(TokenTypeSemi) {
 				// 分号即应该结束此段定义语句，是否取下一个 token 看外部函数是否 needSemiEnd
+				parser.finishNode(varDeclStatement, start)
 				return varDeclStatement
 			} else {
 				if !parser.AssertCurrentTokenIs(TokenTypeComma, "a comma",
@@ -222,6 +279,12 @@ func (parser *Parser) ParseVarDeclStatement() *VarDeclStatement {
 				}
 			}
 		}
+		// 'var'/'val' 或者逗号之后没有变量名，例如 var ;
+		if parser.ErrCount == errCount {
+			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+				fmt.Sprintf("unexpected %s, expected a variable name to declare!", parser.describeCurrentToken()),
+				ParsingUnexpected))
+		}
 	}
 
 	return nil
@@ -236,7 +299,9 @@ func (parser *Parser) ParseBreakStatement() *BreakStatement {
 			"to terminate a break statement!") {
 			return nil
 		}
-		return &BreakStatement{Token: breakToken}
+		breakStatement := &BreakStatement{Token: breakToken}
+		parser.finishNode(breakStatement, breakToken.Start)
+		return breakStatement
 	}
 
 	return nil
@@ -251,7 +316,9 @@ func (parser *Parser) ParseContinueStatement() *ContinueStatement {
 			"to terminate a continue statement!") {
 			return nil
 		}
-		return &ContinueStatement{Token: continueToken}
+		continueStatement := &ContinueStatement{Token: continueToken}
+		parser.finishNode(continueStatement, continueToken.Start)
+		return continueStatement
 	}
 
 	return nil
@@ -267,10 +334,12 @@ func (parser *Parser) ParseReturnStatement() *ReturnStatement {
 				"to terminate a return statement!") {
 				return nil
 			}
-			return &ReturnStatement{
+			returnStatement := &ReturnStatement{
 				Token:      returnToken,
 				Expression: expressionList,
 			}
+			parser.finishNode(returnStatement, returnToken.Start)
+			return returnStatement
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
 				"expected an expression for return statement!", ParsingUnexpected))
@@ -281,7 +350,34 @@ func (parser *Parser) ParseReturnStatement() *ReturnStatement {
 	return nil
 }
 
+func (parser *Parser) ParseThrowStatement() *ThrowStatement {
+	if parser.MatchCurrentTokenType(TokenTypeThrow) {
+		throwToken := parser.CurrentToken
+		parser.PeekNextToken() // 移过 'throw'
+
+		if value := parser.ParseExpression(); value != nil {
+			if !parser.AssertCurrentTokenIs(TokenTypeSemi, "a semicolon",
+				"to terminate a throw statement!") {
+				return nil
+			}
+			throwStatement := &ThrowStatement{
+				Token: throwToken,
+				Value: value,
+			}
+			parser.finishNode(throwStatement, throwToken.Start)
+			return throwStatement
+		} else {
+			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+				"expected an exception value for throw statement!", ParsingUnexpected))
+			return nil
+		}
+	}
+
+	return nil
+}
+
 func (parser *Parser) ParseImportElement() *ImportElement {
+	start := parser.startPos()
 	importElement := new(ImportElement)
 	if moduleName := parser.ParseIdentifier(false); moduleName != nil {
 		importElement.ModuleName = moduleName
@@ -289,6 +385,7 @@ func (parser *Parser) ParseImportElement() *ImportElement {
 			parser.PeekNextToken() // 移过 'as'
 			if asName := parser.ParseIdentifier(false); asName != nil {
 				importElement.As = asName
+				parser.finishNode(importElement, start)
 				return importElement
 			} else {
 				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -297,6 +394,7 @@ func (parser *Parser) ParseImportElement() *ImportElement {
 			}
 		}
 
+		parser.finishNode(importElement, start)
 		return importElement
 	}
 
@@ -321,6 +419,7 @@ func (parser *Parser) ParseImportElementList() []*ImportElement {
 }
 
 func (parser *Parser) ParseImportStatement() ImportStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeFrom) {
 		parser.PeekNextToken() // 移过 'from'
 		if from, isStringLit := parser.ParseLiteral().(*StringLit); isStringLit && from != nil {
@@ -338,6 +437,7 @@ func (parser *Parser) ParseImportStatement() ImportStatement {
 
 						if parser.MatchCurrentTokenType(TokenTypeRightBrace) {
 							parser.PeekNextToken() // 移过 '}'
+							parser.finishNode(listImportStatement, start)
 							return listImportStatement
 						} else {
 							CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -355,6 +455,7 @@ func (parser *Parser) ParseImportStatement() ImportStatement {
 					singleImportStatement.Element = importElement
 					if parser.MatchCurrentTokenType(TokenTypeSemi) {
 						parser.PeekNextToken() // 移过 ';'
+						parser.finishNode(singleImportStatement, start)
 						return singleImportStatement
 					} else {
 						CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -389,6 +490,7 @@ func (parser *Parser) ParseImportStatement() ImportStatement {
 				"to terminate a single global import statement!") {
 				return nil
 			}
+			parser.finishNode(singleGlobalImport, start)
 			return singleGlobalImport
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -400,16 +502,46 @@ func (parser *Parser) ParseImportStatement() ImportStatement {
 	return nil
 }
 
+// 'package' IDENTIFIER ';'，只能作为文件的第一条语句
+func (parser *Parser) ParsePackageStatement() *PackageStatement {
+	if !parser.MatchCurrentTokenType(TokenTypePackage) {
+		return nil
+	}
+	start := parser.startPos()
+	// 此前没有读到任何 Token 即是文件的第一条语句
+	first := parser.LastToken == nil
+	parser.PeekNextToken() // 移过 'package'
+
+	name := parser.ParseIdentifier(false)
+	if name == nil {
+		CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+			"expected a package name after 'package'!", NoPackageNameDefinition))
+		return nil
+	}
+	if !parser.AssertCurrentTokenIs(TokenTypeSemi, "a semicolon", "to terminate a package statement!") {
+		return nil
+	}
+	packageStatement := &PackageStatement{Name: name}
+	parser.finishNode(packageStatement, start)
+	if !first {
+		CoralCompileErrorWithSpan(parser, packageStatement.Start, packageStatement.End, NewCoralError("Syntax",
+			"package declaration must be the first statement of a file!", ParsingUnexpected))
+	}
+	return packageStatement
+}
+
 func (parser *Parser) ParseEnumElement() *EnumElement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeIdentifier) {
 		enumElement := new(EnumElement)
-		enumElement.Name = &Identifier{Token: parser.CurrentToken}
+		enumElement.Name = &Identifier{Span: TokenSpan(parser.CurrentToken), Token: parser.CurrentToken}
 		parser.PeekNextToken() // 移过当前这个名称标识符
 		// 尝试解析等于号，看是否有赋值
 		if parser.MatchCurrentTokenType(TokenTypeEqual) {
 			parser.PeekNextToken() // 移过 '='
 			if decimalLit, isDecimal := parser.ParseLiteral().(*DecimalLit); isDecimal {
 				enumElement.Value = decimalLit
+				parser.finishNode(enumElement, start)
 				return enumElement
 			} else {
 				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -423,6 +555,7 @@ func (parser *Parser) ParseEnumElement() *EnumElement {
 				"expected a comma to separate multiple enum elements!", ParsingUnexpected))
 			return nil
 		}
+		parser.finishNode(enumElement, start)
 		return enumElement
 	}
 
@@ -430,6 +563,7 @@ func (parser *Parser) ParseEnumElement() *EnumElement {
 }
 
 func (parser *Parser) ParseEnumStatement() *EnumStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeEnum) {
 		parser.PeekNextToken() // 移过 'enum'
 		if enumName := parser.ParseIdentifier(false); enumName != nil {
@@ -450,6 +584,8 @@ func (parser *Parser) ParseEnumStatement() *EnumStatement {
 
 				if parser.MatchCurrentTokenType(TokenTypeRightBrace) {
 					parser.PeekNextToken() // 移过 '}'
+					parser.finishNode(enumStatement, start)
+					enumStatement.Doc = parser.DocCommentBefore(start)
 					return enumStatement
 				} else {
 					CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -465,16 +601,18 @@ func (parser *Parser) ParseEnumStatement() *EnumStatement {
 }
 
 func (parser *Parser) ParseBlockStatement() *BlockStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeLeftBrace) {
 		parser.PeekNextToken() // 移过 '{'
 
 		blockStatement := new(BlockStatement)
-		for stmt := parser.ParseStatement(); stmt != nil; stmt = parser.ParseStatement() {
+		for stmt := parser.ParseStatementWithRecovery(true); stmt != nil; stmt = parser.ParseStatementWithRecovery(true) {
 			blockStatement.Statements = append(blockStatement.Statements, stmt)
 		}
 
 		if parser.MatchCurrentTokenType(TokenTypeRightBrace) {
 			parser.PeekNextToken()
+			parser.finishNode(blockStatement, start)
 			return blockStatement
 		} else {
 			// 没有正常解析到右括号
@@ -488,12 +626,14 @@ func (parser *Parser) ParseBlockStatement() *BlockStatement {
 }
 
 func (parser *Parser) ParseIfElement() *IfElement {
+	start := parser.startPos()
 	if condition := parser.ParseExpression(); condition != nil {
 		ifElement := new(IfElement)
 		ifElement.Condition = condition
 
 		if block := parser.ParseBlockStatement(); block != nil {
 			ifElement.Block = block
+			parser.finishNode(ifElement, start)
 			return ifElement
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -508,6 +648,7 @@ func (parser *Parser) ParseIfElement() *IfElement {
 }
 
 func (parser *Parser) ParseIfStatement() *IfStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeIf) {
 		parser.PeekNextToken() // 移过 'if'
 		ifStatement := new(IfStatement)
@@ -531,6 +672,7 @@ func (parser *Parser) ParseIfStatement() *IfStatement {
 				}
 			}
 
+			parser.finishNode(ifStatement, start)
 			return ifStatement
 		}
 	}
@@ -562,6 +704,7 @@ func (parser *Parser) ParseElifStatements() []*IfElement {
 }
 
 func (parser *Parser) ParseSwitchCase() (SwitchStatementCase, bool) {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeCase) {
 		parser.PeekNextToken() // 移过 'case'
 		if caseExpr := parser.ParseExpression(); caseExpr != nil {
@@ -571,6 +714,7 @@ func (parser *Parser) ParseSwitchCase() (SwitchStatementCase, bool) {
 				rangeCase.Range = rangeExpr
 				if block := parser.ParseBlockStatement(); block != nil {
 					rangeCase.Block = block
+					parser.finishNode(rangeCase, start)
 					return rangeCase, false
 				} else {
 					CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -593,6 +737,7 @@ func (parser *Parser) ParseSwitchCase() (SwitchStatementCase, bool) {
 					}
 					if normalBlock := parser.ParseBlockStatement(); normalBlock != nil {
 						normalCase.Block = normalBlock
+						parser.finishNode(normalCase, start)
 						return normalCase, false
 					} else {
 						CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -602,6 +747,7 @@ func (parser *Parser) ParseSwitchCase() (SwitchStatementCase, bool) {
 				} else {
 					if caseBlock := parser.ParseBlockStatement(); caseBlock != nil {
 						normalCase.Block = caseBlock
+						parser.finishNode(normalCase, start)
 						return normalCase, false
 					} else {
 						CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -624,6 +770,7 @@ func (parser *Parser) ParseSwitchCase() (SwitchStatementCase, bool) {
 }
 
 func (parser *Parser) ParseSwitchStatement() *SwitchStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeSwitch) {
 		parser.PeekNextToken() // 移过 'switch'
 
@@ -650,6 +797,7 @@ func (parser *Parser) ParseSwitchStatement() *SwitchStatement {
 
 				if parser.MatchCurrentTokenType(TokenTypeRightBrace) {
 					parser.PeekNextToken() // 移过 '}'
+					parser.finishNode(switchStatement, start)
 					return switchStatement
 				} else {
 					CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -669,6 +817,7 @@ func (parser *Parser) ParseSwitchStatement() *SwitchStatement {
 }
 
 func (parser *Parser) ParseWhileStatement() *WhileStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeWhile) {
 		parser.PeekNextToken() // 移过 'while'
 
@@ -678,6 +827,7 @@ func (parser *Parser) ParseWhileStatement() *WhileStatement {
 
 			if whileBlock := parser.ParseBlockStatement(); whileBlock != nil {
 				whileStatement.Block = whileBlock
+				parser.finishNode(whileStatement, start)
 				return whileStatement
 			} else {
 				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -696,6 +846,7 @@ func (parser *Parser) ParseWhileStatement() *WhileStatement {
 }
 
 func (parser *Parser) ParseForStatement() *ForStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeFor) {
 		parser.PeekNextToken() // 移过 'for'
 		forStatement := new(ForStatement)
@@ -733,6 +884,7 @@ while condition { ... } instead.`)
 
 			if forBlock := parser.ParseBlockStatement(); forBlock != nil {
 				forStatement.Block = forBlock
+				parser.finishNode(forStatement, start)
 				return forStatement
 			} else {
 				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -752,6 +904,7 @@ while condition { ... } instead.`)
 }
 
 func (parser *Parser) ParseEachStatement() *EachStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeEach) {
 		parser.PeekNextToken() // 移过 'each'
 		eachStatement := new(EachStatement)
@@ -774,6 +927,7 @@ func (parser *Parser) ParseEachStatement() *EachStatement {
 
 					if block := parser.ParseBlockStatement(); block != nil {
 						eachStatement.Block = block
+						parser.finishNode(eachStatement, start)
 						return eachStatement
 					} else {
 						CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -801,6 +955,7 @@ func (parser *Parser) ParseEachStatement() *EachStatement {
 }
 
 func (parser *Parser) ParseArgument() *Argument {
+	start := parser.startPos()
 	if argName := parser.ParseIdentifier(false); argName != nil {
 		argument := new(Argument)
 		argument.Name = argName
@@ -809,6 +964,7 @@ func (parser *Parser) ParseArgument() *Argument {
 			argument.Type = argType
 		}
 
+		parser.finishNode(argument, start)
 		return argument
 	}
 
@@ -823,7 +979,7 @@ func (parser *Parser) ParseArgumentList(allowIgnoreTyping bool) []*Argument {
 	for arg := parser.ParseArgument(); arg != nil; arg = parser.ParseArgument() {
 		if arg.Type == nil {
 			// 监测到一个没有类型声明的形参
-			noTypeDescriptorList = append(noTypeDescriptorList, arg) // 记录入队
+			noTypeDescriptorList = append(noTypeDescriptorList, arg) // 记录入队，等到遇见类型时再加入形参列表
 			currentInShorthand = true                                // 亮起标志位，之后如果遇到有类型，则清空队列
 		} else {
 			if currentInShorthand {
@@ -834,8 +990,8 @@ func (parser *Parser) ParseArgumentList(allowIgnoreTyping bool) []*Argument {
 				noTypeDescriptorList = make([]*Argument, 0) // 让 GC 回收原队列切片内存
 				currentInShorthand = false                  // 重置标志
 			}
+			argList = append(argList, arg)
 		}
-		argList = append(argList, arg)
 
 		if parser.MatchCurrentTokenType(TokenTypeComma) {
 			parser.PeekNextToken() // 移过 ','
@@ -850,7 +1006,7 @@ func (parser *Parser) ParseArgumentList(allowIgnoreTyping bool) []*Argument {
 			"expected at least one type for all arguments!!", ParsingUnexpected))
 		return nil
 	}
-	return argList
+	return append(argList, noTypeDescriptorList...) // 允许省略类型时，末尾没有类型的形参保持原样
 }
 
 func (parser *Parser) ParseReturnList() []TypeDescription {
@@ -867,6 +1023,7 @@ func (parser *Parser) ParseReturnList() []TypeDescription {
 }
 
 func (parser *Parser) ParseSignature(allowMismatched bool, allowIgnoreTyping bool) *Signature {
+	start := parser.startPos()
 	signature := new(Signature)
 	if fnGenerics := parser.ParseGenericsArgs(); fnGenerics != nil {
 		signature.Generics = fnGenerics
@@ -905,6 +1062,7 @@ func (parser *Parser) ParseSignature(allowMismatched bool, allowIgnoreTyping boo
 			}
 		}
 
+		parser.finishNode(signature, start)
 		return signature
 	}
 
@@ -912,6 +1070,7 @@ func (parser *Parser) ParseSignature(allowMismatched bool, allowIgnoreTyping boo
 }
 
 func (parser *Parser) ParseGenericsArgElement() *GenericsArgElement {
+	start := parser.startPos()
 	if argName := parser.ParseIdentifier(true); argName != nil {
 		argElement := new(GenericsArgElement)
 		argElement.ArgName = argName
@@ -920,6 +1079,16 @@ func (parser *Parser) ParseGenericsArgElement() *GenericsArgElement {
 			argElement.Generics = argGenerics
 		} // 也可能只是通配符 而不是其他泛型类
 
+		if parser.MatchCurrentTokenType(TokenTypeColon) {
+			parser.PeekNextTokenAvoidAngleConfusing() // 移过 ':'
+			if argElement.Constraint = parser.ParseTypeDescription(); argElement.Constraint == nil {
+				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+					"expected a constraint type after ':' in generics arguments!", ParsingUnexpected))
+				return nil
+			}
+		}
+
+		parser.finishNode(argElement, start)
 		return argElement
 	}
 
@@ -927,6 +1096,7 @@ func (parser *Parser) ParseGenericsArgElement() *GenericsArgElement {
 }
 
 func (parser *Parser) ParseGenericsArgs() *GenericArgs {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeLeftAngle) {
 		parser.PeekNextTokenAvoidAngleConfusing() // 移过 '<'
 		genericsArg := new(GenericArgs)
@@ -950,6 +1120,7 @@ func (parser *Parser) ParseGenericsArgs() *GenericArgs {
 			"to terminate a generics arguments!") {
 			return nil
 		}
+		parser.finishNode(genericsArg, start)
 		return genericsArg
 	}
 
@@ -957,6 +1128,7 @@ func (parser *Parser) ParseGenericsArgs() *GenericArgs {
 }
 
 func (parser *Parser) ParseFnStatement() *FunctionDeclarationStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeFn) {
 		parser.PeekNextToken() // 移过 'fn'
 		fnStmt := new(FunctionDeclarationStatement)
@@ -971,6 +1143,8 @@ func (parser *Parser) ParseFnStatement() *FunctionDeclarationStatement {
 
 				if fnBlock := parser.ParseBlockStatement(); fnBlock != nil {
 					fnStmt.Block = fnBlock
+					parser.finishNode(fnStmt, start)
+					fnStmt.Doc = parser.DocCommentBefore(start)
 					return fnStmt
 				} else {
 					CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -982,6 +1156,10 @@ func (parser *Parser) ParseFnStatement() *FunctionDeclarationStatement {
 					"expected a signature when defining a function statement!", ParsingUnexpected))
 				return nil
 			}
+		} else {
+			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+				"expected an identifier for function name!", ParsingUnexpected))
+			return nil
 		}
 	}
 
@@ -989,6 +1167,7 @@ func (parser *Parser) ParseFnStatement() *FunctionDeclarationStatement {
 }
 
 func (parser *Parser) ParseClassIdentifier() *ClassIdentifier {
+	start := parser.startPos()
 	if className := parser.ParseIdentifier(true); className != nil {
 		classIdentifier := new(ClassIdentifier)
 		classIdentifier.Name = className
@@ -997,6 +1176,7 @@ func (parser *Parser) ParseClassIdentifier() *ClassIdentifier {
 			classIdentifier.Generics = genericsArgs
 		} // 也可能没有泛型参数
 
+		parser.finishNode(classIdentifier, start)
 		return classIdentifier
 	}
 
@@ -1004,13 +1184,23 @@ func (parser *Parser) ParseClassIdentifier() *ClassIdentifier {
 }
 
 func (parser *Parser) ParseClassMember() ClassMember {
+	start := parser.startPos()
+	errCount := parser.ErrCount
 	var scopeType ClassMemberScopeType = ClassMemberScopePrivate
+	var modifier *Token // 最后一个访问或静态修饰符，其后必须是成员定义
 	if parser.MatchCurrentTokenType(TokenTypePublic) {
 		scopeType = ClassMemberScopePublic
+		modifier = parser.CurrentToken
 		parser.PeekNextToken()
 	} else if parser.MatchCurrentTokenType(TokenTypePrivate) {
+		modifier = parser.CurrentToken
 		parser.PeekNextToken()
 	}
+	isStatic := parser.MatchCurrentTokenType(TokenTypeStatic)
+	if isStatic {
+		modifier = parser.CurrentToken
+		parser.PeekNextToken() // 移过 'static'
+	}
 	if memberVarDecl := parser.ParseVarDeclStatement(); memberVarDecl != nil {
 		if !parser.AssertCurrentTokenIs(TokenTypeSemi, "a semicolon",
 			"to terminate a class member variable declaration!") {
@@ -1019,19 +1209,31 @@ func (parser *Parser) ParseClassMember() ClassMember {
 
 		classMemberVar := new(ClassMemberVar)
 		classMemberVar.Scope = scopeType
+		classMemberVar.Static = isStatic
 		classMemberVar.VarDecl = memberVarDecl
+		parser.finishNode(classMemberVar, start)
 		return classMemberVar
 	} else if memberMethodDecl := parser.ParseFnStatement(); memberMethodDecl != nil {
 		classMemberMethod := new(ClassMemberMethod)
 		classMemberMethod.Scope = scopeType
+		classMemberMethod.Static = isStatic
 		classMemberMethod.MethodDecl = memberMethodDecl
+		parser.finishNode(classMemberMethod, start)
+		if memberMethodDecl.Doc == nil {
+			memberMethodDecl.Doc = parser.DocCommentBefore(start) // 文档注释在访问修饰符之前
+		}
 		return classMemberMethod
+	} else if modifier != nil && parser.ErrCount == errCount {
+		CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+			fmt.Sprintf("unexpected %s, expected a member variable or method after '%s'!",
+				parser.describeCurrentToken(), modifier.Str), ParsingUnexpected))
 	}
 
 	return nil
 }
 
 func (parser *Parser) ParseClassStatement() *ClassDeclarationStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeClass) {
 		parser.PeekNextTokenAvoidAngleConfusing() // 移过 'class'
 		classStmt := new(ClassDeclarationStatement)
@@ -1068,38 +1270,31 @@ func (parser *Parser) ParseClassStatement() *ClassDeclarationStatement {
 				return nil
 			}
 
-			hasInitMethod := false
-			for member := parser.ParseClassMember(); member != nil; member = parser.ParseClassMember() {
+			for parser.CurrentToken != nil && !parser.MatchCurrentTokenType(TokenTypeRightBrace) {
+				startToken, errCount := parser.CurrentToken, parser.ErrCount
+				member := parser.ParseClassMember()
+				if member == nil {
+					if parser.ErrCount == errCount {
+						break // 不是类成员，等待外部断言
+					}
+					parser.synchronize(startToken) // 跳过出错的成员，继续解析其余成员
+					continue
+				}
 				classStmt.Members = append(classStmt.Members, member)
 
-				if method, isMethod := member.(*ClassMemberMethod); !hasInitMethod && isMethod && method.MethodDecl.Name.Token.Str == classId.Name.Token.Str {
-					hasInitMethod = true
+				// 构造方法是否缺失、能否有返回值由语义分析检查，以免整个类定义被丢弃
+				if method, isMethod := member.(*ClassMemberMethod); isMethod && method.MethodDecl.Name.Token.Str == classId.Name.Token.Str {
 					method.Scope = ClassMemberScopePublic // 构造方法默认 public
-					if len(method.MethodDecl.Signature.Returns) > 0 {
-						CoralCompileErrorWithPos(parser, NewCoralError("Compile",
-							fmt.Sprintf("Any returns by constructor method of class \"%s\" are not allowed!",
-								classId.Name.Token.Str),
-							NoConstructorMethod))
-						return nil
-					}
-				}
-				if parser.MatchCurrentTokenType(TokenTypeRightBrace) {
-					break // 等待外部断言
 				}
 			}
 
-			if !hasInitMethod {
-				CoralCompileErrorWithPos(parser, NewCoralError("Compile",
-					fmt.Sprintf("expected a constructor for class \"%s\"!", classId.Name.Token.Str),
-					NoConstructorMethod))
-				return nil
-			} // <- 没有构造函数的报错
-
 			if !parser.AssertCurrentTokenIs(TokenTypeRightBrace, "a right brace",
 				"to terminate the class statement definition body!") {
 				return nil
 			}
 
+			parser.finishNode(classStmt, start)
+			classStmt.Doc = parser.DocCommentBefore(start)
 			return classStmt
 
 		} else {
@@ -1113,6 +1308,7 @@ func (parser *Parser) ParseClassStatement() *ClassDeclarationStatement {
 }
 
 func (parser *Parser) ParseInterfaceMethodDecl() *InterfaceMethodDeclaration {
+	start := parser.startPos()
 	var scopeType ClassMemberScopeType = ClassMemberScopePrivate
 	if parser.MatchCurrentTokenType(TokenTypePublic) {
 		scopeType = ClassMemberScopePublic
@@ -1143,6 +1339,7 @@ func (parser *Parser) ParseInterfaceMethodDecl() *InterfaceMethodDeclaration {
 				return nil
 			}
 
+			parser.finishNode(methodDecl, start)
 			return methodDecl
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -1156,6 +1353,7 @@ func (parser *Parser) ParseInterfaceMethodDecl() *InterfaceMethodDeclaration {
 }
 
 func (parser *Parser) ParseInterfaceStatement() *InterfaceDeclarationStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeInterface) {
 		parser.PeekNextTokenAvoidAngleConfusing() // 移过 'interface'
 		interfaceStmt := new(InterfaceDeclarationStatement)
@@ -1206,6 +1404,8 @@ func (parser *Parser) ParseInterfaceStatement() *InterfaceDeclarationStatement {
 				return nil
 			}
 
+			parser.finishNode(interfaceStmt, start)
+			interfaceStmt.Doc = parser.DocCommentBefore(start)
 			return interfaceStmt
 
 		} else {
@@ -1219,6 +1419,7 @@ func (parser *Parser) ParseInterfaceStatement() *InterfaceDeclarationStatement {
 }
 
 func (parser *Parser) ParseErrorCatchHandler() *ErrorCatchHandler {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeCatch) {
 		parser.PeekNextToken() // 移过 'catch'
 		errHandler := new(ErrorCatchHandler)
@@ -1232,6 +1433,7 @@ func (parser *Parser) ParseErrorCatchHandler() *ErrorCatchHandler {
 				if handleBlock := parser.ParseBlockStatement(); handleBlock != nil {
 					errHandler.Handler = handleBlock
 
+					parser.finishNode(errHandler, start)
 					return errHandler
 				} else {
 					CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -1256,6 +1458,7 @@ func (parser *Parser) ParseErrorCatchHandler() *ErrorCatchHandler {
 }
 
 func (parser *Parser) ParseTryCatchStatement() *TryCatchStatement {
+	start := parser.startPos()
 	if parser.MatchCurrentTokenType(TokenTypeTry) {
 		parser.PeekNextToken() // 移过 'try'
 		tryCatchStmt := new(TryCatchStatement)
@@ -1272,6 +1475,7 @@ func (parser *Parser) ParseTryCatchStatement() *TryCatchStatement {
 				if finallyBlock := parser.ParseBlockStatement(); finallyBlock != nil {
 					tryCatchStmt.Finally = finallyBlock
 
+					parser.finishNode(tryCatchStmt, start)
 					return tryCatchStmt
 				} else {
 					CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
@@ -1281,6 +1485,7 @@ func (parser *Parser) ParseTryCatchStatement() *TryCatchStatement {
 				}
 			} // 也可能无 finally
 
+			parser.finishNode(tryCatchStmt, start)
 			return tryCatchStmt
 		} else {
 			CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
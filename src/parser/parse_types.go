@@ -4,11 +4,11 @@ import (
 	. "coral-lang/src/ast"
 	. "coral-lang/src/exception"
 	. "coral-lang/src/lexer"
-	"fmt"
 	"strconv"
 )
 
 func (parser *Parser) ParseTypeDescription() TypeDescription {
+	start := parser.startPos()
 	// 如果是 identifier 说明可能是 GenericsLit
 	if parser.MatchCurrentTokenType(TokenTypeIdentifier) {
 		typeName := parser.ParseTypeName()
@@ -26,11 +26,11 @@ func (parser *Parser) ParseTypeDescription() TypeDescription {
 					}
 					if parser.MatchCurrentTokenType(TokenTypeRightAngle) {
 						parser.PeekNextTokenAvoidAngleConfusing() // 移过 '>'
-						return genericsTypeLit                    // 结束泛型参数解析
-					} else {
-						parser.AssertCurrentTokenIs(TokenTypeComma, "a comma", fmt.Sprintf(
-							"to seperate several generics arguments but got '%s'",
-							parser.CurrentToken.Str))
+						parser.finishNode(genericsTypeLit, start)
+						return genericsTypeLit // 结束泛型参数解析
+					} else if !parser.AssertCurrentTokenIs(TokenTypeComma, "a comma",
+						"to seperate several generics arguments") {
+						return nil
 					}
 				}
 			} else if parser.MatchCurrentTokenType(TokenTypeLeftBracket) {
@@ -52,9 +52,11 @@ func (parser *Parser) ParseTypeDescription() TypeDescription {
 					"to terminate a array type descriptor!") {
 					return nil
 				}
+				parser.finishNode(arrayLit, start)
 				return arrayLit
 			} else {
 				// 否则就将 typeName 返回作为该 typeDescription
+				parser.finishNode(typeName, start)
 				return typeName
 			}
 		}
@@ -75,18 +77,29 @@ func (parser *Parser) ParseTypeDescription() TypeDescription {
 						ParsingUnexpected))
 					return nil
 				}
+			} else {
+				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+					"expected an argument type in function type!", ParsingUnexpected))
+				return nil
 			}
 		}
-		parser.AssertCurrentTokenIs(TokenTypeRightArrow, "a right arrow",
-			"in the function type declaration!")
+		if !parser.AssertCurrentTokenIs(TokenTypeRightArrow, "a right arrow",
+			"in the function type declaration!") {
+			return nil
+		}
 		for {
 			if returnType := parser.ParseTypeDescription(); returnType != nil {
 				funcType.ReturnTypes = append(funcType.ReturnTypes, returnType)
 				if parser.MatchCurrentTokenType(TokenTypeComma) {
 					parser.PeekNextToken() // 移过逗号
 				} else {
+					parser.finishNode(funcType, start)
 					return funcType
 				}
+			} else {
+				CoralCompileErrorWithPos(parser, NewCoralError("Syntax",
+					"expected a return type in function type!", ParsingUnexpected))
+				return nil
 			}
 		}
 	}
@@ -94,8 +107,10 @@ func (parser *Parser) ParseTypeDescription() TypeDescription {
 }
 
 func (parser *Parser) ParseTypeName() *TypeName {
+	start := parser.startPos()
 	if typeNameId := parser.ParseIdentifier(false); typeNameId != nil {
 		typeName := &TypeName{Identifier: typeNameId}
+		parser.finishNode(typeName, start)
 		return typeName
 	}
 
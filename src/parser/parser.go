@@ -4,78 +4,107 @@ import (
 	. "coral-lang/src/ast"
 	. "coral-lang/src/exception"
 	. "coral-lang/src/lexer"
-	. "coral-lang/src/utils"
 	"fmt"
-	"strings"
 )
 
 type Parser struct {
-	Lexer *Lexer
+	Lexer    *Lexer
+	FileName string // 源文件名，用于诊断信息
 
 	LastToken    *Token
 	CurrentToken *Token
 
-	ErrCount  int
-	WarnCount int
+	ErrCount    int
+	WarnCount   int
+	Errors      []*CoralCompileError // 解析过程中收集到的所有错误，由 ParseProgram 一并返回
+	Diagnostics []*Diagnostic        // 带位置的错误与警告，交由调用方选择渲染方式
+
+	reachedEnd bool // @private 词法分析器是否已经读到文件末尾
 }
 
-func CoralCompileErrorWithPos(parser *Parser, c *CoralCompileError) {
-	if parser.LastToken != nil {
-		fmt.Print("\n" + Bold(Green(fmt.Sprintf("* line %d:%d ", parser.LastToken.Line, parser.LastToken.Col))))
-	}
-	fmt.Println(c.Err)
-
-	// 打印错误代码所在行以及附近两行
-	lines := strings.Split(string(parser.Lexer.Content), "\n")
-	var startLineIndex int
-	if parser.LastToken.Line == 1 {
-		startLineIndex = 0
-	} else {
-		startLineIndex = parser.LastToken.Line - 2
-	}
-	for i := 0; i < 3 && (startLineIndex+i) < len(lines); i++ {
-		fmt.Print(Yellow(fmt.Sprintf("%4d", startLineIndex+i+1)))
-		fmt.Printf("| %s\n", lines[startLineIndex+i])
-		if startLineIndex+i == parser.LastToken.Line-1 {
-			trimmed := false
-			trimmedCount := 0
-			for k := 0; k < 6; k++ {
-				fmt.Print(" ")
-			}
-			for j := 0; j < parser.LastToken.Col-1; j++ {
-				if !trimmed {
-					if lines[startLineIndex+i][j] == ' ' {
-						fmt.Print(" ")
-						trimmedCount++
-						continue
-					} else if lines[startLineIndex+i][j] == '\t' {
-						fmt.Print("  ")
-						trimmedCount += 2
-						continue
-					} else {
-						trimmed = true
-					}
-				}
+// 解析器状态快照，试探性的解析失败后据此回溯
+type parserState struct {
+	lexer        Lexer
+	lastToken    *Token
+	currentToken *Token
+	reachedEnd   bool
+	errCount     int
+	warnCount    int
+	errLen       int
+	diagLen      int
+}
 
-				fmt.Print(Yellow("."))
-			}
-			for n := 0; n < trimmedCount; n++ {
-				fmt.Print(Yellow("."))
-			}
-			fmt.Print(Red("^") + "\n")
-		}
+func (parser *Parser) saveState() *parserState {
+	return &parserState{
+		lexer:        *parser.Lexer,
+		lastToken:    parser.LastToken,
+		currentToken: parser.CurrentToken,
+		reachedEnd:   parser.reachedEnd,
+		errCount:     parser.ErrCount,
+		warnCount:    parser.WarnCount,
+		errLen:       len(parser.Errors),
+		diagLen:      len(parser.Diagnostics),
 	}
+}
 
-	parser.ErrCount++
+// 回溯到快照处，期间产生的错误与警告一并撤销
+func (parser *Parser) restoreState(state *parserState) {
+	*parser.Lexer = state.lexer
+	parser.LastToken = state.lastToken
+	parser.CurrentToken = state.currentToken
+	parser.reachedEnd = state.reachedEnd
+	parser.ErrCount = state.errCount
+	parser.WarnCount = state.warnCount
+	parser.Errors = parser.Errors[:state.errLen]
+	parser.Diagnostics = parser.Diagnostics[:state.diagLen]
 }
-func CoralCompileWarningWithPos(parser *Parser, msg string) {
+
+// 报错位置：上一个 Token 之后，文件开头时退而取当前 Token
+func (parser *Parser) errorPos() Position {
 	if parser.LastToken != nil {
-		fmt.Print("\n" + Green(fmt.Sprintf("* line %d:%d ", parser.LastToken.Line, parser.LastToken.Col)))
+		return Position{Line: parser.LastToken.Line, Col: parser.LastToken.Col}
 	}
-	CoralCompileWarning(msg)
+	if parser.CurrentToken != nil {
+		return Position{Line: parser.CurrentToken.Line, Col: parser.CurrentToken.Col}
+	}
+	return Position{Line: parser.Lexer.Line, Col: parser.Lexer.Col}
+}
+
+func CoralCompileErrorWithPos(parser *Parser, c *CoralCompileError) {
+	pos := parser.errorPos()
+	parser.recordError(pos, pos, c)
+}
+
+// 报错并标出一段范围，如某个多余的 Token
+func CoralCompileErrorWithSpan(parser *Parser, start, end Pos, c *CoralCompileError) {
+	parser.recordError(toPosition(start), toPosition(end), c)
+}
+func (parser *Parser) recordError(start, end Position, c *CoralCompileError) {
+	parser.ErrCount++
+	parser.Errors = append(parser.Errors, c)
+	parser.Diagnostics = append(parser.Diagnostics, NewDiagnostic(parser.FileName, start, end, c))
+}
+
+func toPosition(pos Pos) Position {
+	return Position{Line: pos.Line, Col: pos.Col}
+}
+func CoralCompileWarningWithPos(parser *Parser, msg string) {
 	parser.WarnCount++
+
+	pos := parser.errorPos()
+	parser.Diagnostics = append(parser.Diagnostics, NewWarningDiagnostic(parser.FileName, pos, pos, msg))
 }
 
+// 打开源文件并以其内容初始化
+func (parser *Parser) InitFromFile(filePath string) *CoralCompileError {
+	content, err := OpenSourceFile(filePath)
+	if err != nil {
+		return err
+	}
+	parser.FileName = filePath
+	parser.InitFromBytes(content)
+	return nil
+}
 func (parser *Parser) InitFromBytes(content []byte) {
 	parser.Lexer = new(Lexer)
 	parser.Lexer.InitFromBytes(content)
@@ -96,22 +125,65 @@ func (parser *Parser) AssertCurrentTokenIs(tokenType TokenType, expected string,
 	return true
 }
 func (parser *Parser) PeekNextToken() {
-	token, err := parser.Lexer.GetNextToken(true)
-	if err != nil {
-		CoralErrorCrashHandler(err)
-	}
+	parser.peekNextToken(true)
+}
+func (parser *Parser) PeekNextTokenAvoidAngleConfusing() {
+	parser.peekNextToken(false)
+}
 
+// 向词法分析器索取下一个 Token，词法错误会被记录下来并跳过，而不是终止进程
+func (parser *Parser) peekNextToken(avoidAngleConfusing bool) {
+	if parser.reachedEnd && parser.Lexer.BytePos >= len(parser.Lexer.Content) {
+		parser.CurrentToken = nil
+		return // 已到文件末尾：保留最后一个 Token 用于报错定位，末尾的错误（如括号未闭合）也只报告一次
+	}
 	parser.LastToken = parser.CurrentToken
-	parser.CurrentToken = token
+	parser.CurrentToken = nil
+
+	for {
+		bytePos := parser.Lexer.BytePos
+		token, err := parser.Lexer.GetNextToken(avoidAngleConfusing)
+		if err == nil {
+			parser.CurrentToken = token
+			parser.reachedEnd = token == nil
+			return
+		}
+
+		CoralCompileErrorWithPos(parser, err)
+		if parser.Lexer.BytePos >= len(parser.Lexer.Content) {
+			parser.reachedEnd = true
+			return
+		}
+		if parser.Lexer.BytePos == bytePos {
+			parser.Lexer.GoNextChar() // 保证词法分析器总能向前推进
+		}
+	}
 }
-func (parser *Parser) PeekNextTokenAvoidAngleConfusing() {
-	token, err := parser.Lexer.GetNextToken(false)
-	if err != nil {
-		CoralErrorCrashHandler(err)
+
+// 当前 Token 的起点，即将解析的节点由此开始；文件末尾时取词法分析器的位置
+func (parser *Parser) startPos() Pos {
+	if parser.CurrentToken != nil {
+		return parser.CurrentToken.Start
+	}
+	return parser.Lexer.CurrentPos()
+}
+
+// 记录节点的范围：从 start 开始，到上一个移过的 Token 末尾为止
+func (parser *Parser) finishNode(node Node, start Pos) {
+	span := node.GetSpan()
+	span.Start = start
+	span.End = start
+	if parser.LastToken != nil && parser.LastToken.End.Offset > start.Offset {
+		span.End = parser.LastToken.End
 	}
+}
 
-	parser.LastToken = parser.CurrentToken
-	parser.CurrentToken = token
+// 用于报错信息中描述当前 Token
+func (parser *Parser) describeCurrentToken() string {
+	if parser.CurrentToken == nil {
+		return "end of file"
+	}
+	return fmt.Sprintf("token '%s'", parser.CurrentToken.Str)
 }
 func (parser *Parser) GetCurrentTokenPos() string {
 	return fmt.Sprintf("line %d:%d: ", parser.CurrentToken.Line, parser.CurrentToken.Col)
@@ -123,13 +195,99 @@ func (parser *Parser) MatchCurrentTokenType(tokenType TokenType) bool {
 	return false
 }
 
-func (parser *Parser) ParseProgram() *Program {
+// 可以作为同步点的语句（以及类成员）起始关键字
+var statementKeywords = map[TokenType]bool{
+	TokenTypePackage:   true,
+	TokenTypeImport:    true,
+	TokenTypeFrom:      true,
+	TokenTypeVar:       true,
+	TokenTypeVal:       true,
+	TokenTypeFn:        true,
+	TokenTypeClass:     true,
+	TokenTypeInterface: true,
+	TokenTypeEnum:      true,
+	TokenTypeIf:        true,
+	TokenTypeSwitch:    true,
+	TokenTypeWhile:     true,
+	TokenTypeFor:       true,
+	TokenTypeEach:      true,
+	TokenTypeTry:       true,
+	TokenTypeReturn:    true,
+	TokenTypeBreak:     true,
+	TokenTypeContinue:  true,
+	TokenTypePublic:    true,
+	TokenTypePrivate:   true,
+	TokenTypeStatic:    true,
+}
+
+// 解析一条语句，出错时进入恐慌模式：跳过 Token 直到同步点，并以 BadStatement 占位
+// 返回 nil 表示已经没有语句可读：文件末尾，或是块语句的右花括号
+func (parser *Parser) ParseStatementWithRecovery(inBlock bool) Statement {
+	if parser.CurrentToken == nil || (inBlock && parser.MatchCurrentTokenType(TokenTypeRightBrace)) {
+		return nil
+	}
+
+	startToken := parser.CurrentToken
+	errCount := parser.ErrCount
+	if stmt := parser.ParseStatement(); stmt != nil {
+		return stmt
+	}
+	if parser.ErrCount == errCount {
+		// 没有任何一种语句能够匹配，也就没有报错，在此补上并标出这个多余的 Token
+		err := NewCoralError("Syntax",
+			fmt.Sprintf("unexpected %s, expected a statement!", parser.describeCurrentToken()), ParsingUnexpected)
+		if parser.CurrentToken != nil {
+			CoralCompileErrorWithSpan(parser, parser.CurrentToken.Start, parser.CurrentToken.End, err)
+		} else {
+			CoralCompileErrorWithPos(parser, err)
+		}
+	}
+
+	badStatement := &BadStatement{From: startToken}
+	parser.synchronize(startToken)
+	badStatement.To = parser.LastToken
+	parser.finishNode(badStatement, startToken.Start)
+	return badStatement
+}
+
+// 丢弃 Token 直到同步点：分号（一并移过）、右花括号或语句关键字
+// 途中遇到的花括号成对跳过，以免把某个代码块的中间当成同步点
+// startToken 为出错语句的起始 Token，停在它上面意味着没有前进，因而不能作为同步点
+func (parser *Parser) synchronize(startToken *Token) {
+	depth := 0
+	for parser.CurrentToken != nil {
+		switch {
+		case parser.MatchCurrentTokenType(TokenTypeLeftBrace):
+			depth++
+		case parser.MatchCurrentTokenType(TokenTypeRightBrace):
+			if depth > 0 {
+				depth--
+				if depth == 0 {
+					parser.PeekNextToken() // 移过被跳过的代码块的 '}'
+					return
+				}
+			} else if parser.CurrentToken != startToken {
+				return // 留给外层的块语句闭合
+			}
+		case depth == 0 && parser.MatchCurrentTokenType(TokenTypeSemi):
+			parser.PeekNextToken() // 移过 ';'
+			return
+		case depth == 0 && statementKeywords[parser.CurrentToken.Kind] && parser.CurrentToken != startToken:
+			return
+		}
+		parser.PeekNextToken()
+	}
+}
+
+// 解析整个源文件，所有的编译错误都以值的形式返回，由调用方决定如何处理
+// 出错的语句以 BadStatement 占位，所以即使有错也总能得到一棵完整的语法树
+func (parser *Parser) ParseProgram() (*Program, []*CoralCompileError) {
 	program := new(Program)
-	for stmt := parser.ParseStatement(); stmt != nil; stmt = parser.ParseStatement() {
-		// stmt 为 nil 的情况中其实早已被 CoralErrorCrashHandler 处理并退出了
+	program.Start = parser.Lexer.PositionOf(0)
+	for stmt := parser.ParseStatementWithRecovery(false); stmt != nil; stmt = parser.ParseStatementWithRecovery(false) {
 		program.Root = append(program.Root, stmt)
 	}
+	program.End = parser.Lexer.PositionOf(len(parser.Lexer.Content))
 
-	fmt.Println("\n" + Yellow(fmt.Sprintf("(Parser: %d error, %d warning)", parser.ErrCount, parser.WarnCount)))
-	return program
+	return program, parser.Errors
 }
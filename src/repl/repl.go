@@ -0,0 +1,181 @@
+package repl
+
+import (
+	"bufio"
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/interp"
+	. "coral-lang/src/lexer"
+	. "coral-lang/src/parser"
+	. "coral-lang/src/vm"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Package repl 实现了交互式的 coral 环境：逐行读入源代码，凑成完整的输入后检查并以解释器执行
+// 顶层作用域与全局变量在多次输入之间保留，出错时输出诊断信息，会话继续进行
+
+const (
+	FileName       = "<repl>" // 诊断信息中输入所在的文件名
+	Prompt         = "> "     // 等待新输入时的提示符
+	ContinuePrompt = "... "   // 输入尚不完整、等待后续行时的提示符
+)
+
+type REPL struct {
+	analyzer    *Analyzer    // @private 顶层作用域在多次输入之间保留的语义分析器
+	interpreter *Interpreter // @private 全局变量在多次输入之间保留的解释器
+
+	stdout   io.Writer
+	stderr   io.Writer
+	renderer DiagnosticRenderer
+	sources  map[string][]byte // 与渲染器共享，每次输入后更新为当前输入的源代码
+}
+
+// 创建交互式环境，sources 应为渲染器所使用的源代码映射，以便展示出错的代码行
+func NewREPL(stdout, stderr io.Writer, renderer DiagnosticRenderer, sources map[string][]byte) *REPL {
+	analyzer := new(Analyzer)
+	analyzer.InitAnalyzerIncremental()
+	repl := &REPL{
+		analyzer:    analyzer,
+		interpreter: NewInterpreter(analyzer, stdout),
+		stdout:      stdout,
+		stderr:      stderr,
+		renderer:    renderer,
+		sources:     sources,
+	}
+	repl.interpreter.Echo = repl.echo
+	return repl
+}
+
+// 从 in 中逐行读入并执行，直到输入结束
+func (repl *REPL) Run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	var input strings.Builder
+	fmt.Fprint(repl.stdout, Prompt)
+	for scanner.Scan() {
+		input.WriteString(scanner.Text())
+		input.WriteByte('\n')
+		if incomplete, _ := scan(input.String()); incomplete {
+			fmt.Fprint(repl.stdout, ContinuePrompt)
+			continue
+		}
+		repl.Eval(input.String())
+		input.Reset()
+		fmt.Fprint(repl.stdout, Prompt)
+	}
+	// 输入结束时仍未完整的部分照常执行，以便报告其中未闭合的括号
+	if strings.TrimSpace(input.String()) != "" {
+		repl.Eval(input.String())
+	}
+	fmt.Fprintln(repl.stdout)
+}
+
+// 判断输入是否尚不完整：还有未闭合的圆括号、方括号、花括号或者块注释
+func Incomplete(input string) bool {
+	incomplete, _ := scan(input)
+	return incomplete
+}
+
+// 以词法分析器扫描整个输入，返回输入是否尚不完整以及最后一个 Token
+func scan(input string) (bool, *Token) {
+	lexer := new(Lexer)
+	lexer.InitFromString(input)
+	var last *Token
+	for {
+		bytePos := lexer.BytePos
+		token, err := lexer.GetNextToken(true)
+		if err != nil {
+			if err.ErrEnum == LexBlockCommentUnclosed {
+				return true, last
+			}
+			if lexer.BytePos >= len(lexer.Content) {
+				break
+			}
+			if lexer.BytePos == bytePos {
+				lexer.GoNextChar() // 其余的词法错误留给语法解析报告，这里只需继续向前扫描
+			}
+			continue
+		}
+		if token == nil {
+			break
+		}
+		last = token
+	}
+	return lexer.ParenCount > 0 || lexer.BracketCount > 0 || lexer.BraceCount > 0, last
+}
+
+// 执行一次完整的输入，返回是否没有出错；末尾缺少的分号会被自动补上
+func (repl *REPL) Eval(input string) bool {
+	_, last := scan(input)
+	if last == nil {
+		return true // 只有空白与注释
+	}
+	withSemi := input[:last.End.Offset] + ";" + input[last.End.Offset:]
+	sources := []string{withSemi}
+	switch last.Kind {
+	case TokenTypeSemi:
+		sources = []string{input}
+	case TokenTypeRightBrace:
+		// 以 '}' 结尾的可能是块语句，也可能是以表字面量结尾的简单语句，先按原样解析
+		sources = []string{input, withSemi}
+	}
+
+	parser, stmts := parse(sources[0])
+	for _, source := range sources[1:] {
+		if parser.ErrCount == 0 {
+			break
+		}
+		if retry, retryStmts := parse(source); retry.ErrCount == 0 {
+			parser, stmts = retry, retryStmts
+		}
+	}
+	repl.sources[FileName] = parser.Lexer.Content
+	repl.report(parser.Diagnostics)
+	if parser.ErrCount > 0 {
+		return false
+	}
+
+	errs := repl.analyzer.CheckStatements(parser, stmts)
+	repl.report(repl.analyzer.Diagnostics)
+	if len(errs) > 0 {
+		return false
+	}
+
+	if err := repl.interpreter.Execute(stmts); err != nil {
+		repl.report(repl.interpreter.Diagnostics)
+		return false
+	}
+	return true
+}
+
+// 输出顶层表达式语句的值，没有值的表达式（如调用 println）不输出
+func (repl *REPL) echo(expression Expression, results []Value) {
+	if len(results) == 0 {
+		return
+	}
+	formatted := make([]string, len(results))
+	for i, result := range results {
+		formatted[i] = FormatValue(result)
+	}
+	fmt.Fprintln(repl.stdout, strings.Join(formatted, " "))
+}
+
+// 解析一次输入中的全部语句
+func parse(source string) (*Parser, []Statement) {
+	parser := new(Parser)
+	parser.FileName = FileName
+	parser.InitFromString(source)
+	var stmts []Statement
+	for stmt := parser.ParseStatementWithRecovery(false); stmt != nil; stmt = parser.ParseStatementWithRecovery(false) {
+		stmts = append(stmts, stmt)
+	}
+	return parser, stmts
+}
+
+func (repl *REPL) report(diagnostics []*Diagnostic) {
+	if len(diagnostics) > 0 {
+		repl.renderer.Render(repl.stderr, diagnostics)
+	}
+}
@@ -0,0 +1,63 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 内建函数，名称与语义分析中预定义的内建函数一一对应
+var Builtins = map[string]*Builtin{
+	"print":   {Name: "print", Fn: builtinPrint},
+	"println": {Name: "println", Fn: builtinPrintln},
+	"printf":  {Name: "printf", Fn: builtinPrintf},
+}
+
+// 以空格分隔输出全部实参
+func builtinPrint(stdout io.Writer, args []Value) ([]Value, error) {
+	_, err := fmt.Fprint(stdout, joinValues(args))
+	return nil, err
+}
+
+// 同 print，并在最后换行
+func builtinPrintln(stdout io.Writer, args []Value) ([]Value, error) {
+	_, err := fmt.Fprintln(stdout, joinValues(args))
+	return nil, err
+}
+
+// 以第一个实参为格式字符串输出其余实参，格式同 Go 的 fmt 包
+func builtinPrintf(stdout io.Writer, args []Value) ([]Value, error) {
+	if len(args) == 0 {
+		return nil, errors.New("printf expects a format string")
+	}
+	format, isString := args[0].(string)
+	if !isString {
+		return nil, fmt.Errorf("printf expects a format string, got %s", ValueTypeName(args[0]))
+	}
+	operands := make([]interface{}, len(args)-1)
+	for i, arg := range args[1:] {
+		operands[i] = formatOperand(arg)
+	}
+	_, err := fmt.Fprintf(stdout, format, operands...)
+	return nil, err
+}
+
+func joinValues(args []Value) string {
+	texts := make([]string, len(args))
+	for i, arg := range args {
+		texts[i] = FormatValue(arg)
+	}
+	return strings.Join(texts, " ")
+}
+
+// printf 的操作数：基本类型交给 fmt 处理，以便使用 %d、%x、%c 等格式，其余的值先格式化为文本
+func formatOperand(value Value) interface{} {
+	switch value := value.(type) {
+	case int64, uint64, float32, float64, bool, string:
+		return value
+	case Rune:
+		return rune(value)
+	}
+	return FormatValue(value)
+}
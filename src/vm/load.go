@@ -0,0 +1,104 @@
+package vm
+
+import (
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/exception"
+	"fmt"
+)
+
+// 读入 .cbytes 文件的内容并校验其中的指令，校验通过的模块执行时不会越界访问
+func Load(data []byte) (*Module, *CoralCompileError) {
+	module, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := Verify(module); err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+// 校验模块中每一条指令：操作码已知、指令完整、操作数所指的常量、变量、函数与跳转目标都存在
+func Verify(module *Module) *CoralCompileError {
+	if module.Entry < 0 || module.Entry >= len(module.Functions) {
+		return verifyError("entry function %d does not exist", module.Entry)
+	}
+	for _, function := range module.Functions {
+		if function.Params > function.Locals {
+			return verifyError("function %s has %d parameters but only %d local slots",
+				function.Name, function.Params, function.Locals)
+		}
+		// 第一遍找出每条指令的起点，跳转只能以指令的起点或者函数的末尾为目标
+		starts := make(map[int]bool)
+		for pc := 0; pc < len(function.Code); {
+			op := Opcode(function.Code[pc])
+			definition := Lookup(op)
+			if definition == nil {
+				return verifyError("unknown opcode %d in function %s at %d", op, function.Name, pc)
+			}
+			if pc+definition.Length() > len(function.Code) {
+				return verifyError("truncated instruction %s in function %s at %d", op, function.Name, pc)
+			}
+			starts[pc] = true
+			pc += definition.Length()
+		}
+		starts[len(function.Code)] = true
+		for pc := 0; pc < len(function.Code); {
+			op := Opcode(function.Code[pc])
+			definition := Lookup(op)
+			if len(definition.OperandWidths) > 0 {
+				operand := ReadOperand(function.Code[pc+1:], definition.OperandWidths[0])
+				if message := verifyOperand(module, function, starts, op, operand); message != "" {
+					return verifyError("%s %s in function %s at %d", op, message, function.Name, pc)
+				}
+			}
+			pc += definition.Length()
+		}
+	}
+	return nil
+}
+
+func verifyOperand(module *Module, function *Function, starts map[int]bool, op Opcode, operand int) string {
+	switch op {
+	case OpConstant:
+		if operand >= len(module.Constants) {
+			return fmt.Sprintf("refers to missing constant %d", operand)
+		}
+	case OpException, OpIsInstance, OpGetField:
+		if operand >= len(module.Constants) || module.Constants[operand].Kind != ConstantString {
+			return fmt.Sprintf("refers to missing constant %d", operand)
+		}
+	case OpGetBuiltin:
+		if operand >= len(module.Constants) || module.Constants[operand].Kind != ConstantString {
+			return fmt.Sprintf("refers to missing constant %d", operand)
+		}
+		if _, ok := Builtins[module.Constants[operand].Str]; !ok {
+			return fmt.Sprintf("refers to unknown builtin %q", module.Constants[operand].Str)
+		}
+	case OpGetLocal, OpSetLocal:
+		if operand >= function.Locals {
+			return fmt.Sprintf("refers to missing local slot %d", operand)
+		}
+	case OpGetGlobal, OpSetGlobal:
+		if operand >= len(module.Globals) {
+			return fmt.Sprintf("refers to missing global %d", operand)
+		}
+	case OpFunction:
+		if operand >= len(module.Functions) {
+			return fmt.Sprintf("refers to missing function %d", operand)
+		}
+	case OpConvert:
+		if operand > int(TypeString) {
+			return fmt.Sprintf("has unknown type code %d", operand)
+		}
+	case OpJump, OpJumpIfFalse, OpJumpIfFalseOrPop, OpJumpIfTrueOrPop, OpTry:
+		if !starts[operand] {
+			return fmt.Sprintf("jumps to %d which is not the start of an instruction", operand)
+		}
+	}
+	return ""
+}
+
+func verifyError(format string, args ...interface{}) *CoralCompileError {
+	return NewCoralError("Bytecode", fmt.Sprintf(format, args...)+"!", BytecodeFormatError)
+}
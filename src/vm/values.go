@@ -0,0 +1,311 @@
+package vm
+
+import (
+	. "coral-lang/src/bytecode"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Package vm 实现了 Coral 的字节码虚拟机 CVM
+// 读入并校验 .cbytes 模块，从入口函数开始按顺序执行，脚本因此从上到下运行
+
+// 运行时的值，按 Coral 的类型以下列 Go 类型表示：
+//   - 整数：int64，uint64 类型为 uint64，其余位数更小的整数也以 int64 存放，由 OpConvert 截断
+//   - 浮点数：double 类型为 float64，float 类型为 float32
+//   - rune：Rune；bool：bool；String：string
+//   - 数组：*Array；表：*Table；函数：*Closure、*Builtin 或者 Callable；异常：*Exception；nil：Go 的 nil
+type Value interface{}
+
+// 字符，以 UTF-16 码元的范围存放
+type Rune uint16
+
+// 数组是引用类型，赋值时共享同一份元素
+type Array struct {
+	Elements []Value
+}
+
+// 表以字符串为键，同样是引用类型
+type Table struct {
+	Entries map[string]Value
+}
+
+// 按字典序排列的全部键
+func (table *Table) Keys() []string {
+	keys := make([]string, 0, len(table.Entries))
+	for key := range table.Entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// throw 语句抛出的异常，是引用类型
+// 后端尚不支持自定义的类，运行时的异常都是内建的 Exception 类的实例
+type Exception struct {
+	Class   string
+	Message string
+}
+
+// 异常是否为 class 类的实例，Exception 是所有异常的父类
+func (exception *Exception) IsInstance(class string) bool {
+	return exception.Class == class || class == "Exception"
+}
+
+// 读取异常的字段，没有该字段时返回 false
+func (exception *Exception) Field(name string) (Value, bool) {
+	if name == "message" {
+		return exception.Message, true
+	}
+	return nil, false
+}
+
+// 字节码中定义的函数
+type Closure struct {
+	Function *Function
+	Index    int // 在函数表中的下标
+}
+
+// 以 Go 实现的内建函数
+type Builtin struct {
+	Name string
+	Fn   func(stdout io.Writer, args []Value) ([]Value, error)
+}
+
+// 其他执行方式中的函数值，如解释器中由语法树直接求值的函数
+type Callable interface {
+	FunctionName() string
+}
+
+// 将值格式化为 print 等内建函数输出的文本
+func FormatValue(value Value) string {
+	switch value := value.(type) {
+	case nil:
+		return "nil"
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case uint64:
+		return strconv.FormatUint(value, 10)
+	case float32:
+		return strconv.FormatFloat(float64(value), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case Rune:
+		return string(rune(value))
+	case string:
+		return value
+	case *Array:
+		elements := make([]string, len(value.Elements))
+		for i, element := range value.Elements {
+			elements[i] = quoteValue(element)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *Table:
+		var entries []string
+		for _, key := range value.Keys() {
+			entries = append(entries, key+": "+quoteValue(value.Entries[key]))
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case *Closure:
+		return "<fn " + value.Function.Name + ">"
+	case *Builtin:
+		return "<builtin " + value.Name + ">"
+	case Callable:
+		return "<fn " + value.FunctionName() + ">"
+	case *Exception:
+		return value.Class + ": " + value.Message
+	}
+	return "<unknown>"
+}
+
+// 数组与表中的字符串与字符加上引号，以便与数字区分
+func quoteValue(value Value) string {
+	switch value := value.(type) {
+	case string:
+		return strconv.Quote(value)
+	case Rune:
+		return strconv.QuoteRune(rune(value))
+	}
+	return FormatValue(value)
+}
+
+// 值的类型名，用于运行时的错误信息
+func ValueTypeName(value Value) string {
+	switch value := value.(type) {
+	case nil:
+		return "nil"
+	case int64:
+		return "int"
+	case uint64:
+		return "uint64"
+	case float32:
+		return "float"
+	case float64:
+		return "double"
+	case bool:
+		return "bool"
+	case Rune:
+		return "rune"
+	case string:
+		return "String"
+	case *Array:
+		return "array"
+	case *Table:
+		return "table"
+	case *Closure, *Builtin, Callable:
+		return "function"
+	case *Exception:
+		return value.Class
+	}
+	return "unknown"
+}
+
+// 两个值是否相等：基本类型比较值，数组、表与函数比较是否为同一个
+func ValuesEqual(a, b Value) bool {
+	switch a := a.(type) {
+	case *Closure:
+		if b, ok := b.(*Closure); ok {
+			return a.Index == b.Index
+		}
+		return false
+	case *Array, *Table, *Builtin:
+		return a == b
+	}
+	if x, y, ok := numericPair(a, b); ok {
+		switch x := x.(type) {
+		case float32:
+			return x == y.(float32)
+		case float64:
+			return x == y.(float64)
+		case uint64:
+			return x == y.(uint64)
+		case int64:
+			return x == y.(int64)
+		}
+	}
+	return a == b
+}
+
+// 将两个数值转换为同一种表示：其一为 float64 时都转为 float64，其一为 float32 时都转为 float32
+// 其一为 uint64 时都转为 uint64，否则为 int64
+func numericPair(a, b Value) (Value, Value, bool) {
+	if !isNumber(a) || !isNumber(b) {
+		return nil, nil, false
+	}
+	_, aDouble := a.(float64)
+	_, bDouble := b.(float64)
+	_, aUnsigned := a.(uint64)
+	_, bUnsigned := b.(uint64)
+	switch {
+	case aDouble || bDouble:
+		return toFloat(a), toFloat(b), true
+	case isFloat(a) || isFloat(b):
+		return float32(toFloat(a)), float32(toFloat(b)), true
+	case aUnsigned || bUnsigned:
+		return uint64(toInt(a)), uint64(toInt(b)), true
+	}
+	return toInt(a), toInt(b), true
+}
+
+func isNumber(value Value) bool {
+	switch value.(type) {
+	case int64, uint64, float32, float64, Rune:
+		return true
+	}
+	return false
+}
+func isFloat(value Value) bool {
+	switch value.(type) {
+	case float32, float64:
+		return true
+	}
+	return false
+}
+func isInteger(value Value) bool {
+	switch value.(type) {
+	case int64, uint64, Rune:
+		return true
+	}
+	return false
+}
+
+// 数值的 int64 表示，uint64 保留其位模式，浮点数向零截断
+func toInt(value Value) int64 {
+	switch value := value.(type) {
+	case int64:
+		return value
+	case uint64:
+		return int64(value)
+	case float32:
+		return int64(value)
+	case float64:
+		return int64(value)
+	case Rune:
+		return int64(value)
+	case bool:
+		if value {
+			return 1
+		}
+	}
+	return 0
+}
+func toFloat(value Value) float64 {
+	switch value := value.(type) {
+	case int64:
+		return float64(value)
+	case uint64:
+		return float64(value)
+	case float32:
+		return float64(value)
+	case float64:
+		return value
+	case Rune:
+		return float64(value)
+	case bool:
+		if value {
+			return 1
+		}
+	}
+	return 0
+}
+
+// 将值转换为类型编码所代表的基本类型，整数按其位数截断
+func Convert(value Value, code TypeCode) Value {
+	if code == TypeUint64 {
+		if isFloat(value) {
+			return uint64(toFloat(value))
+		}
+		return uint64(toInt(value))
+	}
+	switch code {
+	case TypeInt:
+		return int64(int32(toInt(value)))
+	case TypeInt8:
+		return int64(int8(toInt(value)))
+	case TypeInt16:
+		return int64(int16(toInt(value)))
+	case TypeInt64:
+		return toInt(value)
+	case TypeUint:
+		return int64(uint32(toInt(value)))
+	case TypeUint8:
+		return int64(uint8(toInt(value)))
+	case TypeUint16:
+		return int64(uint16(toInt(value)))
+	case TypeFloat:
+		return float32(toFloat(value))
+	case TypeDouble:
+		return toFloat(value)
+	case TypeRune:
+		return Rune(toInt(value))
+	case TypeBool:
+		if b, isBool := value.(bool); isBool {
+			return b
+		}
+		return toFloat(value) != 0
+	}
+	return value
+}
@@ -0,0 +1,761 @@
+package vm
+
+import (
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/exception"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// 调用栈深度的上限，超过时报告栈溢出而不是耗尽 Go 的内存
+const MaxFrames = 4096
+
+// 区间生成的数组长度上限
+const maxRangeLength = 1 << 24
+
+// 一次函数调用的调用帧
+type frame struct {
+	closure *Closure
+	pc      int // 下一条指令的偏移量
+	base    int // 第 0 个局部变量槽位在操作数栈中的位置
+	start   int // 当前指令的偏移量，用于定位出错的源代码行
+}
+
+// OpTry 登记的异常处理入口
+type handler struct {
+	frame  int // 登记时的调用帧在调用栈中的下标
+	height int // 登记时操作数栈的高度
+	target int // 处理异常的代码在该调用帧的函数中的偏移量
+}
+
+// 调用栈中的一层，由内向外排列，用于报告运行时错误的位置
+type TraceEntry struct {
+	Function string
+	Source   string
+	Line     int
+}
+
+type VM struct {
+	module  *Module
+	globals []Value
+	stack   []Value // 操作数栈，各调用帧的局部变量也存放在其中
+	frames  []*frame
+	tries   []handler // 尚未撤销的异常处理入口，最近登记的在最后
+
+	Stdout io.Writer    // 内建函数 print 等的输出
+	Trace  []TraceEntry // 最近一次运行时错误发生时的调用栈
+}
+
+// 以已经校验过的模块创建虚拟机
+func NewVM(module *Module, stdout io.Writer) *VM {
+	return &VM{
+		module:  module,
+		globals: make([]Value, len(module.Globals)),
+		Stdout:  stdout,
+	}
+}
+
+// 从入口函数开始执行整个模块，出错时返回运行时错误，出错的位置记录在 vm.Trace 中
+func (vm *VM) Run() (err *CoralCompileError) {
+	vm.stack = vm.stack[:0]
+	vm.frames = vm.frames[:0]
+	vm.tries = vm.tries[:0]
+	vm.Trace = nil
+	entry := &Closure{Function: vm.module.Functions[vm.module.Entry], Index: vm.module.Entry}
+	vm.push(entry)
+	if err := vm.call(entry, 0); err != nil {
+		return vm.fail(err)
+	}
+	defer func() {
+		// 校验只保证指令本身合法，手工构造的字节码仍可能使操作数栈失衡
+		if recovered := recover(); recovered != nil {
+			err = vm.fail(fmt.Errorf("corrupted bytecode: %v", recovered))
+		}
+	}()
+	if err := vm.execute(); err != nil {
+		return vm.fail(err)
+	}
+	return nil
+}
+
+// 以当前的调用栈记录出错位置，并转换为运行时错误
+func (vm *VM) fail(err error) *CoralCompileError {
+	for i := len(vm.frames) - 1; i >= 0; i-- {
+		f := vm.frames[i]
+		function := f.closure.Function
+		vm.Trace = append(vm.Trace, TraceEntry{Function: function.Name, Source: function.Source, Line: function.LineOf(f.start)})
+	}
+	return NewCoralError("Runtime", err.Error()+"!", RuntimeError)
+}
+
+// 生成运行时错误的诊断信息，调用链上的每一层调用都作为一条说明
+func (vm *VM) Diagnostic(err *CoralCompileError) *Diagnostic {
+	if len(vm.Trace) == 0 {
+		return NewDiagnostic(vm.module.Source, Position{}, Position{}, err)
+	}
+	position := func(line int) Position {
+		return Position{Line: line, Col: 1}
+	}
+	source := func(entry TraceEntry) string {
+		if entry.Source == "" {
+			return vm.module.Source
+		}
+		return entry.Source
+	}
+	diagnostic := NewDiagnostic(source(vm.Trace[0]), position(vm.Trace[0].Line), position(vm.Trace[0].Line), err)
+	for i := 1; i < len(vm.Trace); i++ {
+		callee := vm.Trace[i-1].Function
+		diagnostic.AddNote(source(vm.Trace[i]), position(vm.Trace[i].Line), position(vm.Trace[i].Line),
+			fmt.Sprintf("%s called from %s", callee, vm.Trace[i].Function))
+	}
+	return diagnostic
+}
+
+func (vm *VM) push(value Value) {
+	vm.stack = append(vm.stack, value)
+}
+func (vm *VM) pop() Value {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+func (vm *VM) peek() Value {
+	return vm.stack[len(vm.stack)-1]
+}
+
+// 调用位于实参之下的函数：字节码函数压入新的调用帧，内建函数则直接执行
+func (vm *VM) call(callee Value, argc int) error {
+	switch callee := callee.(type) {
+	case *Closure:
+		function := callee.Function
+		if argc != function.Params {
+			return fmt.Errorf("%s expects %d arguments, got %d", function.Name, function.Params, argc)
+		}
+		if len(vm.frames) >= MaxFrames {
+			return errors.New("stack overflow")
+		}
+		vm.frames = append(vm.frames, &frame{closure: callee, base: len(vm.stack) - argc})
+		for i := function.Params; i < function.Locals; i++ {
+			vm.push(nil)
+		}
+		return nil
+	case *Builtin:
+		args := append([]Value(nil), vm.stack[len(vm.stack)-argc:]...)
+		vm.stack = vm.stack[:len(vm.stack)-argc-1]
+		results, err := callee.Fn(vm.Stdout, args)
+		if err != nil {
+			return err
+		}
+		vm.stack = append(vm.stack, results...)
+		return nil
+	}
+	return fmt.Errorf("cannot call value of type %s", ValueTypeName(callee))
+}
+
+// 执行指令直到入口函数返回
+func (vm *VM) execute() error {
+	for {
+		f := vm.frames[len(vm.frames)-1]
+		code := f.closure.Function.Code
+		if f.pc >= len(code) {
+			return fmt.Errorf("function %s ended without returning", f.closure.Function.Name)
+		}
+		f.start = f.pc
+		op := Opcode(code[f.pc])
+		definition := Lookup(op)
+		operand := 0
+		if len(definition.OperandWidths) > 0 {
+			operand = ReadOperand(code[f.pc+1:], definition.OperandWidths[0])
+		}
+		f.pc += definition.Length()
+
+		switch op {
+		case OpNop:
+		case OpConstant:
+			vm.push(constantValue(vm.module.Constants[operand]))
+		case OpNil:
+			vm.push(nil)
+		case OpTrue:
+			vm.push(true)
+		case OpFalse:
+			vm.push(false)
+
+		case OpPop:
+			vm.pop()
+		case OpDup:
+			vm.push(vm.peek())
+		case OpDup2:
+			vm.stack = append(vm.stack, vm.stack[len(vm.stack)-2], vm.stack[len(vm.stack)-1])
+
+		case OpGetLocal:
+			vm.push(vm.stack[f.base+operand])
+		case OpSetLocal:
+			vm.stack[f.base+operand] = vm.peek()
+		case OpGetGlobal:
+			vm.push(vm.globals[operand])
+		case OpSetGlobal:
+			vm.globals[operand] = vm.peek()
+		case OpGetBuiltin:
+			vm.push(Builtins[vm.module.Constants[operand].Str])
+
+		case OpAdd, OpSub, OpMul, OpDiv, OpRem, OpPow, OpBitAnd, OpBitOr, OpBitXor, OpShl, OpShr:
+			right := vm.pop()
+			result, err := Arithmetic(op, vm.pop(), right)
+			if err != nil {
+				return err
+			}
+			vm.push(result)
+		case OpNeg, OpBitNot, OpNot:
+			result, err := Unary(op, vm.pop())
+			if err != nil {
+				return err
+			}
+			vm.push(result)
+		case OpEqual:
+			right := vm.pop()
+			vm.push(ValuesEqual(vm.pop(), right))
+		case OpNotEqual:
+			right := vm.pop()
+			vm.push(!ValuesEqual(vm.pop(), right))
+		case OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+			right := vm.pop()
+			result, err := Compare(op, vm.pop(), right)
+			if err != nil {
+				return err
+			}
+			vm.push(result)
+		case OpConvert:
+			vm.push(Convert(vm.pop(), TypeCode(operand)))
+
+		case OpJump:
+			f.pc = operand
+		case OpJumpIfFalse:
+			condition, err := vm.condition(vm.pop())
+			if err != nil {
+				return err
+			}
+			if !condition {
+				f.pc = operand
+			}
+		case OpJumpIfFalseOrPop, OpJumpIfTrueOrPop:
+			condition, err := vm.condition(vm.peek())
+			if err != nil {
+				return err
+			}
+			if condition == (op == OpJumpIfTrueOrPop) {
+				f.pc = operand
+			} else {
+				vm.pop()
+			}
+
+		case OpFunction:
+			vm.push(&Closure{Function: vm.module.Functions[operand], Index: operand})
+		case OpCall:
+			if err := vm.call(vm.stack[len(vm.stack)-operand-1], operand); err != nil {
+				return err
+			}
+		case OpReturn:
+			// 以返回值替换被调用者、实参与局部变量
+			results := append([]Value(nil), vm.stack[len(vm.stack)-operand:]...)
+			vm.stack = append(vm.stack[:f.base-1], results...)
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			if len(vm.frames) == 0 {
+				return nil
+			}
+			// 编译器在返回前已经撤销了当前函数中登记的处理入口，这里只防备手工构造的字节码
+			for len(vm.tries) > 0 && vm.tries[len(vm.tries)-1].frame >= len(vm.frames) {
+				vm.tries = vm.tries[:len(vm.tries)-1]
+			}
+
+		case OpArray:
+			elements := append([]Value(nil), vm.stack[len(vm.stack)-operand:]...)
+			vm.stack = vm.stack[:len(vm.stack)-operand]
+			vm.push(&Array{Elements: elements})
+		case OpTable:
+			table := &Table{Entries: make(map[string]Value, operand)}
+			pairs := vm.stack[len(vm.stack)-2*operand:]
+			for i := 0; i < len(pairs); i += 2 {
+				table.Entries[pairs[i].(string)] = pairs[i+1]
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2*operand]
+			vm.push(table)
+		case OpRange:
+			end := vm.pop()
+			result, err := Range(vm.pop(), end, operand == 1)
+			if err != nil {
+				return err
+			}
+			vm.push(result)
+		case OpIndex:
+			index := vm.pop()
+			result, err := Index(vm.pop(), index)
+			if err != nil {
+				return err
+			}
+			vm.push(result)
+		case OpSetIndex:
+			value := vm.pop()
+			index := vm.pop()
+			if err := SetIndex(vm.pop(), index, value); err != nil {
+				return err
+			}
+			vm.push(value)
+		case OpSlice:
+			var start, end Value
+			if operand&SliceHasEnd != 0 {
+				end = vm.pop()
+			}
+			if operand&SliceHasStart != 0 {
+				start = vm.pop()
+			}
+			result, err := Slice(vm.pop(), start, end)
+			if err != nil {
+				return err
+			}
+			vm.push(result)
+		case OpLength:
+			result, err := Length(vm.pop())
+			if err != nil {
+				return err
+			}
+			vm.push(result)
+		case OpKeys:
+			table, isTable := vm.pop().(*Table)
+			if !isTable {
+				return errors.New("cannot take the keys of a non-table value")
+			}
+			keys := table.Keys()
+			elements := make([]Value, len(keys))
+			for i, key := range keys {
+				elements[i] = key
+			}
+			vm.push(&Array{Elements: elements})
+
+		case OpTry:
+			vm.tries = append(vm.tries, handler{frame: len(vm.frames) - 1, height: len(vm.stack), target: operand})
+		case OpEndTry:
+			if len(vm.tries) == 0 {
+				return errors.New("no exception handler to end")
+			}
+			vm.tries = vm.tries[:len(vm.tries)-1]
+		case OpThrow:
+			exception, isException := vm.pop().(*Exception)
+			if !isException {
+				return errors.New("cannot throw a value that is not an exception")
+			}
+			if len(vm.tries) == 0 {
+				return fmt.Errorf("uncaught exception %s", FormatValue(exception))
+			}
+			// 回退到处理入口所在的调用帧与栈高度，异常作为处理代码的操作数
+			h := vm.tries[len(vm.tries)-1]
+			vm.tries = vm.tries[:len(vm.tries)-1]
+			vm.frames = vm.frames[:h.frame+1]
+			vm.stack = append(vm.stack[:h.height], exception)
+			vm.frames[h.frame].pc = h.target
+		case OpException:
+			message, isString := vm.pop().(string)
+			if !isString {
+				return errors.New("exception message must be a String")
+			}
+			vm.push(&Exception{Class: vm.module.Constants[operand].Str, Message: message})
+		case OpIsInstance:
+			exception, isException := vm.pop().(*Exception)
+			vm.push(isException && exception.IsInstance(vm.module.Constants[operand].Str))
+		case OpGetField:
+			object := vm.pop()
+			name := vm.module.Constants[operand].Str
+			exception, isException := object.(*Exception)
+			if !isException {
+				return fmt.Errorf("cannot get field %s of %s", name, ValueTypeName(object))
+			}
+			field, ok := exception.Field(name)
+			if !ok {
+				return fmt.Errorf("%s has no field %s", exception.Class, name)
+			}
+			vm.push(field)
+		default:
+			return fmt.Errorf("unknown opcode %d", op)
+		}
+	}
+}
+
+func (vm *VM) condition(value Value) (bool, error) {
+	condition, isBool := value.(bool)
+	if !isBool {
+		return false, fmt.Errorf("condition must be a bool, got %s", ValueTypeName(value))
+	}
+	return condition, nil
+}
+
+func constantValue(constant Constant) Value {
+	switch constant.Kind {
+	case ConstantInt:
+		return constant.Int
+	case ConstantFloat:
+		return constant.Float
+	case ConstantString:
+		return constant.Str
+	}
+	return nil
+}
+
+// 二元算术与位运算，两个字符串相加即拼接
+func Arithmetic(op Opcode, left, right Value) (Value, error) {
+	if op == OpAdd {
+		if l, ok := left.(string); ok {
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		}
+	}
+	if op == OpShl || op == OpShr {
+		return shift(op, left, right)
+	}
+	x, y, ok := numericPair(left, right)
+	if !ok {
+		return nil, fmt.Errorf("invalid operation %s on %s and %s", op, ValueTypeName(left), ValueTypeName(right))
+	}
+	switch x := x.(type) {
+	case float32:
+		// 以 float64 计算再舍入，四则运算的结果与以 float32 直接计算相同
+		result, err := floatArithmetic(op, float64(x), float64(y.(float32)))
+		if err != nil {
+			return nil, err
+		}
+		return float32(result.(float64)), nil
+	case float64:
+		return floatArithmetic(op, x, y.(float64))
+	case uint64:
+		return unsignedArithmetic(op, x, y.(uint64))
+	}
+	// 字符参与运算时以 int64 计算，需要时由编译器之后的 OpConvert 转换回 rune
+	return intArithmetic(op, x.(int64), y.(int64))
+}
+
+var errDivisionByZero = errors.New("integer division by zero")
+
+func intArithmetic(op Opcode, x, y int64) (Value, error) {
+	switch op {
+	case OpAdd:
+		return x + y, nil
+	case OpSub:
+		return x - y, nil
+	case OpMul:
+		return x * y, nil
+	case OpDiv, OpRem:
+		if y == 0 {
+			return nil, errDivisionByZero
+		}
+		if op == OpDiv {
+			return x / y, nil
+		}
+		return x % y, nil
+	case OpPow:
+		if y < 0 {
+			return int64(math.Pow(float64(x), float64(y))), nil
+		}
+		result := int64(1)
+		for ; y > 0; y >>= 1 {
+			if y&1 == 1 {
+				result *= x
+			}
+			x *= x
+		}
+		return result, nil
+	case OpBitAnd:
+		return x & y, nil
+	case OpBitOr:
+		return x | y, nil
+	case OpBitXor:
+		return x ^ y, nil
+	}
+	return nil, fmt.Errorf("invalid operation %s on integers", op)
+}
+func unsignedArithmetic(op Opcode, x, y uint64) (Value, error) {
+	switch op {
+	case OpAdd:
+		return x + y, nil
+	case OpSub:
+		return x - y, nil
+	case OpMul:
+		return x * y, nil
+	case OpDiv, OpRem:
+		if y == 0 {
+			return nil, errDivisionByZero
+		}
+		if op == OpDiv {
+			return x / y, nil
+		}
+		return x % y, nil
+	case OpPow:
+		result := uint64(1)
+		for ; y > 0; y >>= 1 {
+			if y&1 == 1 {
+				result *= x
+			}
+			x *= x
+		}
+		return result, nil
+	case OpBitAnd:
+		return x & y, nil
+	case OpBitOr:
+		return x | y, nil
+	case OpBitXor:
+		return x ^ y, nil
+	}
+	return nil, fmt.Errorf("invalid operation %s on integers", op)
+}
+func floatArithmetic(op Opcode, x, y float64) (Value, error) {
+	switch op {
+	case OpAdd:
+		return x + y, nil
+	case OpSub:
+		return x - y, nil
+	case OpMul:
+		return x * y, nil
+	case OpDiv:
+		return x / y, nil
+	case OpRem:
+		return math.Mod(x, y), nil
+	case OpPow:
+		return math.Pow(x, y), nil
+	}
+	return nil, fmt.Errorf("invalid operation %s on floating-point numbers", op)
+}
+
+// 移位的结果与左操作数的表示相同，位数必须非负
+func shift(op Opcode, left, right Value) (Value, error) {
+	if !isInteger(left) || !isInteger(right) {
+		return nil, fmt.Errorf("invalid operation %s on %s and %s", op, ValueTypeName(left), ValueTypeName(right))
+	}
+	count := toInt(right)
+	if _, unsigned := right.(uint64); !unsigned && count < 0 {
+		return nil, fmt.Errorf("negative shift count %d", count)
+	}
+	if count > 64 {
+		count = 64
+	}
+	if x, unsigned := left.(uint64); unsigned {
+		if op == OpShl {
+			return x << uint64(count), nil
+		}
+		return x >> uint64(count), nil
+	}
+	x := toInt(left)
+	if op == OpShl {
+		return x << uint64(count), nil
+	}
+	return x >> uint64(count), nil
+}
+
+func Unary(op Opcode, operand Value) (Value, error) {
+	switch op {
+	case OpNot:
+		if b, isBool := operand.(bool); isBool {
+			return !b, nil
+		}
+	case OpNeg:
+		switch x := operand.(type) {
+		case float32:
+			return -x, nil
+		case float64:
+			return -x, nil
+		case uint64:
+			return -x, nil
+		case int64, Rune:
+			return -toInt(x), nil
+		}
+	case OpBitNot:
+		switch x := operand.(type) {
+		case uint64:
+			return ^x, nil
+		case int64, Rune:
+			return ^toInt(x), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid operation %s on %s", op, ValueTypeName(operand))
+}
+
+// 比较两个数或者两个字符串的大小
+func Compare(op Opcode, left, right Value) (bool, error) {
+	var sign int
+	if l, isString := left.(string); isString {
+		r, isString := right.(string)
+		if !isString {
+			return false, fmt.Errorf("cannot compare String with %s", ValueTypeName(right))
+		}
+		sign = strings.Compare(l, r)
+	} else {
+		x, y, ok := numericPair(left, right)
+		if !ok {
+			return false, fmt.Errorf("cannot compare %s with %s", ValueTypeName(left), ValueTypeName(right))
+		}
+		switch x := x.(type) {
+		case float32:
+			sign = compareFloat(float64(x), float64(y.(float32)))
+		case float64:
+			sign = compareFloat(x, y.(float64))
+		case uint64:
+			sign = compareOrdered(x < y.(uint64), x > y.(uint64))
+		case int64:
+			sign = compareOrdered(x < y.(int64), x > y.(int64))
+		}
+		if sign == 2 { // NaN 与任何数比较都为 false
+			return false, nil
+		}
+	}
+	switch op {
+	case OpLess:
+		return sign < 0, nil
+	case OpLessEqual:
+		return sign <= 0, nil
+	case OpGreater:
+		return sign > 0, nil
+	}
+	return sign >= 0, nil
+}
+func compareOrdered(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+func compareFloat(x, y float64) int {
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return 2
+	}
+	return compareOrdered(x < y, x > y)
+}
+
+// 由起点与终点组成整数数组
+func Range(start, end Value, includeEnd bool) (Value, error) {
+	if !isInteger(start) || !isInteger(end) {
+		return nil, fmt.Errorf("range endpoints must be integers, got %s and %s", ValueTypeName(start), ValueTypeName(end))
+	}
+	_, unsigned := start.(uint64)
+	from, to := toInt(start), toInt(end)
+	if includeEnd {
+		to++
+	}
+	if to-from > maxRangeLength {
+		return nil, fmt.Errorf("range %d..%d is too large", from, to)
+	}
+	var elements []Value
+	for i := from; i < to; i++ {
+		if unsigned {
+			elements = append(elements, uint64(i))
+		} else {
+			elements = append(elements, i)
+		}
+	}
+	return &Array{Elements: elements}, nil
+}
+
+// 读取数组元素、字符串中的字符或者表中的值，表中不存在的键读出 nil
+func Index(container, index Value) (Value, error) {
+	switch container := container.(type) {
+	case *Array:
+		i, err := checkIndex(index, len(container.Elements))
+		if err != nil {
+			return nil, err
+		}
+		return container.Elements[i], nil
+	case string:
+		runes := []rune(container)
+		i, err := checkIndex(index, len(runes))
+		if err != nil {
+			return nil, err
+		}
+		return Rune(runes[i]), nil
+	case *Table:
+		key, isString := index.(string)
+		if !isString {
+			return nil, fmt.Errorf("table key must be a String, got %s", ValueTypeName(index))
+		}
+		return container.Entries[key], nil
+	}
+	return nil, fmt.Errorf("cannot index value of type %s", ValueTypeName(container))
+}
+
+// 写入数组元素或者表中的值，字符串不可修改
+func SetIndex(container, index, value Value) error {
+	switch container := container.(type) {
+	case *Array:
+		i, err := checkIndex(index, len(container.Elements))
+		if err != nil {
+			return err
+		}
+		container.Elements[i] = value
+		return nil
+	case *Table:
+		key, isString := index.(string)
+		if !isString {
+			return fmt.Errorf("table key must be a String, got %s", ValueTypeName(index))
+		}
+		container.Entries[key] = value
+		return nil
+	}
+	return fmt.Errorf("cannot assign to an element of %s", ValueTypeName(container))
+}
+
+func checkIndex(index Value, length int) (int, error) {
+	if !isInteger(index) {
+		return 0, fmt.Errorf("index must be an integer, got %s", ValueTypeName(index))
+	}
+	i := toInt(index)
+	if _, unsigned := index.(uint64); (unsigned && uint64(i) >= uint64(length)) || i < 0 || i >= int64(length) {
+		return 0, fmt.Errorf("index %s out of range [0, %d)", FormatValue(index), length)
+	}
+	return int(i), nil
+}
+
+// 切片 [start, end)，省略的起点为 0，省略的终点为长度，数组的切片是一个新的数组
+func Slice(container, start, end Value) (Value, error) {
+	var length int
+	switch container := container.(type) {
+	case *Array:
+		length = len(container.Elements)
+	case string:
+		length = len([]rune(container))
+	default:
+		return nil, fmt.Errorf("cannot slice value of type %s", ValueTypeName(container))
+	}
+	from, to := 0, length
+	var err error
+	if start != nil {
+		if from, err = checkIndex(start, length+1); err != nil {
+			return nil, err
+		}
+	}
+	if end != nil {
+		if to, err = checkIndex(end, length+1); err != nil {
+			return nil, err
+		}
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid slice indices %d > %d", from, to)
+	}
+	if array, isArray := container.(*Array); isArray {
+		return &Array{Elements: append([]Value(nil), array.Elements[from:to]...)}, nil
+	}
+	return string([]rune(container.(string))[from:to]), nil
+}
+
+// 数组的元素个数、字符串的字符个数或者表的键值对个数
+func Length(value Value) (Value, error) {
+	switch value := value.(type) {
+	case *Array:
+		return int64(len(value.Elements)), nil
+	case string:
+		return int64(len([]rune(value))), nil
+	case *Table:
+		return int64(len(value.Entries)), nil
+	}
+	return nil, fmt.Errorf("cannot take the length of %s", ValueTypeName(value))
+}
@@ -1 +1,624 @@
 package test
+
+import (
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func analyzeString(content string) *Analyzer {
+	analyzer := new(Analyzer)
+	analyzer.InitAnalyzerFromString(content)
+	analyzer.CheckProgram()
+	return analyzer
+}
+
+func TestSymbolTables(t *testing.T) {
+	Convey("测试为各类定义创建符号：", t, func() {
+		analyzer := analyzeString(`var a int = 1;
+fn add(x int, y int) int { return x + y; }
+class Dog { var name String = "dog"; fn Dog() {} fn bark() { println(this.name); } }
+interface Pet { fn play(); }
+enum Color { Red, Green }`)
+		So(len(analyzer.Errors), ShouldEqual, 0)
+
+		root := analyzer.RootScope.SymbolMap
+		So(root["a"].GetKind(), ShouldEqual, IdentifierSymbolKind)
+		So(root["add"].(*IdSymbol).Type.TypeKind(), ShouldEqual, TypeKindFunction)
+		So(root["Pet"].GetKind(), ShouldEqual, TypeSymbolKind)
+
+		dog := root["Dog"].(*TypeSymbol)
+		So(dog.Members.SymbolMap, ShouldContainKey, "name")
+		So(dog.Members.SymbolMap, ShouldContainKey, "bark")
+
+		color := root["Color"].(*EnumSymbol)
+		So(color.CollectionName, ShouldEqual, "Color")
+		So(color.ElementsMap, ShouldContainKey, "Red")
+		So(color.ElementsMap, ShouldContainKey, "Green")
+	})
+
+	Convey("测试函数可以在定义之前被调用，内建函数无需定义：", t, func() {
+		analyzer := analyzeString(`fn main() { hello(); }
+fn hello() { println("hello"); }`)
+		So(len(analyzer.Errors), ShouldEqual, 0)
+	})
+
+	Convey("测试区块内的定义不会泄漏到外层：", t, func() {
+		analyzer := analyzeString(`if true { var inner = 1; }
+var outer = inner;`)
+		So(len(analyzer.Errors), ShouldEqual, 1)
+		So(analyzer.Errors[0].ErrEnum, ShouldEqual, UndeclaredIdentifier)
+	})
+}
+
+func TestDuplicateAndUndeclared(t *testing.T) {
+	Convey("测试同一区块中重复定义：指出此前定义的位置", t, func() {
+		analyzer := analyzeString("var a = 1;\nvar a = 2;")
+		So(len(analyzer.Diagnostics), ShouldEqual, 1)
+		diagnostic := analyzer.Diagnostics[0]
+		So(diagnostic.Code, ShouldEqual, DuplicateDefinition)
+		So(diagnostic.Start, ShouldResemble, Position{Line: 2, Col: 5})
+		So(len(diagnostic.Notes), ShouldEqual, 1)
+		So(diagnostic.Notes[0].Start, ShouldResemble, Position{Line: 1, Col: 5})
+	})
+
+	Convey("测试形参与函数体共享作用域：", t, func() {
+		analyzer := analyzeString("fn f(x int) { var x = 1; }")
+		So(len(analyzer.Errors), ShouldEqual, 1)
+		So(analyzer.Errors[0].ErrEnum, ShouldEqual, DuplicateDefinition)
+	})
+
+	Convey("测试内层区块可以遮蔽外层的定义：", t, func() {
+		analyzer := analyzeString("var a = 1;\nwhile true { var a = 2; }")
+		So(len(analyzer.Errors), ShouldEqual, 0)
+	})
+
+	Convey("测试枚举元素重复：", t, func() {
+		analyzer := analyzeString("enum Color { Red, Red }")
+		So(len(analyzer.Errors), ShouldEqual, 1)
+		So(analyzer.Errors[0].ErrEnum, ShouldEqual, DuplicateDefinition)
+	})
+
+	Convey("测试未定义的标识符：报告其位置", t, func() {
+		analyzer := analyzeString("var a = 1;\nvar b = a + c;")
+		So(len(analyzer.Diagnostics), ShouldEqual, 1)
+		diagnostic := analyzer.Diagnostics[0]
+		So(diagnostic.Code, ShouldEqual, UndeclaredIdentifier)
+		So(diagnostic.Start, ShouldResemble, Position{Line: 2, Col: 13})
+		So(diagnostic.End, ShouldResemble, Position{Line: 2, Col: 14})
+	})
+}
+
+func TestTypeInference(t *testing.T) {
+	Convey("测试由初始值推断变量类型：", t, func() {
+		analyzer := analyzeString(`fn pair() int, String { return 1, "a"; }
+var a = 1;
+var b = 2.5;
+var c = "s" + "t";
+var d int8 = 3;
+var e = d + 1;
+var f = [1, 2];
+var g = (x int) -> x > 0;
+var h int, i String;
+h, i = pair();`)
+		So(len(analyzer.Errors), ShouldEqual, 0)
+
+		typeOf := func(name string) string {
+			return analyzer.RootScope.SymbolMap[name].(*IdSymbol).Type.String()
+		}
+		So(typeOf("a"), ShouldEqual, "int")
+		So(typeOf("b"), ShouldEqual, "float")
+		So(typeOf("c"), ShouldEqual, "String")
+		So(typeOf("e"), ShouldEqual, "int8")
+		So(typeOf("f"), ShouldEqual, "int[]")
+		So(typeOf("g"), ShouldEqual, "fn(int) bool")
+	})
+
+	Convey("测试类成员与构造方法的类型：", t, func() {
+		analyzer := analyzeString(`class Dog {
+  var name String;
+  fn Dog(name String) { this.name = name; }
+  public fn bark() String { return this.name + "!"; }
+}
+var dog = new Dog("wang");
+var sound = dog.bark();`)
+		So(len(analyzer.Errors), ShouldEqual, 0)
+		So(analyzer.RootScope.SymbolMap["sound"].(*IdSymbol).Type, ShouldEqual, StringType)
+	})
+}
+
+func TestTypeMismatch(t *testing.T) {
+	cases := []struct {
+		source  string
+		errEnum int
+		line    int
+		col     int
+	}{
+		{"var a int = \"s\";", TypeMismatch, 1, 13},
+		{"var a int8 = 1;\nvar b int = a;", TypeMismatch, 2, 13},
+		{"var a = 1 + \"s\";", InvalidOperation, 1, 9},
+		{"var a = !1;", InvalidOperation, 1, 9},
+		{"if 1 { }", TypeMismatch, 1, 4},
+		{"while \"x\" { }", TypeMismatch, 1, 7},
+		{"fn f(x int) { }\nf(1, 2);", ValueCountMismatch, 2, 1},
+		{"fn f(x int) { }\nf(\"s\");", TypeMismatch, 2, 3},
+		{"fn f() int { return \"s\"; }", TypeMismatch, 1, 21},
+		{"fn f() int, int { return 1; }", ValueCountMismatch, 1, 19},
+		{"fn f() { }\nvar a = f();", TypeMismatch, 2, 9},
+		{"var a = 1;\na();", InvalidOperation, 2, 1},
+		{"var a = [1, 2];\nvar b = a[\"k\"];", TypeMismatch, 2, 11},
+		{"var a = (true as String);", TypeMismatch, 1, 9},
+		{"var a = 1;\nvar b = a.length;", UndeclaredIdentifier, 2, 11},
+		{"var a = 1;\na = \"s\";", TypeMismatch, 2, 5},
+		{"var a = \"s\";\na++;", InvalidOperation, 2, 1},
+	}
+
+	Convey("测试类型不匹配时报告带位置的错误：", t, func() {
+		for _, c := range cases {
+			analyzer := analyzeString(c.source)
+			So(len(analyzer.Diagnostics), ShouldEqual, 1)
+			So(analyzer.Diagnostics[0].Code, ShouldEqual, c.errEnum)
+			So(analyzer.Diagnostics[0].Start, ShouldResemble, Position{Line: c.line, Col: c.col})
+		}
+	})
+}
+
+func TestAssignTargets(t *testing.T) {
+	cases := []struct {
+		source  string
+		errEnum int
+		line    int
+		col     int
+	}{
+		{"fn f() { }\nfn g() { }\nf = g;", ImmutableAssignment, 3, 1},
+		{"fn f() { }\nprintln = f;", ImmutableAssignment, 2, 1},
+		{"enum C { R, G }\nC.R = C.G;", InvalidOperation, 2, 1},
+		{"var s = \"abc\";\ns.length = 3;", InvalidOperation, 2, 1},
+		{"var a = [1, 2];\na.length++;", InvalidOperation, 2, 1},
+		{"class A { fn A() { } public fn m() { } }\nvar a = new A();\na.m = a.m;", ImmutableAssignment, 3, 1},
+		{"class A { fn A() { } fn m() { } fn n() { m = n; } }", ImmutableAssignment, 1, 42},
+	}
+
+	Convey("测试函数、方法、枚举元素与内建成员不能被赋值：", t, func() {
+		for _, c := range cases {
+			analyzer := analyzeString(c.source)
+			So(len(analyzer.Diagnostics), ShouldEqual, 1)
+			So(analyzer.Diagnostics[0].Code, ShouldEqual, c.errEnum)
+			So(analyzer.Diagnostics[0].Start, ShouldResemble, Position{Line: c.line, Col: c.col})
+		}
+	})
+
+	Convey("测试成员变量与数组元素可以被赋值：", t, func() {
+		analyzer := analyzeString("class A { public var x int = 0; fn A() { } }\nvar a = new A();\na.x = 1;\nvar b = [a];\nb[0].x++;")
+		So(analyzer.Errors, ShouldBeEmpty)
+	})
+}
+
+func TestMissingReturn(t *testing.T) {
+	cases := []struct {
+		source string
+		line   int
+		col    int
+	}{
+		{"fn f() int { }", 1, 14},
+		{"fn f(a int) int {\n  if a > 0 { return 1; }\n}", 3, 1},
+		{"fn f(a int) int { if a > 0 { return 1; } elif a < 0 { } else { return 2; } }", 1, 76},
+		{"fn f(a int) int { switch a { case 1 { return 1; } } }", 1, 53},
+		{"fn f() int { while true { break; } }", 1, 36},
+		{"fn f() int { try { return 1; } catch e Exception { } }", 1, 54},
+		{"val f = (x int) int -> { };", 1, 26},
+	}
+
+	Convey("测试有返回值的函数可能执行到末尾时报错：", t, func() {
+		for _, c := range cases {
+			analyzer := analyzeString(c.source)
+			So(len(analyzer.Diagnostics), ShouldBeGreaterThanOrEqualTo, 1)
+			So(analyzer.Diagnostics[0].Code, ShouldEqual, MissingReturn)
+			So(analyzer.Diagnostics[0].Start, ShouldResemble, Position{Line: c.line, Col: c.col})
+		}
+	})
+
+	Convey("测试每条路径都以 return 或 throw 结束时不报错：", t, func() {
+		analyzer := analyzeString(`fn a(x int) int { if x > 0 { return 1; } elif x < 0 { return -1; } else { return 0; } }
+fn b(x int) int { switch x { case 1 { return 1; } case 2...5 { return 2; } default { return 3; } } }
+fn c() int { while true { each v in [1] { break; } } }
+fn d() int { try { throw new Exception("e"); } catch e Exception { return 1; } }
+fn e() int { try { } finally { return 1; } }
+fn g() int throws Exception { throw new Exception("e"); }
+fn h() { }`)
+		So(analyzer.Errors, ShouldBeEmpty)
+	})
+}
+
+func TestBuiltinTypeUniverse(t *testing.T) {
+	Convey("测试内建区块中的基本类型：宽度、符号与零值", t, func() {
+		analyzer := analyzeString("")
+		for _, name := range []string{"int", "int8", "int16", "int64", "uint", "uint8", "uint16", "uint64",
+			"float", "double", "rune", "bool", "String"} {
+			So(analyzer.BuiltinScope.SymbolMap, ShouldContainKey, name)
+		}
+
+		int8Type := analyzer.BuiltinScope.SymbolMap["int8"].(*TypeSymbol).Type.(*BasicType)
+		So(int8Type.Width, ShouldEqual, 1)
+		So(int8Type.Signed, ShouldBeTrue)
+		So(Uint16Type.Signed, ShouldBeFalse)
+		So(DoubleType.Bits(), ShouldEqual, 64)
+
+		So(ZeroValue(IntType), ShouldEqual, int64(0))
+		So(ZeroValue(DoubleType), ShouldEqual, float64(0))
+		So(ZeroValue(BoolType), ShouldEqual, false)
+		So(ZeroValue(StringType), ShouldEqual, "")
+		So(ZeroValue(&ArrayType{Element: IntType}), ShouldBeNil)
+	})
+
+	Convey("测试类型标注通过内建区块解析，且可以被源文件中的定义遮蔽：", t, func() {
+		analyzer := analyzeString("var a uint8 = 1;\nvar b = a;")
+		So(len(analyzer.Errors), ShouldEqual, 0)
+		So(analyzer.RootScope.SymbolMap["b"].(*IdSymbol).Type, ShouldEqual, Uint8Type)
+
+		analyzer = analyzeString("class int { fn int() {} }\nvar a int = new int();")
+		So(len(analyzer.Errors), ShouldEqual, 0)
+		So(analyzer.RootScope.SymbolMap["a"].(*IdSymbol).Type.TypeKind(), ShouldEqual, TypeKindClass)
+	})
+
+	Convey("测试未定义的类型与把类型名当作值：", t, func() {
+		analyzer := analyzeString("var a integer = 1;")
+		So(analyzer.Errors[0].ErrEnum, ShouldEqual, UndeclaredIdentifier)
+
+		analyzer = analyzeString("var a = int;")
+		So(analyzer.Errors[0].ErrEnum, ShouldEqual, TypeMismatch)
+	})
+}
+
+func TestConstantFolding(t *testing.T) {
+	Convey("测试常量表达式在编译期求值并折叠为字面量：", t, func() {
+		analyzer := analyzeString(`val kb = 1 << 10;
+val mb = kb * kb;
+val half = (mb + 1) / 2;
+val zero uint8 = 0;
+val mask = ~zero;
+val ratio = 1.5 * 4;`)
+		So(len(analyzer.Errors), ShouldEqual, 0)
+
+		root := analyzer.RootScope.SymbolMap
+		So(root["mb"].(*IdSymbol).Constant.String(), ShouldEqual, "1048576")
+		So(root["half"].(*IdSymbol).Constant.String(), ShouldEqual, "524288")
+		So(root["mask"].(*IdSymbol).Constant.String(), ShouldEqual, "255")
+		So(root["ratio"].(*IdSymbol).Constant.String(), ShouldEqual, "6")
+
+		half := analyzer.Ast.Root[2].(*VarDeclStatement).Declarations[0].InitValue
+		So(half.(*BasicPrimaryExpression).It.(*DecimalLit).Value.Str, ShouldEqual, "524288")
+	})
+
+	Convey("测试未定类型的常量以任意精度计算：", t, func() {
+		analyzer := analyzeString("val a int64 = (1 << 100) >> 98;")
+		So(len(analyzer.Errors), ShouldEqual, 0)
+		So(analyzer.RootScope.SymbolMap["a"].(*IdSymbol).Constant.String(), ShouldEqual, "4")
+	})
+
+	Convey("测试 var 变量不参与常量求值：", t, func() {
+		analyzer := analyzeString("var a = 1;\nval b = a + 1;")
+		So(len(analyzer.Errors), ShouldEqual, 0)
+		So(analyzer.RootScope.SymbolMap["b"].(*IdSymbol).Constant, ShouldBeNil)
+	})
+
+	cases := []struct {
+		source    string
+		errEnum   int
+		line, col int
+	}{
+		{"val j int8 = 128;", ConstantOverflow, 1, 14},
+		{"val j uint8 = -1;", ConstantOverflow, 1, 15},
+		{"val j = 1 << 40;", ConstantOverflow, 1, 9},
+		{"val a int8 = 100;\nval b = a + a;", ConstantOverflow, 2, 9},
+		{"var a = (300 as uint8);", ConstantOverflow, 1, 9},
+		{"fn f(x int16) { }\nf(1 << 20);", ConstantOverflow, 2, 3},
+		{"val a = 1 / 0;", ConstantDivisionByZero, 1, 9},
+	}
+
+	Convey("测试常量溢出与除以零：", t, func() {
+		for _, c := range cases {
+			analyzer := analyzeString(c.source)
+			So(len(analyzer.Diagnostics), ShouldEqual, 1)
+			So(analyzer.Diagnostics[0].Code, ShouldEqual, c.errEnum)
+			So(analyzer.Diagnostics[0].Start, ShouldResemble, Position{Line: c.line, Col: c.col})
+		}
+	})
+}
+
+func TestValueImmutability(t *testing.T) {
+	cases := []struct {
+		source    string
+		errEnum   int
+		line, col int
+	}{
+		{"val c int;", UninitializedValue, 1, 5},
+		{"val a = 1;\na = 2;", ImmutableAssignment, 2, 1},
+		{"val a = 1;\na++;", ImmutableAssignment, 2, 1},
+		{"val a = 1;\na += 2;", ImmutableAssignment, 2, 1},
+		{"val a = 1;\nvar b = 2;\nb, a = 3, 4;", ImmutableAssignment, 3, 4},
+		{"val a = [1, 2];", InvalidValueType, 1, 5},
+		{"class Dog { val name String = \"dog\"; fn Dog() {} fn rename() { this.name = \"cat\"; } }", ImmutableAssignment, 1, 64},
+		{"class Dog { val age = 1; fn Dog() {} fn grow() { age++; } }", ImmutableAssignment, 1, 50},
+	}
+
+	Convey("测试 val 必须初始化、不能被赋值，且只能是基本的值类型：", t, func() {
+		for _, c := range cases {
+			analyzer := analyzeString(c.source)
+			So(len(analyzer.Diagnostics), ShouldEqual, 1)
+			So(analyzer.Diagnostics[0].Code, ShouldEqual, c.errEnum)
+			So(analyzer.Diagnostics[0].Start, ShouldResemble, Position{Line: c.line, Col: c.col})
+		}
+	})
+
+	Convey("测试对 val 赋值时指出其定义的位置：", t, func() {
+		analyzer := analyzeString("val a = 1;\nif true { a = 2; }")
+		So(len(analyzer.Diagnostics), ShouldEqual, 1)
+		So(len(analyzer.Diagnostics[0].Notes), ShouldEqual, 1)
+		So(analyzer.Diagnostics[0].Notes[0].Start, ShouldResemble, Position{Line: 1, Col: 5})
+	})
+
+	Convey("测试 var 变量与内层遮蔽 val 的变量可以被赋值：", t, func() {
+		analyzer := analyzeString("val a = 1;\nvar b = a;\nb = 2;\nwhile true { var a = 2; a++; }")
+		So(len(analyzer.Errors), ShouldEqual, 0)
+	})
+}
+
+func TestClassSemantics(t *testing.T) {
+	cases := []struct {
+		source    string
+		errEnum   int
+		line, col int
+	}{
+		{"class A : B { fn A() {} }\nclass B : A { fn B() {} }", InheritanceCycle, 2, 11},
+		{"class A : A { fn A() {} }", InheritanceCycle, 1, 11},
+		{"interface I { fn f(); }\nclass A : I { fn A() {} }", TypeMismatch, 2, 11},
+		{"class B { fn B() {} }\nclass A <- B { fn A() {} }", TypeMismatch, 2, 12},
+		{"interface I { fn f(); }\nclass A <- I { fn A() {} }", InterfaceNotImplemented, 2, 12},
+		{"interface I { fn f() int; }\nclass A <- I { fn A() {} public fn f() {} }", InterfaceNotImplemented, 2, 12},
+		{"class B { fn B() {} public fn f() int { return 1; } }\nclass A : B { fn A() {} fn f() {} }", TypeMismatch, 2, 28},
+		{"class B { public var x = 1; fn B() {} }\nclass A : B { var x = 2; fn A() {} }", DuplicateDefinition, 2, 19},
+		{"class A { fn f() {} }", NoConstructorMethod, 1, 7},
+		{"class A { fn A() int { return 1; } }", NoConstructorMethod, 1, 14},
+		{"class B { fn B(x int) {} }\nclass A : B { fn A() {} }", NoConstructorMethod, 2, 18},
+		{"class B { fn B(x int) {} }\nclass A : B { fn A() { super(\"s\"); } }", TypeMismatch, 2, 30},
+		{"class B { fn B() {} }\nclass A : B { fn A() {} fn f() { super(); } }", InvalidOperation, 2, 34},
+		{"class A { fn A() { super.f(); } }", InvalidOperation, 1, 20},
+		{"var a = this;", InvalidOperation, 1, 9},
+	}
+
+	Convey("测试类的继承、接口实现与构造方法的检查：", t, func() {
+		for _, c := range cases {
+			analyzer := analyzeString(c.source)
+			So(len(analyzer.Diagnostics), ShouldEqual, 1)
+			So(analyzer.Diagnostics[0].Code, ShouldEqual, c.errEnum)
+			So(analyzer.Diagnostics[0].Start, ShouldResemble, Position{Line: c.line, Col: c.col})
+		}
+	})
+
+	Convey("测试继承的成员、子类到父类与接口的赋值，以及 this 与 super 所属的类：", t, func() {
+		analyzer := analyzeString(`interface Named { fn name() String; }
+interface Pet : Named { fn play() bool; }
+class Animal {
+  public var legs = 4;
+  fn Animal(legs int) { this.legs = legs; }
+  public fn describe() String { return "animal"; }
+}
+class Dog : Animal <- Pet {
+  fn Dog() { super(4); }
+  public fn name() String { return "dog"; }
+  public fn play() bool { return legs > 2; }
+  public fn describe() String { return super.describe() + "/" + this.name(); }
+}
+var dog = new Dog();
+var pet Pet = dog;
+var named Named = dog;
+var animal Animal = dog;
+var legs = dog.legs;`)
+		So(analyzer.Errors, ShouldBeEmpty)
+		So(analyzer.RootScope.SymbolMap["legs"].(*IdSymbol).Type, ShouldEqual, IntType)
+
+		dog := analyzer.RootScope.SymbolMap["Dog"].(*TypeSymbol).Type.(*ClassType)
+		So(dog.Base.String(), ShouldEqual, "Animal")
+		So(dog.Interfaces[0].Base.String(), ShouldEqual, "Named")
+
+		// 以 this 与 super 所在的行区分：Animal 的构造方法、Dog 的构造方法与 describe 方法
+		belongsTo := make(map[string]string)
+		for expression := range analyzer.Types {
+			if primary, isPrimary := expression.(*BasicPrimaryExpression); isPrimary {
+				switch literal := primary.It.(type) {
+				case *ThisLit:
+					belongsTo[fmt.Sprintf("this@%d", literal.Start.Line)] = literal.BelongsTo.Name.GetName()
+				case *SuperLit:
+					belongsTo[fmt.Sprintf("super@%d", literal.Start.Line)] = literal.BelongsTo.Name.GetName()
+				}
+			}
+		}
+		So(belongsTo, ShouldResemble, map[string]string{"this@5": "Animal", "super@9": "Animal", "this@12": "Dog", "super@12": "Animal"})
+
+		analyzer = analyzeString("class Animal { fn Animal() {} }\nclass Dog : Animal { fn Dog() {} }\nvar dog Dog = new Animal();")
+		So(analyzer.Errors, ShouldHaveLength, 1)
+		So(analyzer.Errors[0].Message, ShouldEqual, "cannot use value of type Animal as type Dog in variable declaration!")
+	})
+}
+
+func TestMemberAccess(t *testing.T) {
+	const counter = `class Counter {
+  private var secret = 1;
+  public var value = 0;
+  public static var count = 0;
+  fn Counter() { count++; this.value = Counter.count + secret; }
+  public static fn make() Counter { return new Counter(); }
+  private fn hidden() {}
+}
+`
+	cases := []struct {
+		source    string
+		errEnum   int
+		line, col int
+	}{
+		{"var c = new Counter();\nprintln(c.secret);", PrivateMemberAccess, 10, 11},
+		{"var c = new Counter();\nc.hidden();", PrivateMemberAccess, 10, 3},
+		{"var c = new Counter();\nc.count++;", StaticMemberAccess, 10, 3},
+		{"var v = Counter.value;", StaticMemberAccess, 9, 17},
+		{"var k = Counter;", TypeMismatch, 9, 9},
+		{"class Sub : Counter {\n  fn Sub() { secret = 2; }\n}", UndeclaredIdentifier, 10, 14},
+		{"class Sub : Counter {\n  fn Sub() {}\n  static fn f() int { return value; }\n}", StaticMemberAccess, 11, 30},
+		{"class Sub : Counter {\n  fn Sub() {}\n  static fn f() { this.value = 1; }\n}", StaticMemberAccess, 11, 19},
+	}
+
+	Convey("测试私有成员只能在所属的类中访问，静态成员只能通过类名访问：", t, func() {
+		for _, c := range cases {
+			analyzer := analyzeString(counter + c.source)
+			So(len(analyzer.Diagnostics), ShouldEqual, 1)
+			So(analyzer.Diagnostics[0].Code, ShouldEqual, c.errEnum)
+			So(analyzer.Diagnostics[0].Start, ShouldResemble, Position{Line: c.line, Col: c.col})
+		}
+	})
+
+	Convey("测试通过类名访问静态成员，类中以类名指的是类本身而不是构造方法：", t, func() {
+		analyzer := analyzeString(counter + "var c = Counter.make();\nCounter.count = c.value;")
+		So(analyzer.Errors, ShouldBeEmpty)
+		So(analyzer.RootScope.SymbolMap["c"].(*IdSymbol).Type.String(), ShouldEqual, "Counter")
+	})
+
+	Convey("测试实现接口的方法必须是公开的实例方法：", t, func() {
+		analyzer := analyzeString("interface I { fn f(); }\nclass A <- I { fn A() {} fn f() {} }")
+		So(analyzer.Errors, ShouldHaveLength, 1)
+		So(analyzer.Errors[0].Message, ShouldEqual, `class A does not implement I: method "f" must be a public instance method!`)
+	})
+}
+
+func TestGenerics(t *testing.T) {
+	const prelude = `interface Named { fn name() String; }
+class Dog <- Named { fn Dog() {} public fn name() String { return "dog"; } }
+class Cat { fn Cat() {} }
+class Box<T> {
+  public var value T;
+  fn Box(v T) { this.value = v; }
+  public fn get() T { return this.value; }
+}
+fn identity<T>(x T) T { return x; }
+fn describe<T : Named>(x T) String { return x.name(); }
+`
+	cases := []struct {
+		source    string
+		errEnum   int
+		line, col int
+	}{
+		{"var b Box;", TypeArgumentMismatch, 11, 7},
+		{"var b Box<int, int>;", TypeArgumentMismatch, 11, 7},
+		{"var d Dog<int>;", TypeArgumentMismatch, 11, 7},
+		{`var b = new Box<int>("one");`, TypeMismatch, 11, 22},
+		{"var s String = identity(1);", TypeMismatch, 11, 16},
+		{"var e = describe(new Cat());", ConstraintNotSatisfied, 11, 9},
+		{"class Kennel<T : Named> { fn Kennel() {} }\nvar k Kennel<Cat>;", ConstraintNotSatisfied, 12, 7},
+		{"fn none<T>() T { return nil; }\nvar n = none();", TypeArgumentMismatch, 12, 9},
+		{"fn add<T>(x T) int { return x + 1; }", InvalidOperation, 11, 29},
+		{"fn get<T>(x T) int { return x.length; }", UndeclaredIdentifier, 11, 31},
+		{"fn put<T>(x T) { var y int = x; }", TypeMismatch, 11, 30},
+	}
+
+	Convey("测试泛型实参的个数、类型与约束在每个使用的位置检查，泛型参数只能以其约束允许的方式使用：", t, func() {
+		for _, c := range cases {
+			analyzer := analyzeString(prelude + c.source)
+			So(analyzer.Errors, ShouldHaveLength, 1)
+			So(analyzer.Errors[0].ErrEnum, ShouldEqual, c.errEnum)
+			So(analyzer.Diagnostics[len(analyzer.Diagnostics)-1].Start, ShouldResemble, Position{Line: c.line, Col: c.col})
+		}
+	})
+
+	Convey("测试由实参推断泛型函数的泛型实参：", t, func() {
+		analyzer := analyzeString(prelude + `fn apply<A, B>(x A, f (A) -> B) B { return f(x); }
+fn unbox<T>(b Box<T>) T { return b.get(); }
+var i = identity(1);
+var s = apply(2, (x int) String -> "x");
+var f = unbox(new Box<float>(1.5));
+var n = describe(new Dog());`)
+		So(analyzer.Errors, ShouldBeEmpty)
+		root := analyzer.RootScope.SymbolMap
+		So(root["i"].(*IdSymbol).Type.String(), ShouldEqual, "int")
+		So(root["s"].(*IdSymbol).Type.String(), ShouldEqual, "String")
+		So(root["f"].(*IdSymbol).Type.String(), ShouldEqual, "float")
+		So(root["n"].(*IdSymbol).Type.String(), ShouldEqual, "String")
+		So(root["apply"].(*IdSymbol).Type.String(), ShouldEqual, "fn<A, B>(A, fn(A) B) B")
+	})
+
+	Convey("测试泛型类的成员、父类与接口以实参代入：", t, func() {
+		analyzer := analyzeString(prelude + `interface Getter<T> { fn get() T; }
+class Shelf<T> : Box<T> <- Getter<T> { fn Shelf(v T) { super(v); } public fn peek() T { return value; } }
+class Wrong <- Getter<int> { fn Wrong() {} public fn get() String { return ""; } }
+var shelf = new Shelf<String>("a");
+var peeked String = shelf.peek();
+var got String = shelf.value;
+var getter Getter<String> = shelf;
+var other Getter<int> = shelf;`)
+		So(analyzer.Errors, ShouldHaveLength, 2)
+		So(analyzer.Errors[0].Message, ShouldEqual,
+			`class Wrong does not implement Getter<int>: method "get" has type fn() String, want fn() int!`)
+		So(analyzer.Errors[1].Message, ShouldEqual,
+			"cannot use value of type Shelf<String> as type Getter<int> in variable declaration!")
+		So(analyzer.RootScope.SymbolMap["shelf"].(*IdSymbol).Type.String(), ShouldEqual, "Shelf<String>")
+	})
+}
+
+func TestCheckedExceptions(t *testing.T) {
+	const prelude = `class ParseError : Exception { fn ParseError(msg String) { super(msg); } }
+fn parse(s String) int throws ParseError { if s == "" { throw new ParseError("empty"); } return 1; }
+`
+	cases := []struct {
+		source    string
+		errEnum   int
+		line, col int
+	}{
+		{`parse("a");`, UnhandledException, 3, 1},
+		{`fn f() { parse("a"); }`, UnhandledException, 3, 10},
+		{`var g = () -> parse("a");`, UnhandledException, 3, 15},
+		{"class Loader { fn Loader() throws ParseError {} }\nvar l = new Loader();", UnhandledException, 4, 9},
+		{`try { parse("a"); } catch e ParseError { throw e; }`, UnhandledException, 3, 42},
+		{`try {} catch e Exception {} finally { throw new Exception("x"); }`, UnhandledException, 3, 39},
+		{`try { parse("a"); } catch e Exception {} catch p ParseError {}`, UnreachableCatch, 3, 50},
+		{`throw 1;`, TypeMismatch, 3, 7},
+		{`fn f() throws int {}`, TypeMismatch, 3, 15},
+		{`try {} catch e String {}`, TypeMismatch, 3, 16},
+		{"class A { fn A() {} public fn run() {} }\nclass B : A { fn B() {} public fn run() throws Exception {} }", TypeMismatch, 4, 35},
+		{`try {} catch e Exception { e.message = "x"; }`, ImmutableAssignment, 3, 28},
+	}
+
+	Convey("测试抛出的异常须被捕获或在 throws 中声明，catch 的类型不能被前面的 catch 遮蔽：", t, func() {
+		for _, c := range cases {
+			analyzer := analyzeString(prelude + c.source)
+			So(analyzer.Errors, ShouldHaveLength, 1)
+			So(analyzer.Errors[0].ErrEnum, ShouldEqual, c.errEnum)
+			So(analyzer.Diagnostics[len(analyzer.Diagnostics)-1].Start, ShouldResemble, Position{Line: c.line, Col: c.col})
+		}
+	})
+
+	Convey("测试被遮蔽的 catch 指出遮蔽它的 catch：", t, func() {
+		analyzer := analyzeString(prelude + `try { parse("a"); } catch e Exception {} catch p ParseError {}`)
+		So(analyzer.Diagnostics, ShouldHaveLength, 1)
+		So(analyzer.Diagnostics[0].Notes, ShouldHaveLength, 1)
+		So(analyzer.Diagnostics[0].Notes[0].Start, ShouldResemble, Position{Line: 3, Col: 29})
+	})
+
+	Convey("测试已捕获或已声明的异常：", t, func() {
+		analyzer := analyzeString(prelude + `fn wrap(s String) int throws Exception { return parse(s); }
+fn safe(s String) int {
+  try {
+    try { return wrap(s); } finally { println("inner"); }
+  } catch e ParseError {
+    return -1;
+  } catch e Exception {
+    println(e.message);
+  }
+  return 0;
+}
+class Base { fn Base() {} public fn run() throws Exception {} }
+class Derived : Base { fn Derived() {} public fn run() throws ParseError { parse(""); } }`)
+		So(analyzer.Errors, ShouldBeEmpty)
+		So(analyzer.RootScope.SymbolMap["wrap"].(*IdSymbol).Type.String(), ShouldEqual, "fn(String) int throws Exception")
+	})
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"bytes"
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/compiler"
+	. "coral-lang/src/driver"
+	. "coral-lang/src/exception"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func compileString(content string) (*Module, *Compiler) {
+	analyzer := analyzeString(content)
+	So(analyzer.Errors, ShouldBeEmpty)
+	compiler := NewCompiler(analyzer)
+	module, _ := compiler.CompileProgram()
+	return module, compiler
+}
+
+func disassemble(module *Module) string {
+	buf := new(bytes.Buffer)
+	module.Disassemble(buf)
+	return buf.String()
+}
+
+func TestCompileProgram(t *testing.T) {
+	Convey("测试编译：顶层变量为全局变量，函数与 lambda 进入函数表", t, func() {
+		module, compiler := compileString(`var total = 0;
+fn add(x int, y int) int { return x + y; }
+var twice = (v int) -> v * 2;
+total = add(1, 2) + twice(3);
+println(total);`)
+		So(compiler.Errors, ShouldBeEmpty)
+		So(module.Globals, ShouldResemble, []string{"total", "twice"})
+		So(len(module.Functions), ShouldEqual, 3)
+		So(module.Functions[module.Entry].Name, ShouldEqual, "<script>")
+
+		add := module.Functions[1]
+		So(add.Name, ShouldEqual, "add")
+		So(add.Params, ShouldEqual, 2)
+		So(add.Returns, ShouldEqual, 1)
+		So(add.LineOf(0), ShouldEqual, 2)
+
+		asm := disassemble(module)
+		So(asm, ShouldContainSubstring, "(\"println\")")
+		So(asm, ShouldContainSubstring, "FUNCTION             1 (add)")
+		So(asm, ShouldContainSubstring, "function 2 <lambda> (params 1, locals 1, returns 1)")
+	})
+
+	Convey("测试编译：常量表达式在编译期求值，运算结果按类型截断", t, func() {
+		module, _ := compileString(`val kb = 1 << 10;
+var small int8 = 100;
+small += 100;`)
+		So(module.Constants, ShouldContain, Constant{Kind: ConstantInt, Int: 1024})
+		So(disassemble(module), ShouldContainSubstring, "CONVERT              1 (int8)")
+	})
+
+	Convey("测试编译：控制流语句", t, func() {
+		module, compiler := compileString(`enum Color { Red, Green = 5, Blue }
+var sum = 0;
+each v, i in [1, 2, 3] { if v == 2 { continue; } sum += v * i; }
+for var i = 0; i < 10; i++ { if i > 5 { break; } }
+while sum > 0 { sum--; }
+var color = Color.Blue;
+switch sum {
+  case 1, 2 { println("small"); }
+  case 6..10 { println("medium"); }
+  default { println("large"); }
+}`)
+		So(compiler.Errors, ShouldBeEmpty)
+		So(module.Constants, ShouldContain, Constant{Kind: ConstantInt, Int: 6}) // Color.Blue
+		asm := disassemble(module)
+		So(asm, ShouldContainSubstring, "JUMP_IF_TRUE_OR_POP")
+		So(asm, ShouldContainSubstring, "LENGTH")
+	})
+
+	Convey("测试编译：尚不支持的特性", t, func() {
+		_, compiler := compileString(`class Dog { fn Dog() {} }
+var d = new Dog();`)
+		So(len(compiler.Errors), ShouldEqual, 2)
+		So(compiler.Errors[0].ErrEnum, ShouldEqual, UnsupportedFeature)
+
+		_, compiler = compileString(`fn outer() { var n = 1; var f = () -> n; }`)
+		So(len(compiler.Errors), ShouldEqual, 1)
+		So(compiler.Errors[0].Message, ShouldContainSubstring, "capturing the local variable \"n\"")
+
+		_, compiler = compileString(`break;`)
+		So(compiler.Errors[0].ErrEnum, ShouldEqual, InvalidOperation)
+	})
+}
+
+func TestBytecodeFile(t *testing.T) {
+	Convey("测试字节码文件：序列化后可以原样读回", t, func() {
+		module, _ := compileString(`var pi = 3.14;
+var name = "coral";
+fn f() int, String { return 1, name; }
+var a = 0, b = "";
+a, b = f();`)
+		decoded, err := Decode(module.Encode())
+		So(err, ShouldBeNil)
+		So(decoded, ShouldResemble, module)
+	})
+
+	Convey("测试字节码文件：格式错误", t, func() {
+		module, _ := compileString(`println(1);`)
+		data := module.Encode()
+
+		_, err := Decode([]byte("not bytecode"))
+		So(err.ErrEnum, ShouldEqual, BytecodeFormatError)
+
+		badVersion := append([]byte(nil), data...)
+		badVersion[4] = Version + 1
+		_, err = Decode(badVersion)
+		So(err.Message, ShouldContainSubstring, "unsupported bytecode version")
+
+		_, err = Decode(data[:len(data)-2])
+		So(err.Message, ShouldContainSubstring, "unexpected end of bytecode")
+	})
+}
+
+func TestDriverBuild(t *testing.T) {
+	Convey("测试命令行：build 写出 .cbytes 文件", t, func() {
+		dir, _ := ioutil.TempDir("", "coral-build")
+		defer os.RemoveAll(dir)
+		source := filepath.Join(dir, "hello.coral")
+		So(ioutil.WriteFile(source, []byte(`println("hello");`), 0644), ShouldBeNil)
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"build", "-S", source}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldContainSubstring, "function 0 <script>")
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "hello"+FileExtension))
+		So(err, ShouldBeNil)
+		module, decodeErr := Decode(data)
+		So(decodeErr, ShouldBeNil)
+		So(module.Source, ShouldEqual, source)
+
+		output := filepath.Join(dir, "out.cbytes")
+		So(Run([]string{"build", "-o", output, source}, stdout, stderr), ShouldEqual, NormalError)
+		_, err = os.Stat(output)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("测试命令行：build 遇到不支持的特性时报错", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"build", "-format", "plain", "samples/animal.cr"}, stdout, stderr), ShouldEqual, UnsupportedFeature)
+		So(stderr.String(), ShouldContainSubstring, "not supported by the bytecode compiler")
+	})
+}
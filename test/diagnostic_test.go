@@ -0,0 +1,94 @@
+package test
+
+import (
+	"bytes"
+	. "coral-lang/src/driver"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/parser"
+	"encoding/json"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestParserDiagnostics(t *testing.T) {
+	Convey("测试语法解析器收集诊断信息：", t, func() {
+		parser := new(Parser)
+		parser.FileName = "main.cr"
+		parser.InitFromString("var a int;\nimport \"math\"")
+		parser.ParseProgram()
+
+		So(len(parser.Diagnostics), ShouldEqual, 2)
+		warning, err := parser.Diagnostics[0], parser.Diagnostics[1]
+
+		So(warning.Severity, ShouldEqual, SeverityWarning)
+		So(warning.Start, ShouldResemble, Position{Line: 1, Col: 10})
+
+		So(err.Severity, ShouldEqual, SeverityError)
+		So(err.File, ShouldEqual, "main.cr")
+		So(err.Code, ShouldEqual, ParsingUnexpected)
+		So(err.Category, ShouldEqual, "Syntax")
+		So(err.Start.Line, ShouldEqual, 2)
+		So(err.Message, ShouldStartWith, "expected a semicolon")
+	})
+}
+
+func TestDiagnosticRenderers(t *testing.T) {
+	diagnostic := NewDiagnostic("main.cr", Position{Line: 2, Col: 5}, Position{Line: 2, Col: 8},
+		NewCoralError("Compile", "something is wrong", ParsingUnexpected))
+	diagnostic.AddNote("main.cr", Position{Line: 1, Col: 1}, Position{Line: 1, Col: 4}, "see here")
+	diagnostics := []*Diagnostic{diagnostic}
+
+	Convey("测试纯文本渲染：", t, func() {
+		out := new(bytes.Buffer)
+		renderer, ok := NewDiagnosticRenderer("plain", nil)
+		So(ok, ShouldBeTrue)
+		So(renderer.Render(out, diagnostics), ShouldBeNil)
+		So(out.String(), ShouldEqual, "main.cr:2:5: error[12]: something is wrong\n  note: main.cr:1:1: see here\n")
+	})
+
+	Convey("测试 JSON 渲染：", t, func() {
+		out := new(bytes.Buffer)
+		renderer, _ := NewDiagnosticRenderer("json", nil)
+		So(renderer.Render(out, diagnostics), ShouldBeNil)
+
+		var decoded []map[string]interface{}
+		So(json.Unmarshal(out.Bytes(), &decoded), ShouldBeNil)
+		So(decoded[0]["severity"], ShouldEqual, "error")
+		So(decoded[0]["code"], ShouldEqual, ParsingUnexpected)
+		So(decoded[0]["end"].(map[string]interface{})["col"], ShouldEqual, 8)
+		So(len(decoded[0]["notes"].([]interface{})), ShouldEqual, 1)
+	})
+
+	Convey("测试终端渲染：展示出错代码行并标出范围", t, func() {
+		out := new(bytes.Buffer)
+		renderer, _ := NewDiagnosticRenderer("terminal", map[string][]byte{
+			"main.cr": []byte("var a = 1;\nvar bbb = a;\n"),
+		})
+		So(renderer.Render(out, diagnostics), ShouldBeNil)
+		So(out.String(), ShouldContainSubstring, "| var bbb = a;")
+		So(out.String(), ShouldContainSubstring, "^^^")
+		So(out.String(), ShouldContainSubstring, "(1 error, 0 warning)")
+	})
+
+	Convey("测试未知的渲染格式：", t, func() {
+		_, ok := NewDiagnosticRenderer("xml", nil)
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestDriverDiagnosticsFormat(t *testing.T) {
+	Convey("测试命令行：以 JSON 输出诊断信息", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"check", "-format", "json", "samples/dog.cr"}, stdout, stderr), ShouldEqual, ParsingUnexpected)
+
+		var decoded []*Diagnostic
+		So(json.Unmarshal(stderr.Bytes(), &decoded), ShouldBeNil)
+		So(decoded[0].File, ShouldEqual, "samples/dog.cr")
+		So(decoded[0].Start, ShouldResemble, Position{Line: 1, Col: 19})
+	})
+
+	Convey("测试命令行：未知的诊断信息格式", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"check", "-format", "xml", "samples/dog.cr"}, stdout, stderr), ShouldEqual, CommandLineUsageError)
+	})
+}
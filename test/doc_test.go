@@ -0,0 +1,82 @@
+package test
+
+import (
+	"bytes"
+	. "coral-lang/src/driver"
+	. "coral-lang/src/exception"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const docLibrary = `package shapes;
+
+/// 可以计算面积的图形
+interface Shape {
+  public fn area() float;
+}
+
+/**
+ * 矩形，以 ` + "`width`" + ` 与 ` + "`height`" + ` 描述
+ */
+class Rect <- Shape {
+  public var width float = 0.0;
+  var height float = 0.0;
+  /// 以宽和高创建矩形
+  fn Rect(w, h float) {
+    this.width = w;
+    this.height = h;
+  }
+  /// 矩形的面积
+  public fn area() float { return this.width * this.height; }
+  fn secret() {}
+}
+`
+
+const docMain = `from "./shapes" import Rect;
+/// 程序入口
+fn main() {
+  var rect = new Rect(1.0, 2.0);
+  println(rect.area());
+}
+main();
+`
+
+func TestDriverDoc(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "coral-doc")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "shapes.cr"), []byte(docLibrary), 0644)
+	source := filepath.Join(dir, "main.cr")
+	ioutil.WriteFile(source, []byte(docMain), 0644)
+
+	Convey("测试命令行：doc 为每个包输出一页 Markdown", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"doc", source}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldStartWith, "# shapes\n\n源文件：`shapes.cr`\n\n## 接口\n\n### Shape\n\n"+
+			"```coral\ninterface Shape {\n    public fn area() float;\n}\n```\n\n可以计算面积的图形\n")
+		So(stdout.String(), ShouldContainSubstring, "### Rect\n\n```coral\nclass Rect <- Shape\n```\n\n矩形，以 `width` 与 `height` 描述\n")
+		So(stdout.String(), ShouldContainSubstring, "#### Rect.width\n\n```coral\npublic var width float = 0.0\n```\n")
+		So(stdout.String(), ShouldContainSubstring, "#### new Rect\n\n```coral\nfn Rect(w, h float)\n```\n\n以宽和高创建矩形\n")
+		So(stdout.String(), ShouldContainSubstring, "#### Rect.area\n\n```coral\npublic fn area() float\n```\n\n矩形的面积\n")
+		So(stdout.String(), ShouldNotContainSubstring, "Rect.height")
+		So(stdout.String(), ShouldNotContainSubstring, "secret")
+		So(stdout.String(), ShouldEndWith, "\n# main\n\n源文件：`main.cr`\n\n## 函数\n\n### main\n\n```coral\nfn main()\n```\n\n程序入口\n")
+	})
+
+	Convey("测试命令行：doc -o 将各个包的页面写入目录", t, func() {
+		output := filepath.Join(dir, "api")
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"doc", "-o", output, source}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldBeEmpty)
+		page, err := ioutil.ReadFile(filepath.Join(output, "shapes.md"))
+		So(err, ShouldBeNil)
+		So(string(page), ShouldStartWith, "# shapes\n")
+		page, err = ioutil.ReadFile(filepath.Join(output, "main.md"))
+		So(err, ShouldBeNil)
+		So(string(page), ShouldContainSubstring, "程序入口")
+
+		So(Run([]string{"doc", "-format", "plain", "samples/dog.cr"}, stdout, stderr), ShouldEqual, ParsingUnexpected)
+	})
+}
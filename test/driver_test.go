@@ -0,0 +1,58 @@
+package test
+
+import (
+	"bytes"
+	. "coral-lang/src/driver"
+	. "coral-lang/src/exception"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestDriverUsage(t *testing.T) {
+	Convey("测试命令行：缺少子命令或子命令未知", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run(nil, stdout, stderr), ShouldEqual, CommandLineUsageError)
+		So(stderr.String(), ShouldContainSubstring, "Usage: coral")
+
+		stderr.Reset()
+		So(Run([]string{"compile", "a.cr"}, stdout, stderr), ShouldEqual, CommandLineUsageError)
+		So(stderr.String(), ShouldContainSubstring, `unknown command "compile"`)
+
+		stderr.Reset()
+		So(Run([]string{"lex"}, stdout, stderr), ShouldEqual, CommandLineUsageError)
+		So(stderr.String(), ShouldContainSubstring, "expected exactly one source file")
+	})
+
+	Convey("测试命令行：help", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"help"}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldContainSubstring, "Commands:")
+	})
+}
+
+func TestDriverSubCommands(t *testing.T) {
+	Convey("测试命令行：lex 输出 Token 流", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"lex", "samples/animal.cr"}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldStartWith, "1:1\tKeyword\t\"class\"\n")
+		So(stdout.String(), ShouldContainSubstring, "Identifier\t\"Animal\"")
+
+		stdout.Reset()
+		So(Run([]string{"lex", "-trivia", "samples/animal.cr"}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldStartWith, "1:1\tKeyword\t\"class\"\n1:6\tWhitespace\t\" \"\n")
+		So(stdout.String(), ShouldContainSubstring, "Newline\t\"\\n\"")
+	})
+
+	Convey("测试命令行：parse 输出语法树", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"parse", "samples/animal.cr"}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldContainSubstring, "Program\n  Root:\n    [0]: Class_Declaration_Statement [1:1-")
+		So(stdout.String(), ShouldContainSubstring, `Token: "Animal" [1:7-1:13]`)
+	})
+
+	Convey("测试命令行：check 语法错误时返回错误码", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"check", "samples/animal.cr"}, stdout, stderr), ShouldEqual, NormalError)
+		So(Run([]string{"check", "samples/dog.cr"}, stdout, stderr), ShouldEqual, ParsingUnexpected)
+	})
+}
@@ -0,0 +1,225 @@
+package test
+
+import (
+	"bytes"
+	. "coral-lang/src/driver"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/formatter"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func formatString(source string) string {
+	formatted, diagnostics := FormatSource("fmt.cr", []byte(source))
+	So(diagnostics, ShouldBeEmpty)
+	return string(formatted)
+}
+
+func TestFormatSource(t *testing.T) {
+	Convey("测试格式化：缩进、空格与花括号", t, func() {
+		So(formatString(`class Box<T : Named> : Base<T> <- Named{
+  public static var count=0;
+  private var v T;
+  fn Box(v T){super();this.v=v;}
+  public fn get()T{return this.v;}
+}
+fn f<T>(a, b int, xs T[])int,bool throws E{
+var x=(a+b)*2 ; x++;
+if x==1{return 1,true;}elif x>2{}else{x-=1;}
+each v,k in xs[1:3]{println(v as int);}
+}`), ShouldEqual, `class Box<T : Named> : Base<T> <- Named {
+    public static var count = 0;
+    var v T;
+    fn Box(v T) {
+        super();
+        this.v = v;
+    }
+    public fn get() T {
+        return this.v;
+    }
+}
+fn f<T>(a, b int, xs T[]) int, bool throws E {
+    var x = (a + b) * 2;
+    x++;
+    if x == 1 {
+        return 1, true;
+    } elif x > 2 {} else {
+        x -= 1;
+    }
+    each v, k in xs[1:3] {
+        println(v as int);
+    }
+}
+`)
+	})
+
+	Convey("测试格式化：其余各种语句与表达式", t, func() {
+		So(formatString(`enum Color { Red = 1, Green }
+interface Named { public fn name() String; }
+switch c { default { } case 1, 2 { break; } case 0...9 { continue; } }
+for var i=0;i<10;i++,j--{ }
+while !done { xs[:2]; a.b.c(1)[2]; }
+try { throw new E("x"); } catch e E { } finally { }
+var g = (x int) int -> x*2, h = (x) -> { return x; };
+var t = {k: 1, j: "s\n"}, r = ((a)) << 2 >> 1 ** -b;
+var arr int[3], m Map<String, int[]>, fnT (int, int) -> int;
+a, b = b, a;
+return 0x1F, 'c', nil, true, [1.5e3, 0.25];`), ShouldEqual, `enum Color {
+    Red = 1,
+    Green,
+}
+interface Named {
+    public fn name() String;
+}
+switch c {
+    case 1, 2 {
+        break;
+    }
+    case 0...9 {
+        continue;
+    }
+    default {}
+}
+for var i = 0; i < 10; i++, j-- {}
+while !done {
+    xs[:2];
+    a.b.c(1)[2];
+}
+try {
+    throw new E("x");
+} catch e E {} finally {}
+var g = (x int) int -> x * 2, h = (x) -> {
+    return x;
+};
+var t = {k: 1, j: "s\n"}, r = (a) << 2 >> 1 ** -b;
+var arr int[3], m Map<String, int[]>, fnT (int, int) -> int;
+a, b = b, a;
+return 0x1F, 'c', nil, true, [1.5e3, 0.25];
+`)
+	})
+
+	Convey("测试格式化：保留注释与单个空行", t, func() {
+		So(formatString(`// 文件头
+var a = 1;   // 行尾注释
+
+
+/* 块注释 */ var b = 2;
+fn f() {
+  // 只有注释的函数体
+}
+if a { // 条件之后的注释
+  a++;
+}
+   // 文件末尾`), ShouldEqual, `// 文件头
+var a = 1; // 行尾注释
+
+/* 块注释 */
+var b = 2;
+fn f() {
+    // 只有注释的函数体
+}
+if a {
+    // 条件之后的注释
+    a++;
+}
+// 文件末尾
+`)
+	})
+
+	Convey("测试格式化：表达式中的注释留在原处", t, func() {
+		So(formatString(`var x = 1 + /* inline */ 2;
+var a = [1, // one
+  2];
+var c = 1*(a[1] /* d */), t = {k: 1, // k
+  j: 2 /* j */};
+g(/* first */ 1, 2 /* last */);`), ShouldEqual, `var x = 1 + /* inline */ 2;
+var a = [1, // one
+    2];
+var c = 1 * (a[1] /* d */), t = {k: 1, // k
+    j: 2 /* j */};
+g(/* first */ 1, 2 /* last */);
+`)
+	})
+
+	Convey("测试格式化：import 语句保持原来的顺序", t, func() {
+		So(formatString(`package demo;
+from "b" import {x as y, z}
+import "zeta";
+import "alpha" as al;
+
+from "a" import w;
+println(w);`), ShouldEqual, `package demo;
+
+from "b" import { x as y, z }
+import "zeta";
+import "alpha" as al;
+
+from "a" import w;
+
+println(w);
+`)
+	})
+
+	Convey("测试格式化：格式化前后程序的输出相同", t, func() {
+		dir, _ := ioutil.TempDir("", "coral-fmt")
+		defer os.RemoveAll(dir)
+		main := filepath.Join(dir, "main.cr")
+		So(ioutil.WriteFile(filepath.Join(dir, "zeta.cr"), []byte(`println("zeta");`), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "alpha.cr"), []byte(`println("alpha");`), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(main, []byte(`import "./zeta";
+import "./alpha";
+println(1 + /* inline */ 2);`), 0644), ShouldBeNil)
+
+		run := func() string {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			So(Run([]string{"run", main}, stdout, stderr), ShouldEqual, NormalError)
+			return stdout.String()
+		}
+		before := run()
+		So(before, ShouldEqual, "zeta\nalpha\n3\n")
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"fmt", "-w", main}, stdout, stderr), ShouldEqual, NormalError)
+		So(run(), ShouldEqual, before)
+	})
+
+	Convey("测试格式化：结果再格式化一次不变", t, func() {
+		for _, path := range []string{"samples/animal.cr", "samples/modules/main.cr", "samples/packages/main.cr"} {
+			content, _ := ioutil.ReadFile(path)
+			once := formatString(string(content))
+			So(formatString(once), ShouldEqual, once)
+		}
+	})
+
+	Convey("测试格式化：有语法错误时不做格式化", t, func() {
+		formatted, diagnostics := FormatSource("fmt.cr", []byte(`var a = ;`))
+		So(formatted, ShouldBeNil)
+		So(diagnostics, ShouldNotBeEmpty)
+		So(diagnostics[0].Code, ShouldEqual, ParsingUnexpected)
+	})
+}
+
+func TestDriverFmt(t *testing.T) {
+	Convey("测试命令行：fmt 输出、写回与检查", t, func() {
+		dir, _ := ioutil.TempDir("", "coral-fmt")
+		defer os.RemoveAll(dir)
+		source := filepath.Join(dir, "hello.cr")
+		So(ioutil.WriteFile(source, []byte(`var  a=1 ;`), 0644), ShouldBeNil)
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"fmt", source}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldEqual, "var a = 1;\n")
+
+		So(Run([]string{"fmt", "--check", source}, stdout, stderr), ShouldEqual, SourceNotFormatted)
+		So(stderr.String(), ShouldContainSubstring, "hello.cr is not formatted")
+
+		So(Run([]string{"fmt", "-w", source}, stdout, stderr), ShouldEqual, NormalError)
+		content, _ := ioutil.ReadFile(source)
+		So(string(content), ShouldEqual, "var a = 1;\n")
+		So(Run([]string{"fmt", "-check", source}, stdout, stderr), ShouldEqual, NormalError)
+
+		So(Run([]string{"fmt", "-check", "-format", "plain", "samples/dog.cr"}, stdout, stderr), ShouldEqual, ParsingUnexpected)
+	})
+}
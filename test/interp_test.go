@@ -0,0 +1,173 @@
+package test
+
+import (
+	"bytes"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/interp"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+// 以解释器执行一段源代码，返回输出的内容与运行时错误
+func interpretString(content string) (string, *Interpreter, *CoralCompileError) {
+	analyzer := analyzeString(content)
+	So(analyzer.Errors, ShouldBeEmpty)
+	stdout := new(bytes.Buffer)
+	interpreter := NewInterpreter(analyzer, stdout)
+	err := interpreter.Run()
+	return stdout.String(), interpreter, err
+}
+
+// float 与 double 的运算与输出，虚拟机与解释器都以此检查
+const floatPrintingProgram = `var f float = 0.1;
+var d double = 0.1;
+println(f, 0.1 + 0.2, d);
+var g float = 1.0 / 3;
+println(g, g * 3, -g, g as double, 1.0 / 3 as double);
+var zero float;
+println([f, 2.5], f == 0.1, zero);
+printf("%.2f\n", f);`
+
+// 解释器与字节码虚拟机对同一程序的输出应当完全一致
+var differentialPrograms = []string{
+	floatPrintingProgram,
+
+	`println(1 + 2 * 3 ** 2, (1 + 2) * 3, 10 - 4 - 3, 2 ** 3 ** 2, 7 % 4 * 2);
+println(1 < 2 == true, 1 | 2 ^ 3 & 4, 1 << 2 + 1, -3 / 2, 3.0 / 2 + 1);
+var a = 6, b = 4;
+println(a + b * 2, a / b, a % b, a << 2 >> 1, ~a, -a, a > b && b > 0 || false);`,
+
+	`var small int8 = 120;
+each i in 0..10 { small += 1; }
+var wide uint16 = 0;
+wide -= 1;
+var big uint64 = 1 << 63;
+big *= 2;
+var r = 'a';
+r += 1;
+var f float = 1.0 / 3.0;
+var d = 1.0 / 3.0;
+var n = 300;
+var cast = n as int8;
+var widened = small as double;
+println(small, wide, big, r, f, d, cast, widened / 4, small * 20);`,
+
+	`fn fib(n int) int { if n < 2 { return n; } return fib(n - 1) + fib(n - 2); }
+fn swap(x String, y String) String, String { return y, x; }
+var first = "a", second = "b";
+first, second = swap(first, second);
+var square = (v int) -> v * v;
+println(fib(15), first, second, square(9), square);`,
+
+	`var total = 0;
+var i = 0;
+while true {
+  i++;
+  if i % 2 == 0 { continue; } elif i > 15 { break; } else { total += i; }
+}
+for var j = 10; j > 0; j -= 3 { total -= j; }
+each c, k in "héllo" { if c == 'l' { total += k; } }
+var scores = {math: 90, art: 75};
+each score, name in scores { println(name, score); }
+println(total);`,
+
+	`enum Level { Low, Mid = 10, High }
+var levels = [Level.Low, Level.Mid, Level.High];
+each level in levels {
+  switch level {
+    case Level.Low { print("low "); }
+    case Level.Mid { print("mid "); }
+    default { print("high "); }
+  }
+}
+each size in [3, 5, 11] {
+  switch size {
+    case 0..5 { print("small "); }
+    case 5...11 { print("medium "); }
+  }
+}
+var grid = [[1, 2], [3, 4]];
+grid[1][0] += 10;
+var words = ["x", "y", "z"];
+println(grid, words[1:3], words.length, "coral"[1:3], {}, nil);`,
+
+	`fn pick<T>(flag bool, a T, b T) T { if flag { return a; } return b; }
+fn first<T>(xs T[]) T { return xs[0]; }
+fn apply<A, B>(x A, f (A) -> B) B { return f(x); }
+var n = pick(false, 1, 2) + 1;
+println(n, pick(true, "p", "q"), first([1.5, 2.5]), apply(3, (x int) String -> "x"));`,
+
+	`fn check(n int) int throws Exception {
+  if n > 2 { throw new Exception("too big"); }
+  return n * 10;
+}
+fn find() int {
+  try { return check(5); } catch e Exception { println("caught", e.message); return -1; } finally { println("finally"); }
+  return 0;
+}
+fn nested() throws Exception {
+  try {
+    try { check(9); } finally { println("inner finally"); }
+  } catch e Exception {
+    println("rethrow", e);
+    throw e;
+  }
+}
+println(find());
+try { nested(); } catch e Exception { println("outer", e.message); }
+try { println(check(1)); } catch e Exception { println("unreachable"); } finally { println("done"); }`,
+}
+
+func TestInterpreter(t *testing.T) {
+	Convey("测试解释器：与字节码虚拟机的输出一致", t, func() {
+		for _, program := range differentialPrograms {
+			expected, _, err := runString(program)
+			So(err, ShouldBeNil)
+			actual, _, err := interpretString(program)
+			So(err, ShouldBeNil)
+			So(actual, ShouldEqual, expected)
+		}
+	})
+
+	Convey("测试解释器：lambda 可以引用外层函数的局部变量", t, func() {
+		output, _, err := interpretString(`fn counter() (int) -> int {
+  var count = 0;
+  return (step int) int -> { count += step; return count; };
+}
+var next = counter();
+next(1);
+next(2);
+println(next(3), counter()(5));`)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "6 5\n")
+	})
+
+	Convey("测试解释器：return 结束函数，finally 总会执行", t, func() {
+		output, _, err := interpretString(`fn find(xs int[], target int) int {
+  each x, i in xs {
+    try { if x == target { return i; } } finally { print("checked", x, ""); }
+  }
+  return -1;
+}
+println(find([5, 6, 7], 6));`)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "checked 5 checked 6 1\n")
+	})
+
+	Convey("测试解释器：运行时错误带有出错的位置与调用链", t, func() {
+		_, interpreter, err := interpretString(`fn get(xs int[], i int) int {
+  return xs[i];
+}
+println(get([1], 3));`)
+		So(err.ErrEnum, ShouldEqual, RuntimeError)
+		So(err.Message, ShouldEqual, "index 3 out of range [0, 1)!")
+		diagnostic := interpreter.Diagnostics[0]
+		So(diagnostic.Start, ShouldResemble, Position{Line: 2, Col: 10})
+		So(diagnostic.Notes[0].Message, ShouldEqual, "get called from here")
+		So(diagnostic.Notes[0].Start.Line, ShouldEqual, 4)
+
+		_, _, err = interpretString(`class Dog { fn Dog() {} }
+var d = new Dog();`)
+		So(err.ErrEnum, ShouldEqual, UnsupportedFeature)
+	})
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"bytes"
 	. "coral-lang/src/exception"
 	. "coral-lang/src/lexer"
 	. "github.com/smartystreets/goconvey/convey"
@@ -15,7 +16,7 @@ import (
 */
 
 func TestPeekChar(t *testing.T) {
-	content := OpenSourceFile("samples/test.coral")
+	content, _ := OpenSourceFile("samples/test.coral")
 	testLexer := &Lexer{}
 	testLexer.InitFromBytes(content)
 
@@ -25,7 +26,7 @@ func TestPeekChar(t *testing.T) {
 	})
 }
 func TestPeekNextChar(t *testing.T) {
-	content := OpenSourceFile("samples/test.coral")
+	content, _ := OpenSourceFile("samples/test.coral")
 	testLexer := &Lexer{}
 	testLexer.InitFromBytes(content)
 
@@ -36,7 +37,7 @@ func TestPeekNextChar(t *testing.T) {
 	})
 }
 func TestPeekNextCharByStep(t *testing.T) {
-	content := OpenSourceFile("samples/test.coral")
+	content, _ := OpenSourceFile("samples/test.coral")
 	testLexer := &Lexer{}
 	testLexer.InitFromBytes(content)
 
@@ -47,7 +48,7 @@ func TestPeekNextCharByStep(t *testing.T) {
 	})
 }
 func TestGoNextChar(t *testing.T) {
-	content := OpenSourceFile("samples/test.coral")
+	content, _ := OpenSourceFile("samples/test.coral")
 	testLexer := &Lexer{}
 	testLexer.InitFromBytes(content)
 
@@ -216,7 +217,7 @@ func TestReadRune(t *testing.T) {
 	Convey("测试读入字符 1", t, func() {
 		testLexer := &Lexer{}
 		testLexer.InitFromString("'Z'")
-		gotToken, err := testLexer.ReadRune()
+		gotToken, err := testLexer.ReadRuneLit()
 		if err != nil {
 			CoralErrorCrashHandler(err)
 		}
@@ -227,7 +228,7 @@ func TestReadRune(t *testing.T) {
 	Convey("测试读入字符 2：支持转义字符", t, func() {
 		testLexer := &Lexer{}
 		testLexer.InitFromString("'\\u94F8'")
-		gotToken, err := testLexer.ReadRune()
+		gotToken, err := testLexer.ReadRuneLit()
 		if err != nil {
 			CoralErrorCrashHandler(err)
 		}
@@ -401,3 +402,108 @@ func TestGetNextToken(t *testing.T) {
 		}
 	})
 }
+
+func TestTokenSpan(t *testing.T) {
+	Convey("测试 Token 的范围：字节偏移量与行号列号", t, func() {
+		lexer := new(Lexer)
+		lexer.InitFromString("var s = \"a\\tb\";\n/* 跨行\n 注释 */ s = 'é';")
+
+		var tokens []*Token
+		for token, _ := lexer.GetNextToken(false); token != nil; token, _ = lexer.GetNextToken(false) {
+			tokens = append(tokens, token)
+		}
+
+		So(tokens[0].Start, ShouldResemble, Pos{Offset: 0, Line: 1, Col: 1})
+		So(tokens[0].End, ShouldResemble, Pos{Offset: 3, Line: 1, Col: 4})
+
+		str := tokens[3] // 转义字符不影响 Token 在源代码中的范围
+		So(str.Str, ShouldEqual, "a\tb")
+		So(str.Start, ShouldResemble, Pos{Offset: 8, Line: 1, Col: 9})
+		So(str.End, ShouldResemble, Pos{Offset: 14, Line: 1, Col: 15})
+
+		s := tokens[5] // 跨行的块注释之后
+		So(s.Str, ShouldEqual, "s")
+		So(s.Start.Line, ShouldEqual, 3)
+		So(s.Start.Col, ShouldEqual, 8)
+
+		r := tokens[7] // 列号以 UTF-8 字符计，偏移量以字节计
+		So(r.Start.Col, ShouldEqual, 12)
+		So(r.End.Col, ShouldEqual, 15)
+		So(r.End.Offset-r.Start.Offset, ShouldEqual, 4)
+	})
+}
+
+func TestCollectComments(t *testing.T) {
+	Convey("测试 记录注释：按出现顺序记录原文与范围", t, func() {
+		lexer := new(Lexer)
+		lexer.InitFromString("var a = 1; // 行注释\n/* 块 /* 嵌套 */ 注释 */ a++;")
+		for token, _ := lexer.GetNextToken(false); token != nil; token, _ = lexer.GetNextToken(false) {
+		}
+
+		So(len(lexer.Comments), ShouldEqual, 2)
+		So(lexer.Comments[0].Text, ShouldEqual, "// 行注释")
+		So(lexer.Comments[0].Start, ShouldResemble, Pos{Offset: 11, Line: 1, Col: 12})
+		So(lexer.Comments[1].Text, ShouldEqual, "/* 块 /* 嵌套 */ 注释 */")
+		So(lexer.Comments[1].Start.Line, ShouldEqual, 2)
+		So(lexer.Comments[1].End.Col, ShouldEqual, 20)
+	})
+}
+
+// 以保留 trivia 的方式读出全部 Token，再由 Token 流还原出源代码
+func roundTripTokens(content []byte) ([]*Token, *Lexer, string) {
+	lexer := new(Lexer)
+	lexer.KeepTrivia = true
+	lexer.InitFromBytes(content)
+	var tokens []*Token
+	for token, err := lexer.GetNextToken(false); token != nil; token, err = lexer.GetNextToken(false) {
+		So(err, ShouldBeNil)
+		tokens = append(tokens, token)
+	}
+	var out bytes.Buffer
+	So(WriteTokens(&out, tokens, lexer.EndTrivia), ShouldBeNil)
+	return tokens, lexer, out.String()
+}
+
+func TestKeepTrivia(t *testing.T) {
+	Convey("测试 保留 trivia：Token 流逐字节还原出源代码", t, func() {
+		for _, path := range []string{"samples/test.coral", "samples/animal.cr", "samples/modules/main.cr"} {
+			content, _ := OpenSourceFile(path)
+			_, _, restored := roundTripTokens(content)
+			So(restored, ShouldEqual, string(content))
+		}
+
+		source := "\t// 文件头\n\nvar s = \"a\\tb\\n\";  /* 块 */ // 行尾\nx  >>=  0x1F ;\r\n   /* 末尾 */  "
+		_, _, restored := roundTripTokens([]byte(source))
+		So(restored, ShouldEqual, source)
+	})
+
+	Convey("测试 保留 trivia：同一行之后的归于前一个 Token，其余归于后一个 Token", t, func() {
+		tokens, lexer, _ := roundTripTokens([]byte("a; // 行尾\n\n/* 注释 */ b\n"))
+		So(len(tokens), ShouldEqual, 3)
+
+		So(tokens[0].LeadingTrivia, ShouldBeEmpty)
+		So(len(tokens[1].TrailingTrivia), ShouldEqual, 2)
+		So(tokens[1].TrailingTrivia[0].Kind, ShouldEqual, TriviaWhitespace)
+		So(tokens[1].TrailingTrivia[1].Kind, ShouldEqual, TriviaLineComment)
+		So(tokens[1].TrailingTrivia[1].Text, ShouldEqual, "// 行尾")
+
+		leading := tokens[2].LeadingTrivia
+		So(len(leading), ShouldEqual, 4)
+		So(leading[0].KindName(), ShouldEqual, "Newline")
+		So(leading[1].KindName(), ShouldEqual, "Newline")
+		So(leading[2].KindName(), ShouldEqual, "BlockComment")
+		So(leading[2].Start, ShouldResemble, Pos{Offset: 14, Line: 3, Col: 1})
+		So(leading[3].Text, ShouldEqual, " ")
+		So(tokens[2].TrailingTrivia, ShouldBeEmpty)
+
+		So(len(lexer.EndTrivia), ShouldEqual, 1)
+		So(lexer.EndTrivia[0].Kind, ShouldEqual, TriviaNewline)
+		So(len(lexer.Comments), ShouldEqual, 2)
+	})
+
+	Convey("测试 保留 trivia：字符串字面量的原文与转义后的值", t, func() {
+		tokens, _, _ := roundTripTokens([]byte(`"a\"b"`))
+		So(tokens[0].Raw, ShouldEqual, `"a\"b"`)
+		So(tokens[0].Str, ShouldEqual, `a"b`)
+	})
+}
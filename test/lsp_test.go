@@ -0,0 +1,275 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	. "coral-lang/src/driver"
+	. "coral-lang/src/exception"
+	"coral-lang/src/lsp"
+	"encoding/json"
+	"fmt"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 以 JSON-RPC 与服务器通信的脚本化客户端，服务器在另一个 goroutine 中运行
+// 服务器发来的消息由后台的 goroutine 读入，以免双方同时阻塞在写入上
+type lspClient struct {
+	toServer      *io.PipeWriter
+	messages      chan map[string]interface{}
+	notifications []map[string]interface{} // 收到的通知，按先后排列
+	nextID        int
+	exitCode      chan int
+}
+
+func startLspClient() *lspClient {
+	serverIn, toServer := io.Pipe()
+	fromServer, serverOut := io.Pipe()
+	client := &lspClient{
+		toServer: toServer,
+		messages: make(chan map[string]interface{}, 64),
+		exitCode: make(chan int, 1),
+	}
+	go func() {
+		client.exitCode <- lsp.NewServer(serverOut, ioutil.Discard).Run(serverIn)
+		serverOut.Close()
+	}()
+	go func() {
+		reader := bufio.NewReader(fromServer)
+		for {
+			header, err := textproto.NewReader(reader).ReadMIMEHeader()
+			if err != nil {
+				close(client.messages)
+				return
+			}
+			length, _ := strconv.Atoi(header.Get("Content-Length"))
+			content := make([]byte, length)
+			io.ReadFull(reader, content)
+			var message map[string]interface{}
+			json.Unmarshal(content, &message)
+			client.messages <- message
+		}
+	}()
+	return client
+}
+
+func (client *lspClient) send(message map[string]interface{}) {
+	message["jsonrpc"] = "2.0"
+	content, _ := json.Marshal(message)
+	fmt.Fprintf(client.toServer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+func (client *lspClient) notify(method string, params interface{}) {
+	client.send(map[string]interface{}{"method": method, "params": params})
+}
+
+// 发出请求并等待响应，其间收到的通知记录下来
+func (client *lspClient) request(method string, params interface{}) map[string]interface{} {
+	client.nextID++
+	client.send(map[string]interface{}{"id": client.nextID, "method": method, "params": params})
+	for message := range client.messages {
+		if id, isResponse := message["id"]; isResponse && id == float64(client.nextID) {
+			return message
+		}
+		client.notifications = append(client.notifications, message)
+	}
+	return nil
+}
+
+// 等待下一条诊断信息的通知，返回其中的诊断信息
+func (client *lspClient) diagnostics() []interface{} {
+	for len(client.notifications) == 0 {
+		client.notifications = append(client.notifications, <-client.messages)
+	}
+	notification := client.notifications[0]
+	client.notifications = client.notifications[1:]
+	So(notification["method"], ShouldEqual, "textDocument/publishDiagnostics")
+	return notification["params"].(map[string]interface{})["diagnostics"].([]interface{})
+}
+
+func lspPosition(uri string, line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+func lspStart(location interface{}) (string, float64, float64) {
+	fields := location.(map[string]interface{})
+	start := fields["range"].(map[string]interface{})["start"].(map[string]interface{})
+	return fields["uri"].(string), start["line"].(float64), start["character"].(float64)
+}
+func completionLabels(response map[string]interface{}) []string {
+	var labels []string
+	for _, item := range response["result"].(map[string]interface{})["items"].([]interface{}) {
+		labels = append(labels, item.(map[string]interface{})["label"].(string))
+	}
+	return labels
+}
+
+const lspLibrary = `class Animal {
+  var name String;
+  fn Animal(name String) {
+    this.name = name;
+  }
+  public fn speak() String {
+    return this.name;
+  }
+}
+`
+
+const lspMain = `from "./lib" import Animal;
+val limit = 3;
+class Dog : Animal {
+  fn Dog(name String) {
+    super(name);
+  }
+  public fn bark() String {
+    return this.speak();
+  }
+}
+var dog = new Dog("rex");
+println(dog.bark(), limit);
+`
+
+func TestLanguageServer(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "coral-lsp")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "lib.cr"), []byte(lspLibrary), 0644)
+	libPath, _ := filepath.Abs(filepath.Join(dir, "lib.cr"))
+	mainPath, _ := filepath.Abs(filepath.Join(dir, "main.cr"))
+	libURI := (&url.URL{Scheme: "file", Path: filepath.ToSlash(libPath)}).String()
+	mainURI := (&url.URL{Scheme: "file", Path: filepath.ToSlash(mainPath)}).String()
+
+	Convey("测试语言服务器：初始化、打开与修改文档、各种请求以及退出", t, func() {
+		client := startLspClient()
+
+		response := client.request("textDocument/hover", lspPosition(mainURI, 0, 0))
+		So(response["error"].(map[string]interface{})["code"], ShouldEqual, lsp.ServerNotInitializedCode)
+
+		response = client.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}})
+		capabilities := response["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+		So(capabilities["definitionProvider"], ShouldBeTrue)
+		So(capabilities["textDocumentSync"].(map[string]interface{})["change"], ShouldEqual, lsp.TextDocumentSyncIncremental)
+		client.notify("initialized", map[string]interface{}{})
+
+		client.notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": mainURI, "languageId": "coral", "version": 1, "text": lspMain},
+		})
+		So(client.diagnostics(), ShouldBeEmpty)
+
+		// 悬停提示
+		response = client.request("textDocument/hover", lspPosition(mainURI, 11, 22))
+		hover := response["result"].(map[string]interface{})
+		So(hover["contents"].(map[string]interface{})["value"], ShouldEqual, "```coral\nval limit int = 3\n```")
+		response = client.request("textDocument/hover", lspPosition(mainURI, 7, 18))
+		So(response["result"].(map[string]interface{})["contents"].(map[string]interface{})["value"], ShouldContainSubstring,
+			"fn Animal.speak() String")
+		response = client.request("textDocument/hover", lspPosition(mainURI, 11, 2))
+		So(response["result"].(map[string]interface{})["contents"].(map[string]interface{})["value"], ShouldContainSubstring,
+			"fn println(...)")
+
+		// 跳转到定义：本文件中的类，以及引入的模块中的方法
+		response = client.request("textDocument/definition", lspPosition(mainURI, 10, 15))
+		uri, line, character := lspStart(response["result"])
+		So(uri, ShouldEqual, mainURI)
+		So([]float64{line, character}, ShouldResemble, []float64{2, 6})
+		response = client.request("textDocument/definition", lspPosition(mainURI, 7, 17))
+		uri, line, character = lspStart(response["result"])
+		So(uri, ShouldEqual, libURI)
+		So([]float64{line, character}, ShouldResemble, []float64{5, 12})
+		response = client.request("textDocument/definition", lspPosition(mainURI, 11, 2))
+		So(response["result"], ShouldBeNil)
+
+		// 查找引用
+		params := lspPosition(mainURI, 1, 5)
+		params["context"] = map[string]interface{}{"includeDeclaration": true}
+		response = client.request("textDocument/references", params)
+		references := response["result"].([]interface{})
+		So(len(references), ShouldEqual, 2)
+		_, line, character = lspStart(references[0])
+		So([]float64{line, character}, ShouldResemble, []float64{1, 4})
+		_, line, character = lspStart(references[1])
+		So([]float64{line, character}, ShouldResemble, []float64{11, 20})
+
+		// 补全：可见的名称与关键字
+		response = client.request("textDocument/completion", lspPosition(mainURI, 11, 22))
+		labels := completionLabels(response)
+		So(labels, ShouldContain, "limit")
+		So(labels, ShouldNotContain, "dog")
+		response = client.request("textDocument/completion", lspPosition(mainURI, 11, 0))
+		labels = completionLabels(response)
+		So(labels, ShouldContain, "while")
+		So(labels, ShouldContain, "Dog")
+		So(labels, ShouldContain, "println")
+
+		// 以增量的改动在末尾输入 dog.，此时有语法错误，成员补全照常进行
+		client.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": mainURI, "version": 2},
+			"contentChanges": []interface{}{map[string]interface{}{
+				"range": map[string]interface{}{
+					"start": map[string]interface{}{"line": 12, "character": 0},
+					"end":   map[string]interface{}{"line": 12, "character": 0},
+				},
+				"text": "dog.",
+			}},
+		})
+		So(client.diagnostics(), ShouldNotBeEmpty)
+		response = client.request("textDocument/completion", lspPosition(mainURI, 12, 4))
+		So(completionLabels(response), ShouldResemble, []string{"bark", "speak"})
+
+		// 在方法中以 this. 补全时可以看到自身的私有成员
+		response = client.request("textDocument/completion", lspPosition(mainURI, 7, 16))
+		So(completionLabels(response), ShouldResemble, []string{"bark", "speak"})
+
+		// 整体替换文档内容，诊断信息的范围以 UTF-16 编码单元计
+		client.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": mainURI, "version": 3},
+			"contentChanges": []interface{}{map[string]interface{}{"text": "var s = \"😀\"; println(limt);\n"}},
+		})
+		diagnostics := client.diagnostics()
+		So(len(diagnostics), ShouldEqual, 1)
+		diagnostic := diagnostics[0].(map[string]interface{})
+		So(diagnostic["code"], ShouldEqual, UndeclaredIdentifier)
+		So(diagnostic["range"].(map[string]interface{})["start"], ShouldResemble, map[string]interface{}{"line": 0.0, "character": 22.0})
+
+		response = client.request("textDocument/formatting", map[string]interface{}{})
+		So(response["error"].(map[string]interface{})["code"], ShouldEqual, lsp.MethodNotFoundCode)
+
+		client.notify("textDocument/didClose", map[string]interface{}{"textDocument": map[string]interface{}{"uri": mainURI}})
+		So(client.diagnostics(), ShouldBeEmpty)
+
+		response = client.request("shutdown", nil)
+		So(response, ShouldContainKey, "result")
+		So(response["result"], ShouldBeNil)
+		client.notify("exit", nil)
+		So(<-client.exitCode, ShouldEqual, NormalError)
+	})
+}
+
+func TestDriverLsp(t *testing.T) {
+	Convey("测试命令行：lsp 从标准输入读入消息", t, func() {
+		var input bytes.Buffer
+		for _, message := range []string{
+			`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+			`{"jsonrpc":"2.0","method":"exit"}`,
+		} {
+			fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(message), message)
+		}
+		defer func(stdin io.Reader) { Stdin = stdin }(Stdin)
+		Stdin = &input
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		// 没有先收到 shutdown 请求就退出
+		So(Run([]string{"lsp"}, stdout, stderr), ShouldEqual, LanguageServerError)
+		So(stdout.String(), ShouldStartWith, "Content-Length: ")
+		So(stdout.String(), ShouldContainSubstring, `"serverInfo":{"name":"coral-lsp"}`)
+		So(strings.Count(stdout.String(), "Content-Length"), ShouldEqual, 1)
+	})
+}
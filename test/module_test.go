@@ -0,0 +1,117 @@
+package test
+
+import (
+	"bytes"
+	. "coral-lang/src/analyzer"
+	. "coral-lang/src/driver"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/parser"
+	. "github.com/smartystreets/goconvey/convey"
+	"path/filepath"
+	"testing"
+)
+
+// 检查一个源文件及其引入的模块
+func analyzeFile(filePath string) *Analyzer {
+	parser := new(Parser)
+	So(parser.InitFromFile(filePath), ShouldBeNil)
+	analyzer := new(Analyzer)
+	analyzer.InitAnalyzerFromParser(parser)
+	analyzer.CheckProgram()
+	return analyzer
+}
+
+func TestModuleLoader(t *testing.T) {
+	Convey("测试模块加载：相对路径相对于引入者，其余在搜索路径中查找", t, func() {
+		loader := NewModuleLoader([]string{"samples/modules"})
+		path, found := loader.Resolve("samples/modules/main.cr", "./units")
+		So(found, ShouldBeTrue)
+		So(path, ShouldEqual, filepath.Join("samples", "modules", "units.cr"))
+		path, found = loader.Resolve("elsewhere/main.cr", "geometry.cr")
+		So(found, ShouldBeTrue)
+		So(path, ShouldEqual, filepath.Join("samples", "modules", "geometry.cr"))
+		_, found = loader.Resolve("elsewhere/main.cr", "./units")
+		So(found, ShouldBeFalse)
+	})
+
+	Convey("测试模块加载：每个模块只加载一次，被引入的模块排在引入者之前", t, func() {
+		analyzer := analyzeFile("samples/modules/main.cr")
+		So(analyzer.Errors, ShouldBeEmpty)
+		var names []string
+		for _, module := range analyzer.Loader.Order {
+			names = append(names, module.Name)
+		}
+		So(names, ShouldResemble, []string{"units", "geometry", "main"})
+		So(analyzer.Loader.Modules, ShouldHaveLength, 3)
+	})
+
+	Convey("测试模块加载：循环引入、找不到的模块与不存在的成员", t, func() {
+		analyzer := analyzeFile("samples/modules/cycle_a.cr")
+		So(analyzer.Errors, ShouldHaveLength, 1)
+		So(analyzer.Errors[0].ErrEnum, ShouldEqual, ImportCycle)
+		So(analyzer.Errors[0].Message, ShouldEqual, "import cycle: samples/modules/cycle_a.cr -> samples/modules/cycle_b.cr -> samples/modules/cycle_a.cr!")
+		So(analyzer.Diagnostics[0].File, ShouldEqual, "samples/modules/cycle_b.cr")
+
+		analyzer = analyzeFile("samples/modules/broken.cr")
+		So(analyzer.Errors, ShouldHaveLength, 3)
+		So(analyzer.Errors[0].ErrEnum, ShouldEqual, ModuleNotFound)
+		So(analyzer.Errors[1].Message, ShouldEqual, `module units has no member "size"!`)
+		So(analyzer.Errors[2].ErrEnum, ShouldEqual, UndeclaredIdentifier)
+	})
+
+	Convey("测试模块加载：编译执行与解释执行跨文件的程序", t, func() {
+		for _, args := range [][]string{{"run"}, {"run", "-interp"}} {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			So(Run(append(args, "samples/modules/main.cr"), stdout, stderr), ShouldEqual, NormalError)
+			So(stderr.String(), ShouldBeEmpty)
+			So(stdout.String(), ShouldEqual, "geometry loaded in cm\n6 8 cm 2 2\n")
+		}
+	})
+
+	Convey("测试模块加载：运行时错误标出引入的模块中出错的文件", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"run", "-format", "plain", "samples/modules/pick.cr"}, stdout, stderr), ShouldEqual, RuntimeError)
+		So(stderr.String(), ShouldStartWith, "samples/modules/lists.cr:2:1: error[36]: index 5 out of range [0, 2)!\n")
+		So(stderr.String(), ShouldContainSubstring, "note: samples/modules/pick.cr:3:1: pick called from <script>")
+
+		stderr.Reset()
+		So(Run([]string{"run", "-interp", "-format", "plain", "samples/modules/pick.cr"}, stdout, stderr), ShouldEqual, RuntimeError)
+		So(stderr.String(), ShouldStartWith, "samples/modules/lists.cr:2:10: error[36]: index 5 out of range [0, 2)!\n")
+		So(stderr.String(), ShouldContainSubstring, "note: samples/modules/pick.cr:3:9: pick called from here")
+	})
+}
+
+func TestPackages(t *testing.T) {
+	Convey("测试包声明：顶层定义以包名限定，整体引入时以包名作为模块名", t, func() {
+		analyzer := analyzeFile("samples/packages/main.cr")
+		So(analyzer.Errors, ShouldBeEmpty)
+		So(analyzer.RootScope.SymbolMap, ShouldContainKey, "shapes")
+		So(analyzer.Loader.Order[0].Package, ShouldEqual, "shapes")
+		side := analyzer.RootScope.SymbolMap["side"].(*IdSymbol)
+		So(side.QualifiedName(), ShouldEqual, "side")
+		So(analyzer.Loader.Order[1].Lookup("side").(*IdSymbol).QualifiedName(), ShouldEqual, "shapes.side")
+
+		analyzer = analyzeString("package geo;\nenum Dir { North, South }\nfn f() { fn g() {} }\nvar d int = Dir.North;")
+		So(analyzer.Errors, ShouldHaveLength, 1)
+		So(analyzer.Errors[0].Message, ShouldEqual, "cannot use value of type geo.Dir as type int in variable declaration!")
+	})
+
+	Convey("测试包声明：同一目录中的文件声明的包名必须一致", t, func() {
+		analyzer := analyzeFile("samples/packages/mixed.cr")
+		So(analyzer.Errors, ShouldHaveLength, 1)
+		So(analyzer.Errors[0].ErrEnum, ShouldEqual, PackageMismatch)
+		So(analyzer.Errors[0].Message, ShouldEqual, "package beta conflicts with package alpha declared in the same directory!")
+		So(analyzer.Diagnostics[0].File, ShouldEqual, "samples/packages/mixed/beta.cr")
+		So(analyzer.Diagnostics[0].Notes[0].File, ShouldEqual, "samples/packages/mixed/alpha.cr")
+	})
+
+	Convey("测试包声明：调用栈中的函数名以包名限定", t, func() {
+		for _, args := range [][]string{{"run"}, {"run", "-interp"}} {
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			So(Run(append(args, "-format", "plain", "samples/packages/main.cr"), stdout, stderr), ShouldEqual, RuntimeError)
+			So(stdout.String(), ShouldEqual, "12 9\n")
+			So(stderr.String(), ShouldStartWith, "samples/packages/shapes/square.cr:11:")
+			So(stderr.String(), ShouldContainSubstring, "shapes.pick called from")
+		}
+	})
+}
@@ -1,7 +1,11 @@
 package test
 
 import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/lexer"
 	. "coral-lang/src/parser"
+	"fmt"
 	. "github.com/smartystreets/goconvey/convey"
 	"testing"
 )
@@ -12,6 +16,183 @@ func TestMultipleErrorsAndWarnings(t *testing.T) {
 		parser.InitFromString(`fn add(x, y) {
   println("sum: ", x+y)
 }`)
-		parser.ParseProgram()
+		program, errs := parser.ParseProgram()
+		So(len(errs), ShouldEqual, 2)
+		So(len(program.Root), ShouldEqual, 1)
+	})
+}
+
+func TestErrorsAreReturnedAsValues(t *testing.T) {
+	Convey("测试打开不存在的源文件：返回错误而不是退出", t, func() {
+		content, err := OpenSourceFile("samples/not-exist.cr")
+		So(content, ShouldBeNil)
+		So(err.ErrEnum, ShouldEqual, FileSystemOpenFileError)
+	})
+
+	Convey("测试词法错误：记录错误后继续解析", t, func() {
+		parser := new(Parser)
+		parser.InitFromString("var a = 3.5.6;\nvar b = 1;")
+		_, errs := parser.ParseProgram()
+		So(len(errs), ShouldBeGreaterThan, 0)
+		So(errs[0].ErrEnum, ShouldEqual, LexFloatFormatError)
+		So(parser.ErrCount, ShouldEqual, len(errs))
+	})
+
+	Convey("测试切片缺少终点表达式：返回错误", t, func() {
+		parser := new(Parser)
+		parser.InitFromString("a[1:];")
+		_, errs := parser.ParseProgram()
+		So(len(errs), ShouldEqual, 1)
+		So(errs[0].ErrEnum, ShouldEqual, ParsingUnexpected)
+	})
+
+	Convey("测试自增自减的操作数不是基本表达式：报错而不会崩溃", t, func() {
+		parser := new(Parser)
+		parser.InitFromString("var x = 1;\n-x++;\n!x--;\nx++;")
+		program, errs := parser.ParseProgram()
+		So(len(errs), ShouldEqual, 2)
+		So(errs[0].ErrEnum, ShouldEqual, ParsingUnexpected)
+		So(errs[0].Message, ShouldContainSubstring, "must be a primary expression")
+		So(errs[1].Message, ShouldContainSubstring, "must be a primary expression")
+		So(program.Root[len(program.Root)-1].(*IncDecStatement).Expression.GetSpan().Start.Line, ShouldEqual, 4)
+	})
+
+	Convey("测试未闭合的字符串与块注释：报错而不会死循环", t, func() {
+		parser1 := new(Parser)
+		parser1.InitFromString(`x = "abc`)
+		_, errs1 := parser1.ParseProgram()
+		So(errs1[0].ErrEnum, ShouldEqual, LexStringUnclosed)
+
+		parser2 := new(Parser)
+		parser2.InitFromString("/* abc")
+		_, errs2 := parser2.ParseProgram()
+		So(errs2[0].ErrEnum, ShouldEqual, LexBlockCommentUnclosed)
+
+		parser3 := new(Parser)
+		parser3.InitFromString("var c = 1; // 文件末尾的行注释")
+		program, errs3 := parser3.ParseProgram()
+		So(len(errs3), ShouldEqual, 0)
+		So(len(program.Root), ShouldEqual, 1)
+	})
+
+	Convey("测试未知的转义字符：返回错误", t, func() {
+		lexer := new(Lexer)
+		lexer.InitFromString(`"a\qb"`)
+		_, err := lexer.ReadString()
+		So(err.ErrEnum, ShouldEqual, LexUnknownEscapeCharacter)
+	})
+}
+
+func TestPanicModeRecovery(t *testing.T) {
+	Convey("测试恐慌模式：一个文件中的多处错误全部报告", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`var a int = ;
+val b = (a) + 1;
+fn add(x int, y int) int {
+  var c = x +;
+  return x + y
+}
+class Point {
+  var x int = ;
+  fn Point() {}
+  fn move() { x = ) ; }
+}
+while a < { }
+var ok = 1;
+}`)
+		program, errs := parser.ParseProgram()
+		So(len(errs), ShouldBeGreaterThanOrEqualTo, 5)
+
+		var errLines []int
+		for _, diagnostic := range parser.Diagnostics {
+			errLines = append(errLines, diagnostic.Start.Line)
+		}
+		for _, line := range []int{1, 4, 5, 8, 10, 12, 14} {
+			So(errLines, ShouldContain, line)
+		}
+		So(errLines, ShouldNotContain, 2) // 括号表达式不应被误认作 lambda
+
+		So(len(program.Root), ShouldEqual, 7)
+		So(program.Root[0], ShouldHaveSameTypeAs, &BadStatement{})
+		So(program.Root[1], ShouldHaveSameTypeAs, &VarDeclStatement{})
+		So(program.Root[2], ShouldHaveSameTypeAs, &FunctionDeclarationStatement{})
+		So(program.Root[3], ShouldHaveSameTypeAs, &ClassDeclarationStatement{})
+		So(program.Root[4], ShouldHaveSameTypeAs, &BadStatement{})
+		So(program.Root[5], ShouldHaveSameTypeAs, &VarDeclStatement{})
+		So(program.Root[6], ShouldHaveSameTypeAs, &BadStatement{})
+
+		fnBlock := program.Root[2].(*FunctionDeclarationStatement).Block
+		So(len(fnBlock.Statements), ShouldEqual, 2)
+		So(fnBlock.Statements[0].(*BadStatement).From.Str, ShouldEqual, "var")
+		So(len(program.Root[3].(*ClassDeclarationStatement).Members), ShouldEqual, 2)
+	})
+
+	Convey("测试恐慌模式：跳过出错语句中成对的花括号", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`if a b { c; d; }
+e = 1;`)
+		program, errs := parser.ParseProgram()
+		So(len(errs), ShouldEqual, 1)
+		So(len(program.Root), ShouldEqual, 2)
+		So(program.Root[0].(*BadStatement).To.Str, ShouldEqual, "}")
+	})
+
+	Convey("测试恐慌模式：在下一个语句关键字处同步", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`var a = 1
+var b = 2;`)
+		program, errs := parser.ParseProgram()
+		So(len(errs), ShouldEqual, 1)
+		So(len(program.Root), ShouldEqual, 2)
+		So(program.Root[1].(*VarDeclStatement).Declarations[0].VarName.Str, ShouldEqual, "b")
+	})
+
+	Convey("测试恐慌模式：出错语句读过的 Token 都计入 BadStatement，不会有语句从语法树中消失", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`var a = 1;
+var b = 2;
+fn f( { }
+var c = 3
+if { }
+var d = 4;`)
+		program, errs := parser.ParseProgram()
+		So(len(errs), ShouldEqual, 5)
+		var spans []string
+		for _, stmt := range program.Root {
+			span := stmt.GetSpan()
+			spans = append(spans, fmt.Sprintf("%s %s-%s", stmt.NodeType(), span.Start, span.End))
+		}
+		So(spans, ShouldResemble, []string{
+			"Simple_Statement_Variable_Declaration 1:1-1:11",
+			"Simple_Statement_Variable_Declaration 2:1-2:11",
+			"Bad_Statement 3:1-3:10",
+			"Bad_Statement 4:1-4:10",
+			"Bad_Statement 5:1-5:7",
+			"Simple_Statement_Variable_Declaration 6:1-6:11",
+		})
+
+		// 出错的语句之后紧跟着一条正确的语句：前者以 BadStatement 占位，后者照常解析
+		parser2 := new(Parser)
+		parser2.InitFromString("var c = 3\nif a { }")
+		program, errs = parser2.ParseProgram()
+		So(len(errs), ShouldEqual, 1)
+		So(len(program.Root), ShouldEqual, 2)
+		So(program.Root[0].(*BadStatement).To.Str, ShouldEqual, "3")
+		So(program.Root[1], ShouldHaveSameTypeAs, &IfStatement{})
+	})
+
+	Convey("测试恐慌模式：关键字或修饰符之后缺少定义时报错", t, func() {
+		for _, source := range []string{
+			"var ;",
+			"class X { var }",
+			"class X { fn }",
+			"class X { fn X() {} public }",
+		} {
+			parser := new(Parser)
+			parser.InitFromString(source)
+			_, errs := parser.ParseProgram()
+			So(len(errs), ShouldEqual, 1)
+			So(errs[0].ErrEnum, ShouldEqual, ParsingUnexpected)
+		}
 	})
 }
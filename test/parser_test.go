@@ -2,9 +2,12 @@ package test
 
 import (
 	. "coral-lang/src/ast"
+	. "coral-lang/src/exception"
 	. "coral-lang/src/lexer"
 	. "coral-lang/src/parser"
 	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"reflect"
 	"testing"
 )
 
@@ -149,10 +152,45 @@ func TestBinaryExpression(t *testing.T) {
 		binaryExpression, isBinary := parser.ParseExpression().(*BinaryExpression)
 		So(isBinary, ShouldEqual, true)
 
-		So(binaryExpression.Operator.Kind, ShouldEqual, TokenTypeStar)
-		So(binaryExpression.Left.(*BasicPrimaryExpression).It.(*OperandName).GetFullName(), ShouldEqual, "n")
-		So(binaryExpression.Right.(*BinaryExpression).Operator.Kind, ShouldEqual, TokenTypeSlash)
-		So(binaryExpression.Right.(*BinaryExpression).Left.(*BinaryExpression).Operator.Kind, ShouldEqual, TokenTypePlus)
+		// 同一优先级的运算符自左向右结合：(n * (n+ 1)) / 2
+		So(binaryExpression.Operator.Kind, ShouldEqual, TokenTypeSlash)
+		So(binaryExpression.Right.(*BasicPrimaryExpression).It.(*DecimalLit).Value.Str, ShouldEqual, "2")
+		product := binaryExpression.Left.(*BinaryExpression)
+		So(product.Operator.Kind, ShouldEqual, TokenTypeStar)
+		So(product.Left.(*BasicPrimaryExpression).It.(*OperandName).GetFullName(), ShouldEqual, "n")
+		So(product.Right.(*BinaryExpression).Operator.Kind, ShouldEqual, TokenTypePlus)
+	})
+
+	Convey("测试二元表达式：结合性", t, func() {
+		parser := new(Parser)
+		parser.InitFromString("a - b - c")
+		binaryExpression := parser.ParseExpression().(*BinaryExpression)
+		So(binaryExpression.Right.(*BasicPrimaryExpression).It.(*OperandName).GetFullName(), ShouldEqual, "c")
+		So(binaryExpression.Left.(*BinaryExpression).Left.(*BasicPrimaryExpression).It.(*OperandName).GetFullName(), ShouldEqual, "a")
+
+		parser.InitFromString("a = b = 1")
+		binaryExpression = parser.ParseExpression().(*BinaryExpression)
+		So(binaryExpression.Left.(*BasicPrimaryExpression).It.(*OperandName).GetFullName(), ShouldEqual, "a")
+		So(binaryExpression.Right.(*BinaryExpression).Left.(*BasicPrimaryExpression).It.(*OperandName).GetFullName(), ShouldEqual, "b")
+
+		// 括号括起的二元表达式作为一个整体，不与外面的运算符结合
+		parser.InitFromString("a * (b + c) - d")
+		binaryExpression = parser.ParseExpression().(*BinaryExpression)
+		So(binaryExpression.Operator.Kind, ShouldEqual, TokenTypeMinus)
+		So(binaryExpression.Parenthesized, ShouldBeFalse)
+		product := binaryExpression.Left.(*BinaryExpression)
+		So(product.Operator.Kind, ShouldEqual, TokenTypeStar)
+		So(product.Parenthesized, ShouldBeFalse)
+		So(product.Right.(*BinaryExpression).Operator.Kind, ShouldEqual, TokenTypePlus)
+		So(product.Right.(*BinaryExpression).Parenthesized, ShouldBeTrue)
+	})
+
+	Convey("测试二元表达式：移位运算符", t, func() {
+		parser := new(Parser)
+		parser.InitFromString("1 << 10 >> a")
+		binaryExpression := parser.ParseExpression().(*BinaryExpression)
+		So(binaryExpression.Operator.Kind, ShouldEqual, TokenTypeDoubleRightAngle)
+		So(binaryExpression.Left.(*BinaryExpression).Operator.Kind, ShouldEqual, TokenTypeDoubleLeftAngle)
 	})
 
 	Convey("测试二元表达式：3 · 类型强转", t, func() {
@@ -505,6 +543,31 @@ func TestImportStatement(t *testing.T) {
 		So(listImportStatement.Elements[1].As.Token.Str, ShouldEqual, "Resp")
 	})
 }
+func TestPackageStatement(t *testing.T) {
+	Convey("测试包声明语句解析：", t, func() {
+		parser := new(Parser)
+		parser.InitFromString("// 注释不算语句\npackage shapes;\nvar r = 1;")
+		program, errs := parser.ParseProgram()
+		So(errs, ShouldBeEmpty)
+		packageStatement, isPackage := program.Root[0].(*PackageStatement)
+		So(isPackage, ShouldEqual, true)
+		So(packageStatement.Name.GetName(), ShouldEqual, "shapes")
+	})
+
+	Convey("测试包声明语句解析：缺少包名，或者不是文件的第一条语句", t, func() {
+		parser := new(Parser)
+		parser.InitFromString("package ;")
+		_, errs := parser.ParseProgram()
+		So(errs[0].ErrEnum, ShouldEqual, NoPackageNameDefinition)
+
+		parser = new(Parser)
+		parser.InitFromString("var r = 1;\npackage shapes;")
+		program, errs := parser.ParseProgram()
+		So(errs, ShouldHaveLength, 1)
+		So(errs[0].Message, ShouldEqual, "package declaration must be the first statement of a file!")
+		So(program.Root, ShouldHaveLength, 2)
+	})
+}
 func TestEnumStatement(t *testing.T) {
 	Convey("测试枚举定义语句解析：", t, func() {
 		parser := new(Parser)
@@ -837,6 +900,39 @@ func TestFnStatement(t *testing.T) {
 		So(fnStatement.Signature.Returns[1].(*TypeName).Identifier.Token.Str, ShouldEqual, "bool")
 		So(fnStatement.Signature.Throws[0].(*TypeName).Identifier.Token.Str, ShouldEqual, "NullPointerException")
 	})
+
+	Convey("测试函数定义语句：泛型参数的约束", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`fn max<T : Comparable<T>, K>(a T, b T) T { return a; }`)
+
+		fnStatement, isFn := parser.ParseStatement().(*FunctionDeclarationStatement)
+		So(isFn, ShouldEqual, true)
+		So(parser.Diagnostics, ShouldBeEmpty)
+
+		constraint := fnStatement.Signature.Generics.Args[0].Constraint.(*GenericsTypeLit)
+		So(constraint.BasicType.Identifier.Token.Str, ShouldEqual, "Comparable")
+		So(constraint.GenericsArgs[0].(*TypeName).Identifier.Token.Str, ShouldEqual, "T")
+		So(fnStatement.Signature.Generics.Args[1].ArgName.Token.Str, ShouldEqual, "K")
+		So(fnStatement.Signature.Generics.Args[1].Constraint, ShouldBeNil)
+	})
+
+	Convey("测试函数定义语句：形参类型的简写", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`fn area(w, h float, name String) float { return w * h; }`)
+
+		fnStatement := parser.ParseStatement().(*FunctionDeclarationStatement)
+		arguments := fnStatement.Signature.Arguments
+		So(len(arguments), ShouldEqual, 3)
+		So(arguments[0].Name.Token.Str, ShouldEqual, "w")
+		So(arguments[0].Type, ShouldEqual, arguments[1].Type) // 简写的形参共用同一个类型节点
+		So(arguments[1].Type.(*TypeName).Identifier.Token.Str, ShouldEqual, "float")
+		So(arguments[2].Name.Token.Str, ShouldEqual, "name")
+
+		parser.InitFromString(`(x, y) -> x + y`)
+		lambda := parser.ParseExpression().(*BasicPrimaryExpression).It.(*LambdaLit)
+		So(len(lambda.Signature.Arguments), ShouldEqual, 2)
+		So(lambda.Signature.Arguments[1].Type, ShouldBeNil)
+	})
 }
 func TestClassStatement(t *testing.T) {
 	Convey("测试类定义语句：", t, func() {
@@ -905,6 +1001,42 @@ func TestClassStatement(t *testing.T) {
 			"rent")
 		So(classStatement.Members[3].(*ClassMemberMethod).Scope, ShouldEqual, ClassMemberScopePublic)
 	})
+
+	Convey("测试类定义语句：静态成员", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`class Counter {
+      public static var count = 0;
+      fn Counter() {}
+      static fn reset() { count = 0; }
+    }`)
+		classStatement, isClass := parser.ParseStatement().(*ClassDeclarationStatement)
+		So(isClass, ShouldEqual, true)
+		So(parser.ErrCount, ShouldEqual, 0)
+
+		count := classStatement.Members[0].(*ClassMemberVar)
+		So(count.Scope, ShouldEqual, ClassMemberScopePublic)
+		So(count.Static, ShouldEqual, true)
+		So(classStatement.Members[1].(*ClassMemberMethod).Static, ShouldEqual, false)
+		reset := classStatement.Members[2].(*ClassMemberMethod)
+		So(reset.Scope, ShouldEqual, ClassMemberScopePrivate)
+		So(reset.Static, ShouldEqual, true)
+	})
+
+	Convey("测试类定义语句：修饰符之后缺少成员定义时报错", t, func() {
+		for source, modifier := range map[string]string{
+			"class X { public static }":           "static",
+			"class X { private }":                 "private",
+			"class X { static }":                  "static",
+			"class X { static public fn f() {} }": "static",
+		} {
+			parser := new(Parser)
+			parser.InitFromString(source)
+			_, errs := parser.ParseProgram()
+			So(len(errs), ShouldEqual, 1)
+			So(errs[0].ErrEnum, ShouldEqual, ParsingUnexpected)
+			So(errs[0].Message, ShouldContainSubstring, "after '"+modifier+"'")
+		}
+	})
 }
 func TestInterfaceStatement(t *testing.T) {
 	Convey("测试接口定义语句：", t, func() {
@@ -965,7 +1097,68 @@ func TestTryCatchStatement(t *testing.T) {
 		So(tryCatchStmt.Finally.Statements[0].(*CallExpression).Operand.(*BasicPrimaryExpression).It.(*OperandName).Name.Token.Str, ShouldEqual, "println")
 		So(tryCatchStmt.Finally.Statements[0].(*CallExpression).Params[0].(*BasicPrimaryExpression).It.(*StringLit).Value.Str, ShouldEqual, "hahaha, it's ok")
 	})
+
+	Convey("测试抛出异常语句：", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`throw new Exception("oops");`)
+
+		throwStmt, isThrow := parser.ParseStatement().(*ThrowStatement)
+		So(isThrow, ShouldEqual, true)
+		So(parser.Diagnostics, ShouldBeEmpty)
+		So(throwStmt.Token.Kind, ShouldEqual, TokenTypeThrow)
+		newInstance := throwStmt.Value.(*NewInstanceExpression)
+		So(newInstance.Class.(*TypeName).Identifier.Token.Str, ShouldEqual, "Exception")
+
+		parser = new(Parser)
+		parser.InitFromString(`throw;`)
+		So(parser.ParseStatement(), ShouldBeNil)
+		So(parser.Diagnostics, ShouldNotBeEmpty)
+	})
 }
+
+func TestDocComments(t *testing.T) {
+	Convey("测试文档注释：连续的 /// 行注释与 /** */ 块注释", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`/// 第一行
+///   第二行
+fn add(a, b int) int { return a + b; }
+
+/**
+ * 颜色
+ *
+ * - 红
+ */
+enum Color { Red }
+
+/// 与定义之间隔着空行，不是文档注释
+
+interface Named { public fn name() String; }
+x = 1; // 行尾的注释
+class Box {
+  /// 构造方法
+  fn Box() {}
+  /// 写在访问修饰符之前
+  public static fn of() Box { return new Box(); }
+  //// 四个斜线只是普通的注释
+  fn hidden() {}
+}`)
+		program, errs := parser.ParseProgram()
+		So(len(errs), ShouldEqual, 0)
+
+		fnStmt := program.Root[0].(*FunctionDeclarationStatement)
+		So(fnStmt.Doc.Text, ShouldEqual, "第一行\n  第二行")
+		So([]int{fnStmt.Doc.Start.Line, fnStmt.Doc.End.Line}, ShouldResemble, []int{1, 2})
+		So(program.Root[1].(*EnumStatement).Doc.Text, ShouldEqual, "颜色\n\n- 红")
+		So(program.Root[2].(*InterfaceDeclarationStatement).Doc, ShouldBeNil)
+
+		members := program.Root[4].(*ClassDeclarationStatement).Members
+		So(program.Root[4].(*ClassDeclarationStatement).Doc, ShouldBeNil)
+		So(members[0].(*ClassMemberMethod).MethodDecl.Doc.Text, ShouldEqual, "构造方法")
+		So(members[1].(*ClassMemberMethod).MethodDecl.Doc.Text, ShouldEqual, "写在访问修饰符之前")
+		So(members[2].(*ClassMemberMethod).MethodDecl.Doc, ShouldBeNil)
+	})
+}
+
 func TestTypeDescriptions(t *testing.T) {
 	Convey("*补充* - 测试解析类型声明 & 一条语句的 lambda：", t, func() {
 		parser := new(Parser)
@@ -990,3 +1183,113 @@ func TestTypeDescriptions(t *testing.T) {
 		So(binaryResult.Operator.Kind, ShouldEqual, TokenTypePlus)
 	})
 }
+
+// 检查语法树中每个节点的范围都不为空，且都落在父节点的范围之内
+func checkSpansNested(node Node, parent *Span, bad *[]string) {
+	span := node.GetSpan()
+	if span.End.Offset <= span.Start.Offset ||
+		(parent != nil && (span.Start.Offset < parent.Start.Offset || span.End.Offset > parent.End.Offset)) {
+		*bad = append(*bad, node.NodeType())
+	}
+	checkChildSpans(reflect.ValueOf(node), span, bad)
+}
+
+func checkChildSpans(v reflect.Value, parent *Span, bad *[]string) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if child, isNode := v.Interface().(Node); isNode && child.GetSpan() != parent {
+			checkSpansNested(child, parent, bad)
+			return
+		}
+		checkChildSpans(v.Elem(), parent, bad)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" && !v.Type().Field(i).Anonymous {
+				checkChildSpans(v.Field(i), parent, bad)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			checkChildSpans(v.Index(i), parent, bad)
+		}
+	}
+}
+
+func TestNodeSpan(t *testing.T) {
+	Convey("测试节点的范围：二元表达式与括号", t, func() {
+		parser := new(Parser)
+		parser.InitFromString("(a + b) * c")
+		binary := parser.ParseExpression().(*BinaryExpression)
+		So(binary.Start, ShouldResemble, Pos{Offset: 0, Line: 1, Col: 1})
+		So(binary.End, ShouldResemble, Pos{Offset: 11, Line: 1, Col: 12})
+		So(binary.Left.GetSpan().End.Offset, ShouldEqual, 7) // 包含右括号
+	})
+
+	Convey("测试节点的范围：跨行的语句", t, func() {
+		parser := new(Parser)
+		parser.InitFromString("var a = 1;\nif a > 0 {\n  a++;\n}")
+		program, _ := parser.ParseProgram()
+
+		varDecl := program.Root[0].GetSpan()
+		So(varDecl.Start.Offset, ShouldEqual, 0)
+		So(varDecl.End.Offset, ShouldEqual, 10) // 包含结尾的分号
+
+		ifStmt := program.Root[1].(*IfStatement)
+		So(ifStmt.Start, ShouldResemble, Pos{Offset: 11, Line: 2, Col: 1})
+		So(ifStmt.End, ShouldResemble, Pos{Offset: 30, Line: 4, Col: 2})
+		So(ifStmt.If.Block.Statements[0].GetSpan().Start, ShouldResemble, Pos{Offset: 24, Line: 3, Col: 3})
+		So(ifStmt.If.Block.Statements[0].GetSpan().Contains(27), ShouldBeTrue)
+	})
+
+	Convey("测试节点的范围：样例文件中所有节点的范围都嵌套在父节点之内", t, func() {
+		content, _ := ioutil.ReadFile("samples/animal.cr")
+		parser := new(Parser)
+		parser.InitFromBytes(content)
+		program, errs := parser.ParseProgram()
+		So(len(errs), ShouldEqual, 0)
+
+		var bad []string
+		for _, stmt := range program.Root {
+			checkSpansNested(stmt, &program.Span, &bad)
+		}
+		So(bad, ShouldBeEmpty)
+		So(program.End.Offset, ShouldEqual, len(content))
+	})
+
+	Convey("测试节点的范围：各类语句中所有节点的范围都嵌套在父节点之内", t, func() {
+		parser := new(Parser)
+		parser.InitFromString(`from "httplib" import Request as Req;
+enum Sex { Male, Female = 2 }
+interface A<T> : B<T> {
+  public fn cc<T>() string throws MMException;
+}
+fn main() {
+  var arr = [1, 2, 3], m = {a: 1};
+  switch arr[0] {
+    case 0...59 { println("Failed."); }
+    default { println(-arr[1:2].length); }
+  }
+  for var i = 0; i < 3; i++ { continue; }
+  each num, i in arr { break; }
+  while arr[0] < 10 { arr[0] += 1; }
+  try {
+    val f = (x int) int -> x * 2 as float;
+  } catch e MathException {
+    println(new Exception<String>(e.message()));
+  } finally {
+    return 0;
+  }
+}`)
+		program, errs := parser.ParseProgram()
+		So(len(errs), ShouldEqual, 0)
+
+		var bad []string
+		for _, stmt := range program.Root {
+			checkSpansNested(stmt, &program.Span, &bad)
+		}
+		So(bad, ShouldBeEmpty)
+	})
+}
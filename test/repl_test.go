@@ -0,0 +1,72 @@
+package test
+
+import (
+	"bytes"
+	. "coral-lang/src/driver"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/repl"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+)
+
+// 以一组输入行运行交互式环境，返回标准输出与标准错误的内容
+func replLines(lines ...string) (string, string) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	renderer, _ := NewDiagnosticRenderer("plain", nil)
+	NewREPL(stdout, stderr, renderer, make(map[string][]byte)).Run(strings.NewReader(strings.Join(lines, "\n")))
+	return stdout.String(), stderr.String()
+}
+
+func TestREPL(t *testing.T) {
+	Convey("测试交互式环境：根据括号与块注释判断输入是否完整", t, func() {
+		So(Incomplete("var x = 1"), ShouldBeFalse)
+		So(Incomplete("fn add(a int,"), ShouldBeTrue)
+		So(Incomplete("var xs = [1, 2,"), ShouldBeTrue)
+		So(Incomplete("if true {\n  println(1);"), ShouldBeTrue)
+		So(Incomplete("/* note"), ShouldBeTrue)
+		So(Incomplete("var s = \"{\""), ShouldBeFalse)
+	})
+
+	Convey("测试交互式环境：输出表达式的值，变量与函数在多次输入之间保留", t, func() {
+		stdout, stderr := replLines(
+			"var x = 40",
+			"x + 2",
+			"fn twice(n int) int {",
+			"  return n * 2;",
+			"}",
+			"twice(x)",
+			"var scores = {math: 90}",
+			`scores["math"]; println("done")`,
+		)
+		So(stderr, ShouldBeEmpty)
+		So(stdout, ShouldEqual, "> > 42\n> ... ... > 80\n> > 90\ndone\n> \n")
+	})
+
+	Convey("测试交互式环境：出错时报告诊断信息，会话继续进行", t, func() {
+		stdout, stderr := replLines(
+			"var y = missing + 1",
+			"var y = 2",
+			"y ** 3",
+			"[1, 2][5]",
+			"y = 'a'",
+			"y",
+			"(1 +",
+		)
+		So(stderr, ShouldContainSubstring, `<repl>:1:9: error[25]: undeclared identifier "missing"!`)
+		So(stderr, ShouldContainSubstring, "<repl>:1:1: error[36]: index 5 out of range [0, 2)!")
+		So(stderr, ShouldContainSubstring, "<repl>:1:5: error[26]: cannot use value of type rune as type int in assignment!")
+		So(stderr, ShouldContainSubstring, "<repl>:1:6: error[8]: Unclosed parentheses '(' !")
+		So(stdout, ShouldEqual, "> > > 8\n> > > 2\n> ... \n")
+	})
+
+	Convey("测试交互式环境：coral repl 从标准输入读取", t, func() {
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		Stdin = strings.NewReader("1 + 1\n")
+		So(Run([]string{"repl"}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldEqual, "> 2\n> \n")
+
+		So(Run([]string{"repl", "a.cr"}, stdout, stderr), ShouldEqual, CommandLineUsageError)
+		So(stderr.String(), ShouldContainSubstring, `unexpected argument "a.cr"`)
+	})
+}
@@ -0,0 +1,227 @@
+package test
+
+import (
+	"bytes"
+	. "coral-lang/src/bytecode"
+	. "coral-lang/src/driver"
+	. "coral-lang/src/exception"
+	. "coral-lang/src/vm"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 编译并执行一段源代码，返回输出的内容与运行时错误
+func runString(content string) (string, *VM, *CoralCompileError) {
+	module, compiler := compileString(content)
+	So(compiler.Errors, ShouldBeEmpty)
+	So(Verify(module), ShouldBeNil)
+	stdout := new(bytes.Buffer)
+	machine := NewVM(module, stdout)
+	err := machine.Run()
+	return stdout.String(), machine, err
+}
+
+func TestVirtualMachine(t *testing.T) {
+	Convey("测试虚拟机：脚本从上到下执行，函数可以递归调用", t, func() {
+		output, _, err := runString(`fn fib(n int) int {
+  if n < 2 { return n; }
+  return fib(n - 1) + fib(n - 2);
+}
+println("start");
+var fibs = [fib(1), fib(10), fib(20)];
+println(fibs);
+fn divmod(a int, b int) int, int { return a / b, a % b; }
+var q = 0, r = 0;
+q, r = divmod(17, 5);
+println(q, r);`)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "start\n[1, 55, 6765]\n3 2\n")
+	})
+
+	Convey("测试虚拟机：循环、分支与 lambda", t, func() {
+		output, _, err := runString(`var sum = 0;
+each v, i in [10, 20, 30] { if i == 1 { continue; } sum += v; }
+for var i = 0; i < 100; i++ { if i > 4 { break; } sum += i; }
+var n = 3;
+while n > 0 { n--; sum++; }
+var twice = (x int) -> x * 2;
+switch twice(sum) {
+  case 1, 2 { println("small"); }
+  case 100..200 { println("medium", sum); }
+  default { println("large"); }
+}
+var ok = sum > 0 && sum != 53 || false;
+println(ok);`)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "medium 53\nfalse\n")
+	})
+
+	Convey("测试虚拟机：整数按位数截断，字符串、数组与表", t, func() {
+		output, _, err := runString(`var small int8 = 100;
+small += 100;
+var big uint64 = 0;
+big--;
+var f float = 0.1;
+println(small, big, f, 7 / 2, 7.0 / 2, 2 ** 10, -7 % 3, 1 << 4 | 1);
+var s = "héllo";
+println(s + "!", s[1], s[1:3]);
+var xs = [3, 1, 2];
+var ys = xs;
+ys[0] = 9;
+println(xs, xs[0:2]);
+var table = {b: 2, a: 1};
+table["c"] = 3;
+each v, k in table { print(k, v, ""); }
+println();
+printf("%d-%s-%.2f-%c\n", 42, "go", 3.14159, 'x');`)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "-56 18446744073709551615 0.1 3 3.5 1024 -1 17\n"+
+			"héllo! é él\n"+
+			"[9, 1, 2] [9, 1]\n"+
+			"a 1 b 2 c 3 \n"+
+			"42-go-3.14-x\n")
+	})
+
+	Convey("测试虚拟机：float 以 32 位精度运算并输出，double 则为 64 位", t, func() {
+		output, _, err := runString(floatPrintingProgram)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "0.1 0.3 0.1\n"+
+			"0.33333334 1 -0.33333334 0.3333333432674408 0.3333333333333333\n"+
+			"[0.1, 2.5] true 0\n"+
+			"0.10\n")
+	})
+
+	Convey("测试虚拟机：运行时错误带有出错的行与调用链", t, func() {
+		output, machine, err := runString(`println("before");
+fn at(xs int[], i int) int {
+  return xs[i];
+}
+var index = 5;
+println(at([1, 2, 3], index));`)
+		So(output, ShouldEqual, "before\n")
+		So(err.ErrEnum, ShouldEqual, RuntimeError)
+		So(err.Message, ShouldContainSubstring, "index 5 out of range [0, 3)")
+		So(machine.Trace, ShouldResemble, []TraceEntry{{Function: "at", Line: 3}, {Function: "<script>", Line: 6}})
+
+		diagnostic := machine.Diagnostic(err)
+		So(diagnostic.Start.Line, ShouldEqual, 3)
+		So(diagnostic.Notes[0].Message, ShouldEqual, "at called from <script>")
+
+		_, _, err = runString(`var zero = 0;
+println(1 / zero);`)
+		So(err.Message, ShouldContainSubstring, "integer division by zero")
+
+		_, machine, err = runString(`fn forever(n int) int { return forever(n + 1); }
+forever(0);`)
+		So(err.Message, ShouldContainSubstring, "stack overflow")
+		So(len(machine.Trace), ShouldEqual, MaxFrames)
+	})
+
+	Convey("测试虚拟机：异常回退到最近的 catch，return、break 与 continue 离开 try 前执行 finally", t, func() {
+		output, _, err := runString(`fn check(n int) int throws Exception {
+  if n > 2 { throw new Exception("too big"); }
+  return n;
+}
+fn find(xs int[], target int) int {
+  each x, i in xs {
+    try { if x == target { return i; } } finally { print("checked", x, ""); }
+  }
+  return -1;
+}
+println(find([5, 6, 7], 6));
+each n in [1, 2, 3, 4] {
+  try {
+    if n == 2 { continue; }
+    if n == 4 { break; }
+    print(check(n), "");
+  } catch e Exception {
+    print(e.message, "");
+  } finally {
+    print("/", "");
+  }
+}
+println();`)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "checked 5 checked 6 1\n1 / / too big / / \n")
+	})
+
+	Convey("测试虚拟机：没有被捕获的异常是运行时错误", t, func() {
+		module, _ := compileString(`var e = new Exception("boom");`)
+		script := module.Functions[module.Entry]
+		// 在脚本末尾的 RETURN 之前抛出 e
+		end := len(script.Code) - Lookup(OpReturn).Length()
+		code := append([]byte(nil), script.Code[:end]...)
+		code = append(code, byte(OpGetGlobal), 0, 0, byte(OpThrow))
+		script.Code = append(code, script.Code[end:]...)
+		So(Verify(module), ShouldBeNil)
+
+		err := NewVM(module, new(bytes.Buffer)).Run()
+		So(err.ErrEnum, ShouldEqual, RuntimeError)
+		So(err.Message, ShouldEqual, "uncaught exception Exception: boom!")
+	})
+}
+
+func TestVerifyBytecode(t *testing.T) {
+	Convey("测试字节码校验：拒绝越界的操作数与跳转", t, func() {
+		module, _ := compileString(`var x = 1;
+println(x);`)
+		So(Verify(module), ShouldBeNil)
+
+		script := module.Functions[module.Entry]
+		code := append([]byte(nil), script.Code...)
+
+		script.Code = append(append([]byte(nil), code...), byte(OpGetGlobal), 9, 0)
+		So(Verify(module).Message, ShouldContainSubstring, "refers to missing global 9")
+
+		script.Code = append(append([]byte(nil), code...), byte(OpJump), 1, 0, 0, 0)
+		So(Verify(module).Message, ShouldContainSubstring, "is not the start of an instruction")
+
+		script.Code = append(append([]byte(nil), code...), byte(OpTry), 1, 0, 0, 0)
+		So(Verify(module).Message, ShouldContainSubstring, "is not the start of an instruction")
+
+		script.Code = append(append([]byte(nil), code...), byte(OpConstant), 0)
+		So(Verify(module).Message, ShouldContainSubstring, "truncated instruction")
+
+		script.Code = append(append([]byte(nil), code...), 0xff)
+		So(Verify(module).ErrEnum, ShouldEqual, BytecodeFormatError)
+	})
+}
+
+func TestDriverRun(t *testing.T) {
+	Convey("测试命令行：run 执行源文件与 .cbytes 文件", t, func() {
+		dir, _ := ioutil.TempDir("", "coral-run")
+		defer os.RemoveAll(dir)
+		source := filepath.Join(dir, "hello.coral")
+		So(ioutil.WriteFile(source, []byte(`var greeting = "hello";
+println(greeting, 1 + 2);`), 0644), ShouldBeNil)
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"run", source}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldEqual, "hello 3\n")
+
+		So(Run([]string{"build", source}, stdout, stderr), ShouldEqual, NormalError)
+		stdout.Reset()
+		So(Run([]string{"run", filepath.Join(dir, "hello"+FileExtension)}, stdout, stderr), ShouldEqual, NormalError)
+		So(stdout.String(), ShouldEqual, "hello 3\n")
+	})
+
+	Convey("测试命令行：run 报告运行时错误", t, func() {
+		dir, _ := ioutil.TempDir("", "coral-run")
+		defer os.RemoveAll(dir)
+		source := filepath.Join(dir, "crash.coral")
+		So(ioutil.WriteFile(source, []byte(`var xs = [1];
+println(xs[1]);`), 0644), ShouldBeNil)
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		So(Run([]string{"run", "-format", "plain", source}, stdout, stderr), ShouldEqual, RuntimeError)
+		So(stderr.String(), ShouldContainSubstring, source+":2:1: error")
+		So(stderr.String(), ShouldContainSubstring, "index 1 out of range [0, 1)")
+
+		bad := filepath.Join(dir, "bad"+FileExtension)
+		So(ioutil.WriteFile(bad, []byte("CBYT"), 0644), ShouldBeNil)
+		So(Run([]string{"run", "-format", "plain", bad}, stdout, stderr), ShouldEqual, BytecodeFormatError)
+	})
+}
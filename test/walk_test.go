@@ -0,0 +1,277 @@
+package test
+
+import (
+	. "coral-lang/src/ast"
+	. "coral-lang/src/parser"
+	. "github.com/smartystreets/goconvey/convey"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// 涵盖各种节点的一段程序
+const walkSource = `package demo;
+import "math" as m;
+from "httplib" import Request as Req;
+from "./lib" import { A, B as C }
+/// 性别
+enum Sex { Male, Female = 2 }
+interface Named<T> : Base<T> {
+  public fn name<U>() String throws MMException;
+}
+class Box<T : Named> : Base<T> <- Named, Other {
+  public static var count int = 0;
+  fn Box() { super(); }
+  public fn get() T { return this.value; }
+}
+fn main(a int, b int, f (int) -> bool) int[] {
+  var arr = [1, 0x1F, 0o17, 0b101, 1.5, 1e3, 'c', nil, true, false], m = {a: 1};
+  val lambda = (x int) int -> x * 2 as float;
+  arr[0], arr[1] = arr[1:2], -arr[0:2].length;
+  a.b.c.d = new Map<String, int[]>(1);
+  switch arr[0] {
+    case 0...59 { println("Failed."); }
+    case 60, 61 { break; }
+    default { println(-arr[1:2].length); }
+  }
+  for var i = 0; i < 3; i++ { continue; }
+  each num, i in arr { break; }
+  while arr[0] < 10 { arr[0] += 1; }
+  if a { a--; } elif b { b++; } else { throw new Exception("oops"); }
+  try {
+    return 1, 2;
+  } catch e MathException {
+    println(e);
+  } finally {
+    return 0;
+  }
+}`
+
+func parseWalkSource(source string) *Program {
+	parser := new(Parser)
+	parser.InitFromString(source)
+	program, errs := parser.ParseProgram()
+	So(errs, ShouldBeEmpty)
+	return program
+}
+
+// 以反射找出全部的子节点，作为 Walk 的参照
+func reflectNodes(v reflect.Value, nodes *[]Node) {
+	switch v.Kind() {
+	case reflect.Interface:
+		reflectNodes(v.Elem(), nodes)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if node, isNode := v.Interface().(Node); isNode {
+			*nodes = append(*nodes, node)
+		}
+		reflectNodes(v.Elem(), nodes)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath == "" && !field.Anonymous && field.Name != "BelongsTo" {
+				reflectNodes(v.Field(i), nodes)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			reflectNodes(v.Index(i), nodes)
+		}
+	}
+}
+
+func countNodeTypes(nodes []Node) map[string]int {
+	counts := make(map[string]int)
+	for _, node := range nodes {
+		counts[node.NodeType()]++
+	}
+	return counts
+}
+
+// 记录访问的顺序与深度
+type depthVisitor struct {
+	depth    *int
+	maxDepth *int
+	visited  *[]Node
+}
+
+func (visitor depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		*visitor.depth--
+		return nil
+	}
+	*visitor.visited = append(*visitor.visited, node)
+	*visitor.depth++
+	if *visitor.depth > *visitor.maxDepth {
+		*visitor.maxDepth = *visitor.depth
+	}
+	return visitor
+}
+
+func TestWalk(t *testing.T) {
+	Convey("测试遍历语法树：访问到的节点与反射找出的节点一致", t, func() {
+		sample, _ := ioutil.ReadFile("samples/animal.cr")
+		for _, source := range []string{walkSource, string(sample)} {
+			program := parseWalkSource(source)
+			var expected, visited []Node
+			reflectNodes(reflect.ValueOf(program.Root), &expected)
+			InspectProgram(program, func(node Node) bool {
+				if node != nil {
+					visited = append(visited, node)
+				}
+				return true
+			})
+			So(len(visited), ShouldEqual, len(expected))
+			So(countNodeTypes(visited), ShouldResemble, countNodeTypes(expected))
+		}
+	})
+
+	Convey("测试遍历语法树：先父后子，子节点按源代码的先后，每个节点之后以 nil 结束", t, func() {
+		program := parseWalkSource(walkSource)
+		depth, maxDepth := 0, 0
+		var visited []Node
+		WalkProgram(depthVisitor{&depth, &maxDepth, &visited}, program)
+		So(depth, ShouldEqual, 0)
+		So(maxDepth, ShouldBeGreaterThan, 5)
+		for i := 1; i < len(visited); i++ {
+			if visited[i].NodeType() == "Doc_Comment" || visited[i-1].NodeType() == "Doc_Comment" {
+				continue // 文档注释在定义之前
+			}
+			So(visited[i].GetSpan().Start.Offset, ShouldBeGreaterThanOrEqualTo, visited[i-1].GetSpan().Start.Offset)
+		}
+	})
+
+	Convey("测试遍历语法树：成员链表、签名与泛型参数，两个形参共用的类型节点访问两次", t, func() {
+		program := parseWalkSource(`a.b.c.d = 1;
+fn pick<T : Named>(x, y T) T throws E { return x; }`)
+		var names []string
+		InspectProgram(program, func(node Node) bool {
+			if id, isIdentifier := node.(*Identifier); isIdentifier {
+				names = append(names, id.GetName())
+			}
+			return true
+		})
+		So(names, ShouldResemble, []string{"a", "b", "c", "d", "pick", "T", "Named", "x", "T", "y", "T", "T", "E", "x"})
+	})
+
+	Convey("测试遍历语法树：f 返回 false 时跳过子节点", t, func() {
+		program := parseWalkSource(walkSource)
+		var functions, identifiers int
+		InspectProgram(program, func(node Node) bool {
+			switch node.(type) {
+			case *FunctionDeclarationStatement:
+				functions++
+				return false
+			case *Identifier:
+				identifiers++
+			}
+			return true
+		})
+		So(functions, ShouldEqual, 3) // 类中的两个方法与 main，其中的标识符都被跳过
+		So(identifiers, ShouldBeGreaterThan, 0)
+
+		var visited int
+		Inspect(program.Root[0], func(node Node) bool {
+			if node != nil {
+				visited++
+			}
+			return true
+		})
+		So(visited, ShouldEqual, 2) // package 语句及其名称
+	})
+}
+
+func TestApply(t *testing.T) {
+	Convey("测试改写语法树：替换节点", t, func() {
+		program := parseWalkSource(`val x = a + b * c;`)
+		expr := program.Root[0].(*VarDeclStatement).Declarations[0].InitValue
+
+		// 交换每个二元表达式的左右两侧
+		result := Apply(expr, nil, func(cursor *Cursor) bool {
+			if binary, isBinary := cursor.Node().(*BinaryExpression); isBinary {
+				binary.Left, binary.Right = binary.Right, binary.Left
+			}
+			return true
+		})
+		So(result, ShouldEqual, expr)
+		var names []string
+		Inspect(expr, func(node Node) bool {
+			if id, isIdentifier := node.(*Identifier); isIdentifier {
+				names = append(names, id.GetName())
+			}
+			return true
+		})
+		So(names, ShouldResemble, []string{"c", "b", "a"})
+
+		// 以右侧替换整个二元表达式，包括根节点；替换之后的节点被继续遍历
+		var parents []string
+		result = Apply(expr, func(cursor *Cursor) bool {
+			if binary, isBinary := cursor.Node().(*BinaryExpression); isBinary {
+				cursor.Replace(binary.Right)
+			}
+			if cursor.Parent() != nil {
+				parents = append(parents, cursor.Parent().NodeType()+"."+cursor.Name())
+			}
+			return true
+		}, nil)
+		So(result.(*BasicPrimaryExpression).It.(*OperandName).GetFullName(), ShouldEqual, "a")
+		So(parents, ShouldResemble, []string{"Basic_Primary_Expression.It", "Operand_Name.Name"})
+		So(program.Root[0].(*VarDeclStatement).Declarations[0].InitValue, ShouldEqual, expr)
+	})
+
+	Convey("测试改写语法树：删除与插入切片中的节点", t, func() {
+		program := parseWalkSource(`fn main() {
+  println(1);
+  trace(2);
+  println(3);
+  trace(4);
+}
+trace(5);`)
+		var visited []int
+		ApplyProgram(program, func(cursor *Cursor) bool {
+			call, isCall := cursor.Node().(*CallExpression)
+			if !isCall || cursor.Index() < 0 {
+				return true
+			}
+			visited = append(visited, cursor.Index())
+			if call.Operand.(*BasicPrimaryExpression).It.(*OperandName).GetFullName() == "trace" {
+				cursor.Delete()
+			} else {
+				cursor.InsertBefore(call.Params[0])
+				cursor.InsertAfter(call.Params[0])
+			}
+			return false
+		}, nil)
+		So(visited, ShouldResemble, []int{0, 3, 3, 6, 1}) // 插入与删除之后的下标
+		So(len(program.Root), ShouldEqual, 1)
+		statements := program.Root[0].(*FunctionDeclarationStatement).Block.Statements
+		So(len(statements), ShouldEqual, 6)
+		So(statements[1].NodeType(), ShouldEqual, "Call_Expression")
+		So(statements[0], ShouldEqual, statements[1].(*CallExpression).Params[0])
+		So(statements[2], ShouldEqual, statements[1].(*CallExpression).Params[0])
+	})
+
+	Convey("测试改写语法树：post 返回 false 时结束遍历，类型不符的替换会 panic", t, func() {
+		program := parseWalkSource(walkSource)
+		var visited int
+		ApplyProgram(program, func(cursor *Cursor) bool {
+			visited++
+			return true
+		}, func(cursor *Cursor) bool {
+			_, isEnum := cursor.Node().(*EnumStatement)
+			return !isEnum
+		})
+		So(visited, ShouldBeLessThan, 30)
+
+		So(func() {
+			ApplyProgram(program, func(cursor *Cursor) bool {
+				if _, isPackage := cursor.Node().(*PackageStatement); isPackage {
+					cursor.Replace(&Identifier{})
+				}
+				return true
+			}, nil)
+		}, ShouldPanic)
+	})
+}